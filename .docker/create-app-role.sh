@@ -0,0 +1,16 @@
+#!/bin/sh
+# Creates the role the services connect as. It is neither a superuser nor
+# has BYPASSRLS, so the row-level security policies apply to it. The tables
+# created later by the migrations are granted to it too.
+set -e
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<-EOSQL
+	CREATE ROLE "$PG_APP_USER" LOGIN PASSWORD '$PG_APP_PASS' NOSUPERUSER NOBYPASSRLS;
+	GRANT USAGE ON SCHEMA public TO "$PG_APP_USER";
+	GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO "$PG_APP_USER";
+	GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO "$PG_APP_USER";
+	ALTER DEFAULT PRIVILEGES FOR ROLE "$POSTGRES_USER" IN SCHEMA public
+		GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO "$PG_APP_USER";
+	ALTER DEFAULT PRIVILEGES FOR ROLE "$POSTGRES_USER" IN SCHEMA public
+		GRANT USAGE, SELECT ON SEQUENCES TO "$PG_APP_USER";
+EOSQL
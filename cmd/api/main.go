@@ -12,15 +12,31 @@ import (
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/romankravchuk/eldorado/internal/config"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/envelope"
 	"github.com/romankravchuk/eldorado/internal/pkg/logger"
 	"github.com/romankravchuk/eldorado/internal/pkg/sl"
 	"github.com/romankravchuk/eldorado/internal/server/http/api"
+	"github.com/romankravchuk/eldorado/internal/server/http/debug"
 	"github.com/romankravchuk/eldorado/internal/server/http/handlers"
+	accounthandlers "github.com/romankravchuk/eldorado/internal/server/http/handlers/account"
+	adminhandlers "github.com/romankravchuk/eldorado/internal/server/http/handlers/admin"
 	authhandlers "github.com/romankravchuk/eldorado/internal/server/http/handlers/auth"
+	settingshandlers "github.com/romankravchuk/eldorado/internal/server/http/handlers/settings"
 	taskshandlers "github.com/romankravchuk/eldorado/internal/server/http/handlers/tasks"
+	templateshandlers "github.com/romankravchuk/eldorado/internal/server/http/handlers/templates"
 	"github.com/romankravchuk/eldorado/internal/server/http/middleware"
+	"github.com/romankravchuk/eldorado/internal/services/archive"
 	"github.com/romankravchuk/eldorado/internal/services/auth/client"
+	"github.com/romankravchuk/eldorado/internal/services/export"
+	"github.com/romankravchuk/eldorado/internal/services/settings"
 	"github.com/romankravchuk/eldorado/internal/services/tasks"
+	"github.com/romankravchuk/eldorado/internal/services/templates"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/cache/codec"
+	"github.com/romankravchuk/eldorado/internal/storages/migrations"
+	"github.com/romankravchuk/eldorado/internal/storages/resilience"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks/pg"
 )
 
 func init() {
@@ -36,21 +52,250 @@ func main() {
 
 	log := logger.New(cfg.Env, os.Stderr)
 
+	debug.Serve(cfg.MetricsAddr, log)
+
+	poolCfg := storages.PoolConfig{
+		MaxOpenConns:    cfg.Postgres.MaxOpenConns,
+		MaxIdleConns:    cfg.Postgres.MaxIdleConns,
+		ConnMaxLifetime: cfg.Postgres.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Postgres.ConnMaxIdleTime,
+		DialTimeout:     cfg.Postgres.DialTimeout,
+		PingTimeout:     cfg.Postgres.PingTimeout,
+		ConnectAttempts: cfg.Postgres.ConnectAttempts,
+		ConnectBackoff:  cfg.Postgres.ConnectBackoff,
+	}
+	redisCfg := storages.RedisConfig{
+		PoolSize:        cfg.Redis.PoolSize,
+		MinIdleConns:    cfg.Redis.MinIdleConns,
+		MaxIdleConns:    cfg.Redis.MaxIdleConns,
+		ConnMaxLifetime: cfg.Redis.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Redis.ConnMaxIdleTime,
+		DialTimeout:     cfg.Redis.DialTimeout,
+		PingTimeout:     cfg.Redis.PingTimeout,
+		ConnectAttempts: cfg.Redis.ConnectAttempts,
+		ConnectBackoff:  cfg.Redis.ConnectBackoff,
+	}
+
+	// No keys disable the encryption of the task contents.
+	var keyring *envelope.Keyring
+	if len(cfg.Encryption.Keys) > 0 {
+		keyring, err = envelope.NewKeyring(cfg.Encryption.Keys, cfg.Encryption.PrimaryKey)
+		if err != nil {
+			slog.Error("failed to load encryption keys", sl.Err(err))
+			os.Exit(1)
+		}
+	}
+	if keyring != nil && cfg.Database.Driver != storages.DriverPostgres {
+		log.Warn("task encryption is only supported by postgres, tasks are stored in plaintext")
+	}
+
+	dbURL := cfg.Database.URL(cfg.Postgres)
+
+	if cfg.Database.CheckSchema {
+		if err := migrations.CheckSchema(context.Background(), cfg.Database.Driver, dbURL, storages.WithPoolConfig(poolCfg)); err != nil {
+			slog.Error("failed to check database schema", sl.Err(err))
+			os.Exit(1)
+		}
+	}
+
+	if cfg.Database.Driver == storages.DriverPostgres {
+		if err := storages.CheckRowSecurity(context.Background(), dbURL, storages.WithPoolConfig(poolCfg)); err != nil {
+			slog.Error("failed to check workspace isolation", sl.Err(err))
+			os.Exit(1)
+		}
+	}
+
+	// The services share one pool, so the pool settings bound the
+	// connections of the process.
+	db, err := storages.OpenDB(cfg.Database.Driver, dbURL, storages.WithPoolConfig(poolCfg))
+	if err != nil {
+		slog.Error("failed to open database", sl.Err(err))
+		os.Exit(1)
+	}
+
 	authClient, err := client.New(cfg.AuthServiceAddr)
 	if err != nil {
 		slog.Error("failed to create auth service client", sl.Err(err))
 		os.Exit(1)
 	}
 
+	var cacheOpt tasks.Option
+	switch {
+	case cfg.Redis.URL == "":
+		log.Warn("redis url is not set, tasks are cached in memory")
+		cacheOpt = tasks.WithMemoryCache(cfg.Cache.LocalSize, cfg.Redis.TTL)
+	case cfg.Cache.LocalSize > 0:
+		cacheOpt = tasks.WithTieredCache(
+			cfg.Redis.URL,
+			cfg.Cache.InvalidationChannel,
+			cfg.Cache.LocalSize,
+			cfg.Cache.LocalTTL,
+			cfg.Redis.TTL,
+			storages.WithRedisConfig(redisCfg),
+		)
+	default:
+		cacheOpt = tasks.WithRedisCache(cfg.Redis.URL, cfg.Redis.TTL, storages.WithRedisConfig(redisCfg))
+	}
+
+	format, err := codec.ParseFormat(cfg.Cache.Codec)
+	if err != nil {
+		slog.Error("failed to parse cache codec", sl.Err(err))
+		os.Exit(1)
+	}
+
+	versioned, err := codec.New(format, cfg.Cache.Compress)
+	if err != nil {
+		slog.Error("failed to create cache codec", sl.Err(err))
+		os.Exit(1)
+	}
+
+	var cacheCodec codec.Codec = versioned
+	if keyring != nil {
+		cacheCodec = codec.NewEncrypted(versioned, keyring)
+	}
+
+	var replicas *storages.Replicas
+	if len(cfg.Postgres.Replicas) > 0 {
+		replicas, err = storages.NewReplicas(cfg.Postgres.Replicas,
+			storages.WithHealthCheck(cfg.Postgres.ReplicaCheckInterval, cfg.Postgres.ReplicaCheckTimeout),
+			storages.WithReplicasPool(poolCfg),
+			storages.WithReadYourWrites(cfg.Postgres.ReadYourWrites, func(ctx context.Context) string {
+				userID, _ := ctx.Value(api.UserIDKey).(string)
+				return userID
+			}),
+		)
+		if err != nil {
+			slog.Error("failed to open postgres replicas", sl.Err(err))
+			os.Exit(1)
+		}
+		log.Info("postgres replicas opened",
+			slog.Int("count", len(cfg.Postgres.Replicas)),
+			slog.Int("healthy", replicas.Healthy()),
+		)
+	}
+
 	svc, err := tasks.New(
-		tasks.WithTaskPostgresStorage(cfg.Postgres.URL),
-		tasks.WithRedisCache(cfg.Redis.URL, cfg.Redis.TTL),
+		tasks.WithTaskDBPool(cfg.Database.Driver, db, replicas, keyring),
+		cacheOpt,
+		tasks.WithCodec(cacheCodec),
+		tasks.WithCoalescing(cfg.Cache.Coalesce),
+		tasks.WithStaleWhileRevalidate(cfg.Cache.StaleTTL),
+		tasks.WithEvents(cfg.Outbox.Enabled),
+		tasks.WithEventContents(keyring == nil),
+		tasks.WithResilience(resilience.Config{
+			Retries:          cfg.Resilience.Tasks.Retries,
+			BaseDelay:        cfg.Resilience.Tasks.BaseDelay,
+			MaxDelay:         cfg.Resilience.Tasks.MaxDelay,
+			FailureThreshold: cfg.Resilience.Tasks.BreakerFailures,
+			OpenTimeout:      cfg.Resilience.Tasks.BreakerTimeout,
+			CallTimeout:      cfg.Resilience.Tasks.CallTimeout,
+		}),
+		tasks.WithLogger(log),
 	)
 	if err != nil {
 		slog.Error("failed to create tasks service", sl.Err(err))
 		os.Exit(1)
 	}
 
+	listenCtx, stopListening := context.WithCancel(context.Background())
+	defer stopListening()
+
+	// SQLite has no NOTIFY, there only the api writes the tasks.
+	if cfg.Cache.ListenPostgres && cfg.Database.Driver == storages.DriverPostgres {
+		go pg.ListenForever(listenCtx, dbURL,
+			func(workspaceID, userID string) {
+				ctx, cancel := context.WithTimeout(storages.WithWorkspace(listenCtx, workspaceID), time.Second)
+				defer cancel()
+
+				svc.Invalidate(ctx, userID)
+			},
+			func() {
+				// The changes made while the listener was down could be
+				// of any list.
+				log.Warn("tasks changes listener reconnected, resetting the tasks cache")
+				svc.ResetCache()
+			},
+			func(err error) {
+				log.Error("failed to listen to tasks changes, restarting", sl.Err(err))
+			},
+		)
+	}
+
+	templatesSvc, err := templates.New(
+		templates.WithTemplatesDBPool(cfg.Database.Driver, db, keyring),
+		templates.WithTasks(svc),
+	)
+	if err != nil {
+		slog.Error("failed to create templates service", sl.Err(err))
+		os.Exit(1)
+	}
+
+	settingsSvc, err := settings.New(
+		settings.WithSettingsDBPool(cfg.Database.Driver, db),
+		settings.WithDefaults(data.Settings{ArchiveAfterDays: cfg.Archive.AfterDays}),
+	)
+	if err != nil {
+		slog.Error("failed to create settings service", sl.Err(err))
+		os.Exit(1)
+	}
+
+	archiveLock, err := storages.NewLock(db, cfg.Database.Driver, archive.LockID)
+	if err != nil {
+		slog.Error("failed to create archive lock", sl.Err(err))
+		os.Exit(1)
+	}
+
+	archiver, err := archive.New(
+		archive.WithLogger(log),
+		archive.WithArchiver(svc),
+		archive.WithLock(archiveLock),
+		archive.WithCron(cfg.Archive.Schedule),
+		archive.WithDefaultDays(cfg.Archive.AfterDays),
+	)
+	if err != nil {
+		slog.Error("failed to create archive service", sl.Err(err))
+		os.Exit(1)
+	}
+
+	if err = archiver.Start(); err != nil {
+		slog.Error("failed to schedule tasks archiving", sl.Err(err))
+		os.Exit(1)
+	}
+
+	exportCtx, stopExports := context.WithCancel(context.Background())
+	defer stopExports()
+
+	var exportSvc *export.Service
+	exportsDone := make(chan struct{})
+	if cfg.Export.Secret != "" {
+		opts := []export.Option{
+			export.WithDBPool(cfg.Database.Driver, db, keyring),
+			export.WithRabbitMQ(cfg.RabbitMQ.URL, cfg.RabbitMQ.QueueName),
+			export.WithLinks(cfg.Export.BaseURL, cfg.Export.Secret, cfg.Export.TTL),
+			export.WithPolling(cfg.Export.Interval),
+			export.WithLogger(log),
+		}
+		if cfg.Redis.URL != "" {
+			opts = append(opts, export.WithRedisSessionsStorage(cfg.Redis.URL, storages.WithRedisConfig(redisCfg)))
+		} else {
+			log.Warn("redis url is not set, sessions are not exported")
+		}
+
+		exportSvc, err = export.New(opts...)
+		if err != nil {
+			slog.Error("failed to create export service", sl.Err(err))
+			os.Exit(1)
+		}
+
+		go func() {
+			defer close(exportsDone)
+			exportSvc.Run(exportCtx)
+		}()
+	} else {
+		close(exportsDone)
+		log.Warn("export secret is not set, data exports are disabled")
+	}
+
 	mux := chi.NewMux()
 	mux.NotFound(api.MakeHTTPHandlerFunc(handlers.Handle404))
 	mux.MethodNotAllowed(api.MakeHTTPHandlerFunc(handlers.Handle404))
@@ -69,11 +314,49 @@ func main() {
 		r.With(middleware.JWT(log, authClient)).Route("/tasks", func(r chi.Router) {
 			r.Post("/", api.MakeHTTPHandlerFunc(taskshandlers.HandleCreateTask(log, svc)))
 			r.Get("/", api.MakeHTTPHandlerFunc(taskshandlers.HandleGetTasks(log, svc)))
+			r.Post("/quick", api.MakeHTTPHandlerFunc(taskshandlers.HandleQuickAddTask(log, svc)))
+			r.Get("/archive", api.MakeHTTPHandlerFunc(taskshandlers.HandleGetArchivedTasks(log, svc)))
 			r.Route("/{id}", func(r chi.Router) {
 				r.Put("/", api.MakeHTTPHandlerFunc(taskshandlers.HandleUpdateTask(log, svc)))
 				r.Delete("/", api.MakeHTTPHandlerFunc(taskshandlers.HandleDeleteTask(log, svc)))
+				r.Post("/snooze", api.MakeHTTPHandlerFunc(taskshandlers.HandleSnoozeTask(log, svc)))
+				r.Delete("/snooze", api.MakeHTTPHandlerFunc(taskshandlers.HandleUnsnoozeTask(log, svc)))
+				r.Post("/unarchive", api.MakeHTTPHandlerFunc(taskshandlers.HandleUnarchiveTask(log, svc)))
+				r.Route("/dependencies", func(r chi.Router) {
+					r.Get("/", api.MakeHTTPHandlerFunc(taskshandlers.HandleGetDependencies(log, svc)))
+					r.Post("/", api.MakeHTTPHandlerFunc(taskshandlers.HandleAddDependency(log, svc)))
+					r.Delete("/{blockerID}", api.MakeHTTPHandlerFunc(taskshandlers.HandleRemoveDependency(log, svc)))
+				})
+			})
+		})
+		r.With(middleware.JWT(log, authClient)).Route("/templates", func(r chi.Router) {
+			r.Post("/", api.MakeHTTPHandlerFunc(templateshandlers.HandleCreateTemplate(log, templatesSvc)))
+			r.Get("/", api.MakeHTTPHandlerFunc(templateshandlers.HandleGetTemplates(log, templatesSvc)))
+			r.Route("/{id}", func(r chi.Router) {
+				r.Get("/", api.MakeHTTPHandlerFunc(templateshandlers.HandleGetTemplate(log, templatesSvc)))
+				r.Delete("/", api.MakeHTTPHandlerFunc(templateshandlers.HandleDeleteTemplate(log, templatesSvc)))
+				r.Post("/instantiate", api.MakeHTTPHandlerFunc(templateshandlers.HandleInstantiateTemplate(log, templatesSvc)))
+			})
+		})
+		if cfg.Admin.Token != "" {
+			r.With(middleware.Admin(log, cfg.Admin.Token)).Route("/admin", func(r chi.Router) {
+				r.Get("/cache/users/{userID}", api.MakeHTTPHandlerFunc(adminhandlers.HandleInspectUserCache(log, svc)))
+				r.Delete("/cache/users/{userID}", api.MakeHTTPHandlerFunc(adminhandlers.HandleFlushUserCache(log, svc)))
 			})
+		}
+		r.With(middleware.JWT(log, authClient)).Route("/settings", func(r chi.Router) {
+			r.Get("/", api.MakeHTTPHandlerFunc(settingshandlers.HandleGetSettings(log, settingsSvc)))
+			r.Put("/", api.MakeHTTPHandlerFunc(settingshandlers.HandleUpdateSettings(log, settingsSvc)))
+		})
+		r.With(middleware.JWT(log, authClient)).Route("/me", func(r chi.Router) {
+			r.Delete("/", api.MakeHTTPHandlerFunc(accounthandlers.HandleDeleteAccount(log, authClient)))
+			if exportSvc != nil {
+				r.Post("/export", api.MakeHTTPHandlerFunc(accounthandlers.HandleRequestExport(log, exportSvc)))
+			}
 		})
+		if exportSvc != nil {
+			r.Get("/exports/{id}", api.MakeHTTPHandlerFunc(accounthandlers.HandleDownloadExport(log, exportSvc)))
+		}
 	})
 
 	srv := http.Server{
@@ -100,15 +383,35 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
 	defer cancel()
 
-	go func() {
-		if err := srv.Shutdown(ctx); err != nil {
-			slog.Error("failed to gracefully shutdown server", sl.Err(err))
-			os.Exit(1)
+	// The requests in flight still use the services, they are closed
+	// after the server in reverse order of their construction.
+	code := 0
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("failed to gracefully shutdown server", sl.Err(err))
+		code = 1
+	}
+
+	stopExports()
+	<-exportsDone
+
+	if exportSvc != nil {
+		if err := exportSvc.Close(); err != nil {
+			slog.Error("failed to close export service", sl.Err(err))
 		}
-	}()
+	}
+
+	archiver.Stop()
+	stopListening()
+	svc.Close()
 
-	<-ctx.Done()
+	if err := replicas.Close(); err != nil {
+		slog.Error("failed to close postgres replicas", sl.Err(err))
+	}
+
+	if err := storages.ClosePool(db); err != nil {
+		slog.Error("failed to close database", sl.Err(err))
+	}
 
 	slog.Info("server successfully sutdown")
-	os.Exit(0)
+	os.Exit(code)
 }
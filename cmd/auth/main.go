@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"net"
 	"os"
@@ -11,8 +12,13 @@ import (
 	"github.com/romankravchuk/eldorado/internal/pkg/logger"
 	"github.com/romankravchuk/eldorado/internal/pkg/sl"
 	"github.com/romankravchuk/eldorado/internal/pkg/validator"
+	"github.com/romankravchuk/eldorado/internal/server/http/debug"
 	"github.com/romankravchuk/eldorado/internal/services/auth"
 	"github.com/romankravchuk/eldorado/internal/services/auth/proto"
+	"github.com/romankravchuk/eldorado/internal/services/deletion"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/migrations"
+	"github.com/romankravchuk/eldorado/internal/storages/resilience"
 	"google.golang.org/grpc"
 )
 
@@ -29,6 +35,10 @@ var serviceRules = map[string]map[string]string{
 	"refresh": {
 		"Refresh": "required",
 	},
+	"delete-account": {
+		"UserID":   "required,uuid",
+		"Password": "required",
+	},
 }
 
 func main() {
@@ -37,22 +47,98 @@ func main() {
 
 	log := logger.New(cfg.Env, os.Stderr)
 
+	poolCfg := storages.PoolConfig{
+		MaxOpenConns:    cfg.Postgres.MaxOpenConns,
+		MaxIdleConns:    cfg.Postgres.MaxIdleConns,
+		ConnMaxLifetime: cfg.Postgres.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Postgres.ConnMaxIdleTime,
+		DialTimeout:     cfg.Postgres.DialTimeout,
+		PingTimeout:     cfg.Postgres.PingTimeout,
+		ConnectAttempts: cfg.Postgres.ConnectAttempts,
+		ConnectBackoff:  cfg.Postgres.ConnectBackoff,
+	}
+	redisCfg := storages.RedisConfig{
+		PoolSize:        cfg.Redis.PoolSize,
+		MinIdleConns:    cfg.Redis.MinIdleConns,
+		MaxIdleConns:    cfg.Redis.MaxIdleConns,
+		ConnMaxLifetime: cfg.Redis.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Redis.ConnMaxIdleTime,
+		DialTimeout:     cfg.Redis.DialTimeout,
+		PingTimeout:     cfg.Redis.PingTimeout,
+		ConnectAttempts: cfg.Redis.ConnectAttempts,
+		ConnectBackoff:  cfg.Redis.ConnectBackoff,
+	}
+
+	debug.Serve(cfg.MetricsAddr, log)
+
 	log.Debug("the debug mode is activated")
 	log.Debug("config loaded", slog.Any("cfg", cfg))
 
+	dbURL := cfg.Database.URL(cfg.Postgres)
+
+	if cfg.Database.CheckSchema {
+		err = migrations.CheckSchema(context.Background(), cfg.Database.Driver, dbURL, storages.WithPoolConfig(poolCfg))
+		failOnError("failed to check database schema", err)
+	}
+
+	if cfg.Database.Driver == storages.DriverPostgres {
+		err = storages.CheckRowSecurity(context.Background(), dbURL, storages.WithPoolConfig(poolCfg))
+		failOnError("failed to check workspace isolation", err)
+	}
+
 	validator.RegisterRules(&proto.SignUpRequest{}, serviceRules["sign-up"])
 	validator.RegisterRules(&proto.TokenRequest{}, serviceRules["token"])
 	validator.RegisterRules(&proto.RefreshRequest{}, serviceRules["refresh"])
+	validator.RegisterRules(&proto.DeleteAccountRequest{}, serviceRules["delete-account"])
+
+	var replicas *storages.Replicas
+	if len(cfg.Postgres.Replicas) > 0 {
+		// Without a user in the context a write pins the reads of all
+		// clients, so a user can sign in right after signing up.
+		replicas, err = storages.NewReplicas(cfg.Postgres.Replicas,
+			storages.WithHealthCheck(cfg.Postgres.ReplicaCheckInterval, cfg.Postgres.ReplicaCheckTimeout),
+			storages.WithReplicasPool(poolCfg),
+			storages.WithReadYourWrites(cfg.Postgres.ReadYourWrites, nil),
+		)
+		failOnError("failed to open postgres replicas", err)
+	}
 
-	svc, err := auth.New(
+	opts := []auth.Option{
 		auth.WithLogger(log),
-		auth.WithUsersPostgresStorage(cfg.Postgres.URL),
-		auth.WtihRedisSessionsStorage(cfg.Redis.URL),
+		auth.WithUsersDBStorage(cfg.Database.Driver, dbURL, replicas, storages.WithPoolConfig(poolCfg)),
+		auth.WithEvents(cfg.Outbox.Enabled),
+		auth.WithResilience(resilience.Config{
+			Retries:          cfg.Resilience.Users.Retries,
+			BaseDelay:        cfg.Resilience.Users.BaseDelay,
+			MaxDelay:         cfg.Resilience.Users.MaxDelay,
+			FailureThreshold: cfg.Resilience.Users.BreakerFailures,
+			OpenTimeout:      cfg.Resilience.Users.BreakerTimeout,
+			CallTimeout:      cfg.Resilience.Users.CallTimeout,
+		}),
+		auth.WtihRedisSessionsStorage(cfg.Redis.URL, storages.WithRedisConfig(redisCfg)),
 		auth.WithAccessCreds(cfg.AccessCreds.PrivateKey, cfg.AccessCreds.PublicKey, cfg.AccessCreds.Expires),
 		auth.WithRefreshCreds(cfg.RefreshCreds.PrivateKey, cfg.RefreshCreds.PublicKey, cfg.RefreshCreds.Expires),
-	)
+		auth.WithGracePeriod(cfg.Deletion.GracePeriod),
+	}
+	if cfg.RabbitMQ.URL != "" {
+		opts = append(opts, auth.WithRabbitMQ(cfg.RabbitMQ.URL, cfg.RabbitMQ.QueueName))
+	} else {
+		log.Warn("rabbitmq url is not set, account deletions are not confirmed by email")
+	}
+
+	svc, err := auth.New(opts...)
 	failOnError("failed to create auth service", err)
 
+	purger, err := deletion.New(
+		deletion.WithLogger(log),
+		deletion.WithPurger(svc),
+		deletion.WithCron(cfg.Deletion.Schedule),
+	)
+	failOnError("failed to create deletion service", err)
+
+	err = purger.Start()
+	failOnError("failed to schedule deleted accounts purge", err)
+
 	lis, err := net.Listen("tcp", ":"+cfg.Port)
 	failOnError("failed to create listener", err)
 
@@ -73,6 +159,15 @@ func main() {
 	log.Info("the auth service stopped")
 
 	gsrv.GracefulStop()
+	purger.Stop()
+
+	if err := svc.Close(); err != nil {
+		log.Error("failed to close auth service", sl.Err(err))
+	}
+
+	if err := replicas.Close(); err != nil {
+		log.Error("failed to close postgres replicas", sl.Err(err))
+	}
 	os.Exit(0)
 }
 
@@ -0,0 +1,140 @@
+// Command migrate applies the database migrations embedded in the binary.
+//
+//	migrate [-driver postgres|sqlite] [-url URL] up
+//	migrate [-driver postgres|sqlite] [-url URL] down N
+//	migrate [-driver postgres|sqlite] [-url URL] status
+//	migrate [-driver postgres|sqlite] [-url URL] force V
+//
+// The driver defaults to the DB_DRIVER environment variable or postgres.
+// The url of postgres defaults to PG_URL and the url of sqlite, the path to
+// the database file, to SQLITE_PATH.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/migrations"
+)
+
+func init() {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo})))
+}
+
+func main() {
+	driver := flag.String("driver", envOr("DB_DRIVER", storages.DriverPostgres), "database driver: postgres or sqlite")
+	url := flag.String("url", "", "postgres url or sqlite database path")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-driver DRIVER] [-url URL] up | down N | status | force V\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *url == "" {
+		switch *driver {
+		case storages.DriverPostgres:
+			*url = os.Getenv("PG_URL")
+		case storages.DriverSQLite:
+			*url = os.Getenv("SQLITE_PATH")
+		}
+	}
+
+	if *url == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+
+	db, err := storages.OpenDB(*driver, *url)
+	if err != nil {
+		slog.Error("failed to connect to database", sl.Err(err))
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	m, err := migrations.New(db, migrations.WithDriver(*driver))
+	if err != nil {
+		slog.Error("failed to load migrations", sl.Err(err))
+		os.Exit(1)
+	}
+
+	if err := run(ctx, m, flag.Args()); err != nil {
+		if errors.Is(err, migrations.ErrNoChange) {
+			slog.Info("no change")
+			return
+		}
+		slog.Error("failed to migrate", sl.Err(err))
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, m *migrations.Migrator, args []string) error {
+	switch args[0] {
+	case "up":
+		n, err := m.Up(ctx)
+		if n > 0 {
+			slog.Info("migrations applied", slog.Int("count", n))
+		}
+		return err
+	case "down":
+		count, err := intArg(args)
+		if err != nil {
+			return err
+		}
+		n, err := m.Down(ctx, int(count))
+		if n > 0 {
+			slog.Info("migrations reverted", slog.Int("count", n))
+		}
+		return err
+	case "force":
+		version, err := intArg(args)
+		if err != nil {
+			return err
+		}
+		if err := m.Force(ctx, version); err != nil {
+			return err
+		}
+		slog.Info("version forced", slog.Uint64("version", version))
+		return nil
+	case "status":
+		s, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("version: %d (latest %d), dirty: %t\n", s.Version, s.Latest, s.Dirty)
+		for _, mig := range s.Applied {
+			fmt.Printf("  applied  %06d_%s\n", mig.Version, mig.Name)
+		}
+		for _, mig := range s.Pending {
+			fmt.Printf("  pending  %06d_%s\n", mig.Version, mig.Name)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func intArg(args []string) (uint64, error) {
+	if len(args) != 2 {
+		return 0, fmt.Errorf("%s needs exactly one number", args[0])
+	}
+	return strconv.ParseUint(args[1], 10, 64)
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
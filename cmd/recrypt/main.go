@@ -0,0 +1,126 @@
+// Command recrypt encrypts the task contents under the primary encryption
+// key. The tasks and the templates stored in plaintext are encrypted, the
+// data keys wrapped with the other keys are rewrapped, so the old keys can
+// be dropped from the configuration afterwards.
+//
+//	recrypt [-batch N]
+//	recrypt -decrypt [-batch N]
+//
+// With -decrypt the encrypted tasks and templates are stored in plaintext, which the down
+// migration of the encryption needs. The keys and the database are read
+// from the configuration of the api, see API_CONFIG_PATH.
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/romankravchuk/eldorado/internal/config"
+	"github.com/romankravchuk/eldorado/internal/pkg/envelope"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	taskspg "github.com/romankravchuk/eldorado/internal/storages/tasks/pg"
+	templatespg "github.com/romankravchuk/eldorado/internal/storages/templates/pg"
+)
+
+func init() {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo})))
+}
+
+func main() {
+	batch := flag.Int("batch", 500, "rows rewritten in one transaction")
+	decrypt := flag.Bool("decrypt", false, "store the encrypted tasks and templates in plaintext")
+	flag.Parse()
+
+	if *batch <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadApiConfig()
+	if err != nil {
+		slog.Error("failed to load configuration for api", sl.Err(err))
+		os.Exit(1)
+	}
+
+	if len(cfg.Encryption.Keys) == 0 {
+		slog.Error("encryption keys are not set")
+		os.Exit(1)
+	}
+
+	keyring, err := envelope.NewKeyring(cfg.Encryption.Keys, cfg.Encryption.PrimaryKey)
+	if err != nil {
+		slog.Error("failed to load encryption keys", sl.Err(err))
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+
+	poolCfg := storages.PoolConfig{
+		MaxOpenConns:    cfg.Postgres.MaxOpenConns,
+		MaxIdleConns:    cfg.Postgres.MaxIdleConns,
+		ConnMaxLifetime: cfg.Postgres.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Postgres.ConnMaxIdleTime,
+		DialTimeout:     cfg.Postgres.DialTimeout,
+		PingTimeout:     cfg.Postgres.PingTimeout,
+		ConnectAttempts: cfg.Postgres.ConnectAttempts,
+		ConnectBackoff:  cfg.Postgres.ConnectBackoff,
+	}
+
+	db, err := storages.NewDBPool(storages.DriverPostgres, cfg.Postgres.URL, storages.WithPoolConfig(poolCfg))
+	if err != nil {
+		slog.Error("failed to connect to database", sl.Err(err))
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	tasksStorage, err := taskspg.New(db, taskspg.WithKeyring(keyring))
+	if err != nil {
+		slog.Error("failed to create tasks storage", sl.Err(err))
+		os.Exit(1)
+	}
+
+	templatesStorage, err := templatespg.New(db, templatespg.WithKeyring(keyring))
+	if err != nil {
+		slog.Error("failed to create templates storage", sl.Err(err))
+		os.Exit(1)
+	}
+
+	rewrites := []struct {
+		table   string
+		rewrite func(ctx context.Context, limit int) (int, error)
+	}{
+		{"tasks", tasksStorage.Recrypt},
+		{"templates", templatesStorage.Recrypt},
+	}
+	if *decrypt {
+		rewrites[0].rewrite = tasksStorage.Decrypt
+		rewrites[1].rewrite = templatesStorage.Decrypt
+	}
+
+	ctx = storages.AllWorkspaces(ctx)
+
+	for _, r := range rewrites {
+		var total int
+		for ctx.Err() == nil {
+			n, err := r.rewrite(ctx, *batch)
+			if err != nil {
+				slog.Error("failed to rewrite "+r.table, sl.Err(err), slog.Int("rewritten", total))
+				os.Exit(1)
+			}
+			if n == 0 {
+				break
+			}
+
+			total += n
+			slog.Info(r.table+" rewritten", slog.Int("rewritten", total))
+		}
+
+		slog.Info("done", slog.String("table", r.table), slog.Int("rewritten", total), slog.String("primary_key", keyring.Primary()), slog.Bool("decrypt", *decrypt))
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/romankravchuk/eldorado/internal/config"
+	"github.com/romankravchuk/eldorado/internal/pkg/logger"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/server/http/debug"
+	"github.com/romankravchuk/eldorado/internal/services/relay"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/migrations"
+)
+
+func main() {
+	cfg, err := config.LoadRelayConfig()
+	failOnError("failed to load config", err)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	log := logger.New(cfg.Env, os.Stderr)
+
+	poolCfg := storages.PoolConfig{
+		MaxOpenConns:    cfg.Postgres.MaxOpenConns,
+		MaxIdleConns:    cfg.Postgres.MaxIdleConns,
+		ConnMaxLifetime: cfg.Postgres.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Postgres.ConnMaxIdleTime,
+		DialTimeout:     cfg.Postgres.DialTimeout,
+		PingTimeout:     cfg.Postgres.PingTimeout,
+		ConnectAttempts: cfg.Postgres.ConnectAttempts,
+		ConnectBackoff:  cfg.Postgres.ConnectBackoff,
+	}
+
+	debug.Serve(cfg.MetricsAddr, log)
+
+	log.Debug("the debug mode is activated")
+
+	dbURL := cfg.Database.URL(cfg.Postgres)
+
+	if cfg.Database.CheckSchema {
+		err = migrations.CheckSchema(ctx, cfg.Database.Driver, dbURL, storages.WithPoolConfig(poolCfg))
+		failOnError("failed to check database schema", err)
+	}
+
+	r, err := relay.New(
+		relay.WithLogger(log),
+		relay.WithDBStorage(cfg.Database.Driver, dbURL, storages.WithPoolConfig(poolCfg)),
+		relay.WithRabbitMQ(cfg.RabbitMQ.URL, cfg.Outbox.Exchange),
+		relay.WithPolling(cfg.Outbox.Interval, cfg.Outbox.BatchSize),
+		relay.WithRetention(cfg.Outbox.Retention),
+	)
+	failOnError("failed to create relay", err)
+	defer r.Close()
+
+	log.Info("the relay started", slog.String("exchange", cfg.Outbox.Exchange))
+
+	if err = r.Run(ctx); err != nil {
+		log.Error("the relay stopped", sl.Err(err))
+		r.Close()
+		os.Exit(1)
+	}
+
+	log.Info("the relay stopped")
+}
+
+func failOnError(msg string, err error) {
+	if err != nil {
+		slog.Error(msg, sl.Err(err))
+		os.Exit(1)
+	}
+}
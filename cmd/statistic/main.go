@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/romankravchuk/eldorado/internal/config"
+	"github.com/romankravchuk/eldorado/internal/pkg/envelope"
 	"github.com/romankravchuk/eldorado/internal/pkg/logger"
 	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/server/http/debug"
 	"github.com/romankravchuk/eldorado/internal/services/statistic"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/migrations"
 )
 
 func main() {
@@ -21,12 +26,46 @@ func main() {
 
 	log := logger.New(cfg.Env, os.Stderr)
 
+	poolCfg := storages.PoolConfig{
+		MaxOpenConns:    cfg.Postgres.MaxOpenConns,
+		MaxIdleConns:    cfg.Postgres.MaxIdleConns,
+		ConnMaxLifetime: cfg.Postgres.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Postgres.ConnMaxIdleTime,
+		DialTimeout:     cfg.Postgres.DialTimeout,
+		PingTimeout:     cfg.Postgres.PingTimeout,
+		ConnectAttempts: cfg.Postgres.ConnectAttempts,
+		ConnectBackoff:  cfg.Postgres.ConnectBackoff,
+	}
+
+	// No keys disable the encryption of the task contents.
+	var keyring *envelope.Keyring
+	if len(cfg.Encryption.Keys) > 0 {
+		keyring, err = envelope.NewKeyring(cfg.Encryption.Keys, cfg.Encryption.PrimaryKey)
+		failOnError("failed to load encryption keys", err)
+	}
+
+	debug.Serve(cfg.MetricsAddr, log)
+
 	log.Debug("the debug mode is activated")
 
+	if cfg.Database.CheckSchema {
+		err = migrations.CheckSchema(context.Background(), storages.DriverPostgres, cfg.Postgres.URL, storages.WithPoolConfig(poolCfg))
+		failOnError("failed to check database schema", err)
+	}
+
+	var replicas *storages.Replicas
+	if len(cfg.Postgres.Replicas) > 0 {
+		replicas, err = storages.NewReplicas(cfg.Postgres.Replicas,
+			storages.WithHealthCheck(cfg.Postgres.ReplicaCheckInterval, cfg.Postgres.ReplicaCheckTimeout),
+			storages.WithReplicasPool(poolCfg),
+		)
+		failOnError("failed to open postgres replicas", err)
+	}
+
 	svc, err := statistic.New(
 		statistic.WithCron(cfg.Schedule),
 		statistic.WithLogger(log),
-		statistic.WithPosgresTasksStorage(cfg.Postgres.URL),
+		statistic.WithPosgresTasksStorage(cfg.Postgres.URL, replicas, keyring, storages.WithPoolConfig(poolCfg)),
 		statistic.WithRabbitMQ(cfg.RabbitMQ.URL, cfg.RabbitMQ.QueueName),
 	)
 	failOnError("failed to create statistic service", err)
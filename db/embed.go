@@ -0,0 +1,11 @@
+// Package db embeds the database migrations.
+package db
+
+import "embed"
+
+// Migrations holds the SQL files of the migrations/ directory. The
+// postgres migrations are at its root and the sqlite ones are in
+// migrations/sqlite.
+//
+//go:embed migrations/*.sql migrations/sqlite/*.sql
+var Migrations embed.FS
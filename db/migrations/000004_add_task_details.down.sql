@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS "public".idx_tasks_tags;
+ALTER TABLE "public".tasks
+DROP COLUMN IF EXISTS due_on,
+DROP COLUMN IF EXISTS tags,
+DROP COLUMN IF EXISTS priority,
+DROP COLUMN IF EXISTS recurrence;
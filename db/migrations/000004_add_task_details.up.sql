@@ -0,0 +1,6 @@
+ALTER TABLE "public".tasks
+ADD COLUMN IF NOT EXISTS due_on timestamp,
+ADD COLUMN IF NOT EXISTS tags text[] DEFAULT '{}' NOT NULL,
+ADD COLUMN IF NOT EXISTS priority smallint DEFAULT 0 NOT NULL,
+ADD COLUMN IF NOT EXISTS recurrence varchar(100) DEFAULT '' NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_tasks_tags ON "public".tasks USING gin (tags);
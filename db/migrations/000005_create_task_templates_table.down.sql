@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "public".task_templates CASCADE;
+ALTER TABLE "public".tasks
+DROP COLUMN IF EXISTS checklist;
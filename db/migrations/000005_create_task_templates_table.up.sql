@@ -0,0 +1,18 @@
+ALTER TABLE "public".tasks
+ADD COLUMN IF NOT EXISTS checklist jsonb DEFAULT '[]' NOT NULL;
+CREATE TABLE IF NOT EXISTS "public".task_templates (
+    id uuid DEFAULT uuid_generate_v4() NOT NULL,
+    user_id uuid NOT NULL,
+    name varchar(100) NOT NULL,
+    title varchar(100) NOT NULL,
+    description text DEFAULT '' NOT NULL,
+    tags text[] DEFAULT '{}' NOT NULL,
+    priority smallint DEFAULT 0 NOT NULL,
+    recurrence varchar(100) DEFAULT '' NOT NULL,
+    checklist jsonb DEFAULT '[]' NOT NULL,
+    created_on timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT pk_task_templates PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS unq_task_templates_user_name ON "public".task_templates (user_id, name);
+ALTER TABLE "public".task_templates
+ADD CONSTRAINT fk_task_templates_users FOREIGN KEY (user_id) REFERENCES "public".users(id);
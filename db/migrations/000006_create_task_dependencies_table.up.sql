@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS "public".task_dependencies (
+    task_id uuid NOT NULL,
+    blocked_by_id uuid NOT NULL,
+    created_on timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT pk_task_dependencies PRIMARY KEY (task_id, blocked_by_id),
+    CONSTRAINT chk_task_dependencies_self CHECK (task_id <> blocked_by_id)
+);
+CREATE INDEX IF NOT EXISTS idx_task_dependencies_blocked_by ON "public".task_dependencies (blocked_by_id);
+ALTER TABLE "public".task_dependencies
+ADD CONSTRAINT fk_task_dependencies_task FOREIGN KEY (task_id) REFERENCES "public".tasks(id) ON DELETE CASCADE,
+ADD CONSTRAINT fk_task_dependencies_blocked_by FOREIGN KEY (blocked_by_id) REFERENCES "public".tasks(id) ON DELETE CASCADE;
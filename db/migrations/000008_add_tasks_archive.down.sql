@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "public".user_settings CASCADE;
+DROP INDEX IF EXISTS "public".idx_tasks_archive_candidates;
+ALTER TABLE "public".tasks
+DROP COLUMN IF EXISTS completed_on,
+DROP COLUMN IF EXISTS archived_on;
@@ -0,0 +1,14 @@
+ALTER TABLE "public".tasks
+ADD COLUMN IF NOT EXISTS completed_on timestamp,
+ADD COLUMN IF NOT EXISTS archived_on timestamp;
+UPDATE "public".tasks SET completed_on = updated_on WHERE is_completed = true AND completed_on IS NULL;
+CREATE INDEX IF NOT EXISTS idx_tasks_archive_candidates ON "public".tasks (completed_on) WHERE is_completed = true AND archived_on IS NULL AND is_deleted = false;
+CREATE TABLE IF NOT EXISTS "public".user_settings (
+    user_id uuid NOT NULL,
+    archive_after_days integer,
+    updated_on timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT pk_user_settings PRIMARY KEY (user_id),
+    CONSTRAINT chk_user_settings_archive_after_days CHECK (archive_after_days >= 0)
+);
+ALTER TABLE "public".user_settings
+ADD CONSTRAINT fk_user_settings_users FOREIGN KEY (user_id) REFERENCES "public".users(id);
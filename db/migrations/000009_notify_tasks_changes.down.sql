@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS trg_task_dependencies_notify_changed ON "public".task_dependencies;
+DROP FUNCTION IF EXISTS "public".notify_task_dependencies_changed();
+DROP TRIGGER IF EXISTS trg_tasks_notify_changed ON "public".tasks;
+DROP FUNCTION IF EXISTS "public".notify_tasks_changed();
@@ -0,0 +1,36 @@
+CREATE OR REPLACE FUNCTION "public".notify_tasks_changed() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('tasks_changed', OLD.user_id::text);
+        RETURN OLD;
+    END IF;
+    PERFORM pg_notify('tasks_changed', NEW.user_id::text);
+    IF TG_OP = 'UPDATE' AND NEW.user_id <> OLD.user_id THEN
+        PERFORM pg_notify('tasks_changed', OLD.user_id::text);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_tasks_notify_changed
+AFTER INSERT OR UPDATE OR DELETE ON "public".tasks
+FOR EACH ROW EXECUTE FUNCTION "public".notify_tasks_changed();
+
+-- Dependencies change the blocked flag of the listed tasks.
+CREATE OR REPLACE FUNCTION "public".notify_task_dependencies_changed() RETURNS trigger AS $$
+DECLARE
+    changed_task_id uuid;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed_task_id := OLD.task_id;
+    ELSE
+        changed_task_id := NEW.task_id;
+    END IF;
+    PERFORM pg_notify('tasks_changed', t.user_id::text) FROM "public".tasks t WHERE t.id = changed_task_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_task_dependencies_notify_changed
+AFTER INSERT OR DELETE ON "public".task_dependencies
+FOR EACH ROW EXECUTE FUNCTION "public".notify_task_dependencies_changed();
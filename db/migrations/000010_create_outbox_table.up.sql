@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS "public".outbox (
+    id bigserial NOT NULL,
+    event_type varchar(100) NOT NULL,
+    aggregate_id varchar(100) NOT NULL,
+    payload jsonb NOT NULL,
+    created_on timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    sent_on timestamp NULL,
+    CONSTRAINT pk_outbox PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON "public".outbox (id) WHERE sent_on IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_sent_on ON "public".outbox (sent_on) WHERE sent_on IS NOT NULL;
@@ -0,0 +1,41 @@
+CREATE OR REPLACE FUNCTION "public".notify_tasks_changed() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('tasks_changed', OLD.user_id::text);
+        RETURN OLD;
+    END IF;
+    PERFORM pg_notify('tasks_changed', NEW.user_id::text);
+    IF TG_OP = 'UPDATE' AND NEW.user_id <> OLD.user_id THEN
+        PERFORM pg_notify('tasks_changed', OLD.user_id::text);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION "public".notify_task_dependencies_changed() RETURNS trigger AS $$
+DECLARE
+    changed_task_id uuid;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed_task_id := OLD.task_id;
+    ELSE
+        changed_task_id := NEW.task_id;
+    END IF;
+    PERFORM pg_notify('tasks_changed', t.user_id::text) FROM "public".tasks t WHERE t.id = changed_task_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP POLICY IF EXISTS users_workspace_isolation ON "public".users;
+ALTER TABLE "public".users NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE "public".users DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tasks_workspace_isolation ON "public".tasks;
+ALTER TABLE "public".tasks NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE "public".tasks DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX IF EXISTS "public".idx_tasks_workspace_user;
+ALTER TABLE "public".tasks DROP COLUMN IF EXISTS workspace_id;
+
+DROP TABLE IF EXISTS "public".workspace_members CASCADE;
+DROP TABLE IF EXISTS "public".workspaces CASCADE;
@@ -0,0 +1,97 @@
+CREATE TABLE IF NOT EXISTS "public".workspaces (
+    id uuid DEFAULT uuid_generate_v4() NOT NULL,
+    name varchar(150) NOT NULL,
+    created_on timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT pk_workspaces PRIMARY KEY (id)
+);
+CREATE TABLE IF NOT EXISTS "public".workspace_members (
+    workspace_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    role varchar(20) DEFAULT 'member' NOT NULL,
+    created_on timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT pk_workspace_members PRIMARY KEY (workspace_id, user_id),
+    CONSTRAINT fk_workspace_members_workspaces FOREIGN KEY (workspace_id) REFERENCES "public".workspaces(id) ON DELETE CASCADE,
+    CONSTRAINT fk_workspace_members_users FOREIGN KEY (user_id) REFERENCES "public".users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user ON "public".workspace_members (user_id);
+
+-- Every existing user gets a personal workspace with the ID of the user,
+-- and their tasks are moved there.
+INSERT INTO "public".workspaces (id, name, created_on)
+SELECT id, username, created_on FROM "public".users ON CONFLICT DO NOTHING;
+INSERT INTO "public".workspace_members (workspace_id, user_id, role, created_on)
+SELECT id, id, 'owner', created_on FROM "public".users ON CONFLICT DO NOTHING;
+
+ALTER TABLE "public".tasks ADD COLUMN IF NOT EXISTS workspace_id uuid;
+UPDATE "public".tasks SET workspace_id = user_id WHERE workspace_id IS NULL;
+ALTER TABLE "public".tasks ALTER COLUMN workspace_id SET NOT NULL;
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_tasks_workspaces') THEN
+        ALTER TABLE "public".tasks
+        ADD CONSTRAINT fk_tasks_workspaces FOREIGN KEY (workspace_id) REFERENCES "public".workspaces(id);
+    END IF;
+END;
+$$;
+CREATE INDEX IF NOT EXISTS idx_tasks_workspace_user ON "public".tasks (workspace_id, user_id);
+
+-- The connections of the storages set the app.workspace_id session setting,
+-- or app.all_workspaces for the calls made on behalf of no workspace, when
+-- a statement comes with another scope than the last one, see
+-- scopeConnector. Without them no rows are visible. FORCE applies the policies to the owner of the tables
+-- too, superusers and the roles with BYPASSRLS still skip them.
+ALTER TABLE "public".tasks ENABLE ROW LEVEL SECURITY;
+ALTER TABLE "public".tasks FORCE ROW LEVEL SECURITY;
+CREATE POLICY tasks_workspace_isolation ON "public".tasks
+USING (
+    current_setting('app.all_workspaces', true) = 'on'
+    OR workspace_id = NULLIF(current_setting('app.workspace_id', true), '')::uuid
+)
+WITH CHECK (
+    current_setting('app.all_workspaces', true) = 'on'
+    OR workspace_id = NULLIF(current_setting('app.workspace_id', true), '')::uuid
+);
+
+-- A workspace sees its members, only the calls made on behalf of no
+-- workspace, such as the sign up, create users.
+ALTER TABLE "public".users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE "public".users FORCE ROW LEVEL SECURITY;
+CREATE POLICY users_workspace_isolation ON "public".users
+USING (
+    current_setting('app.all_workspaces', true) = 'on'
+    OR EXISTS (
+        SELECT 1 FROM "public".workspace_members m
+        WHERE m.user_id = users.id AND m.workspace_id = NULLIF(current_setting('app.workspace_id', true), '')::uuid
+    )
+)
+WITH CHECK (current_setting('app.all_workspaces', true) = 'on');
+
+-- The lists are cached per workspace and user, so the notifications carry
+-- both as workspace_id:user_id.
+CREATE OR REPLACE FUNCTION "public".notify_tasks_changed() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('tasks_changed', OLD.workspace_id::text || ':' || OLD.user_id::text);
+        RETURN OLD;
+    END IF;
+    PERFORM pg_notify('tasks_changed', NEW.workspace_id::text || ':' || NEW.user_id::text);
+    IF TG_OP = 'UPDATE' AND (NEW.user_id <> OLD.user_id OR NEW.workspace_id <> OLD.workspace_id) THEN
+        PERFORM pg_notify('tasks_changed', OLD.workspace_id::text || ':' || OLD.user_id::text);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION "public".notify_task_dependencies_changed() RETURNS trigger AS $$
+DECLARE
+    changed_task_id uuid;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed_task_id := OLD.task_id;
+    ELSE
+        changed_task_id := NEW.task_id;
+    END IF;
+    PERFORM pg_notify('tasks_changed', t.workspace_id::text || ':' || t.user_id::text) FROM "public".tasks t WHERE t.id = changed_task_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
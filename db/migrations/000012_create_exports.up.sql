@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS "public".exports (
+    id uuid DEFAULT uuid_generate_v4() NOT NULL,
+    user_id uuid NOT NULL,
+    status varchar(20) DEFAULT 'pending' NOT NULL,
+    archive bytea NULL,
+    created_on timestamp DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    expires_on timestamp NULL,
+    CONSTRAINT pk_exports PRIMARY KEY (id),
+    CONSTRAINT fk_exports_users FOREIGN KEY (user_id) REFERENCES "public".users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_exports_pending ON "public".exports (created_on) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_exports_expires_on ON "public".exports (expires_on) WHERE expires_on IS NOT NULL;
@@ -0,0 +1,7 @@
+-- Decrypt the tasks first with cmd/recrypt -decrypt, the ciphertexts do
+-- not fit the columns.
+ALTER TABLE "public".tasks
+DROP COLUMN IF EXISTS key_id,
+DROP COLUMN IF EXISTS data_key,
+ALTER COLUMN title TYPE varchar(100),
+ALTER COLUMN description TYPE varchar(255);
@@ -0,0 +1,7 @@
+-- The encrypted title and description are base64 encoded and longer than
+-- the plaintext, the lengths are checked by the api.
+ALTER TABLE "public".tasks
+ALTER COLUMN title TYPE text,
+ALTER COLUMN description TYPE text,
+ADD COLUMN IF NOT EXISTS key_id varchar(64),
+ADD COLUMN IF NOT EXISTS data_key bytea;
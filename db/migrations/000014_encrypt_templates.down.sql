@@ -0,0 +1,6 @@
+-- Decrypt the templates first with cmd/recrypt -decrypt, the ciphertexts do
+-- not fit the columns.
+ALTER TABLE "public".task_templates
+DROP COLUMN IF EXISTS key_id,
+DROP COLUMN IF EXISTS data_key,
+ALTER COLUMN title TYPE varchar(100);
@@ -0,0 +1,6 @@
+-- The templates keep the task contents, they are encrypted like the tasks,
+-- see 000013_encrypt_tasks.
+ALTER TABLE "public".task_templates
+ALTER COLUMN title TYPE text,
+ADD COLUMN IF NOT EXISTS key_id varchar(64),
+ADD COLUMN IF NOT EXISTS data_key bytea;
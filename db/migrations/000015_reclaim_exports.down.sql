@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "public".idx_exports_in_progress;
+DROP INDEX IF EXISTS "public".idx_exports_building;
+ALTER TABLE "public".exports DROP COLUMN IF EXISTS claimed_on;
@@ -0,0 +1,10 @@
+ALTER TABLE "public".exports ADD COLUMN IF NOT EXISTS claimed_on timestamp NULL;
+-- The exports claimed before the column are claimed again.
+UPDATE "public".exports SET claimed_on = created_on WHERE status = 'building';
+CREATE INDEX IF NOT EXISTS idx_exports_building ON "public".exports (claimed_on) WHERE status = 'building';
+-- A user has one export in progress, the later duplicates are dropped.
+DELETE FROM "public".exports e WHERE status IN ('pending', 'building') AND EXISTS (
+    SELECT 1 FROM "public".exports o
+    WHERE o.user_id = e.user_id AND o.status IN ('pending', 'building') AND (o.created_on, o.id) < (e.created_on, e.id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exports_in_progress ON "public".exports (user_id) WHERE status IN ('pending', 'building');
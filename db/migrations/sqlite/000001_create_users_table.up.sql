@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS users (
+    id text NOT NULL,
+    email varchar(150) NOT NULL,
+    username varchar(150) NOT NULL,
+    name varchar(150) NOT NULL,
+    encrypted_password varchar(1024) NOT NULL,
+    created_on datetime DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    deleted_on datetime,
+    CONSTRAINT pk_users PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS unq_users_email ON users (email);
+CREATE UNIQUE INDEX IF NOT EXISTS unq_users_username ON users (username);
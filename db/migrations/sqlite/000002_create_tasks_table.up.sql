@@ -0,0 +1,24 @@
+-- tags and checklist are JSON arrays, times are UTC.
+CREATE TABLE IF NOT EXISTS tasks (
+    id text NOT NULL,
+    user_id text NOT NULL,
+    title varchar(100) NOT NULL,
+    description varchar(255) NOT NULL,
+    is_completed boolean DEFAULT false NOT NULL,
+    is_deleted boolean DEFAULT false NOT NULL,
+    due_on datetime,
+    tags text DEFAULT '[]' NOT NULL,
+    priority smallint DEFAULT 0 NOT NULL,
+    recurrence varchar(100) DEFAULT '' NOT NULL,
+    checklist text DEFAULT '[]' NOT NULL,
+    hidden_until datetime,
+    completed_on datetime,
+    archived_on datetime,
+    created_on datetime DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_on datetime DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    deleted_on datetime,
+    CONSTRAINT pk_tasks PRIMARY KEY (id),
+    CONSTRAINT fk_tasks_users FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE INDEX IF NOT EXISTS idx_tasks_user ON tasks (user_id);
+CREATE INDEX IF NOT EXISTS idx_tasks_archive_candidates ON tasks (completed_on) WHERE is_completed = true AND archived_on IS NULL AND is_deleted = false;
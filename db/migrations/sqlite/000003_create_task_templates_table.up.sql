@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS task_templates (
+    id text NOT NULL,
+    user_id text NOT NULL,
+    name varchar(100) NOT NULL,
+    title varchar(100) NOT NULL,
+    description text DEFAULT '' NOT NULL,
+    tags text DEFAULT '[]' NOT NULL,
+    priority smallint DEFAULT 0 NOT NULL,
+    recurrence varchar(100) DEFAULT '' NOT NULL,
+    checklist text DEFAULT '[]' NOT NULL,
+    created_on datetime DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT pk_task_templates PRIMARY KEY (id),
+    CONSTRAINT fk_task_templates_users FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS unq_task_templates_user_name ON task_templates (user_id, name);
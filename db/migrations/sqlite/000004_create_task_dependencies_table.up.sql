@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS task_dependencies (
+    task_id text NOT NULL,
+    blocked_by_id text NOT NULL,
+    created_on datetime DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT pk_task_dependencies PRIMARY KEY (task_id, blocked_by_id),
+    CONSTRAINT chk_task_dependencies_self CHECK (task_id <> blocked_by_id),
+    CONSTRAINT fk_task_dependencies_task FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+    CONSTRAINT fk_task_dependencies_blocked_by FOREIGN KEY (blocked_by_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_task_dependencies_blocked_by ON task_dependencies (blocked_by_id);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id text NOT NULL,
+    archive_after_days integer,
+    updated_on datetime DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT pk_user_settings PRIMARY KEY (user_id),
+    CONSTRAINT chk_user_settings_archive_after_days CHECK (archive_after_days >= 0),
+    CONSTRAINT fk_user_settings_users FOREIGN KEY (user_id) REFERENCES users(id)
+);
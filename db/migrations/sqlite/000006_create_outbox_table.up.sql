@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id integer NOT NULL,
+    event_type text NOT NULL,
+    aggregate_id text NOT NULL,
+    payload text NOT NULL,
+    created_on datetime DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    sent_on datetime NULL,
+    CONSTRAINT pk_outbox PRIMARY KEY (id AUTOINCREMENT)
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (id) WHERE sent_on IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_sent_on ON outbox (sent_on) WHERE sent_on IS NOT NULL;
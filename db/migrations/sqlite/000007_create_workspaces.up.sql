@@ -0,0 +1,28 @@
+-- SQLite has no row-level security, the storages filter the tasks by
+-- workspace themselves. The tasks column has no foreign key, so the down
+-- migration can drop it.
+CREATE TABLE IF NOT EXISTS workspaces (
+    id text NOT NULL,
+    name varchar(150) NOT NULL,
+    created_on datetime DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT pk_workspaces PRIMARY KEY (id)
+);
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id text NOT NULL,
+    user_id text NOT NULL,
+    role varchar(20) DEFAULT 'member' NOT NULL,
+    created_on datetime DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT pk_workspace_members PRIMARY KEY (workspace_id, user_id),
+    CONSTRAINT fk_workspace_members_workspaces FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE,
+    CONSTRAINT fk_workspace_members_users FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user ON workspace_members (user_id);
+
+-- Every existing user gets a personal workspace with the ID of the user,
+-- and their tasks are moved there.
+INSERT OR IGNORE INTO workspaces (id, name, created_on) SELECT id, username, created_on FROM users;
+INSERT OR IGNORE INTO workspace_members (workspace_id, user_id, role, created_on) SELECT id, id, 'owner', created_on FROM users;
+
+ALTER TABLE tasks ADD COLUMN workspace_id text;
+UPDATE tasks SET workspace_id = user_id WHERE workspace_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_tasks_workspace_user ON tasks (workspace_id, user_id);
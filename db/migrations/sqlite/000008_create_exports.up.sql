@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS exports (
+    id text NOT NULL,
+    user_id text NOT NULL,
+    status text DEFAULT 'pending' NOT NULL,
+    archive blob NULL,
+    created_on datetime DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    expires_on datetime NULL,
+    CONSTRAINT pk_exports PRIMARY KEY (id),
+    CONSTRAINT fk_exports_users FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_exports_pending ON exports (created_on) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_exports_expires_on ON exports (expires_on) WHERE expires_on IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_exports_in_progress;
+DROP INDEX IF EXISTS idx_exports_building;
+ALTER TABLE exports DROP COLUMN claimed_on;
@@ -0,0 +1,11 @@
+ALTER TABLE exports ADD COLUMN claimed_on datetime NULL;
+-- The exports claimed before the column are claimed again.
+UPDATE exports SET claimed_on = created_on WHERE status = 'building';
+CREATE INDEX IF NOT EXISTS idx_exports_building ON exports (claimed_on) WHERE status = 'building';
+-- A user has one export in progress, the later duplicates are dropped.
+DELETE FROM exports WHERE status IN ('pending', 'building') AND EXISTS (
+    SELECT 1 FROM exports o
+    WHERE o.user_id = exports.user_id AND o.status IN ('pending', 'building')
+      AND (julianday(o.created_on), o.id) < (julianday(exports.created_on), exports.id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exports_in_progress ON exports (user_id) WHERE status IN ('pending', 'building');
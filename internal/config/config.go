@@ -9,18 +9,40 @@ import (
 )
 
 type APIConfig struct {
-	Env             string   `yaml:"env"`
-	AuthServiceAddr string   `yaml:"auth_service_addr" env:"AUTH_SERVICE_ADDR"`
-	Postgres        postgres `yaml:"postgres"`
-	Server          server   `yaml:"server"`
-	Redis           redis    `yaml:"redis"`
+	Env             string     `yaml:"env"`
+	AuthServiceAddr string     `yaml:"auth_service_addr" env:"AUTH_SERVICE_ADDR"`
+	Database        database   `yaml:"database"`
+	Postgres        postgres   `yaml:"postgres"`
+	Server          server     `yaml:"server"`
+	Redis           redis      `yaml:"redis"`
+	Cache           cache      `yaml:"cache"`
+	Archive         archive    `yaml:"archive"`
+	Admin           admin      `yaml:"admin"`
+	Outbox          outbox     `yaml:"outbox"`
+	Export          export     `yaml:"export"`
+	RabbitMQ        rabbitmq   `yaml:"rabbitmq"`
+	Resilience      resilience `yaml:"resilience"`
+	Encryption      encryption `yaml:"encryption"`
+	MetricsAddr     string     `yaml:"metrics_addr" env:"METRICS_ADDR"`
+}
+
+type RelayConfig struct {
+	Env         string   `yaml:"env"`
+	Database    database `yaml:"database"`
+	Postgres    postgres `yaml:"postgres"`
+	RabbitMQ    rabbitmq `yaml:"rabbitmq"`
+	Outbox      outbox   `yaml:"outbox"`
+	MetricsAddr string   `yaml:"metrics_addr" env:"METRICS_ADDR"`
 }
 
 type StatisticServiceConfig struct {
-	Env      string   `yaml:"env"`
-	Schedule string   `yaml:"schedule"`
-	RabbitMQ rabbitmq `yaml:"rabbitmq"`
-	Postgres postgres `yaml:"postgres"`
+	Env         string     `yaml:"env"`
+	Schedule    string     `yaml:"schedule"`
+	RabbitMQ    rabbitmq   `yaml:"rabbitmq"`
+	Database    database   `yaml:"database"`
+	Postgres    postgres   `yaml:"postgres"`
+	Encryption  encryption `yaml:"encryption"`
+	MetricsAddr string     `yaml:"metrics_addr" env:"METRICS_ADDR"`
 }
 
 type EmailServiceConfig struct {
@@ -30,12 +52,18 @@ type EmailServiceConfig struct {
 }
 
 type AuthServiceConfig struct {
-	Env          string   `yaml:"env"`
-	Port         string   `env:"PORT"`
-	AccessCreds  rsacreds `yaml:"access"`
-	RefreshCreds rsacreds `yaml:"refresh"`
-	Postgres     postgres `yaml:"postgres"`
-	Redis        redis    `yaml:"redis"`
+	Env          string     `yaml:"env"`
+	Port         string     `env:"PORT"`
+	AccessCreds  rsacreds   `yaml:"access"`
+	RefreshCreds rsacreds   `yaml:"refresh"`
+	Database     database   `yaml:"database"`
+	Postgres     postgres   `yaml:"postgres"`
+	Redis        redis      `yaml:"redis"`
+	Outbox       outbox     `yaml:"outbox"`
+	Deletion     deletion   `yaml:"deletion"`
+	RabbitMQ     rabbitmq   `yaml:"rabbitmq"`
+	Resilience   resilience `yaml:"resilience"`
+	MetricsAddr  string     `yaml:"metrics_addr" env:"METRICS_ADDR"`
 }
 
 type rsacreds struct {
@@ -54,10 +82,115 @@ type server struct {
 type redis struct {
 	URL string        `yaml:"url" env:"REDIS_URL"`
 	TTL time.Duration `yaml:"cache_ttl" env:"REDIS_CACHE_TTL" env-default:"30m"`
+	// The pool settings of the clients. Zero keeps the value of the url,
+	// if it has one, or the go-redis default.
+	PoolSize        int           `yaml:"pool_size" env:"REDIS_POOL_SIZE"`
+	MinIdleConns    int           `yaml:"min_idle_conns" env:"REDIS_MIN_IDLE_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"REDIS_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"REDIS_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"REDIS_CONN_MAX_IDLE_TIME"`
+	DialTimeout     time.Duration `yaml:"dial_timeout" env:"REDIS_DIAL_TIMEOUT"`
+	PingTimeout     time.Duration `yaml:"ping_timeout" env:"REDIS_PING_TIMEOUT" env-default:"5s"`
+	ConnectAttempts int           `yaml:"connect_attempts" env:"REDIS_CONNECT_ATTEMPTS" env-default:"5"`
+	ConnectBackoff  time.Duration `yaml:"connect_backoff" env:"REDIS_CONNECT_BACKOFF" env-default:"1s"`
+}
+
+type cache struct {
+	Coalesce            bool          `yaml:"coalesce" env:"CACHE_COALESCE" env-default:"true"`
+	StaleTTL            time.Duration `yaml:"stale_ttl" env:"CACHE_STALE_TTL" env-default:"0s"`
+	LocalSize           int           `yaml:"local_size" env:"CACHE_LOCAL_SIZE" env-default:"10000"`
+	LocalTTL            time.Duration `yaml:"local_ttl" env:"CACHE_LOCAL_TTL" env-default:"30s"`
+	InvalidationChannel string        `yaml:"invalidation_channel" env:"CACHE_INVALIDATION_CHANNEL" env-default:"tasks:invalidations"`
+	Codec               string        `yaml:"codec" env:"CACHE_CODEC" env-default:"binary"`
+	Compress            bool          `yaml:"compress" env:"CACHE_COMPRESS" env-default:"false"`
+	ListenPostgres      bool          `yaml:"listen_postgres" env:"CACHE_LISTEN_POSTGRES" env-default:"true"`
+}
+
+type admin struct {
+	Token string `yaml:"token" env:"ADMIN_TOKEN"`
+}
+
+type export struct {
+	Secret   string        `yaml:"secret" env:"EXPORT_SECRET"`
+	BaseURL  string        `yaml:"base_url" env:"EXPORT_BASE_URL" env-default:"http://localhost:8080"`
+	TTL      time.Duration `yaml:"ttl" env:"EXPORT_TTL" env-default:"72h"`
+	Interval time.Duration `yaml:"interval" env:"EXPORT_INTERVAL" env-default:"5s"`
+}
+
+type encryption struct {
+	// Keys are base64 encoded AES keys of 16, 24 or 32 bytes by their IDs.
+	Keys map[string]string `yaml:"keys" env:"ENCRYPTION_KEYS" env-separator:","`
+	// PrimaryKey encrypts, the rest of the keys only decrypt.
+	PrimaryKey string `yaml:"primary_key" env:"ENCRYPTION_PRIMARY_KEY"`
+}
+
+type deletion struct {
+	GracePeriod time.Duration `yaml:"grace_period" env:"DELETION_GRACE_PERIOD" env-default:"720h"`
+	Schedule    string        `yaml:"schedule" env:"DELETION_SCHEDULE" env-default:"0 4 * * *"`
+}
+
+type archive struct {
+	Schedule  string `yaml:"schedule" env:"ARCHIVE_SCHEDULE" env-default:"0 3 * * *"`
+	AfterDays int    `yaml:"after_days" env:"ARCHIVE_AFTER_DAYS" env-default:"30"`
+}
+
+type database struct {
+	Driver      string `yaml:"driver" env:"DB_DRIVER" env-default:"postgres"`
+	SQLitePath  string `yaml:"sqlite_path" env:"SQLITE_PATH" env-default:"eldorado.db"`
+	CheckSchema bool   `yaml:"check_schema" env:"CHECK_SCHEMA" env-default:"false"`
+}
+
+// URL returns the url of the configured driver: the postgres url or
+// the path to the sqlite database file.
+func (d database) URL(pg postgres) string {
+	if d.Driver == "sqlite" {
+		return d.SQLitePath
+	}
+	return pg.URL
 }
 
 type postgres struct {
-	URL string `yaml:"url" env:"PG_URL"`
+	URL                  string        `yaml:"url" env:"PG_URL"`
+	Replicas             []string      `yaml:"replicas" env:"PG_REPLICA_URLS" env-separator:","`
+	ReplicaCheckInterval time.Duration `yaml:"replica_check_interval" env:"PG_REPLICA_CHECK_INTERVAL" env-default:"5s"`
+	ReplicaCheckTimeout  time.Duration `yaml:"replica_check_timeout" env:"PG_REPLICA_CHECK_TIMEOUT" env-default:"1s"`
+	ReadYourWrites       time.Duration `yaml:"read_your_writes" env:"PG_READ_YOUR_WRITES" env-default:"0s"`
+	// The pool settings apply to the primary, every replica and the sqlite
+	// database. The services of a process share one pool of the primary,
+	// so MaxOpenConns bounds the connections of a process to each of them.
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"PG_MAX_OPEN_CONNS" env-default:"25"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"PG_MAX_IDLE_CONNS" env-default:"10"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"PG_CONN_MAX_LIFETIME" env-default:"30m"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"PG_CONN_MAX_IDLE_TIME" env-default:"5m"`
+	DialTimeout     time.Duration `yaml:"dial_timeout" env:"PG_DIAL_TIMEOUT" env-default:"5s"`
+	PingTimeout     time.Duration `yaml:"ping_timeout" env:"PG_PING_TIMEOUT" env-default:"5s"`
+	ConnectAttempts int           `yaml:"connect_attempts" env:"PG_CONNECT_ATTEMPTS" env-default:"5"`
+	ConnectBackoff  time.Duration `yaml:"connect_backoff" env:"PG_CONNECT_BACKOFF" env-default:"1s"`
+}
+
+type resilience struct {
+	Tasks storagePolicy `yaml:"tasks" env-prefix:"RESILIENCE_TASKS_"`
+	Users storagePolicy `yaml:"users" env-prefix:"RESILIENCE_USERS_"`
+}
+
+// storagePolicy is the retry and circuit breaker policy of a storage.
+// The defaults fit the 150ms timeout of the api handlers.
+type storagePolicy struct {
+	Retries         int           `yaml:"retries" env:"RETRIES" env-default:"2"`
+	BaseDelay       time.Duration `yaml:"base_delay" env:"BASE_DELAY" env-default:"10ms"`
+	MaxDelay        time.Duration `yaml:"max_delay" env:"MAX_DELAY" env-default:"50ms"`
+	BreakerFailures int           `yaml:"breaker_failures" env:"BREAKER_FAILURES" env-default:"5"`
+	BreakerTimeout  time.Duration `yaml:"breaker_timeout" env:"BREAKER_TIMEOUT" env-default:"5s"`
+	CallTimeout     time.Duration `yaml:"call_timeout" env:"CALL_TIMEOUT"`
+}
+
+type outbox struct {
+	// Only the relay empties the outbox, enable it when the relay runs.
+	Enabled   bool          `yaml:"enabled" env:"OUTBOX_ENABLED" env-default:"false"`
+	Exchange  string        `yaml:"exchange" env:"OUTBOX_EXCHANGE" env-default:"eldorado.events"`
+	Interval  time.Duration `yaml:"interval" env:"OUTBOX_INTERVAL" env-default:"1s"`
+	BatchSize int           `yaml:"batch_size" env:"OUTBOX_BATCH_SIZE" env-default:"100"`
+	Retention time.Duration `yaml:"retention" env:"OUTBOX_RETENTION" env-default:"168h"`
 }
 
 type rabbitmq struct {
@@ -100,6 +233,20 @@ func LoadStatisticServiceConfig() (*StatisticServiceConfig, error) {
 	return &cfg, err
 }
 
+func LoadRelayConfig() (*RelayConfig, error) {
+	path, err := configPath("RELAY_CONFIG_PATH")
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg RelayConfig
+	if err = cleanenv.ReadConfig(path, &cfg); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
 func LoadAuthServiceConfig() (*AuthServiceConfig, error) {
 	path, err := configPath("AUTH_SERVICE_CONFIG_PATH")
 	if err != nil {
@@ -0,0 +1,26 @@
+package data
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event is a domain event kept in the outbox until it is published. The
+// payload is the published message, see the events package.
+type Event struct {
+	ID          int64     `db:"id"`
+	Type        string    `db:"event_type"`
+	AggregateID string    `db:"aggregate_id"`
+	Payload     []byte    `db:"payload"`
+	CreatedOn   time.Time `db:"created_on"`
+}
+
+// NewEvent returns the event of the given type with the JSON encoded payload.
+func NewEvent(typ, aggregateID string, payload any) (Event, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{Type: typ, AggregateID: aggregateID, Payload: b}, nil
+}
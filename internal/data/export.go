@@ -0,0 +1,23 @@
+package data
+
+import "time"
+
+// The statuses of an export. A pending export is built by the first
+// worker that claims it.
+const (
+	ExportPending  = "pending"
+	ExportBuilding = "building"
+	ExportReady    = "ready"
+	ExportFailed   = "failed"
+)
+
+// Export is an archive of everything kept about a user. The archive is
+// deleted once it expires.
+type Export struct {
+	ID        string    `db:"id"`
+	UserID    string    `db:"user_id"`
+	Status    string    `db:"status"`
+	Archive   []byte    `db:"archive"`
+	CreatedOn time.Time `db:"created_on"`
+	ExpiresOn time.Time `db:"expires_on"`
+}
@@ -0,0 +1,10 @@
+package data
+
+import "time"
+
+// Session is a token issued to a user, see sessions.Storage. The token
+// itself is not exposed.
+type Session struct {
+	ID        string    `db:"id"`
+	ExpiresOn time.Time `db:"expires_on"`
+}
@@ -0,0 +1,8 @@
+package data
+
+type Settings struct {
+	UserID string `db:"user_id"`
+	// ArchiveAfterDays is how many days completed tasks stay in the task list
+	// before they are archived. Zero disables archiving.
+	ArchiveAfterDays int `db:"archive_after_days"`
+}
@@ -2,14 +2,72 @@ package data
 
 import "time"
 
+type Priority int8
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityMedium:
+		return "medium"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "none"
+	}
+}
+
+// ParsePriority returns a priority by its name. Empty name is PriorityNone.
+func ParsePriority(s string) (Priority, bool) {
+	switch s {
+	case "", "none":
+		return PriorityNone, true
+	case "low":
+		return PriorityLow, true
+	case "medium":
+		return PriorityMedium, true
+	case "high":
+		return PriorityHigh, true
+	default:
+		return PriorityNone, false
+	}
+}
+
+type ChecklistItem struct {
+	Text   string `json:"text"`
+	IsDone bool   `json:"is_done"`
+}
+
 type Task struct {
-	ID          string    `db:"id"`
-	UserID      string    `db:"user_id"`
-	Title       string    `db:"title"`
-	Description string    `db:"description"`
-	IsCompleted bool      `db:"is_completed"`
-	IsDeleted   bool      `db:"is_deleted"`
-	CreatedOn   time.Time `db:"created_on"`
+	ID          string          `db:"id"`
+	UserID      string          `db:"user_id"`
+	WorkspaceID string          `db:"workspace_id"`
+	Title       string          `db:"title"`
+	Description string          `db:"description"`
+	IsCompleted bool            `db:"is_completed"`
+	IsDeleted   bool            `db:"is_deleted"`
+	IsBlocked   bool            `db:"is_blocked"`
+	DueOn       time.Time       `db:"due_on"`
+	Tags        []string        `db:"tags"`
+	Priority    Priority        `db:"priority"`
+	Recurrence  string          `db:"recurrence"`
+	Checklist   []ChecklistItem `db:"checklist"`
+	HiddenUntil time.Time       `db:"hidden_until"`
+	CompletedOn time.Time       `db:"completed_on"`
+	ArchivedOn  time.Time       `db:"archived_on"`
+	CreatedOn   time.Time       `db:"created_on"`
+}
+
+// IsSnoozed reports whether the task is hidden from default lists at the given time.
+func (t Task) IsSnoozed(now time.Time) bool {
+	return !t.HiddenUntil.IsZero() && t.HiddenUntil.After(now)
 }
 
 type StatisticTask struct {
@@ -0,0 +1,20 @@
+package data
+
+import "time"
+
+// Template is a named blueprint for new tasks.
+//
+// Title, description and checklist items may contain placeholders
+// like {{date}} that are substituted when a task is created from the template.
+type Template struct {
+	ID          string    `db:"id"`
+	UserID      string    `db:"user_id"`
+	Name        string    `db:"name"`
+	Title       string    `db:"title"`
+	Description string    `db:"description"`
+	Tags        []string  `db:"tags"`
+	Priority    Priority  `db:"priority"`
+	Recurrence  string    `db:"recurrence"`
+	Checklist   []string  `db:"checklist"`
+	CreatedOn   time.Time `db:"created_on"`
+}
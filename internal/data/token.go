@@ -8,9 +8,10 @@ import (
 )
 
 type TokenPayload struct {
-	ID     string
-	UserID string
-	Email  string
+	ID          string
+	UserID      string
+	WorkspaceID string
+	Email       string
 }
 
 type TokenDetails struct {
@@ -20,9 +21,10 @@ type TokenDetails struct {
 }
 
 type Claims struct {
-	TokenID string `json:"token_id"`
-	UserID  string `json:"user_id"`
-	Email   string `json:"email"`
+	TokenID     string `json:"token_id"`
+	UserID      string `json:"user_id"`
+	WorkspaceID string `json:"workspace_id"`
+	Email       string `json:"email"`
 	jwt.RegisteredClaims
 }
 
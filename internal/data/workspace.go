@@ -0,0 +1,23 @@
+package data
+
+import "time"
+
+const (
+	RoleOwner  = "owner"
+	RoleMember = "member"
+)
+
+// Workspace isolates the tasks of its members from the other workspaces.
+type Workspace struct {
+	ID        string    `db:"id"`
+	Name      string    `db:"name"`
+	CreatedOn time.Time `db:"created_on"`
+}
+
+// Membership is the role of a user in a workspace.
+type Membership struct {
+	WorkspaceID string    `db:"workspace_id"`
+	UserID      string    `db:"user_id"`
+	Role        string    `db:"role"`
+	CreatedOn   time.Time `db:"created_on"`
+}
@@ -0,0 +1,230 @@
+// Package envelope encrypts the fields of a record with AES-GCM envelope
+// encryption. Every record has its own random data key that encrypts the
+// fields, and the data key is stored wrapped, encrypted, with a master key
+// of the Keyring. Rotating a master key only rewraps the data keys.
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// dataKeySize is the size of the data keys, AES-256.
+const dataKeySize = 32
+
+var (
+	// ErrUnknownKey is returned for a data key wrapped with a master key
+	// that is not in the keyring.
+	ErrUnknownKey = errors.New("the encryption key is unknown")
+	// ErrNoPrimaryKey is returned by NewDataKey and Rewrap of a keyring
+	// that only decrypts, or of a nil keyring.
+	ErrNoPrimaryKey = errors.New("the primary encryption key is not set")
+)
+
+// Keyring holds the master keys by their IDs. New data keys are wrapped
+// with the primary key, the rest of the keys only unwrap the old ones.
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyring returns the keyring of the base64 encoded AES keys, 16, 24
+// or 32 bytes long. An empty primary makes a keyring that only decrypts.
+func NewKeyring(keys map[string]string, primary string) (*Keyring, error) {
+	k := &Keyring{primary: primary, keys: make(map[string]cipher.AEAD, len(keys))}
+
+	for id, encoded := range keys {
+		if id == "" {
+			return nil, errors.New("encryption key id could not be empty")
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode encryption key %q: %w", id, err)
+		}
+
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %q: %w", id, err)
+		}
+
+		k.keys[id] = aead
+	}
+
+	if _, ok := k.keys[primary]; primary != "" && !ok {
+		return nil, fmt.Errorf("%w: primary %q", ErrUnknownKey, primary)
+	}
+
+	return k, nil
+}
+
+// Primary returns the ID of the primary key, empty if the keyring only
+// decrypts or is nil.
+func (k *Keyring) Primary() string {
+	if k == nil {
+		return ""
+	}
+
+	return k.primary
+}
+
+// NewDataKey returns a new data key wrapped with the primary key.
+//
+// If the keyring has no primary key, or is nil, returns ErrNoPrimaryKey.
+func (k *Keyring) NewDataKey() (*DataKey, error) {
+	if k.Primary() == "" {
+		return nil, ErrNoPrimaryKey
+	}
+
+	key := make([]byte, dataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	return k.wrap(key)
+}
+
+// Unwrap returns the data key wrapped with the master key of keyID.
+//
+// If the keyring has no such key, or is nil, returns ErrUnknownKey.
+func (k *Keyring) Unwrap(keyID string, wrapped []byte) (*DataKey, error) {
+	if k == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+
+	master, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+
+	key, err := open(master, wrapped, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataKey{KeyID: keyID, Wrapped: wrapped, aead: aead}, nil
+}
+
+// Rewrap returns the data key wrapped with the master key of keyID
+// rewrapped with the primary key. The fields it encrypts stay valid.
+//
+// If the keyring has no primary key, or is nil, returns ErrNoPrimaryKey.
+func (k *Keyring) Rewrap(keyID string, wrapped []byte) (*DataKey, error) {
+	if k.Primary() == "" {
+		return nil, ErrNoPrimaryKey
+	}
+
+	master, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+
+	key, err := open(master, wrapped, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	return k.wrap(key)
+}
+
+func (k *Keyring) wrap(key []byte) (*DataKey, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := seal(k.keys[k.primary], key, []byte(k.primary))
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataKey{KeyID: k.primary, Wrapped: wrapped, aead: aead}, nil
+}
+
+// DataKey encrypts the fields of one record. KeyID and Wrapped are stored
+// alongside the fields.
+type DataKey struct {
+	// KeyID is the ID of the master key Wrapped is encrypted with.
+	KeyID   string
+	Wrapped []byte
+
+	aead cipher.AEAD
+}
+
+// Seal returns the base64 encoded ciphertext of the field. The name of
+// the field is authenticated, so the ciphertexts of the fields of a record
+// can not be swapped.
+func (d *DataKey) Seal(field, plaintext string) (string, error) {
+	b, err := d.SealBytes(field, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// Open returns the plaintext of the field sealed by Seal.
+func (d *DataKey) Open(field, sealed string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", field, err)
+	}
+
+	plaintext, err := d.OpenBytes(field, b)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// SealBytes is Seal of a binary field, the ciphertext is not encoded.
+func (d *DataKey) SealBytes(field string, plaintext []byte) ([]byte, error) {
+	return seal(d.aead, plaintext, []byte(field))
+}
+
+// OpenBytes returns the plaintext of the field sealed by SealBytes.
+func (d *DataKey) OpenBytes(field string, sealed []byte) ([]byte, error) {
+	plaintext, err := open(d.aead, sealed, []byte(field))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", field, err)
+	}
+
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal returns the random nonce followed by the ciphertext.
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(aead cipher.AEAD, b, additional []byte) ([]byte, error) {
+	if len(b) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	return aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], additional)
+}
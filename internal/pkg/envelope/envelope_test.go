@@ -0,0 +1,155 @@
+package envelope
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func key(b byte) string {
+	k := make([]byte, dataKeySize)
+	for i := range k {
+		k[i] = b
+	}
+
+	return base64.StdEncoding.EncodeToString(k)
+}
+
+func keyring(t *testing.T, keys map[string]string, primary string) *Keyring {
+	t.Helper()
+
+	k, err := NewKeyring(keys, primary)
+	require.NoError(t, err)
+
+	return k
+}
+
+func TestSealOpen(t *testing.T) {
+	k := keyring(t, map[string]string{"v1": key(1)}, "v1")
+
+	dk, err := k.NewDataKey()
+	require.NoError(t, err)
+	require.Equal(t, "v1", dk.KeyID)
+
+	sealed, err := dk.Seal("title", "Pay rent")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		field   string
+		sealed  string
+		want    string
+		wantErr bool
+	}{
+		{name: "round trip", field: "title", sealed: sealed, want: "Pay rent"},
+		{name: "swapped field", field: "description", sealed: sealed, wantErr: true},
+		{name: "too short", field: "title", sealed: base64.StdEncoding.EncodeToString([]byte("short")), wantErr: true},
+		{name: "not base64", field: "title", sealed: "%%%", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The data key is unwrapped as it is when the record is read.
+			dk, err := k.Unwrap(dk.KeyID, dk.Wrapped)
+			require.NoError(t, err)
+
+			got, err := dk.Open(tt.field, tt.sealed)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	old := keyring(t, map[string]string{"v1": key(1)}, "v1")
+
+	dk, err := old.NewDataKey()
+	require.NoError(t, err)
+
+	sealed, err := dk.Seal("title", "Pay rent")
+	require.NoError(t, err)
+
+	rotated := keyring(t, map[string]string{"v1": key(1), "v2": key(2)}, "v2")
+	decryptOnly := keyring(t, map[string]string{"v1": key(1)}, "")
+
+	tests := []struct {
+		name    string
+		do      func() (*DataKey, error)
+		keyID   string
+		wantErr error
+	}{
+		{
+			name:  "unwrap",
+			do:    func() (*DataKey, error) { return rotated.Unwrap(dk.KeyID, dk.Wrapped) },
+			keyID: "v1",
+		},
+		{
+			name:    "unwrap unknown key",
+			do:      func() (*DataKey, error) { return rotated.Unwrap("v0", dk.Wrapped) },
+			wantErr: ErrUnknownKey,
+		},
+		{
+			name:    "unwrap nil keyring",
+			do:      func() (*DataKey, error) { return (*Keyring)(nil).Unwrap(dk.KeyID, dk.Wrapped) },
+			wantErr: ErrUnknownKey,
+		},
+		{
+			name:  "rewrap after rotation",
+			do:    func() (*DataKey, error) { return rotated.Rewrap(dk.KeyID, dk.Wrapped) },
+			keyID: "v2",
+		},
+		{
+			name:    "rewrap unknown key",
+			do:      func() (*DataKey, error) { return rotated.Rewrap("v0", dk.Wrapped) },
+			wantErr: ErrUnknownKey,
+		},
+		{
+			name:    "rewrap decrypt only",
+			do:      func() (*DataKey, error) { return decryptOnly.Rewrap(dk.KeyID, dk.Wrapped) },
+			wantErr: ErrNoPrimaryKey,
+		},
+		{
+			name:    "rewrap nil keyring",
+			do:      func() (*DataKey, error) { return (*Keyring)(nil).Rewrap(dk.KeyID, dk.Wrapped) },
+			wantErr: ErrNoPrimaryKey,
+		},
+		{
+			name:    "new data key decrypt only",
+			do:      decryptOnly.NewDataKey,
+			wantErr: ErrNoPrimaryKey,
+		},
+		{
+			name:    "new data key nil keyring",
+			do:      (*Keyring)(nil).NewDataKey,
+			wantErr: ErrNoPrimaryKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.do()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.keyID, got.KeyID)
+
+			// The old fields stay valid with the unwrapped or rewrapped key.
+			dk, err := rotated.Unwrap(got.KeyID, got.Wrapped)
+			require.NoError(t, err)
+
+			plaintext, err := dk.Open("title", sealed)
+			require.NoError(t, err)
+			assert.Equal(t, "Pay rent", plaintext)
+		})
+	}
+}
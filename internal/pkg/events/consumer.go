@@ -0,0 +1,204 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+)
+
+// Handler handles an event. The event is acked if it returns nil.
+type Handler func(ctx context.Context, e Envelope) error
+
+type ConsumerOption func(*Consumer) error
+
+// WithBindings sets the routing keys the queue is bound to, e.g.
+// "task.completed" or "task.*". Defaults to "#", all events.
+func WithBindings(keys ...string) ConsumerOption {
+	return func(c *Consumer) error {
+		if len(keys) == 0 {
+			return errors.New("bindings could not be empty")
+		}
+		c.bindings = keys
+		return nil
+	}
+}
+
+// WithPrefetch sets how many unacked events the broker sends ahead.
+// Defaults to 10.
+func WithPrefetch(n int) ConsumerOption {
+	return func(c *Consumer) error {
+		if n <= 0 {
+			return errors.New("prefetch must be positive")
+		}
+		c.prefetch = n
+		return nil
+	}
+}
+
+// WithDeadLetter sets the exchange the rejected events are routed to,
+// see Consume. Without it they are dropped.
+func WithDeadLetter(exchange string) ConsumerOption {
+	return func(c *Consumer) error {
+		c.deadLetter = exchange
+		return nil
+	}
+}
+
+func WithLogger(log *slog.Logger) ConsumerOption {
+	return func(c *Consumer) error {
+		if log == nil {
+			return errors.New("logger is nil")
+		}
+		c.log = log
+		return nil
+	}
+}
+
+// Consumer receives the events of the topic exchange from a durable queue.
+type Consumer struct {
+	conn *amqp.Connection
+	ch   *amqp.Channel
+
+	exchange   string
+	queue      string
+	bindings   []string
+	prefetch   int
+	deadLetter string
+
+	log *slog.Logger
+}
+
+// NewConsumer connects to the broker at url and declares the durable topic
+// exchange, the durable queue and its bindings. The declarations are
+// idempotent, so several instances of a service share the queue and the
+// events are spread between them.
+func NewConsumer(url, exchange, queue string, opts ...ConsumerOption) (*Consumer, error) {
+	c := &Consumer{
+		exchange: exchange,
+		queue:    queue,
+		bindings: []string{"#"},
+		prefetch: 10,
+		log:      slog.Default(),
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+
+	c.conn = conn
+
+	if err = c.declare(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Consumer) declare() error {
+	ch, err := c.conn.Channel()
+	if err != nil {
+		return err
+	}
+
+	c.ch = ch
+
+	if err = ch.Qos(c.prefetch, 0, false); err != nil {
+		return err
+	}
+
+	if err = ch.ExchangeDeclare(c.exchange, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		return err
+	}
+
+	var args amqp.Table
+	if c.deadLetter != "" {
+		args = amqp.Table{"x-dead-letter-exchange": c.deadLetter}
+	}
+
+	if _, err = ch.QueueDeclare(c.queue, true, false, false, false, args); err != nil {
+		return err
+	}
+
+	for _, key := range c.bindings {
+		if err = ch.QueueBind(c.queue, key, c.exchange, false, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Consume passes the events of the queue to h one by one until ctx is done
+// or the connection is lost, in which case it returns amqp.ErrClosed. The
+// prefetched events are requeued by Close.
+//
+// An event h returns nil for is acked. An event h fails is requeued once
+// and rejected if it fails again, as well as an event that is not an
+// Envelope. The delivery is at least once, so h must be idempotent, e.g.
+// by Envelope.ID.
+func (c *Consumer) Consume(ctx context.Context, h Handler) error {
+	deliveries, err := c.ch.Consume(c.queue, "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case d, ok := <-deliveries:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return amqp.ErrClosed
+			}
+
+			c.handle(ctx, d, h)
+		}
+	}
+}
+
+func (c *Consumer) handle(ctx context.Context, d amqp.Delivery, h Handler) {
+	log := c.log.With(slog.String("routing_key", d.RoutingKey), slog.String("message_id", d.MessageId))
+
+	var e Envelope
+	if err := json.Unmarshal(d.Body, &e); err != nil {
+		log.Error("failed to decode event", sl.Err(err))
+		c.settle(log, d.Reject(false))
+		return
+	}
+
+	log = log.With(slog.String("event_id", e.ID))
+
+	if err := h(ctx, e); err != nil {
+		log.Error("failed to handle event", sl.Err(err), slog.Bool("redelivered", d.Redelivered))
+		c.settle(log, d.Nack(false, !d.Redelivered))
+		return
+	}
+
+	c.settle(log, d.Ack(false))
+}
+
+func (c *Consumer) settle(log *slog.Logger, err error) {
+	if err != nil {
+		log.Error("failed to settle event", sl.Err(err))
+	}
+}
+
+// Close closes the connection to the broker.
+func (c *Consumer) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,99 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// settlement is how a delivery was settled.
+type settlement struct {
+	acked    bool
+	nacked   bool
+	rejected bool
+	requeue  bool
+}
+
+// fakeAcknowledger records the settlement of a delivery.
+type fakeAcknowledger struct {
+	settled []settlement
+}
+
+func (a *fakeAcknowledger) Ack(uint64, bool) error {
+	a.settled = append(a.settled, settlement{acked: true})
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(_ uint64, _ bool, requeue bool) error {
+	a.settled = append(a.settled, settlement{nacked: true, requeue: requeue})
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(_ uint64, requeue bool) error {
+	a.settled = append(a.settled, settlement{rejected: true, requeue: requeue})
+	return nil
+}
+
+func TestHandle(t *testing.T) {
+	c := &Consumer{log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	e, err := New(TypeTaskCompleted, TaskVersion, Task{ID: "task", UserID: "user"})
+	require.NoError(t, err)
+
+	body, err := json.Marshal(e)
+	require.NoError(t, err)
+
+	errHandler := errors.New("failed")
+
+	tests := []struct {
+		name        string
+		body        []byte
+		redelivered bool
+		err         error
+		want        settlement
+	}{
+		{name: "handled", body: body, want: settlement{acked: true}},
+		{name: "failed", body: body, err: errHandler, want: settlement{nacked: true, requeue: true}},
+		{name: "failed again", body: body, redelivered: true, err: errHandler, want: settlement{nacked: true}},
+		{name: "not an envelope", body: []byte("task completed"), want: settlement{rejected: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ack := &fakeAcknowledger{}
+			d := amqp.Delivery{
+				Acknowledger: ack,
+				RoutingKey:   TypeTaskCompleted,
+				Redelivered:  tt.redelivered,
+				Body:         tt.body,
+			}
+
+			var got []Envelope
+			c.handle(context.Background(), d, func(_ context.Context, e Envelope) error {
+				got = append(got, e)
+				return tt.err
+			})
+
+			assert.Equal(t, []settlement{tt.want}, ack.settled)
+
+			if tt.want.rejected {
+				assert.Empty(t, got, "an event that is not an envelope is handled")
+				return
+			}
+
+			require.Len(t, got, 1)
+			assert.Equal(t, e.ID, got[0].ID)
+
+			var task Task
+			require.NoError(t, got[0].Decode(&task))
+			assert.Equal(t, "task", task.ID)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Package events describes the domain events published to the RabbitMQ
+// topic exchange and consumes them.
+//
+// Every message is an Envelope in JSON. The routing key is the event
+// type, e.g. task.completed, so a queue bound to "task.*" receives all
+// task events. The schema of Data is versioned per type: an incompatible
+// change bumps the version, and consumers should skip the versions they
+// do not know.
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/data"
+)
+
+// The event types, which are the routing keys of the messages.
+const (
+	TypeUserCreated   = "user.created"
+	TypeTaskCreated   = "task.created"
+	TypeTaskUpdated   = "task.updated"
+	TypeTaskCompleted = "task.completed"
+	TypeTaskSnoozed   = "task.snoozed"
+	TypeTaskDeleted   = "task.deleted"
+)
+
+// The current schema versions of Data.
+const (
+	UserVersion = 1
+	TaskVersion = 1
+)
+
+// Envelope is a published event.
+type Envelope struct {
+	// ID identifies the event. It is kept on redelivery, so consumers
+	// drop duplicates by it.
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// New returns the envelope of the event that occurred now.
+func New(typ string, version int, v any) (Envelope, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	return Envelope{
+		ID:         uuid.NewString(),
+		Type:       typ,
+		Version:    version,
+		OccurredAt: time.Now().UTC(),
+		Data:       b,
+	}, nil
+}
+
+// Decode decodes Data into v, e.g. Task.
+func (e Envelope) Decode(v any) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// User is the data of the user events, version 1.
+type User struct {
+	ID       string `json:"id"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+// Task is the data of the task events, version 1. The task.deleted event
+// has the IDs only. The times are nil if they are not set. The title and
+// the description are set only if the producer keeps the task contents in
+// the events.
+type Task struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	Title       string     `json:"title,omitempty"`
+	Description string     `json:"description,omitempty"`
+	IsCompleted bool       `json:"is_completed"`
+	Priority    string     `json:"priority,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	DueOn       *time.Time `json:"due_on,omitempty"`
+	HiddenUntil *time.Time `json:"hidden_until,omitempty"`
+}
+
+// UserOf returns the event data of the user.
+func UserOf(u data.User) User {
+	return User{ID: u.ID, Email: u.Email, Username: u.Username}
+}
+
+// TaskOf returns the event data of the task.
+func TaskOf(t data.Task) Task {
+	return Task{
+		ID:          t.ID,
+		UserID:      t.UserID,
+		Title:       t.Title,
+		Description: t.Description,
+		IsCompleted: t.IsCompleted,
+		Priority:    t.Priority.String(),
+		Tags:        t.Tags,
+		DueOn:       timeOrNil(t.DueOn),
+		HiddenUntil: timeOrNil(t.HiddenUntil),
+	}
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
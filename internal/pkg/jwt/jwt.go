@@ -18,9 +18,10 @@ func CreateToken(payload *data.TokenPayload, ttl time.Duration, key *rsa.Private
 	}
 
 	claims := data.Claims{
-		TokenID: payload.ID,
-		UserID:  payload.UserID,
-		Email:   payload.Email,
+		TokenID:     payload.ID,
+		UserID:      payload.UserID,
+		WorkspaceID: payload.WorkspaceID,
+		Email:       payload.Email,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 			NotBefore: jwt.NewNumericDate(now),
@@ -60,9 +61,10 @@ func ValidateToken(token string, key *rsa.PublicKey) (*data.TokenPayload, error)
 	}
 
 	payload := &data.TokenPayload{
-		ID:     claims.TokenID,
-		UserID: claims.UserID,
-		Email:  claims.Email,
+		ID:          claims.TokenID,
+		UserID:      claims.UserID,
+		WorkspaceID: claims.WorkspaceID,
+		Email:       claims.Email,
 	}
 
 	return payload, nil
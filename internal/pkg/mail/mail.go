@@ -0,0 +1,71 @@
+// Package mail publishes emails to the RabbitMQ queue of the email sender,
+// see the emailsender service.
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const mimeHeaders = "MIME-version: 1.0;\nContent-Type: text/plain; charset=\"utf-8\";\n\n"
+
+// Queue publishes the emails to the email queue.
+type Queue struct {
+	conn *amqp.Connection
+	ch   *amqp.Channel
+	q    amqp.Queue
+}
+
+// New connects to RabbitMQ at url and declares the email queue the same
+// way the email sender does.
+func New(url, queueName string) (*Queue, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	q, err := ch.QueueDeclare(
+		queueName,
+		false,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &Queue{conn: conn, ch: ch, q: q}, nil
+}
+
+// Send publishes a plain text email. The email sender reads the recipient
+// from the To header.
+func (q *Queue) Send(ctx context.Context, to, subject, body string) error {
+	msg := fmt.Sprintf("To: %s\nSubject: %s\n%s%s", to, subject, mimeHeaders, body)
+
+	return q.ch.PublishWithContext(ctx,
+		"",
+		q.q.Name,
+		false,
+		false,
+		amqp.Publishing{
+			ContentType: "text/plain",
+			Body:        []byte(msg),
+		},
+	)
+}
+
+// Close closes the connection.
+func (q *Queue) Close() error {
+	return q.conn.Close()
+}
@@ -0,0 +1,407 @@
+// Package quickadd parses a single free-text line into task attributes.
+//
+// A line such as "Pay rent tomorrow 9am #home !high every month" is split into
+// the title ("Pay rent"), the due date and time, tags, priority and recurrence.
+// Date and recurrence words are recognized in English and Russian, everything
+// that is not recognized becomes part of the title.
+package quickadd
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+)
+
+var ErrEmptyTitle = errors.New("the title could not be empty")
+
+// Result is a parsed quick-add line.
+type Result struct {
+	Title string
+	// Due is zero if the line has no date and no time.
+	Due time.Time
+	// HasTime reports whether the time of day was given explicitly.
+	HasTime    bool
+	Tags       []string
+	Priority   data.Priority
+	Recurrence Recurrence
+}
+
+// Parse parses the line relative to now. Relative dates ("tomorrow",
+// "in 3 days", "friday") are resolved in the location of now.
+//
+// If nothing is left for the title returns ErrEmptyTitle.
+func Parse(line string, now time.Time) (Result, error) {
+	p := &parser{
+		words: strings.Fields(line),
+		now:   now,
+	}
+
+	for p.pos < len(p.words) {
+		if p.matchTag() || p.matchPriority() || p.matchRecurrence() || p.matchDate() || p.matchTime() {
+			continue
+		}
+
+		p.title = append(p.title, p.words[p.pos])
+		p.pos++
+	}
+
+	res := p.result()
+	if res.Title == "" {
+		return Result{}, ErrEmptyTitle
+	}
+
+	return res, nil
+}
+
+type parser struct {
+	words []string
+	pos   int
+	now   time.Time
+
+	title []string
+	tags  []string
+
+	priority   data.Priority
+	recurrence Recurrence
+
+	date    time.Time
+	hasDate bool
+
+	hour, min int
+	hasTime   bool
+}
+
+// word returns the normalized word at offset n from the current position or
+// an empty string if it is out of range.
+func (p *parser) word(n int) string {
+	if p.pos+n >= len(p.words) {
+		return ""
+	}
+
+	return strings.TrimRight(strings.ToLower(p.words[p.pos+n]), ",;")
+}
+
+func (p *parser) result() Result {
+	res := Result{
+		Title:      strings.Join(p.title, " "),
+		Tags:       p.tags,
+		Priority:   p.priority,
+		Recurrence: p.recurrence,
+		HasTime:    p.hasTime,
+	}
+
+	today := startOfDay(p.now)
+
+	switch {
+	case p.hasDate || p.hasTime:
+		day := today
+		if p.hasDate {
+			day = p.date
+		}
+
+		res.Due = day
+		if p.hasTime {
+			res.Due = time.Date(day.Year(), day.Month(), day.Day(), p.hour, p.min, 0, 0, day.Location())
+			if !p.hasDate && res.Due.Before(p.now) {
+				res.Due = res.Due.AddDate(0, 0, 1)
+			}
+		}
+	case len(p.recurrence.Weekdays) > 0:
+		res.Due = nextWeekday(today, p.recurrence.Weekdays[0])
+	}
+
+	return res
+}
+
+func (p *parser) matchTag() bool {
+	w := p.words[p.pos]
+	if len(w) < 2 || w[0] != '#' {
+		return false
+	}
+
+	tag := strings.TrimRight(w[1:], ",;.")
+	for _, t := range p.tags {
+		if strings.EqualFold(t, tag) {
+			p.pos++
+			return true
+		}
+	}
+
+	p.tags = append(p.tags, tag)
+	p.pos++
+	return true
+}
+
+func (p *parser) matchPriority() bool {
+	w := p.word(0)
+	if len(w) < 2 || w[0] != '!' {
+		return false
+	}
+
+	prio, ok := priorities[w[1:]]
+	if !ok {
+		return false
+	}
+
+	p.priority = prio
+	p.pos++
+	return true
+}
+
+func (p *parser) matchRecurrence() bool {
+	w := p.word(0)
+
+	if freq, ok := adverbs[w]; ok {
+		p.recurrence = Recurrence{Freq: freq, Interval: 1}
+		p.pos++
+		return true
+	}
+
+	if _, ok := every[w]; !ok {
+		return false
+	}
+
+	next := p.word(1)
+
+	if wd, ok := weekdays[next]; ok {
+		p.recurrence = Recurrence{Freq: Weekly, Interval: 1, Weekdays: []time.Weekday{wd}}
+		p.pos += 2
+		return true
+	}
+
+	if next == "weekday" || next == "workday" || next == "будний" {
+		p.recurrence = Recurrence{Freq: Weekly, Interval: 1, Weekdays: workdays}
+		p.pos += 2
+		return true
+	}
+
+	if freq, ok := units[next]; ok {
+		p.recurrence = Recurrence{Freq: freq, Interval: 1}
+		p.pos += 2
+		return true
+	}
+
+	n, ok := number(next)
+	if next == "other" {
+		n, ok = 2, true
+	}
+	if !ok {
+		return false
+	}
+
+	freq, ok := units[p.word(2)]
+	if !ok {
+		return false
+	}
+
+	p.recurrence = Recurrence{Freq: freq, Interval: n}
+	p.pos += 3
+	return true
+}
+
+func (p *parser) matchDate() bool {
+	today := startOfDay(p.now)
+	w := p.word(0)
+
+	if offset, ok := relativeDays[w]; ok {
+		p.setDate(today.AddDate(0, 0, offset), 1)
+		return true
+	}
+
+	if w == "day" && p.word(1) == "after" && p.word(2) == "tomorrow" {
+		p.setDate(today.AddDate(0, 0, 2), 3)
+		return true
+	}
+
+	// "on friday", "в пятницу", "во вторник".
+	skip := 0
+	if w == "on" || w == "в" || w == "во" {
+		skip = 1
+	}
+
+	if wd, ok := weekdays[p.word(skip)]; ok {
+		p.setDate(nextWeekday(today, wd), skip+1)
+		return true
+	}
+
+	if d, n, ok := p.absoluteDate(skip); ok {
+		p.setDate(d, skip+n)
+		return true
+	}
+
+	if w == "next" {
+		if wd, ok := weekdays[p.word(1)]; ok {
+			p.setDate(nextWeekday(today, wd), 2)
+			return true
+		}
+
+		if unit, ok := units[p.word(1)]; ok {
+			p.setDate(addUnit(today, unit, 1), 2)
+			return true
+		}
+	}
+
+	// "in 3 days", "in a week", "через 3 дня", "через неделю".
+	if w == "in" || w == "через" {
+		if unit, ok := units[p.word(1)]; ok && w == "через" {
+			p.setDate(addUnit(today, unit, 1), 2)
+			return true
+		}
+
+		n, ok := number(p.word(1))
+		if !ok {
+			return false
+		}
+
+		unit, ok := units[p.word(2)]
+		if !ok {
+			return false
+		}
+
+		p.setDate(addUnit(today, unit, n), 3)
+		return true
+	}
+
+	return false
+}
+
+// absoluteDate matches "2024-05-01", "01.05.2024", "01.05", "may 1", "1st may"
+// and "1 мая" at the given offset. Dates without a year that already passed
+// this year are moved to the next year.
+func (p *parser) absoluteDate(offset int) (time.Time, int, bool) {
+	loc := p.now.Location()
+	w := strings.TrimRight(p.word(offset), ".")
+
+	if d, err := time.ParseInLocation("2006-01-02", w, loc); err == nil {
+		return d, 1, true
+	}
+
+	if d, err := time.ParseInLocation("02.01.2006", w, loc); err == nil {
+		return d, 1, true
+	}
+
+	if d, err := time.ParseInLocation("2.1.2006", w, loc); err == nil {
+		return d, 1, true
+	}
+
+	for _, layout := range []string{"02.01", "2.1"} {
+		if d, err := time.ParseInLocation(layout, w, loc); err == nil {
+			return p.upcoming(d.Month(), d.Day()), 1, true
+		}
+	}
+
+	if month, ok := months[w]; ok {
+		if day, ok := dayOfMonth(p.word(offset + 1)); ok {
+			return p.upcoming(month, day), 2, true
+		}
+	}
+
+	if day, ok := dayOfMonth(w); ok {
+		if month, ok := months[strings.TrimRight(p.word(offset+1), ".")]; ok {
+			return p.upcoming(month, day), 2, true
+		}
+	}
+
+	return time.Time{}, 0, false
+}
+
+func (p *parser) upcoming(month time.Month, day int) time.Time {
+	today := startOfDay(p.now)
+
+	d := time.Date(today.Year(), month, day, 0, 0, 0, 0, today.Location())
+	if d.Before(today) {
+		d = d.AddDate(1, 0, 0)
+	}
+
+	return d
+}
+
+func (p *parser) setDate(d time.Time, consumed int) {
+	p.date = d
+	p.hasDate = true
+	p.pos += consumed
+}
+
+func (p *parser) matchTime() bool {
+	w := p.word(0)
+
+	// A bare hour is only a time after a preposition: "at 9", "в 9 вечера".
+	prefixed := w == "at" || w == "в" || w == "@"
+	skip := 0
+	if prefixed {
+		skip = 1
+	}
+
+	if h, ok := namedTimes[p.word(skip)]; ok {
+		p.setTime(h, 0, skip+1)
+		return true
+	}
+
+	next := p.word(skip + 1)
+	bare := prefixed || next == "am" || next == "pm"
+
+	hour, min, meridiem, ok := clock(p.word(skip), bare)
+	if !ok {
+		return false
+	}
+
+	consumed := skip + 1
+
+	if meridiem == "" {
+		if m, ok := meridiems[p.word(consumed)]; ok {
+			meridiem = m
+			consumed++
+		}
+	}
+
+	if meridiem != "" {
+		if hour < 1 || hour > 12 {
+			return false
+		}
+
+		switch {
+		case meridiem == "pm" && hour != 12:
+			hour += 12
+		case meridiem == "am" && hour == 12:
+			hour = 0
+		}
+	}
+
+	p.setTime(hour, min, consumed)
+	return true
+}
+
+func (p *parser) setTime(hour, min, consumed int) {
+	p.hour, p.min = hour, min
+	p.hasTime = true
+	p.pos += consumed
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// nextWeekday returns the closest day after from that falls on wd.
+func nextWeekday(from time.Time, wd time.Weekday) time.Time {
+	days := (int(wd) - int(from.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+
+	return from.AddDate(0, 0, days)
+}
+
+func addUnit(t time.Time, unit Frequency, n int) time.Time {
+	switch unit {
+	case Weekly:
+		return t.AddDate(0, 0, 7*n)
+	case Monthly:
+		return t.AddDate(0, n, 0)
+	case Yearly:
+		return t.AddDate(n, 0, 0)
+	default:
+		return t.AddDate(0, 0, n)
+	}
+}
@@ -0,0 +1,234 @@
+package quickadd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// now is Wednesday, 15 May 2024, 10:00.
+var now = time.Date(2024, time.May, 15, 10, 0, 0, 0, time.UTC)
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func at(year int, month time.Month, d, hour, min int) time.Time {
+	return time.Date(year, month, d, hour, min, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want Result
+	}{
+		{
+			name: "title only",
+			line: "Pay rent",
+			want: Result{Title: "Pay rent"},
+		},
+		{
+			name: "everything",
+			line: "Pay rent tomorrow 9am #home !high every month",
+			want: Result{
+				Title:      "Pay rent",
+				Due:        at(2024, time.May, 16, 9, 0),
+				HasTime:    true,
+				Tags:       []string{"home"},
+				Priority:   data.PriorityHigh,
+				Recurrence: Recurrence{Freq: Monthly, Interval: 1},
+			},
+		},
+		{
+			name: "everything in russian",
+			line: "Позвонить маме завтра в 9 вечера #семья !срочно",
+			want: Result{
+				Title:    "Позвонить маме",
+				Due:      at(2024, time.May, 16, 21, 0),
+				HasTime:  true,
+				Tags:     []string{"семья"},
+				Priority: data.PriorityHigh,
+			},
+		},
+
+		// Relative dates.
+		{name: "today", line: "call bob today", want: Result{Title: "call bob", Due: day(2024, time.May, 15)}},
+		{name: "tomorrow", line: "call bob tmrw", want: Result{Title: "call bob", Due: day(2024, time.May, 16)}},
+		{name: "day after tomorrow", line: "call bob day after tomorrow", want: Result{Title: "call bob", Due: day(2024, time.May, 17)}},
+		{name: "in days", line: "call bob in 3 days", want: Result{Title: "call bob", Due: day(2024, time.May, 18)}},
+		{name: "in zero days", line: "call bob in 0 days", want: Result{Title: "call bob in 0 days"}},
+		{name: "in negative days", line: "call bob in -3 days", want: Result{Title: "call bob in -3 days"}},
+		{name: "in too many years", line: "call bob in 100000 years", want: Result{Title: "call bob in 100000 years"}},
+		{name: "in at most years", line: "call bob in 1000 years", want: Result{Title: "call bob", Due: day(3024, time.May, 15)}},
+		{name: "через много лет", line: "позвонить через 1001 год", want: Result{Title: "позвонить через 1001 год"}},
+		{name: "in a week", line: "call bob in a week", want: Result{Title: "call bob", Due: day(2024, time.May, 22)}},
+		{name: "next month", line: "call bob next month", want: Result{Title: "call bob", Due: day(2024, time.June, 15)}},
+		{name: "сегодня", line: "позвонить сегодня", want: Result{Title: "позвонить", Due: day(2024, time.May, 15)}},
+		{name: "завтра", line: "позвонить Завтра", want: Result{Title: "позвонить", Due: day(2024, time.May, 16)}},
+		{name: "послезавтра", line: "позвонить послезавтра", want: Result{Title: "позвонить", Due: day(2024, time.May, 17)}},
+		{name: "через дня", line: "позвонить через 3 дня", want: Result{Title: "позвонить", Due: day(2024, time.May, 18)}},
+		{name: "через неделю", line: "позвонить через неделю", want: Result{Title: "позвонить", Due: day(2024, time.May, 22)}},
+
+		// Weekdays are always in the future, the same weekday is a week ahead.
+		{name: "weekday", line: "call bob friday", want: Result{Title: "call bob", Due: day(2024, time.May, 17)}},
+		{name: "on weekday", line: "call bob on mon", want: Result{Title: "call bob", Due: day(2024, time.May, 20)}},
+		{name: "same weekday", line: "call bob wednesday", want: Result{Title: "call bob", Due: day(2024, time.May, 22)}},
+		{name: "next weekday", line: "call bob next tuesday", want: Result{Title: "call bob", Due: day(2024, time.May, 21)}},
+		{name: "в пятницу", line: "встреча в пятницу", want: Result{Title: "встреча", Due: day(2024, time.May, 17)}},
+		{name: "во вторник", line: "встреча во вторник", want: Result{Title: "встреча", Due: day(2024, time.May, 21)}},
+		{name: "вс", line: "встреча вс", want: Result{Title: "встреча", Due: day(2024, time.May, 19)}},
+
+		// Absolute dates.
+		{name: "iso date", line: "pay 2024-06-01", want: Result{Title: "pay", Due: day(2024, time.June, 1)}},
+		{name: "dotted date", line: "pay 01.06.2024", want: Result{Title: "pay", Due: day(2024, time.June, 1)}},
+		{name: "short dotted date", line: "pay 1.6.2024", want: Result{Title: "pay", Due: day(2024, time.June, 1)}},
+		{name: "dotted date without year", line: "pay 20.05", want: Result{Title: "pay", Due: day(2024, time.May, 20)}},
+		{name: "month and day", line: "pay may 20", want: Result{Title: "pay", Due: day(2024, time.May, 20)}},
+		{name: "day and month", line: "pay 1st june", want: Result{Title: "pay", Due: day(2024, time.June, 1)}},
+		{name: "день и месяц", line: "оплатить 20 мая", want: Result{Title: "оплатить", Due: day(2024, time.May, 20)}},
+		{name: "в день и месяц", line: "оплатить в 1 июня", want: Result{Title: "оплатить", Due: day(2024, time.June, 1)}},
+		{name: "today without year", line: "pay may 15", want: Result{Title: "pay", Due: day(2024, time.May, 15)}},
+
+		// Dates without a year that passed this year roll over to the next.
+		{name: "passed month and day", line: "pay may 1", want: Result{Title: "pay", Due: day(2025, time.May, 1)}},
+		{name: "passed dotted date", line: "pay 14.05", want: Result{Title: "pay", Due: day(2025, time.May, 14)}},
+		{name: "passed день и месяц", line: "оплатить 1 января", want: Result{Title: "оплатить", Due: day(2025, time.January, 1)}},
+		{name: "passed date with year", line: "pay 2024-05-01", want: Result{Title: "pay", Due: day(2024, time.May, 1)}},
+
+		// Times without a date are today, or tomorrow if they passed.
+		{name: "24h time", line: "call 21:00", want: Result{Title: "call", Due: at(2024, time.May, 15, 21, 0), HasTime: true}},
+		{name: "pm", line: "call 9:30pm", want: Result{Title: "call", Due: at(2024, time.May, 15, 21, 30), HasTime: true}},
+		{name: "separate pm", line: "call 3 pm", want: Result{Title: "call", Due: at(2024, time.May, 15, 15, 0), HasTime: true}},
+		{name: "passed am", line: "call 9am", want: Result{Title: "call", Due: at(2024, time.May, 16, 9, 0), HasTime: true}},
+		{name: "12am", line: "call 12am", want: Result{Title: "call", Due: at(2024, time.May, 16, 0, 0), HasTime: true}},
+		{name: "12pm", line: "call 12pm", want: Result{Title: "call", Due: at(2024, time.May, 15, 12, 0), HasTime: true}},
+		{name: "at hour", line: "call at 11", want: Result{Title: "call", Due: at(2024, time.May, 15, 11, 0), HasTime: true}},
+		{name: "noon", line: "lunch noon", want: Result{Title: "lunch", Due: at(2024, time.May, 15, 12, 0), HasTime: true}},
+		{name: "в утра", line: "позвонить в 9 утра", want: Result{Title: "позвонить", Due: at(2024, time.May, 16, 9, 0), HasTime: true}},
+		{name: "в дня", line: "позвонить в 3 дня", want: Result{Title: "позвонить", Due: at(2024, time.May, 15, 15, 0), HasTime: true}},
+		{name: "в полночь", line: "позвонить в полночь", want: Result{Title: "позвонить", Due: at(2024, time.May, 16, 0, 0), HasTime: true}},
+		{name: "date and time", line: "call friday 8am", want: Result{Title: "call", Due: at(2024, time.May, 17, 8, 0), HasTime: true}},
+		{name: "passed time of a date", line: "call today 8am", want: Result{Title: "call", Due: at(2024, time.May, 15, 8, 0), HasTime: true}},
+		{name: "bare hour is title", line: "read 7 books", want: Result{Title: "read 7 books"}},
+		{name: "invalid meridiem hour", line: "call 13pm", want: Result{Title: "call 13pm"}},
+		{name: "invalid minutes", line: "call 9:7", want: Result{Title: "call 9:7"}},
+
+		// Tags and priority.
+		{name: "tags", line: "buy milk #shop #home", want: Result{Title: "buy milk", Tags: []string{"shop", "home"}}},
+		{name: "duplicate tags", line: "buy milk #shop, #Shop", want: Result{Title: "buy milk", Tags: []string{"shop"}}},
+		{name: "hash only", line: "buy # milk", want: Result{Title: "buy # milk"}},
+		{name: "low", line: "buy milk !low", want: Result{Title: "buy milk", Priority: data.PriorityLow}},
+		{name: "medium", line: "buy milk !2", want: Result{Title: "buy milk", Priority: data.PriorityMedium}},
+		{name: "низкий", line: "купить молоко !низкий", want: Result{Title: "купить молоко", Priority: data.PriorityLow}},
+		{name: "last priority wins", line: "buy milk !low !high", want: Result{Title: "buy milk", Priority: data.PriorityHigh}},
+		{name: "unknown priority", line: "buy milk !soon", want: Result{Title: "buy milk !soon"}},
+
+		// Recurrence.
+		{name: "daily", line: "stretch daily", want: Result{Title: "stretch", Recurrence: Recurrence{Freq: Daily, Interval: 1}}},
+		{name: "every unit", line: "stretch every week", want: Result{Title: "stretch", Recurrence: Recurrence{Freq: Weekly, Interval: 1}}},
+		{name: "every n units", line: "water plants every 3 days", want: Result{Title: "water plants", Recurrence: Recurrence{Freq: Daily, Interval: 3}}},
+		{name: "every other", line: "pay every other month", want: Result{Title: "pay", Recurrence: Recurrence{Freq: Monthly, Interval: 2}}},
+		{
+			name: "every weekday",
+			line: "gym every monday",
+			want: Result{Title: "gym", Due: day(2024, time.May, 20), Recurrence: Recurrence{Freq: Weekly, Interval: 1, Weekdays: []time.Weekday{time.Monday}}},
+		},
+		{
+			name: "every workday",
+			line: "standup every weekday",
+			want: Result{Title: "standup", Due: day(2024, time.May, 20), Recurrence: Recurrence{Freq: Weekly, Interval: 1, Weekdays: workdays}},
+		},
+		{
+			name: "every weekday with a date",
+			line: "gym every friday tomorrow",
+			want: Result{Title: "gym", Due: day(2024, time.May, 16), Recurrence: Recurrence{Freq: Weekly, Interval: 1, Weekdays: []time.Weekday{time.Friday}}},
+		},
+		{name: "ежемесячно", line: "платить ежемесячно", want: Result{Title: "платить", Recurrence: Recurrence{Freq: Monthly, Interval: 1}}},
+		{name: "каждую неделю", line: "бег каждую неделю", want: Result{Title: "бег", Recurrence: Recurrence{Freq: Weekly, Interval: 1}}},
+		{name: "каждые n", line: "полив каждые 2 дня", want: Result{Title: "полив", Recurrence: Recurrence{Freq: Daily, Interval: 2}}},
+		{
+			name: "каждую пятницу",
+			line: "уборка каждую пятницу",
+			want: Result{Title: "уборка", Due: day(2024, time.May, 17), Recurrence: Recurrence{Freq: Weekly, Interval: 1, Weekdays: []time.Weekday{time.Friday}}},
+		},
+		{name: "every without unit", line: "every single day", want: Result{Title: "every single day"}},
+		{name: "every zero", line: "stretch every 0 days", want: Result{Title: "stretch every 0 days"}},
+		{name: "every negative", line: "stretch every -2 weeks", want: Result{Title: "stretch every -2 weeks"}},
+		{name: "every too many", line: "stretch every 1001 days", want: Result{Title: "stretch every 1001 days"}},
+		{name: "every at most", line: "stretch every 1000 days", want: Result{Title: "stretch", Recurrence: Recurrence{Freq: Daily, Interval: 1000}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.line, now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseEmptyTitle(t *testing.T) {
+	for _, line := range []string{"", "   ", "tomorrow 9am", "#home !high every month", "завтра в 9 вечера"} {
+		t.Run(line, func(t *testing.T) {
+			_, err := Parse(line, now)
+			assert.ErrorIs(t, err, ErrEmptyTitle)
+		})
+	}
+}
+
+func TestParseLocation(t *testing.T) {
+	// 23:30 UTC is already the next day in Moscow.
+	msk := time.FixedZone("MSK", 3*60*60)
+	now := time.Date(2024, time.May, 15, 23, 30, 0, 0, time.UTC).In(msk)
+
+	got, err := Parse("call tomorrow 9am", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.May, 17, 9, 0, 0, 0, msk), got.Due)
+}
+
+func TestParseYearRollover(t *testing.T) {
+	now := time.Date(2024, time.December, 31, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		line string
+		want time.Time
+	}{
+		{"tomorrow", day(2025, time.January, 1)},
+		{"jan 1", day(2025, time.January, 1)},
+		{"31.12", day(2024, time.December, 31)},
+		{"next week", day(2025, time.January, 7)},
+		{"monday", day(2025, time.January, 6)},
+		{"8am", at(2025, time.January, 1, 8, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := Parse("party "+tt.line, now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Due)
+		})
+	}
+}
+
+func TestRecurrenceString(t *testing.T) {
+	tests := []struct {
+		r    Recurrence
+		want string
+	}{
+		{Recurrence{}, ""},
+		{Recurrence{Freq: Daily, Interval: 1}, "FREQ=DAILY"},
+		{Recurrence{Freq: Monthly, Interval: 2}, "FREQ=MONTHLY;INTERVAL=2"},
+		{Recurrence{Freq: Weekly, Interval: 2, Weekdays: []time.Weekday{time.Monday, time.Friday}}, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR"},
+		{Recurrence{Freq: Weekly, Interval: 1, Weekdays: []time.Weekday{time.Sunday}}, "FREQ=WEEKLY;BYDAY=SU"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.r.String())
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package quickadd
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// Recurrence is a repeat rule of a task.
+type Recurrence struct {
+	Freq     Frequency
+	Interval int
+	Weekdays []time.Weekday
+}
+
+var byDay = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+func (r Recurrence) IsZero() bool {
+	return r.Freq == ""
+}
+
+// String returns the recurrence in the iCalendar RRULE format,
+// e.g. "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR".
+//
+// Zero recurrence is formatted as an empty string.
+func (r Recurrence) String() string {
+	if r.IsZero() {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("FREQ=")
+	sb.WriteString(string(r.Freq))
+
+	if r.Interval > 1 {
+		sb.WriteString(";INTERVAL=")
+		sb.WriteString(strconv.Itoa(r.Interval))
+	}
+
+	if len(r.Weekdays) > 0 {
+		days := make([]string, len(r.Weekdays))
+		for i, wd := range r.Weekdays {
+			days[i] = byDay[wd]
+		}
+
+		sb.WriteString(";BYDAY=")
+		sb.WriteString(strings.Join(days, ","))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,273 @@
+package quickadd
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+)
+
+var priorities = map[string]data.Priority{
+	"low":     data.PriorityLow,
+	"l":       data.PriorityLow,
+	"1":       data.PriorityLow,
+	"низкий":  data.PriorityLow,
+	"medium":  data.PriorityMedium,
+	"med":     data.PriorityMedium,
+	"m":       data.PriorityMedium,
+	"2":       data.PriorityMedium,
+	"средний": data.PriorityMedium,
+	"high":    data.PriorityHigh,
+	"h":       data.PriorityHigh,
+	"3":       data.PriorityHigh,
+	"urgent":  data.PriorityHigh,
+	"высокий": data.PriorityHigh,
+	"срочно":  data.PriorityHigh,
+}
+
+var relativeDays = map[string]int{
+	"today":       0,
+	"сегодня":     0,
+	"tomorrow":    1,
+	"tmrw":        1,
+	"завтра":      1,
+	"послезавтра": 2,
+}
+
+var weekdays = map[string]time.Weekday{
+	"monday":      time.Monday,
+	"mon":         time.Monday,
+	"понедельник": time.Monday,
+	"пн":          time.Monday,
+	"tuesday":     time.Tuesday,
+	"tue":         time.Tuesday,
+	"tues":        time.Tuesday,
+	"вторник":     time.Tuesday,
+	"вт":          time.Tuesday,
+	"wednesday":   time.Wednesday,
+	"wed":         time.Wednesday,
+	"среда":       time.Wednesday,
+	"среду":       time.Wednesday,
+	"ср":          time.Wednesday,
+	"thursday":    time.Thursday,
+	"thu":         time.Thursday,
+	"thurs":       time.Thursday,
+	"четверг":     time.Thursday,
+	"чт":          time.Thursday,
+	"friday":      time.Friday,
+	"fri":         time.Friday,
+	"пятница":     time.Friday,
+	"пятницу":     time.Friday,
+	"пт":          time.Friday,
+	"saturday":    time.Saturday,
+	"sat":         time.Saturday,
+	"суббота":     time.Saturday,
+	"субботу":     time.Saturday,
+	"сб":          time.Saturday,
+	"sunday":      time.Sunday,
+	"sun":         time.Sunday,
+	"воскресенье": time.Sunday,
+	"вс":          time.Sunday,
+}
+
+var workdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+var months = map[string]time.Month{
+	"january":   time.January,
+	"jan":       time.January,
+	"январь":    time.January,
+	"января":    time.January,
+	"янв":       time.January,
+	"february":  time.February,
+	"feb":       time.February,
+	"февраль":   time.February,
+	"февраля":   time.February,
+	"фев":       time.February,
+	"march":     time.March,
+	"mar":       time.March,
+	"март":      time.March,
+	"марта":     time.March,
+	"мар":       time.March,
+	"april":     time.April,
+	"apr":       time.April,
+	"апрель":    time.April,
+	"апреля":    time.April,
+	"апр":       time.April,
+	"may":       time.May,
+	"май":       time.May,
+	"мая":       time.May,
+	"june":      time.June,
+	"jun":       time.June,
+	"июнь":      time.June,
+	"июня":      time.June,
+	"июн":       time.June,
+	"july":      time.July,
+	"jul":       time.July,
+	"июль":      time.July,
+	"июля":      time.July,
+	"июл":       time.July,
+	"august":    time.August,
+	"aug":       time.August,
+	"август":    time.August,
+	"августа":   time.August,
+	"авг":       time.August,
+	"september": time.September,
+	"sep":       time.September,
+	"sept":      time.September,
+	"сентябрь":  time.September,
+	"сентября":  time.September,
+	"сен":       time.September,
+	"october":   time.October,
+	"oct":       time.October,
+	"октябрь":   time.October,
+	"октября":   time.October,
+	"окт":       time.October,
+	"november":  time.November,
+	"nov":       time.November,
+	"ноябрь":    time.November,
+	"ноября":    time.November,
+	"ноя":       time.November,
+	"december":  time.December,
+	"dec":       time.December,
+	"декабрь":   time.December,
+	"декабря":   time.December,
+	"дек":       time.December,
+}
+
+var units = map[string]Frequency{
+	"day":     Daily,
+	"days":    Daily,
+	"день":    Daily,
+	"дня":     Daily,
+	"дней":    Daily,
+	"week":    Weekly,
+	"weeks":   Weekly,
+	"неделя":  Weekly,
+	"неделю":  Weekly,
+	"недели":  Weekly,
+	"недель":  Weekly,
+	"month":   Monthly,
+	"months":  Monthly,
+	"месяц":   Monthly,
+	"месяца":  Monthly,
+	"месяцев": Monthly,
+	"year":    Yearly,
+	"years":   Yearly,
+	"год":     Yearly,
+	"года":    Yearly,
+	"лет":     Yearly,
+}
+
+var adverbs = map[string]Frequency{
+	"daily":       Daily,
+	"ежедневно":   Daily,
+	"weekly":      Weekly,
+	"еженедельно": Weekly,
+	"monthly":     Monthly,
+	"ежемесячно":  Monthly,
+	"yearly":      Yearly,
+	"annually":    Yearly,
+	"ежегодно":    Yearly,
+}
+
+var every = map[string]struct{}{
+	"every":   {},
+	"каждый":  {},
+	"каждую":  {},
+	"каждое":  {},
+	"каждые":  {},
+	"каждого": {},
+}
+
+var namedTimes = map[string]int{
+	"noon":     12,
+	"midnight": 0,
+	"полдень":  12,
+	"полночь":  0,
+}
+
+var meridiems = map[string]string{
+	"am":     "am",
+	"pm":     "pm",
+	"утра":   "am",
+	"ночи":   "am",
+	"дня":    "pm",
+	"вечера": "pm",
+}
+
+var numerals = map[string]int{
+	"a":     1,
+	"an":    1,
+	"one":   1,
+	"two":   2,
+	"three": 3,
+	"four":  4,
+	"five":  5,
+	"пару":  2,
+}
+
+// maxCount is the largest count of "in 3 days" and "every 2 weeks", so the
+// due dates and the recurrences stay in the range of the database.
+const maxCount = 1000
+
+// number parses a count from 1 to maxCount, in digits or in words.
+func number(w string) (int, bool) {
+	if n, ok := numerals[w]; ok {
+		return n, true
+	}
+
+	n, err := strconv.Atoi(w)
+	if err != nil || n < 1 || n > maxCount {
+		return 0, false
+	}
+
+	return n, true
+}
+
+func dayOfMonth(w string) (int, bool) {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		w = strings.TrimSuffix(w, suffix)
+	}
+
+	d, err := strconv.Atoi(w)
+	if err != nil || d < 1 || d > 31 {
+		return 0, false
+	}
+
+	return d, true
+}
+
+// clock parses "9am", "9:30pm", "21:00" and, if bare is true, a single hour
+// like "9". The meridiem is returned as "am", "pm" or an empty string.
+func clock(w string, bare bool) (hour, min int, meridiem string, ok bool) {
+	switch {
+	case strings.HasSuffix(w, "am"):
+		meridiem, w = "am", strings.TrimSuffix(w, "am")
+	case strings.HasSuffix(w, "pm"):
+		meridiem, w = "pm", strings.TrimSuffix(w, "pm")
+	}
+
+	h, m, found := strings.Cut(w, ":")
+	if !found && meridiem == "" && !bare {
+		return 0, 0, "", false
+	}
+
+	hour, err := strconv.Atoi(h)
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, 0, "", false
+	}
+
+	if found {
+		if len(m) != 2 {
+			return 0, 0, "", false
+		}
+
+		min, err = strconv.Atoi(m)
+		if err != nil || min < 0 || min > 59 {
+			return 0, 0, "", false
+		}
+	}
+
+	return hour, min, meridiem, true
+}
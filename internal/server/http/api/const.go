@@ -3,12 +3,14 @@ package api
 const (
 	RequestIDHeader     = "X-Request-ID"
 	AuthorizationHeader = "Authorization"
+	AdminTokenHeader    = "X-Admin-Token"
 )
 
 type key int
 
 const (
-	UserIDKey    key = 0
-	RequestIDKey key = 1
-	TokenKey     key = 2
+	UserIDKey      key = 0
+	RequestIDKey   key = 1
+	TokenKey       key = 2
+	WorkspaceIDKey key = 3
 )
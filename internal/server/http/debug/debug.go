@@ -0,0 +1,28 @@
+// Package debug serves the runtime metrics of the services on an address
+// apart from their public API.
+package debug
+
+import (
+	"expvar"
+	"log/slog"
+	"net/http"
+
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+)
+
+// Serve serves /debug/vars at addr in background. Empty addr disables it.
+func Serve(addr string, log *slog.Logger) {
+	if addr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	go func() {
+		log.Info("serving metrics", slog.String("addr", addr))
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Error("failed to serve metrics", sl.Err(err))
+		}
+	}()
+}
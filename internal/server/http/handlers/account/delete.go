@@ -0,0 +1,104 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/pkg/validator"
+	"github.com/romankravchuk/eldorado/internal/server/http/api"
+	"github.com/romankravchuk/eldorado/internal/server/http/api/response"
+	"github.com/romankravchuk/eldorado/internal/services/auth/proto"
+)
+
+// HandleDeleteAccount deletes the account of the user after checking
+// their password. The account is erased after the grace period of the
+// auth service, signing in before cancels the deletion.
+func HandleDeleteAccount(log *slog.Logger, client proto.AuthServiceClient) api.APIFunc {
+	const op = "server.http.handlers.account.DeleteAccount"
+
+	type req struct {
+		Password string `json:"password" validate:"required"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(api.UserIDKey).(string)
+		if !ok {
+			msg := "forbidden"
+
+			log.Error(msg, slog.String("error", "no user id in context"))
+
+			return response.APIError{
+				Status:  http.StatusForbidden,
+				Message: msg,
+			}
+		}
+
+		input := new(req)
+		if err := json.NewDecoder(r.Body).Decode(input); err != nil {
+			msg := "invalid request"
+
+			log.Error(msg, sl.Err(err))
+
+			return response.APIError{
+				Status:  http.StatusBadRequest,
+				Message: msg,
+			}
+		}
+
+		if err := validator.ValidateStruct(*input); err != nil {
+			msg := "invalid request"
+
+			log.Error(msg, sl.Err(err))
+
+			return response.APIError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
+		defer cancel()
+
+		resp, err := client.DeleteAccount(ctx, &proto.DeleteAccountRequest{
+			UserID:   userID,
+			Password: input.Password,
+		})
+		if err != nil {
+			msg := "internal server error"
+
+			log.Error(msg, sl.Err(err), slog.String("user_id", userID))
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+		if resp.Meta.Error != "" {
+			msg := "invalid request"
+			if resp.Meta.Status >= http.StatusInternalServerError {
+				msg = "internal server error"
+			}
+
+			log.Error(msg, slog.String("user_id", userID), slog.Any("response", resp))
+
+			return response.APIError{
+				Status:  int(resp.Meta.Status),
+				Message: msg,
+			}
+		}
+
+		log.Info("account deleted", slog.String("user_id", userID), slog.String("delete_on", resp.DeleteOn))
+
+		return response.JSON(w, http.StatusAccepted, response.M{"delete_on": resp.DeleteOn})
+	}
+}
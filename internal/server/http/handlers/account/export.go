@@ -0,0 +1,137 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/server/http/api"
+	"github.com/romankravchuk/eldorado/internal/server/http/api/response"
+	"github.com/romankravchuk/eldorado/internal/services/export"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name ExportRequester
+type ExportRequester interface {
+	Request(ctx context.Context, userID string) (data.Export, error)
+}
+
+// HandleRequestExport queues an export of the user data. The download
+// link is emailed once the archive is built. A user has one export in
+// progress at a time.
+func HandleRequestExport(log *slog.Logger, requester ExportRequester) api.APIFunc {
+	const op = "server.http.handlers.account.RequestExport"
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(api.UserIDKey).(string)
+		if !ok {
+			msg := "forbidden"
+
+			log.Error(msg, slog.String("error", "no user id in context"))
+
+			return response.APIError{
+				Status:  http.StatusForbidden,
+				Message: msg,
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 150*time.Millisecond)
+		defer cancel()
+
+		e, err := requester.Request(ctx, userID)
+		if err != nil {
+			if errors.Is(err, export.ErrInProgress) {
+				log.Error("export in progress", sl.Err(err), slog.String("user_id", userID))
+
+				return response.APIError{
+					Status:  http.StatusConflict,
+					Message: err.Error(),
+				}
+			}
+
+			msg := "internal server error"
+
+			log.Error(msg, sl.Err(err), slog.String("user_id", userID))
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		log.Info("export requested", slog.String("user_id", userID), slog.String("export_id", e.ID))
+
+		return response.JSON(w, http.StatusAccepted, response.M{
+			"export": response.M{
+				"id":     e.ID,
+				"status": e.Status,
+			},
+		})
+	}
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name ExportDownloader
+type ExportDownloader interface {
+	Download(ctx context.Context, id, expires, signature string) (data.Export, error)
+}
+
+// HandleDownloadExport serves the archive of the signed link. The link is
+// the credential, so the route is not behind the JWT middleware.
+func HandleDownloadExport(log *slog.Logger, downloader ExportDownloader) api.APIFunc {
+	const op = "server.http.handlers.account.DownloadExport"
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id := chi.URLParam(r, "id")
+		q := r.URL.Query()
+
+		ctx, cancel := context.WithTimeout(r.Context(), time.Second)
+		defer cancel()
+
+		e, err := downloader.Download(ctx, id, q.Get("expires"), q.Get("signature"))
+		if err != nil {
+			if errors.Is(err, export.ErrInvalidLink) {
+				log.Error("invalid export link", sl.Err(err), slog.String("export_id", id))
+
+				return response.NotFound("export")
+			}
+
+			msg := "internal server error"
+
+			log.Error(msg, sl.Err(err), slog.String("export_id", id))
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		log.Info("export downloaded", slog.String("user_id", e.UserID), slog.String("export_id", e.ID))
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"eldorado-export-%s.zip\"", e.CreatedOn.Format("2006-01-02")))
+		w.WriteHeader(http.StatusOK)
+
+		// The headers are sent, a failed write can only be logged.
+		if _, err = w.Write(e.Archive); err != nil {
+			log.Error("failed to write export", sl.Err(err), slog.String("export_id", e.ID))
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,141 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/pkg/validator"
+	"github.com/romankravchuk/eldorado/internal/server/http/api"
+	"github.com/romankravchuk/eldorado/internal/server/http/api/response"
+	"github.com/romankravchuk/eldorado/internal/services/tasks"
+	"github.com/romankravchuk/eldorado/internal/storages"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name CacheInspector
+type CacheInspector interface {
+	InspectCache(ctx context.Context, userID string) (tasks.CacheEntry, error)
+}
+
+// HandleInspectUserCache returns the cache entries of the user in the
+// workspace of the required workspace_id query parameter.
+func HandleInspectUserCache(log *slog.Logger, inspector CacheInspector) api.APIFunc {
+	const op = "server.http.handlers.admin.InspectUserCache"
+
+	type entry struct {
+		Key                 string `json:"key"`
+		Found               bool   `json:"found"`
+		Size                int    `json:"size"`
+		FreshUntil          string `json:"fresh_until,omitempty"`
+		Tasks               int    `json:"tasks"`
+		PendingInvalidation bool   `json:"pending_invalidation"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID := chi.URLParam(r, "userID")
+		workspaceID, err := workspaceParam(r)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(storages.WithWorkspace(r.Context(), workspaceID), time.Second)
+		defer cancel()
+
+		e, err := inspector.InspectCache(ctx, userID)
+		if err != nil {
+			msg := "internal server error"
+
+			log.Error(msg, sl.Err(err), slog.String("user_id", userID), slog.String("workspace_id", workspaceID))
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		obj := entry{
+			Key:                 e.Key,
+			Found:               e.Found,
+			Size:                e.Size,
+			Tasks:               e.Tasks,
+			PendingInvalidation: e.PendingInvalidation,
+		}
+		if !e.FreshUntil.IsZero() {
+			obj.FreshUntil = e.FreshUntil.Format(time.RFC3339)
+		}
+
+		return response.JSON(w, http.StatusOK, response.M{
+			"entries": []entry{obj},
+		})
+	}
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name CacheFlusher
+type CacheFlusher interface {
+	FlushCache(ctx context.Context, userID string) error
+}
+
+// HandleFlushUserCache drops the cache entries of the user in the
+// workspace of the required workspace_id query parameter.
+func HandleFlushUserCache(log *slog.Logger, flusher CacheFlusher) api.APIFunc {
+	const op = "server.http.handlers.admin.FlushUserCache"
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID := chi.URLParam(r, "userID")
+		workspaceID, err := workspaceParam(r)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(storages.WithWorkspace(r.Context(), workspaceID), time.Second)
+		defer cancel()
+
+		if err = flusher.FlushCache(ctx, userID); err != nil {
+			msg := "internal server error"
+
+			log.Error(msg, sl.Err(err), slog.String("user_id", userID), slog.String("workspace_id", workspaceID))
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		log.Info("user cache flushed", slog.String("user_id", userID), slog.String("workspace_id", workspaceID))
+
+		return response.JSON(w, http.StatusOK, response.M{"message": "ok"})
+	}
+}
+
+// workspaceParam returns the workspace_id query parameter. The task lists
+// are cached per workspace, so without it there is nothing to look up.
+func workspaceParam(r *http.Request) (string, error) {
+	params := struct {
+		WorkspaceID string `validate:"required,uuid"`
+	}{
+		WorkspaceID: r.URL.Query().Get("workspace_id"),
+	}
+
+	if err := validator.ValidateStruct(params); err != nil {
+		return "", response.APIError{
+			Status:  http.StatusBadRequest,
+			Message: err.Error(),
+		}
+	}
+
+	return params.WorkspaceID, nil
+}
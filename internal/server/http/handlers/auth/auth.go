@@ -90,8 +90,9 @@ func HandleGetTokenPairs(log *slog.Logger, client proto.AuthServiceClient) api.A
 	const op = "server.http.handlers.auth.GetTokenPairs"
 
 	type req struct {
-		Email    string `json:"email" validate:"required,email"`
-		Password string `json:"password" validate:"required,gte=8,alphanum,lte=20"`
+		Email       string `json:"email" validate:"required,email"`
+		Password    string `json:"password" validate:"required,gte=8,alphanum,lte=20"`
+		WorkspaceID string `json:"workspace_id" validate:"omitempty,uuid"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) error {
@@ -127,8 +128,9 @@ func HandleGetTokenPairs(log *slog.Logger, client proto.AuthServiceClient) api.A
 		defer cancel()
 
 		resp, err := client.Token(ctx, &proto.TokenRequest{
-			Email:    input.Email,
-			Password: input.Password,
+			Email:       input.Email,
+			Password:    input.Password,
+			WorkspaceID: input.WorkspaceID,
 		})
 		if err != nil {
 			msg := "internal server error"
@@ -0,0 +1,143 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/pkg/validator"
+	"github.com/romankravchuk/eldorado/internal/server/http/api"
+	"github.com/romankravchuk/eldorado/internal/server/http/api/response"
+)
+
+type settings struct {
+	ArchiveAfterDays int `json:"archive_after_days"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name SettingsGetter
+type SettingsGetter interface {
+	Get(ctx context.Context, userID string) (data.Settings, error)
+}
+
+func HandleGetSettings(log *slog.Logger, getter SettingsGetter) api.APIFunc {
+	const op = "server.http.handlers.settings.GetSettings"
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(api.UserIDKey).(string)
+		if !ok {
+			msg := "forbidden"
+
+			log.Error(msg, slog.String("error", "no user id in context"))
+
+			return response.APIError{
+				Status:  http.StatusForbidden,
+				Message: msg,
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 150*time.Millisecond)
+		defer cancel()
+
+		s, err := getter.Get(ctx, userID)
+		if err != nil {
+			msg := "internal server error"
+
+			log.Error(msg, sl.Err(err), slog.String("user_id", userID))
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		return response.JSON(w, http.StatusOK, response.M{
+			"settings": settings{ArchiveAfterDays: s.ArchiveAfterDays},
+		})
+	}
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name SettingsUpdater
+type SettingsUpdater interface {
+	Update(ctx context.Context, userID string, s data.Settings) (data.Settings, error)
+}
+
+// HandleUpdateSettings replaces the settings of the user.
+// Zero archive_after_days disables archiving of completed tasks.
+func HandleUpdateSettings(log *slog.Logger, updater SettingsUpdater) api.APIFunc {
+	const op = "server.http.handlers.settings.UpdateSettings"
+
+	type req struct {
+		ArchiveAfterDays *int `json:"archive_after_days" validate:"required,min=0,max=3650"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(api.UserIDKey).(string)
+		if !ok {
+			msg := "forbidden"
+
+			log.Error(msg, slog.String("error", "no user id in context"))
+
+			return response.APIError{
+				Status:  http.StatusForbidden,
+				Message: msg,
+			}
+		}
+
+		input := new(req)
+		if err := json.NewDecoder(r.Body).Decode(input); err != nil {
+			msg := "invalid request"
+
+			log.Error(msg, sl.Err(err))
+
+			return response.APIError{
+				Status:  http.StatusBadRequest,
+				Message: msg,
+			}
+		}
+
+		if err := validator.ValidateStruct(*input); err != nil {
+			msg := "invalid request"
+
+			log.Error(msg, sl.Err(err))
+
+			return response.APIError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 150*time.Millisecond)
+		defer cancel()
+
+		s, err := updater.Update(ctx, userID, data.Settings{ArchiveAfterDays: *input.ArchiveAfterDays})
+		if err != nil {
+			msg := "internal server error"
+
+			log.Error(msg, sl.Err(err), slog.String("user_id", userID))
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		return response.JSON(w, http.StatusOK, response.M{
+			"settings": settings{ArchiveAfterDays: s.ArchiveAfterDays},
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/server/http/api"
+	"github.com/romankravchuk/eldorado/internal/server/http/api/response"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name ArchivedTasksLister
+type ArchivedTasksLister interface {
+	ListArchived(ctx context.Context, userID string) ([]data.Task, error)
+}
+
+// HandleGetArchivedTasks returns the archived tasks of the user.
+func HandleGetArchivedTasks(log *slog.Logger, lister ArchivedTasksLister) api.APIFunc {
+	const op = "server.http.handlers.tasks.GetArchivedTasks"
+
+	type task struct {
+		ID          string   `json:"id"`
+		Title       string   `json:"title"`
+		Description string   `json:"description"`
+		CreatedOn   string   `json:"created_at"`
+		CompletedOn string   `json:"completed_on,omitempty"`
+		ArchivedOn  string   `json:"archived_on"`
+		Tags        []string `json:"tags,omitempty"`
+		Priority    string   `json:"priority,omitempty"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(api.UserIDKey).(string)
+		if !ok {
+			msg := "forbidden"
+
+			log.Error(msg, slog.String("error", "no user id in context"))
+
+			return response.APIError{
+				Status:  http.StatusForbidden,
+				Message: msg,
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 150*time.Millisecond)
+		defer cancel()
+
+		tt, err := lister.ListArchived(ctx, userID)
+		if err != nil {
+			msg := "internal server error"
+
+			log.Error(msg, sl.Err(err), slog.String("user_id", userID))
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		objs := make([]task, len(tt))
+		for i, t := range tt {
+			objs[i] = task{
+				ID:          t.ID,
+				Title:       t.Title,
+				Description: t.Description,
+				CreatedOn:   t.CreatedOn.Format(time.RFC3339),
+				ArchivedOn:  t.ArchivedOn.Format(time.RFC3339),
+				Tags:        t.Tags,
+			}
+			if !t.CompletedOn.IsZero() {
+				objs[i].CompletedOn = t.CompletedOn.Format(time.RFC3339)
+			}
+			if t.Priority != data.PriorityNone {
+				objs[i].Priority = t.Priority.String()
+			}
+		}
+
+		return response.JSON(w, http.StatusOK, response.M{
+			"tasks": objs,
+		})
+	}
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name TaskUnarchiver
+type TaskUnarchiver interface {
+	Unarchive(ctx context.Context, userID, id string) error
+}
+
+// HandleUnarchiveTask brings the archived task back to the task list.
+func HandleUnarchiveTask(log *slog.Logger, unarchiver TaskUnarchiver) api.APIFunc {
+	const op = "server.http.handlers.tasks.UnarchiveTask"
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(api.UserIDKey).(string)
+		if !ok {
+			msg := "forbidden"
+
+			log.Error(msg, slog.String("error", "no user id in context"))
+
+			return response.APIError{
+				Status:  http.StatusForbidden,
+				Message: msg,
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 150*time.Millisecond)
+		defer cancel()
+
+		if err := unarchiver.Unarchive(ctx, userID, chi.URLParam(r, "id")); err != nil {
+			if errors.Is(err, tasks.ErrNotFound) {
+				log.Error("archived task not found", sl.Err(err), slog.String("task_id", chi.URLParam(r, "id")))
+
+				return response.NotFound("archived task")
+			}
+
+			msg := "internal server error"
+
+			log.Error(msg,
+				sl.Err(err),
+				slog.String("user_id", userID),
+				slog.String("task_id", chi.URLParam(r, "id")),
+			)
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		return response.JSON(w, http.StatusOK, response.M{"message": "ok"})
+	}
+}
@@ -0,0 +1,245 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/pkg/validator"
+	"github.com/romankravchuk/eldorado/internal/server/http/api"
+	"github.com/romankravchuk/eldorado/internal/server/http/api/response"
+	"github.com/romankravchuk/eldorado/internal/storages/dependencies"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name DependenciesGetter
+type DependenciesGetter interface {
+	Dependencies(ctx context.Context, userID, id string) (blockers, dependents []data.Task, err error)
+}
+
+func HandleGetDependencies(log *slog.Logger, getter DependenciesGetter) api.APIFunc {
+	const op = "server.http.handlers.tasks.GetDependencies"
+
+	type task struct {
+		ID          string `json:"id"`
+		Title       string `json:"title"`
+		IsCompleted bool   `json:"is_completed"`
+	}
+
+	toObjs := func(tt []data.Task) []task {
+		objs := make([]task, len(tt))
+		for i, t := range tt {
+			objs[i] = task{ID: t.ID, Title: t.Title, IsCompleted: t.IsCompleted}
+		}
+		return objs
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(api.UserIDKey).(string)
+		if !ok {
+			msg := "forbidden"
+
+			log.Error(msg, slog.String("error", "no user id in context"))
+
+			return response.APIError{
+				Status:  http.StatusForbidden,
+				Message: msg,
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 150*time.Millisecond)
+		defer cancel()
+
+		blockers, dependents, err := getter.Dependencies(ctx, userID, chi.URLParam(r, "id"))
+		if err != nil {
+			if errors.Is(err, tasks.ErrNotFound) {
+				log.Error("task not found", sl.Err(err), slog.String("task_id", chi.URLParam(r, "id")))
+
+				return response.NotFound("task")
+			}
+
+			msg := "internal server error"
+
+			log.Error(msg,
+				sl.Err(err),
+				slog.String("user_id", userID),
+				slog.String("task_id", chi.URLParam(r, "id")),
+			)
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		return response.JSON(w, http.StatusOK, response.M{
+			"blocked_by": toObjs(blockers),
+			"blocking":   toObjs(dependents),
+		})
+	}
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name DependencyAdder
+type DependencyAdder interface {
+	AddDependency(ctx context.Context, userID, id, blockerID string) error
+}
+
+func HandleAddDependency(log *slog.Logger, adder DependencyAdder) api.APIFunc {
+	const op = "server.http.handlers.tasks.AddDependency"
+
+	type req struct {
+		BlockedBy string `json:"blocked_by" validate:"required,uuid"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(api.UserIDKey).(string)
+		if !ok {
+			msg := "forbidden"
+
+			log.Error(msg, slog.String("error", "no user id in context"))
+
+			return response.APIError{
+				Status:  http.StatusForbidden,
+				Message: msg,
+			}
+		}
+
+		input := new(req)
+		if err := json.NewDecoder(r.Body).Decode(input); err != nil {
+			msg := "invalid request"
+
+			log.Error(msg, sl.Err(err))
+
+			return response.APIError{
+				Status:  http.StatusBadRequest,
+				Message: msg,
+			}
+		}
+
+		if err := validator.ValidateStruct(*input); err != nil {
+			msg := "invalid request"
+
+			log.Error(msg, sl.Err(err))
+
+			return response.APIError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 150*time.Millisecond)
+		defer cancel()
+
+		if err := adder.AddDependency(ctx, userID, chi.URLParam(r, "id"), input.BlockedBy); err != nil {
+			switch {
+			case errors.Is(err, tasks.ErrNotFound):
+				log.Error("task not found", sl.Err(err), slog.String("task_id", chi.URLParam(r, "id")))
+
+				return response.NotFound("task")
+			case errors.Is(err, dependencies.ErrCycle), errors.Is(err, dependencies.ErrAlreadyExists):
+				log.Error("invalid dependency", sl.Err(err),
+					slog.String("task_id", chi.URLParam(r, "id")),
+					slog.String("blocked_by", input.BlockedBy),
+				)
+
+				return response.APIError{
+					Status:  http.StatusConflict,
+					Message: err.Error(),
+				}
+			}
+
+			msg := "internal server error"
+
+			log.Error(msg,
+				sl.Err(err),
+				slog.String("user_id", userID),
+				slog.String("task_id", chi.URLParam(r, "id")),
+				slog.Any("request_body", input),
+			)
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		return response.JSON(w, http.StatusCreated, response.M{"message": "ok"})
+	}
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name DependencyRemover
+type DependencyRemover interface {
+	RemoveDependency(ctx context.Context, userID, id, blockerID string) error
+}
+
+func HandleRemoveDependency(log *slog.Logger, remover DependencyRemover) api.APIFunc {
+	const op = "server.http.handlers.tasks.RemoveDependency"
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(api.UserIDKey).(string)
+		if !ok {
+			msg := "forbidden"
+
+			log.Error(msg, slog.String("error", "no user id in context"))
+
+			return response.APIError{
+				Status:  http.StatusForbidden,
+				Message: msg,
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 150*time.Millisecond)
+		defer cancel()
+
+		err := remover.RemoveDependency(ctx, userID, chi.URLParam(r, "id"), chi.URLParam(r, "blockerID"))
+		if err != nil {
+			switch {
+			case errors.Is(err, tasks.ErrNotFound):
+				log.Error("task not found", sl.Err(err), slog.String("task_id", chi.URLParam(r, "id")))
+
+				return response.NotFound("task")
+			case errors.Is(err, dependencies.ErrNotFound):
+				log.Error("dependency not found", sl.Err(err), slog.String("task_id", chi.URLParam(r, "id")))
+
+				return response.NotFound("dependency")
+			}
+
+			msg := "internal server error"
+
+			log.Error(msg,
+				sl.Err(err),
+				slog.String("user_id", userID),
+				slog.String("task_id", chi.URLParam(r, "id")),
+			)
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		return response.JSON(w, http.StatusOK, response.M{"message": "ok"})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
@@ -15,18 +16,27 @@ import (
 
 //go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name TasksLister
 type TasksLister interface {
-	List(ctx context.Context, userID string) ([]data.Task, error)
+	List(ctx context.Context, userID string, includeSnoozed bool) ([]data.Task, error)
 }
 
+// HandleGetTasks returns the tasks of the user.
+// Snoozed tasks are listed only with the include_snoozed=true query parameter.
 func HandleGetTasks(log *slog.Logger, lister TasksLister) api.APIFunc {
 	const op = "server.http.handlers.tasks.GetTasks"
 
 	type task struct {
-		ID          string `json:"id"`
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		CreatedOn   string `json:"created_at"`
-		IsCompleted bool   `json:"is_completed"`
+		ID          string               `json:"id"`
+		Title       string               `json:"title"`
+		Description string               `json:"description"`
+		CreatedOn   string               `json:"created_at"`
+		IsCompleted bool                 `json:"is_completed"`
+		IsBlocked   bool                 `json:"blocked"`
+		DueOn       string               `json:"due_on,omitempty"`
+		Tags        []string             `json:"tags,omitempty"`
+		Priority    string               `json:"priority,omitempty"`
+		Recurrence  string               `json:"recurrence,omitempty"`
+		Checklist   []data.ChecklistItem `json:"checklist,omitempty"`
+		HiddenUntil string               `json:"hidden_until,omitempty"`
 	}
 	return func(w http.ResponseWriter, r *http.Request) error {
 		log := log.With(
@@ -46,10 +56,12 @@ func HandleGetTasks(log *slog.Logger, lister TasksLister) api.APIFunc {
 			}
 		}
 
+		includeSnoozed, _ := strconv.ParseBool(r.URL.Query().Get("include_snoozed"))
+
 		ctx, cancel := context.WithTimeout(r.Context(), 150*time.Millisecond)
 		defer cancel()
 
-		tasks, err := lister.List(ctx, userID)
+		tasks, err := lister.List(ctx, userID, includeSnoozed)
 		if err != nil {
 			msg := "internal server error"
 
@@ -69,6 +81,19 @@ func HandleGetTasks(log *slog.Logger, lister TasksLister) api.APIFunc {
 				Description: t.Description,
 				CreatedOn:   t.CreatedOn.Format(time.RFC3339),
 				IsCompleted: t.IsCompleted,
+				IsBlocked:   t.IsBlocked,
+				Tags:        t.Tags,
+				Recurrence:  t.Recurrence,
+				Checklist:   t.Checklist,
+			}
+			if !t.DueOn.IsZero() {
+				objs[i].DueOn = t.DueOn.Format(time.RFC3339)
+			}
+			if t.Priority != data.PriorityNone {
+				objs[i].Priority = t.Priority.String()
+			}
+			if !t.HiddenUntil.IsZero() {
+				objs[i].HiddenUntil = t.HiddenUntil.Format(time.RFC3339)
 			}
 		}
 
@@ -0,0 +1,179 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/quickadd"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/pkg/validator"
+	"github.com/romankravchuk/eldorado/internal/server/http/api"
+	"github.com/romankravchuk/eldorado/internal/server/http/api/response"
+)
+
+func HandleQuickAddTask(log *slog.Logger, creater TaskCreater) api.APIFunc {
+	const op = "server.http.handlers.tasks.QuickAddTask"
+
+	type req struct {
+		Text     string `json:"text" validate:"required,min=3,max=500"`
+		Timezone string `json:"timezone" validate:"omitempty,timezone"`
+	}
+
+	type parsed struct {
+		Title      string   `json:"title"`
+		DueOn      string   `json:"due_on,omitempty"`
+		HasTime    bool     `json:"has_time"`
+		Tags       []string `json:"tags"`
+		Priority   string   `json:"priority"`
+		Recurrence string   `json:"recurrence,omitempty"`
+	}
+
+	type task struct {
+		ID          string   `json:"id"`
+		Title       string   `json:"title"`
+		Description string   `json:"description"`
+		IsCompleted bool     `json:"is_completed"`
+		DueOn       string   `json:"due_on,omitempty"`
+		Tags        []string `json:"tags,omitempty"`
+		Priority    string   `json:"priority,omitempty"`
+		Recurrence  string   `json:"recurrence,omitempty"`
+		CreatedOn   string   `json:"created_on"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(api.UserIDKey).(string)
+		if !ok {
+			msg := "forbidden"
+
+			log.Error(msg, slog.String("error", "no user id in context"))
+
+			return response.APIError{
+				Status:  http.StatusForbidden,
+				Message: msg,
+			}
+		}
+
+		input := new(req)
+		if err := json.NewDecoder(r.Body).Decode(input); err != nil {
+			msg := "invalid request"
+
+			log.Error(msg, sl.Err(err))
+
+			return response.APIError{
+				Status:  http.StatusBadRequest,
+				Message: msg,
+			}
+		}
+
+		if err := validator.ValidateStruct(*input); err != nil {
+			msg := "invalid request"
+
+			log.Error(msg, sl.Err(err))
+
+			return response.APIError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			}
+		}
+
+		loc := time.UTC
+		if input.Timezone != "" {
+			loc, _ = time.LoadLocation(input.Timezone)
+		}
+
+		res, err := quickadd.Parse(input.Text, time.Now().In(loc))
+		if err != nil {
+			msg := "invalid request"
+
+			log.Error(msg, sl.Err(err), slog.String("text", input.Text))
+
+			if errors.Is(err, quickadd.ErrEmptyTitle) {
+				msg = err.Error()
+			}
+
+			return response.APIError{
+				Status:  http.StatusBadRequest,
+				Message: msg,
+			}
+		}
+
+		if utf8.RuneCountInString(res.Title) > 100 {
+			msg := "the title is too long"
+
+			log.Error(msg, slog.String("text", input.Text))
+
+			return response.APIError{
+				Status:  http.StatusBadRequest,
+				Message: msg,
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 150*time.Millisecond)
+		defer cancel()
+
+		t, err := creater.Create(ctx, userID, data.Task{
+			Title:      res.Title,
+			DueOn:      res.Due,
+			Tags:       res.Tags,
+			Priority:   res.Priority,
+			Recurrence: res.Recurrence.String(),
+		})
+		if err != nil {
+			msg := "internal server error"
+
+			log.Error(msg, sl.Err(err), slog.String("user_id", userID))
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		p := parsed{
+			Title:      res.Title,
+			HasTime:    res.HasTime,
+			Tags:       res.Tags,
+			Priority:   res.Priority.String(),
+			Recurrence: res.Recurrence.String(),
+		}
+		if p.Tags == nil {
+			p.Tags = []string{}
+		}
+		if !res.Due.IsZero() {
+			p.DueOn = res.Due.Format(time.RFC3339)
+		}
+
+		obj := task{
+			ID:          t.ID,
+			Title:       t.Title,
+			Description: t.Description,
+			IsCompleted: t.IsCompleted,
+			Tags:        t.Tags,
+			Recurrence:  t.Recurrence,
+			CreatedOn:   t.CreatedOn.Format(time.RFC3339),
+		}
+		if !t.DueOn.IsZero() {
+			obj.DueOn = t.DueOn.Format(time.RFC3339)
+		}
+		if t.Priority != data.PriorityNone {
+			obj.Priority = t.Priority.String()
+		}
+
+		return response.JSON(w, http.StatusCreated, response.M{
+			"task":   obj,
+			"parsed": p,
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/pkg/validator"
+	"github.com/romankravchuk/eldorado/internal/server/http/api"
+	"github.com/romankravchuk/eldorado/internal/server/http/api/response"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name TaskSnoozer
+type TaskSnoozer interface {
+	Snooze(ctx context.Context, userID, id string, until time.Time) error
+}
+
+// HandleSnoozeTask hides the task from default lists either for a duration
+// ("90m", "2h", "3d", "1w") or until an absolute RFC 3339 time, up to
+// maxSnooze from now.
+func HandleSnoozeTask(log *slog.Logger, snoozer TaskSnoozer) api.APIFunc {
+	const op = "server.http.handlers.tasks.SnoozeTask"
+
+	type req struct {
+		For   string `json:"for" validate:"required_without=Until,excluded_with=Until"`
+		Until string `json:"until" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(api.UserIDKey).(string)
+		if !ok {
+			msg := "forbidden"
+
+			log.Error(msg, slog.String("error", "no user id in context"))
+
+			return response.APIError{
+				Status:  http.StatusForbidden,
+				Message: msg,
+			}
+		}
+
+		input := new(req)
+		if err := json.NewDecoder(r.Body).Decode(input); err != nil {
+			msg := "invalid request"
+
+			log.Error(msg, sl.Err(err))
+
+			return response.APIError{
+				Status:  http.StatusBadRequest,
+				Message: msg,
+			}
+		}
+
+		if err := validator.ValidateStruct(*input); err != nil {
+			msg := "invalid request"
+
+			log.Error(msg, sl.Err(err))
+
+			return response.APIError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			}
+		}
+
+		now := time.Now()
+
+		var until time.Time
+		if input.For != "" {
+			d, err := parseDuration(input.For)
+			if err != nil || d <= 0 {
+				msg := "for must be a positive duration like 2h, 3d or 1w, up to 520w"
+
+				log.Error(msg, slog.String("for", input.For))
+
+				return response.APIError{
+					Status:  http.StatusBadRequest,
+					Message: msg,
+				}
+			}
+			until = now.Add(d)
+		} else {
+			until, _ = time.Parse(time.RFC3339, input.Until)
+			if !until.After(now) || until.After(now.Add(maxSnooze)) {
+				msg := "until must be in the future, up to 520 weeks from now"
+
+				log.Error(msg, slog.String("until", input.Until))
+
+				return response.APIError{
+					Status:  http.StatusBadRequest,
+					Message: msg,
+				}
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 150*time.Millisecond)
+		defer cancel()
+
+		if err := snoozer.Snooze(ctx, userID, chi.URLParam(r, "id"), until); err != nil {
+			if errors.Is(err, tasks.ErrNotFound) {
+				log.Error("task not found", sl.Err(err), slog.String("task_id", chi.URLParam(r, "id")))
+
+				return response.NotFound("task")
+			}
+
+			msg := "internal server error"
+
+			log.Error(msg,
+				sl.Err(err),
+				slog.String("user_id", userID),
+				slog.String("task_id", chi.URLParam(r, "id")),
+				slog.Any("request_body", input),
+			)
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		return response.JSON(w, http.StatusOK, response.M{
+			"hidden_until": until.UTC().Format(time.RFC3339),
+		})
+	}
+}
+
+// HandleUnsnoozeTask shows the snoozed task in default lists again.
+func HandleUnsnoozeTask(log *slog.Logger, snoozer TaskSnoozer) api.APIFunc {
+	const op = "server.http.handlers.tasks.UnsnoozeTask"
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(api.UserIDKey).(string)
+		if !ok {
+			msg := "forbidden"
+
+			log.Error(msg, slog.String("error", "no user id in context"))
+
+			return response.APIError{
+				Status:  http.StatusForbidden,
+				Message: msg,
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 150*time.Millisecond)
+		defer cancel()
+
+		if err := snoozer.Snooze(ctx, userID, chi.URLParam(r, "id"), time.Time{}); err != nil {
+			if errors.Is(err, tasks.ErrNotFound) {
+				log.Error("task not found", sl.Err(err), slog.String("task_id", chi.URLParam(r, "id")))
+
+				return response.NotFound("task")
+			}
+
+			msg := "internal server error"
+
+			log.Error(msg,
+				sl.Err(err),
+				slog.String("user_id", userID),
+				slog.String("task_id", chi.URLParam(r, "id")),
+			)
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		return response.JSON(w, http.StatusOK, response.M{"message": "ok"})
+	}
+}
+
+// maxSnooze is the longest a task is snoozed for, 520 weeks, whether
+// the snooze is given as a duration or as a time.
+const maxSnooze = 520 * 7 * 24 * time.Hour
+
+// parseDuration extends time.ParseDuration with days ("3d") and weeks ("1w").
+// Negative days and weeks and durations over maxSnooze are rejected. The
+// days and the weeks are checked before they are converted, so they do
+// not overflow.
+func parseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil || v < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			if v > int(maxSnooze/unit) {
+				return 0, fmt.Errorf("duration %q is too long", s)
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d > maxSnooze {
+		return 0, fmt.Errorf("duration %q is too long", s)
+	}
+
+	return d, nil
+}
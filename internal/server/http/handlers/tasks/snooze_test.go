@@ -0,0 +1,152 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/romankravchuk/eldorado/internal/server/http/api"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSnoozer records the snoozes of the task "task" of the user "user".
+type fakeSnoozer struct {
+	until  time.Time
+	called bool
+}
+
+func (s *fakeSnoozer) Snooze(_ context.Context, userID, id string, until time.Time) error {
+	if userID != "user" || id != "task" {
+		return tasks.ErrNotFound
+	}
+
+	s.called = true
+	s.until = until
+	return nil
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    time.Duration
+		wantErr bool
+	}{
+		{s: "90m", want: 90 * time.Minute},
+		{s: "2h", want: 2 * time.Hour},
+		{s: "3d", want: 72 * time.Hour},
+		{s: "1w", want: 7 * 24 * time.Hour},
+		{s: "520w", want: maxSnooze},
+		{s: "3640d", want: maxSnooze},
+		{s: "0d", want: 0},
+		{s: "521w", wantErr: true},
+		{s: "3641d", wantErr: true},
+		{s: "87361h", wantErr: true},
+		{s: "99999999w", wantErr: true},
+		{s: "9223372036854775807d", wantErr: true},
+		{s: "-99999999999w", wantErr: true},
+		{s: "-1d", wantErr: true},
+		{s: "1.5d", wantErr: true},
+		{s: "d", wantErr: true},
+		{s: "tomorrow", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := parseDuration(tt.s)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestHandleSnoozeTask(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	until := time.Now().Add(30 * 24 * time.Hour).UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name       string
+		taskID     string
+		body       string
+		wantStatus int
+		// wantFor is the duration the task is snoozed for, the until times
+		// are checked as is.
+		wantFor   time.Duration
+		wantUntil time.Time
+	}{
+		{name: "for hours", taskID: "task", body: `{"for": "2h"}`, wantStatus: http.StatusOK, wantFor: 2 * time.Hour},
+		{name: "for days", taskID: "task", body: `{"for": "3d"}`, wantStatus: http.StatusOK, wantFor: 72 * time.Hour},
+		{name: "for weeks", taskID: "task", body: `{"for": "1w"}`, wantStatus: http.StatusOK, wantFor: 7 * 24 * time.Hour},
+		{name: "until", taskID: "task", body: `{"until": "` + until.Format(time.RFC3339) + `"}`, wantStatus: http.StatusOK, wantUntil: until},
+		{name: "for zero", taskID: "task", body: `{"for": "0d"}`, wantStatus: http.StatusBadRequest},
+		{name: "for negative", taskID: "task", body: `{"for": "-2h"}`, wantStatus: http.StatusBadRequest},
+		{name: "for overflowing weeks", taskID: "task", body: `{"for": "99999999w"}`, wantStatus: http.StatusBadRequest},
+		{name: "for too long", taskID: "task", body: `{"for": "521w"}`, wantStatus: http.StatusBadRequest},
+		{name: "for invalid", taskID: "task", body: `{"for": "soon"}`, wantStatus: http.StatusBadRequest},
+		{name: "until in the past", taskID: "task", body: `{"until": "2000-01-02T15:04:05Z"}`, wantStatus: http.StatusBadRequest},
+		{name: "until too far", taskID: "task", body: `{"until": "9999-12-31T00:00:00Z"}`, wantStatus: http.StatusBadRequest},
+		{name: "until past the limit", taskID: "task", body: `{"until": "` + time.Now().Add(maxSnooze+time.Hour).UTC().Format(time.RFC3339) + `"}`, wantStatus: http.StatusBadRequest},
+		{name: "until not RFC 3339", taskID: "task", body: `{"until": "2999-01-02"}`, wantStatus: http.StatusBadRequest},
+		{name: "for and until", taskID: "task", body: `{"for": "2h", "until": "` + until.Format(time.RFC3339) + `"}`, wantStatus: http.StatusBadRequest},
+		{name: "neither", taskID: "task", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid json", taskID: "task", body: `{"for":`, wantStatus: http.StatusBadRequest},
+		{name: "task of another user", taskID: "other", body: `{"for": "2h"}`, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snoozer := &fakeSnoozer{}
+
+			router := chi.NewRouter()
+			router.Post("/{id}/snooze", api.MakeHTTPHandlerFunc(HandleSnoozeTask(log, snoozer)))
+
+			req := httptest.NewRequest(http.MethodPost, "/"+tt.taskID+"/snooze", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), api.UserIDKey, "user"))
+			rec := httptest.NewRecorder()
+
+			start := time.Now()
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantStatus != http.StatusOK {
+				assert.False(t, snoozer.called && tt.wantStatus == http.StatusBadRequest, "an invalid snooze reached the service")
+				return
+			}
+
+			var body struct {
+				HiddenUntil time.Time `json:"hidden_until"`
+			}
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+
+			if tt.wantFor > 0 {
+				assert.WithinRange(t, snoozer.until, start.Add(tt.wantFor), time.Now().Add(tt.wantFor))
+			} else {
+				assert.True(t, tt.wantUntil.Equal(snoozer.until), "got until %v, want %v", snoozer.until, tt.wantUntil)
+			}
+			assert.Equal(t, snoozer.until.Truncate(time.Second).Unix(), body.HiddenUntil.Unix())
+		})
+	}
+}
+
+func TestHandleSnoozeTaskForbidden(t *testing.T) {
+	snoozer := &fakeSnoozer{}
+	h := api.MakeHTTPHandlerFunc(HandleSnoozeTask(slog.New(slog.NewTextHandler(io.Discard, nil)), snoozer))
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/task/snooze", strings.NewReader(`{"for": "2h"}`)))
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.False(t, snoozer.called)
+}
@@ -3,8 +3,10 @@ package tasks
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -14,13 +16,17 @@ import (
 	"github.com/romankravchuk/eldorado/internal/pkg/validator"
 	"github.com/romankravchuk/eldorado/internal/server/http/api"
 	"github.com/romankravchuk/eldorado/internal/server/http/api/response"
+	taskssvc "github.com/romankravchuk/eldorado/internal/services/tasks"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
 )
 
 //go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name TaskUpdater
 type TaskUpdater interface {
-	Update(ctx context.Context, id string, t data.Task) (data.Task, error)
+	Update(ctx context.Context, id string, t data.Task, force bool) (data.Task, error)
 }
 
+// HandleUpdateTask updates the task. Completing a task blocked by open tasks
+// is refused with 409 unless the force=true query parameter is set.
 func HandleUpdateTask(log *slog.Logger, updater TaskUpdater) api.APIFunc {
 	const op = "server.http.handlers.tasks.UpdateTask"
 
@@ -78,6 +84,8 @@ func HandleUpdateTask(log *slog.Logger, updater TaskUpdater) api.APIFunc {
 			}
 		}
 
+		force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+
 		ctx, cancel := context.WithTimeout(r.Context(), 150*time.Millisecond)
 		defer cancel()
 
@@ -86,8 +94,22 @@ func HandleUpdateTask(log *slog.Logger, updater TaskUpdater) api.APIFunc {
 			Title:       input.Title,
 			Description: input.Description,
 			IsCompleted: input.IsCompleted,
-		})
+		}, force)
 		if err != nil {
+			switch {
+			case errors.Is(err, tasks.ErrNotFound):
+				log.Error("task not found", sl.Err(err), slog.String("task_id", chi.URLParam(r, "id")))
+
+				return response.NotFound("task")
+			case errors.Is(err, taskssvc.ErrBlocked):
+				log.Error("task is blocked", sl.Err(err), slog.String("task_id", chi.URLParam(r, "id")))
+
+				return response.APIError{
+					Status:  http.StatusConflict,
+					Message: err.Error(),
+				}
+			}
+
 			msg := "internal server error"
 
 			log.Error(msg,
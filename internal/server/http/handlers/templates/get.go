@@ -0,0 +1,159 @@
+package templates
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/server/http/api"
+	"github.com/romankravchuk/eldorado/internal/server/http/api/response"
+	"github.com/romankravchuk/eldorado/internal/storages/templates"
+)
+
+type template struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Priority    string   `json:"priority"`
+	Recurrence  string   `json:"recurrence,omitempty"`
+	Checklist   []string `json:"checklist"`
+	CreatedOn   string   `json:"created_on"`
+}
+
+func newTemplate(t data.Template) template {
+	obj := template{
+		ID:          t.ID,
+		Name:        t.Name,
+		Title:       t.Title,
+		Description: t.Description,
+		Tags:        t.Tags,
+		Priority:    t.Priority.String(),
+		Recurrence:  t.Recurrence,
+		Checklist:   t.Checklist,
+		CreatedOn:   t.CreatedOn.Format(time.RFC3339),
+	}
+	if obj.Tags == nil {
+		obj.Tags = []string{}
+	}
+	if obj.Checklist == nil {
+		obj.Checklist = []string{}
+	}
+	return obj
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name TemplatesLister
+type TemplatesLister interface {
+	List(ctx context.Context, userID string) ([]data.Template, error)
+}
+
+func HandleGetTemplates(log *slog.Logger, lister TemplatesLister) api.APIFunc {
+	const op = "server.http.handlers.templates.GetTemplates"
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(api.UserIDKey).(string)
+		if !ok {
+			msg := "forbidden"
+
+			log.Error(msg, slog.String("error", "no user id in context"))
+
+			return response.APIError{
+				Status:  http.StatusForbidden,
+				Message: msg,
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 150*time.Millisecond)
+		defer cancel()
+
+		tt, err := lister.List(ctx, userID)
+		if err != nil {
+			msg := "internal server error"
+
+			log.Error(msg, sl.Err(err), slog.String("user_id", userID))
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		objs := make([]template, len(tt))
+		for i, t := range tt {
+			objs[i] = newTemplate(t)
+		}
+
+		return response.JSON(w, http.StatusOK, response.M{
+			"templates": objs,
+		})
+	}
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name TemplateGetter
+type TemplateGetter interface {
+	Get(ctx context.Context, userID, id string) (data.Template, error)
+}
+
+func HandleGetTemplate(log *slog.Logger, getter TemplateGetter) api.APIFunc {
+	const op = "server.http.handlers.templates.GetTemplate"
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(api.UserIDKey).(string)
+		if !ok {
+			msg := "forbidden"
+
+			log.Error(msg, slog.String("error", "no user id in context"))
+
+			return response.APIError{
+				Status:  http.StatusForbidden,
+				Message: msg,
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 150*time.Millisecond)
+		defer cancel()
+
+		t, err := getter.Get(ctx, userID, chi.URLParam(r, "id"))
+		if err != nil {
+			if errors.Is(err, templates.ErrNotFound) {
+				log.Error("template not found", sl.Err(err), slog.String("template_id", chi.URLParam(r, "id")))
+
+				return response.NotFound("template")
+			}
+
+			msg := "internal server error"
+
+			log.Error(msg,
+				sl.Err(err),
+				slog.String("user_id", userID),
+				slog.String("template_id", chi.URLParam(r, "id")),
+			)
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		return response.JSON(w, http.StatusOK, response.M{
+			"template": newTemplate(t),
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package templates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/pkg/validator"
+	"github.com/romankravchuk/eldorado/internal/server/http/api"
+	"github.com/romankravchuk/eldorado/internal/server/http/api/response"
+	templatessvc "github.com/romankravchuk/eldorado/internal/services/templates"
+	"github.com/romankravchuk/eldorado/internal/storages/templates"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name TemplateInstantiator
+type TemplateInstantiator interface {
+	Instantiate(ctx context.Context, userID, id string, vars map[string]string, now time.Time) (data.Task, error)
+}
+
+// HandleInstantiateTemplate creates a new task from the template.
+//
+// The request body is optional. Vars extend and override the built-in
+// placeholders, timezone is used to compute {{date}}, {{time}} and others.
+func HandleInstantiateTemplate(log *slog.Logger, instantiator TemplateInstantiator) api.APIFunc {
+	const op = "server.http.handlers.templates.InstantiateTemplate"
+
+	type req struct {
+		Vars     map[string]string `json:"vars" validate:"max=20"`
+		Timezone string            `json:"timezone" validate:"omitempty,timezone"`
+	}
+
+	type task struct {
+		ID          string               `json:"id"`
+		Title       string               `json:"title"`
+		Description string               `json:"description"`
+		IsCompleted bool                 `json:"is_completed"`
+		Tags        []string             `json:"tags,omitempty"`
+		Priority    string               `json:"priority,omitempty"`
+		Recurrence  string               `json:"recurrence,omitempty"`
+		Checklist   []data.ChecklistItem `json:"checklist,omitempty"`
+		CreatedOn   string               `json:"created_on"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(api.UserIDKey).(string)
+		if !ok {
+			msg := "forbidden"
+
+			log.Error(msg, slog.String("error", "no user id in context"))
+
+			return response.APIError{
+				Status:  http.StatusForbidden,
+				Message: msg,
+			}
+		}
+
+		input := new(req)
+		if err := json.NewDecoder(r.Body).Decode(input); err != nil && !errors.Is(err, io.EOF) {
+			msg := "invalid request"
+
+			log.Error(msg, sl.Err(err))
+
+			return response.APIError{
+				Status:  http.StatusBadRequest,
+				Message: msg,
+			}
+		}
+
+		if err := validator.ValidateStruct(*input); err != nil {
+			msg := "invalid request"
+
+			log.Error(msg, sl.Err(err))
+
+			return response.APIError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			}
+		}
+
+		loc := time.UTC
+		if input.Timezone != "" {
+			loc, _ = time.LoadLocation(input.Timezone)
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 150*time.Millisecond)
+		defer cancel()
+
+		t, err := instantiator.Instantiate(ctx, userID, chi.URLParam(r, "id"), input.Vars, time.Now().In(loc))
+		if err != nil {
+			switch {
+			case errors.Is(err, templates.ErrNotFound):
+				log.Error("template not found", sl.Err(err), slog.String("template_id", chi.URLParam(r, "id")))
+
+				return response.NotFound("template")
+			case errors.Is(err, templatessvc.ErrTitleTooShort),
+				errors.Is(err, templatessvc.ErrTitleTooLong),
+				errors.Is(err, templatessvc.ErrDescriptionTooLong),
+				errors.Is(err, templatessvc.ErrChecklistItemLength):
+				log.Error("invalid request", sl.Err(err), slog.String("template_id", chi.URLParam(r, "id")))
+
+				return response.APIError{
+					Status:  http.StatusBadRequest,
+					Message: err.Error(),
+				}
+			}
+
+			msg := "internal server error"
+
+			log.Error(msg,
+				sl.Err(err),
+				slog.String("user_id", userID),
+				slog.String("template_id", chi.URLParam(r, "id")),
+			)
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		obj := task{
+			ID:          t.ID,
+			Title:       t.Title,
+			Description: t.Description,
+			IsCompleted: t.IsCompleted,
+			Tags:        t.Tags,
+			Recurrence:  t.Recurrence,
+			Checklist:   t.Checklist,
+			CreatedOn:   t.CreatedOn.Format(time.RFC3339),
+		}
+		if t.Priority != data.PriorityNone {
+			obj.Priority = t.Priority.String()
+		}
+
+		return response.JSON(w, http.StatusCreated, response.M{
+			"task": obj,
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package templates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/pkg/validator"
+	"github.com/romankravchuk/eldorado/internal/server/http/api"
+	"github.com/romankravchuk/eldorado/internal/server/http/api/response"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+	"github.com/romankravchuk/eldorado/internal/storages/templates"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name TemplateCreater
+type TemplateCreater interface {
+	Create(ctx context.Context, userID string, t data.Template) (data.Template, error)
+	CreateFromTask(ctx context.Context, userID, taskID, name string) (data.Template, error)
+}
+
+// HandleCreateTemplate creates a template either from the request body or,
+// if task_id is given, from the existing task.
+func HandleCreateTemplate(log *slog.Logger, creater TemplateCreater) api.APIFunc {
+	const op = "server.http.handlers.templates.CreateTemplate"
+
+	type req struct {
+		Name        string   `json:"name" validate:"required,min=1,max=100"`
+		TaskID      string   `json:"task_id" validate:"omitempty,uuid"`
+		Title       string   `json:"title" validate:"required_without=TaskID,max=100"`
+		Description string   `json:"description" validate:"max=255"`
+		Tags        []string `json:"tags" validate:"max=20,dive,min=1,max=50"`
+		Priority    string   `json:"priority" validate:"omitempty,oneof=none low medium high"`
+		Recurrence  string   `json:"recurrence" validate:"max=100"`
+		Checklist   []string `json:"checklist" validate:"max=50,dive,min=1,max=255"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(api.UserIDKey).(string)
+		if !ok {
+			msg := "forbidden"
+
+			log.Error(msg, slog.String("error", "no user id in context"))
+
+			return response.APIError{
+				Status:  http.StatusForbidden,
+				Message: msg,
+			}
+		}
+
+		input := new(req)
+		if err := json.NewDecoder(r.Body).Decode(input); err != nil {
+			msg := "invalid request"
+
+			log.Error(msg, sl.Err(err))
+
+			return response.APIError{
+				Status:  http.StatusBadRequest,
+				Message: msg,
+			}
+		}
+
+		if err := validator.ValidateStruct(*input); err != nil {
+			msg := "invalid request"
+
+			log.Error(msg, sl.Err(err))
+
+			return response.APIError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 150*time.Millisecond)
+		defer cancel()
+
+		var (
+			t   data.Template
+			err error
+		)
+		if input.TaskID != "" {
+			t, err = creater.CreateFromTask(ctx, userID, input.TaskID, input.Name)
+		} else {
+			prio, _ := data.ParsePriority(input.Priority)
+
+			t, err = creater.Create(ctx, userID, data.Template{
+				Name:        input.Name,
+				Title:       input.Title,
+				Description: input.Description,
+				Tags:        input.Tags,
+				Priority:    prio,
+				Recurrence:  input.Recurrence,
+				Checklist:   input.Checklist,
+			})
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, tasks.ErrNotFound):
+				log.Error("task not found", sl.Err(err), slog.String("task_id", input.TaskID))
+
+				return response.NotFound("task")
+			case errors.Is(err, templates.ErrAlreadyExists):
+				log.Error("template already exists", sl.Err(err), slog.String("name", input.Name))
+
+				return response.APIError{
+					Status:  http.StatusConflict,
+					Message: err.Error(),
+				}
+			}
+
+			msg := "internal server error"
+
+			log.Error(msg, sl.Err(err), slog.String("user_id", userID), slog.Any("request_body", input))
+
+			return response.APIError{
+				Status:  http.StatusInternalServerError,
+				Message: msg,
+			}
+		}
+
+		return response.JSON(w, http.StatusCreated, response.M{
+			"template": newTemplate(t),
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/romankravchuk/eldorado/internal/server/http/api"
+	"github.com/romankravchuk/eldorado/internal/server/http/api/response"
+)
+
+// Admin lets through only the requests with the admin token in the
+// X-Admin-Token header.
+func Admin(log *slog.Logger, token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			got := r.Header.Get(api.AdminTokenHeader)
+
+			if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				msg := "forbidden"
+
+				log.Error(msg,
+					slog.String("error", "invalid admin token"),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+				)
+
+				response.JSON(w, http.StatusForbidden, response.M{"error": msg})
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/romankravchuk/eldorado/internal/server/http/api"
 	"github.com/romankravchuk/eldorado/internal/server/http/api/response"
 	"github.com/romankravchuk/eldorado/internal/services/auth/proto"
+	"github.com/romankravchuk/eldorado/internal/storages"
 )
 
 const (
@@ -74,10 +75,27 @@ func JWT(log *slog.Logger, client proto.AuthServiceClient) func(http.Handler) ht
 				return
 			}
 
-			log.Info("request verified", slog.String("token", token), slog.String("user_id", resp.UserID))
+			// Tokens issued before the workspaces have no scope.
+			if resp.WorkspaceID == "" {
+				msg := "forbidden"
+
+				log.Error(msg, slog.String("error", "token has no workspace"), slog.String("user_id", resp.UserID))
+
+				response.JSON(w, http.StatusForbidden, response.M{"error": msg})
+
+				return
+			}
+
+			log.Info("request verified",
+				slog.String("token", token),
+				slog.String("user_id", resp.UserID),
+				slog.String("workspace_id", resp.WorkspaceID),
+			)
 
 			ctx := context.WithValue(r.Context(), api.UserIDKey, resp.UserID)
 			ctx = context.WithValue(ctx, api.TokenKey, token)
+			ctx = context.WithValue(ctx, api.WorkspaceIDKey, resp.WorkspaceID)
+			ctx = storages.WithWorkspace(ctx, resp.WorkspaceID)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		}
@@ -0,0 +1,145 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/services"
+)
+
+// Archiver archives the old completed tasks.
+type Archiver interface {
+	Archive(ctx context.Context, defaultDays int) (int, error)
+}
+
+// LockID is the key of the advisory lock held while archiving, so the
+// job scheduled by every replica of the api runs in one of them.
+const LockID int64 = 7310452918823307554
+
+// Locker takes the lock of the job. If the lock is held by another process
+// TryLock returns false.
+type Locker interface {
+	TryLock(ctx context.Context) (release func(), ok bool, err error)
+}
+
+type Option func(s *Service) error
+
+func WithLogger(log *slog.Logger) Option {
+	return func(s *Service) error {
+		if log == nil {
+			return errors.New("logger is nil")
+		}
+		s.log = log
+		return nil
+	}
+}
+
+func WithArchiver(archiver Archiver) Option {
+	return func(s *Service) error {
+		if archiver == nil {
+			return services.ErrNilTasksService
+		}
+		s.archiver = archiver
+		return nil
+	}
+}
+
+// WithLock sets the lock taken before every run of the job. Without it the
+// job runs in every process that starts the service.
+func WithLock(locker Locker) Option {
+	return func(s *Service) error {
+		if locker == nil {
+			return errors.New("locker is nil")
+		}
+		s.locker = locker
+		return nil
+	}
+}
+
+func WithCron(schedule string) Option {
+	return func(s *Service) error {
+		s.cron = cron.New()
+		s.schedule = schedule
+		return nil
+	}
+}
+
+// WithDefaultDays sets how many days completed tasks of users without
+// their own setting are kept before archiving.
+func WithDefaultDays(days int) Option {
+	return func(s *Service) error {
+		s.defaultDays = days
+		return nil
+	}
+}
+
+// Service periodically archives the old completed tasks.
+type Service struct {
+	log *slog.Logger
+
+	cron     *cron.Cron
+	schedule string
+
+	archiver    Archiver
+	locker      Locker
+	defaultDays int
+}
+
+func New(opts ...Option) (*Service, error) {
+	s := &Service{log: slog.Default()}
+
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Start schedules the archiving job and returns immediately.
+func (s *Service) Start() error {
+	_, err := s.cron.AddFunc(s.schedule, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		s.run(ctx)
+	})
+	if err != nil {
+		return err
+	}
+	s.cron.Start()
+	return nil
+}
+
+// run archives the tasks, if the lock is not held by another process.
+func (s *Service) run(ctx context.Context) {
+	if s.locker != nil {
+		release, ok, err := s.locker.TryLock(ctx)
+		if err != nil {
+			s.log.Error("failed to take the archiving lock", sl.Err(err))
+			return
+		}
+		if !ok {
+			s.log.Debug("tasks are archived by another process")
+			return
+		}
+		defer release()
+	}
+
+	users, err := s.archiver.Archive(ctx, s.defaultDays)
+	if err != nil {
+		s.log.Error("failed to archive tasks", sl.Err(err))
+		return
+	}
+	s.log.Info("tasks archived", slog.Int("users", users))
+}
+
+// Stop stops the scheduler and waits for a running job to finish.
+func (s *Service) Stop() {
+	<-s.cron.Stop().Done()
+}
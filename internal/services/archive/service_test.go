@@ -0,0 +1,136 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeArchiver counts the runs of the job.
+type fakeArchiver struct {
+	runs int
+	days int
+	err  error
+}
+
+func (a *fakeArchiver) Archive(_ context.Context, defaultDays int) (int, error) {
+	a.runs++
+	a.days = defaultDays
+	return 1, a.err
+}
+
+// fakeLocker is a lock shared by the services of a test, as the advisory
+// lock is by the replicas of the api.
+type fakeLocker struct {
+	held     bool
+	released int
+	err      error
+}
+
+func (l *fakeLocker) TryLock(context.Context) (func(), bool, error) {
+	if l.err != nil {
+		return nil, false, l.err
+	}
+	if l.held {
+		return nil, false, nil
+	}
+
+	l.held = true
+	return func() {
+		l.held = false
+		l.released++
+	}, true, nil
+}
+
+func newTestService(t *testing.T, archiver Archiver, opts ...Option) *Service {
+	opts = append([]Option{
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithArchiver(archiver),
+		WithCron("@every 1h"),
+		WithDefaultDays(30),
+	}, opts...)
+
+	s, err := New(opts...)
+	require.NoError(t, err)
+	return s
+}
+
+func TestRun(t *testing.T) {
+	archiver := &fakeArchiver{}
+	s := newTestService(t, archiver)
+
+	s.run(context.Background())
+	assert.Equal(t, 1, archiver.runs)
+	assert.Equal(t, 30, archiver.days)
+
+	// A failed run is logged, the next one runs again.
+	archiver.err = errors.New("failed")
+	s.run(context.Background())
+	assert.Equal(t, 2, archiver.runs)
+}
+
+func TestRunLocked(t *testing.T) {
+	lock := &fakeLocker{}
+	archiver := &fakeArchiver{}
+	s := newTestService(t, archiver, WithLock(lock))
+
+	s.run(context.Background())
+	assert.Equal(t, 1, archiver.runs)
+	assert.Equal(t, 1, lock.released, "the lock is not released after the run")
+	assert.False(t, lock.held)
+
+	// Another replica is archiving.
+	lock.held = true
+	s.run(context.Background())
+	assert.Equal(t, 1, archiver.runs, "the job ran without the lock")
+
+	lock.held = false
+	lock.err = errors.New("database is unavailable")
+	s.run(context.Background())
+	assert.Equal(t, 1, archiver.runs, "the job ran when the lock failed")
+}
+
+func TestRunLockedByOneReplica(t *testing.T) {
+	lock := &fakeLocker{}
+	archiver := &fakeArchiver{}
+
+	first := newTestService(t, archiver, WithLock(lock))
+	second := newTestService(t, archiver, WithLock(lock))
+
+	release, ok, err := lock.TryLock(context.Background())
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	first.run(context.Background())
+	second.run(context.Background())
+	assert.Equal(t, 0, archiver.runs)
+
+	release()
+	second.run(context.Background())
+	assert.Equal(t, 1, archiver.runs)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(WithArchiver(nil))
+	assert.Error(t, err)
+
+	_, err = New(WithLock(nil))
+	assert.Error(t, err)
+
+	_, err = New(WithLogger(nil))
+	assert.Error(t, err)
+}
+
+func TestStart(t *testing.T) {
+	s := newTestService(t, &fakeArchiver{}, WithCron("every night"))
+	assert.Error(t, s.Start(), "an invalid schedule is accepted")
+
+	s = newTestService(t, &fakeArchiver{})
+	require.NoError(t, s.Start())
+	s.Stop()
+}
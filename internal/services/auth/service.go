@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
@@ -10,20 +11,40 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/events"
 	"github.com/romankravchuk/eldorado/internal/pkg/jwt"
 	"github.com/romankravchuk/eldorado/internal/pkg/logger"
+	"github.com/romankravchuk/eldorado/internal/pkg/mail"
 	"github.com/romankravchuk/eldorado/internal/pkg/sl"
 	"github.com/romankravchuk/eldorado/internal/pkg/validator"
 	"github.com/romankravchuk/eldorado/internal/services"
 	"github.com/romankravchuk/eldorado/internal/services/auth/proto"
 	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/outbox"
+	outboxpg "github.com/romankravchuk/eldorado/internal/storages/outbox/pg"
+	outboxsqlite "github.com/romankravchuk/eldorado/internal/storages/outbox/sqlite"
+	"github.com/romankravchuk/eldorado/internal/storages/resilience"
 	"github.com/romankravchuk/eldorado/internal/storages/sessions"
 	"github.com/romankravchuk/eldorado/internal/storages/sessions/redis"
 	"github.com/romankravchuk/eldorado/internal/storages/users"
 	"github.com/romankravchuk/eldorado/internal/storages/users/pg"
+	"github.com/romankravchuk/eldorado/internal/storages/users/resilient"
+	"github.com/romankravchuk/eldorado/internal/storages/users/sqlite"
+	"github.com/romankravchuk/eldorado/internal/storages/workspaces"
+	workspacespg "github.com/romankravchuk/eldorado/internal/storages/workspaces/pg"
+	workspacessqlite "github.com/romankravchuk/eldorado/internal/storages/workspaces/sqlite"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// defaultGracePeriod is how long a deleted account can be restored,
+// see WithGracePeriod.
+const defaultGracePeriod = 30 * 24 * time.Hour
+
+// Mailer sends an email, see mail.Queue.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
 type Option func(*Service) error
 
 func WithUsersStorage(users users.Storage) Option {
@@ -37,22 +58,140 @@ func WithUsersStorage(users users.Storage) Option {
 	}
 }
 
-func WithUsersPostgresStorage(url string) Option {
+// WithWorkspacesStorage sets the storage the workspaces of the signed up
+// users are created in and the tokens are scoped by.
+func WithWorkspacesStorage(workspaces workspaces.Storage) Option {
+	return func(s *Service) error {
+		if workspaces == nil {
+			return services.ErrNilWorkspacesStorage
+		}
+
+		s.workspaces = workspaces
+		return nil
+	}
+}
+
+// WithUsersPostgresStorage opens the users, workspaces and outbox storages
+// of the postgres database at url. The users are looked up on the replicas,
+// if they are not nil.
+func WithUsersPostgresStorage(url string, replicas *storages.Replicas, poolOpts ...storages.PoolOption) Option {
 	return func(s *Service) error {
-		pool, err := storages.NewDBPool("postgres", url)
+		pool, err := storages.NewDBPool("postgres", url, poolOpts...)
+		if err != nil {
+			return err
+		}
+
+		users, err := pg.New(pool, pg.WithReplicas(replicas))
+		if err != nil {
+			return err
+		}
+
+		workspaces, err := workspacespg.New(pool)
 		if err != nil {
 			return err
 		}
 
-		users, err := pg.New(pool)
+		events, err := outboxpg.New(pool)
 		if err != nil {
 			return err
 		}
 
+		tx, err := storages.NewTransactor(pool)
+		if err != nil {
+			return err
+		}
+
+		s.outbox = events
+		s.tx = tx
+
+		if err := WithWorkspacesStorage(workspaces)(s); err != nil {
+			return err
+		}
+
 		return WithUsersStorage(users)(s)
 	}
 }
 
+// WithUsersSQLiteStorage opens the users, workspaces and outbox storages
+// of the sqlite database at path.
+func WithUsersSQLiteStorage(path string, poolOpts ...storages.PoolOption) Option {
+	return func(s *Service) error {
+		pool, err := storages.NewSQLitePool(path, poolOpts...)
+		if err != nil {
+			return err
+		}
+
+		users, err := sqlite.New(pool)
+		if err != nil {
+			return err
+		}
+
+		workspaces, err := workspacessqlite.New(pool)
+		if err != nil {
+			return err
+		}
+
+		events, err := outboxsqlite.New(pool)
+		if err != nil {
+			return err
+		}
+
+		tx, err := storages.NewTransactor(pool)
+		if err != nil {
+			return err
+		}
+
+		s.outbox = events
+		s.tx = tx
+
+		if err := WithWorkspacesStorage(workspaces)(s); err != nil {
+			return err
+		}
+
+		return WithUsersStorage(users)(s)
+	}
+}
+
+// WithUsersDBStorage opens the users storage of the driver,
+// storages.DriverPostgres or storages.DriverSQLite.
+// Only postgres supports the read replicas.
+func WithUsersDBStorage(driver, url string, replicas *storages.Replicas, poolOpts ...storages.PoolOption) Option {
+	switch {
+	case driver == storages.DriverPostgres:
+		return WithUsersPostgresStorage(url, replicas, poolOpts...)
+	case replicas != nil:
+		return func(*Service) error {
+			return storages.ErrReplicasUnsupported
+		}
+	case driver == storages.DriverSQLite:
+		return WithUsersSQLiteStorage(url, poolOpts...)
+	}
+
+	return func(*Service) error {
+		return fmt.Errorf("%w: %q", storages.ErrUnknownDriver, driver)
+	}
+}
+
+// WithEvents makes the service record the user.created event in the
+// outbox of the users storage, in the transaction of the sign up.
+// The relay publishes it.
+func WithEvents(enabled bool) Option {
+	return func(s *Service) error {
+		s.events = enabled
+		return nil
+	}
+}
+
+// WithResilience retries the reads of the users storage failed on
+// transient errors and fails its calls fast while the database is down,
+// see resilience.Config. It applies to the storage of any option.
+func WithResilience(cfg resilience.Config) Option {
+	return func(s *Service) error {
+		s.resilience = &cfg
+		return nil
+	}
+}
+
 func WithAccessCreds(pvKey, pbKey string, ttl time.Duration) Option {
 	return func(s *Service) error {
 		pem, pub, err := jwt.ParseKeyPairs(pvKey, pbKey)
@@ -92,9 +231,9 @@ func WithSessionsStorage(sessions sessions.Storage) Option {
 	}
 }
 
-func WtihRedisSessionsStorage(url string) Option {
+func WtihRedisSessionsStorage(url string, redisOpts ...storages.RedisOption) Option {
 	return func(s *Service) error {
-		client, err := storages.NewRedisClient(url)
+		client, err := storages.NewRedisClient(url, redisOpts...)
 		if err != nil {
 			return err
 		}
@@ -103,6 +242,44 @@ func WtihRedisSessionsStorage(url string) Option {
 	}
 }
 
+// WithMailer sets the mailer of the account deletion confirmations.
+// Without it no confirmation is sent.
+func WithMailer(mailer Mailer) Option {
+	return func(s *Service) error {
+		if mailer == nil {
+			return errors.New("mailer could not be nil")
+		}
+		s.mailer = mailer
+		return nil
+	}
+}
+
+// WithRabbitMQ sends the confirmations to the email queue of the email
+// sender.
+func WithRabbitMQ(url, queueName string) Option {
+	return func(s *Service) error {
+		q, err := mail.New(url, queueName)
+		if err != nil {
+			return err
+		}
+
+		s.closeMailer = q.Close
+		return WithMailer(q)(s)
+	}
+}
+
+// WithGracePeriod sets how long a deleted account can be restored by
+// signing in before PurgeDeleted deletes it. Defaults to 30 days.
+func WithGracePeriod(d time.Duration) Option {
+	return func(s *Service) error {
+		if d <= 0 {
+			return errors.New("grace period must be positive")
+		}
+		s.gracePeriod = d
+		return nil
+	}
+}
+
 func WithLogger(log *slog.Logger) Option {
 	return func(s *Service) error {
 		if log == nil {
@@ -115,8 +292,19 @@ func WithLogger(log *slog.Logger) Option {
 }
 
 type Service struct {
-	users    users.Storage
-	sessions sessions.Storage
+	users      users.Storage
+	workspaces workspaces.Storage
+	sessions   sessions.Storage
+
+	tx     storages.Transactor
+	outbox outbox.Storage
+	events bool
+
+	resilience *resilience.Config
+
+	mailer      Mailer
+	closeMailer func() error
+	gracePeriod time.Duration
 
 	log *slog.Logger
 
@@ -127,7 +315,7 @@ type Service struct {
 }
 
 func New(opts ...Option) (*Service, error) {
-	s := &Service{}
+	s := &Service{tx: storages.NopTransactor{}, gracePeriod: defaultGracePeriod}
 
 	for _, opt := range opts {
 		if err := opt(s); err != nil {
@@ -135,9 +323,21 @@ func New(opts ...Option) (*Service, error) {
 		}
 	}
 
+	if s.resilience != nil && s.users != nil {
+		s.users = resilient.New(s.users, resilience.New("users", *s.resilience))
+	}
+
 	return s, nil
 }
 
+// Close closes the connection of the mailer opened by WithRabbitMQ.
+func (s *Service) Close() error {
+	if s.closeMailer == nil {
+		return nil
+	}
+	return s.closeMailer()
+}
+
 func (s *Service) SignUp(ctx context.Context, in *proto.SignUpRequest) (*proto.Response, error) {
 	const op = "services.auth.SignUp"
 
@@ -166,11 +366,29 @@ func (s *Service) SignUp(ctx context.Context, in *proto.SignUpRequest) (*proto.R
 		}, nil
 	}
 
-	err = s.users.Save(ctx, &data.User{
+	user := data.User{
 		Email:             in.Email,
 		Username:          in.Username,
 		Name:              in.Username,
 		EncryptedPassword: string(hash),
+	}
+
+	// The user is not a member of any workspace yet.
+	ctx = storages.AllWorkspaces(ctx)
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.users.Save(ctx, &user); err != nil {
+			return err
+		}
+
+		if s.workspaces != nil {
+			personal := data.Workspace{Name: user.Username}
+			if err := s.workspaces.Create(ctx, &personal, user.ID); err != nil {
+				return err
+			}
+		}
+
+		return s.record(ctx, events.TypeUserCreated, events.UserOf(user))
 	})
 	if err != nil {
 		if errors.Is(err, users.ErrAlreadyExists) {
@@ -199,6 +417,26 @@ func (s *Service) SignUp(ctx context.Context, in *proto.SignUpRequest) (*proto.R
 	}, nil
 }
 
+// record adds the user event to the outbox, if the events are enabled.
+// It must run in the transaction of the change.
+func (s *Service) record(ctx context.Context, typ string, u events.User) error {
+	if !s.events || s.outbox == nil {
+		return nil
+	}
+
+	env, err := events.New(typ, events.UserVersion, u)
+	if err != nil {
+		return err
+	}
+
+	e, err := data.NewEvent(typ, u.ID, env)
+	if err != nil {
+		return err
+	}
+
+	return s.outbox.Add(ctx, &e)
+}
+
 func (s *Service) Token(ctx context.Context, in *proto.TokenRequest) (*proto.TokenResponse, error) {
 	const op = "services.auth.Token"
 
@@ -217,7 +455,8 @@ func (s *Service) Token(ctx context.Context, in *proto.TokenRequest) (*proto.Tok
 		}, nil
 	}
 
-	u, err := s.users.FindByEmail(ctx, in.GetEmail())
+	// The workspace is not known until the user is found.
+	u, err := s.users.FindByEmail(storages.AllWorkspaces(ctx), in.GetEmail())
 	if err != nil {
 		if errors.Is(err, users.ErrNotFound) {
 			msg := "the user with given email not found"
@@ -244,6 +483,21 @@ func (s *Service) Token(ctx context.Context, in *proto.TokenRequest) (*proto.Tok
 		}, nil
 	}
 
+	// The account is purged once the grace period is over, until then
+	// it is restored by signing in.
+	if !u.DeletedOn.IsZero() && time.Since(u.DeletedOn) >= s.gracePeriod {
+		msg := "the user with given email not found"
+
+		log.Error(msg, slog.String("user_id", u.ID), slog.Time("deleted_on", u.DeletedOn))
+
+		return &proto.TokenResponse{
+			Meta: &proto.Response{
+				Status: http.StatusNotFound,
+				Error:  msg,
+			},
+		}, nil
+	}
+
 	if bcrypt.CompareHashAndPassword([]byte(u.EncryptedPassword), []byte(in.GetPassword())) != nil {
 		msg := "invalid password"
 
@@ -257,11 +511,56 @@ func (s *Service) Token(ctx context.Context, in *proto.TokenRequest) (*proto.Tok
 		}, nil
 	}
 
+	if !u.DeletedOn.IsZero() {
+		if err := s.users.Restore(storages.AllWorkspaces(ctx), u.ID); err != nil && !errors.Is(err, users.ErrNotFound) {
+			msg := "failed to cancel account deletion"
+
+			log.Error(msg, sl.Err(err), slog.String("user_id", u.ID))
+
+			return &proto.TokenResponse{
+				Meta: &proto.Response{
+					Status: http.StatusInternalServerError,
+					Error:  msg,
+				},
+			}, nil
+		}
+
+		log.Info("account deletion cancelled", slog.String("user_id", u.ID))
+	}
+
+	workspaceID, err := s.workspaceOf(ctx, u.ID, in.GetWorkspaceID())
+	if err != nil {
+		if errors.Is(err, workspaces.ErrNotFound) {
+			msg := "the user is not a member of the workspace"
+
+			log.Error(msg, sl.Err(err), slog.String("workspace_id", in.GetWorkspaceID()))
+
+			return &proto.TokenResponse{
+				Meta: &proto.Response{
+					Status: http.StatusForbidden,
+					Error:  msg,
+				},
+			}, nil
+		}
+
+		msg := "failed to find workspace of user"
+
+		log.Error(msg, sl.Err(err))
+
+		return &proto.TokenResponse{
+			Meta: &proto.Response{
+				Status: http.StatusInternalServerError,
+				Error:  msg,
+			},
+		}, nil
+	}
+
 	access, err := jwt.CreateToken(
 		&data.TokenPayload{
-			ID:     uuid.NewString(),
-			UserID: u.ID,
-			Email:  u.Email,
+			ID:          uuid.NewString(),
+			UserID:      u.ID,
+			WorkspaceID: workspaceID,
+			Email:       u.Email,
 		},
 		s.access.TTL,
 		s.access.PrivateKey,
@@ -279,7 +578,7 @@ func (s *Service) Token(ctx context.Context, in *proto.TokenRequest) (*proto.Tok
 		}, nil
 	}
 
-	err = s.sessions.Set(ctx, access.Payload.ID, []byte(access.Token), s.access.TTL)
+	err = s.sessions.Set(ctx, access.Payload.UserID, access.Payload.ID, []byte(access.Token), s.access.TTL)
 	if err != nil {
 		msg := "failed to add session to storage"
 
@@ -288,9 +587,10 @@ func (s *Service) Token(ctx context.Context, in *proto.TokenRequest) (*proto.Tok
 
 	refresh, err := jwt.CreateToken(
 		&data.TokenPayload{
-			ID:     uuid.NewString(),
-			UserID: u.ID,
-			Email:  u.Email,
+			ID:          uuid.NewString(),
+			UserID:      u.ID,
+			WorkspaceID: workspaceID,
+			Email:       u.Email,
 		},
 		s.refresh.TTL,
 		s.refresh.PrivateKey,
@@ -308,7 +608,7 @@ func (s *Service) Token(ctx context.Context, in *proto.TokenRequest) (*proto.Tok
 		}, nil
 	}
 
-	err = s.sessions.Set(ctx, refresh.Payload.ID, []byte(refresh.Token), s.refresh.TTL)
+	err = s.sessions.Set(ctx, refresh.Payload.UserID, refresh.Payload.ID, []byte(refresh.Token), s.refresh.TTL)
 	if err != nil {
 		msg := "failed to store refresh token in storage"
 
@@ -324,6 +624,40 @@ func (s *Service) Token(ctx context.Context, in *proto.TokenRequest) (*proto.Tok
 	}, nil
 }
 
+// workspaceOf returns the workspace the tokens of the user are scoped to:
+// the requested one, or the oldest workspace of the user if none is.
+//
+// If the user is not a member of the requested workspace, or of any,
+// returns workspaces.ErrNotFound.
+func (s *Service) workspaceOf(ctx context.Context, userID, requested string) (string, error) {
+	if s.workspaces == nil {
+		return "", workspaces.ErrNotFound
+	}
+
+	if requested != "" {
+		if _, err := uuid.Parse(requested); err != nil {
+			return "", workspaces.ErrNotFound
+		}
+
+		m, err := s.workspaces.FindMembership(ctx, requested, userID)
+		if err != nil {
+			return "", err
+		}
+
+		return m.WorkspaceID, nil
+	}
+
+	ms, err := s.workspaces.Memberships(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if len(ms) == 0 {
+		return "", workspaces.ErrNotFound
+	}
+
+	return ms[0].WorkspaceID, nil
+}
+
 func (s *Service) Refresh(ctx context.Context, in *proto.RefreshRequest) (*proto.RefreshResponse, error) {
 	const op = "services.auth.Token"
 
@@ -370,6 +704,35 @@ func (s *Service) Refresh(ctx context.Context, in *proto.RefreshRequest) (*proto
 		}, nil
 	}
 
+	// The sessions stored before they were indexed by user are not
+	// revoked with the account, see DeleteAccount, so a deleted user is
+	// checked here as well.
+	if _, err = s.users.FindByID(storages.AllWorkspaces(ctx), payload.UserID); err != nil {
+		if errors.Is(err, users.ErrNotFound) {
+			msg := "the user not found"
+
+			log.Error(msg, sl.Err(err), slog.String("user_id", payload.UserID))
+
+			return &proto.RefreshResponse{
+				Meta: &proto.Response{
+					Status: http.StatusForbidden,
+					Error:  msg,
+				},
+			}, nil
+		}
+
+		msg := "failed to find user by id"
+
+		log.Error(msg, sl.Err(err), slog.String("user_id", payload.UserID))
+
+		return &proto.RefreshResponse{
+			Meta: &proto.Response{
+				Status: http.StatusInternalServerError,
+				Error:  msg,
+			},
+		}, nil
+	}
+
 	access, err := jwt.CreateToken(
 		payload,
 		s.access.TTL,
@@ -388,7 +751,7 @@ func (s *Service) Refresh(ctx context.Context, in *proto.RefreshRequest) (*proto
 		}, nil
 	}
 
-	if err = s.sessions.Set(ctx, access.Payload.ID, []byte(access.Token), s.access.TTL); err != nil {
+	if err = s.sessions.Set(ctx, access.Payload.UserID, access.Payload.ID, []byte(access.Token), s.access.TTL); err != nil {
 		msg := "failed to add access token to storage"
 
 		log.Error(msg, sl.Err(err), slog.Any("access_token", access), slog.Any("request", in))
@@ -442,7 +805,153 @@ func (s *Service) Verify(ctx context.Context, in *proto.VerifyRequest) (*proto.V
 	}
 
 	return &proto.VerifyResponse{
-		Meta:   &proto.Response{Status: http.StatusOK},
-		UserID: payload.UserID,
+		Meta:        &proto.Response{Status: http.StatusOK},
+		UserID:      payload.UserID,
+		WorkspaceID: payload.WorkspaceID,
+	}, nil
+}
+
+// DeleteAccount marks the account of the user deleted, revokes all their
+// sessions and emails a confirmation. The account is purged after the
+// grace period, see PurgeDeleted, unless the user signs in before.
+//
+// The sessions are revoked by the sessions:user:<id> index, the ones
+// stored before the index are not. Their refresh tokens are rejected by
+// Refresh for a deleted user and their access tokens expire after the
+// access ttl.
+func (s *Service) DeleteAccount(ctx context.Context, in *proto.DeleteAccountRequest) (*proto.DeleteAccountResponse, error) {
+	const op = "services.auth.DeleteAccount"
+
+	log := s.log.With("op", op, slog.String("user_id", in.GetUserID()))
+
+	if err := validator.ValidateStruct(in); err != nil {
+		msg := "failed to validate request"
+
+		log.Error(msg, sl.Err(err))
+
+		return &proto.DeleteAccountResponse{
+			Meta: &proto.Response{
+				Status: http.StatusBadRequest,
+				Error:  err.Error(),
+			},
+		}, nil
+	}
+
+	// The account is deleted from all of the workspaces of the user.
+	ctx = storages.AllWorkspaces(ctx)
+
+	u, err := s.users.FindByID(ctx, in.GetUserID())
+	if err != nil {
+		if errors.Is(err, users.ErrNotFound) {
+			msg := "the user not found"
+
+			log.Error(msg, sl.Err(err))
+
+			return &proto.DeleteAccountResponse{
+				Meta: &proto.Response{
+					Status: http.StatusNotFound,
+					Error:  msg,
+				},
+			}, nil
+		}
+
+		msg := "failed to find user by id"
+
+		log.Error(msg, sl.Err(err))
+
+		return &proto.DeleteAccountResponse{
+			Meta: &proto.Response{
+				Status: http.StatusInternalServerError,
+				Error:  msg,
+			},
+		}, nil
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(u.EncryptedPassword), []byte(in.GetPassword())) != nil {
+		msg := "invalid password"
+
+		log.Error(msg)
+
+		return &proto.DeleteAccountResponse{
+			Meta: &proto.Response{
+				Status: http.StatusForbidden,
+				Error:  msg,
+			},
+		}, nil
+	}
+
+	if err = s.users.Delete(ctx, u.ID); err != nil {
+		msg := "failed to delete user"
+
+		log.Error(msg, sl.Err(err))
+
+		return &proto.DeleteAccountResponse{
+			Meta: &proto.Response{
+				Status: http.StatusInternalServerError,
+				Error:  msg,
+			},
+		}, nil
+	}
+
+	deleteOn := time.Now().UTC().Add(s.gracePeriod)
+
+	// The tokens stop passing Verify once their sessions are gone. An
+	// account with sessions left is not deleted, the user can try again.
+	if err = s.sessions.DeleteAll(ctx, u.ID); err != nil {
+		msg := "failed to revoke sessions"
+
+		log.Error(msg, sl.Err(err))
+
+		if err := s.users.Restore(ctx, u.ID); err != nil {
+			log.Error("failed to cancel account deletion", sl.Err(err))
+		}
+
+		return &proto.DeleteAccountResponse{
+			Meta: &proto.Response{
+				Status: http.StatusInternalServerError,
+				Error:  msg,
+			},
+		}, nil
+	}
+
+	if s.mailer != nil {
+		body := fmt.Sprintf(
+			"Hello, %s!\n\nYour account is deleted. It will be erased with all your tasks on %s.\n"+
+				"If you did not mean it, sign in before then to restore the account.\n",
+			u.Name, deleteOn.Format(time.RFC1123),
+		)
+		if err = s.mailer.Send(ctx, u.Email, "Your account is deleted", body); err != nil {
+			log.Error("failed to send deletion confirmation", sl.Err(err))
+		}
+	}
+
+	log.Info("account deleted", slog.Time("delete_on", deleteOn))
+
+	return &proto.DeleteAccountResponse{
+		Meta:     &proto.Response{Status: http.StatusOK},
+		DeleteOn: deleteOn.Format(time.RFC3339),
 	}, nil
 }
+
+// PurgeDeleted erases the accounts deleted longer than the grace period
+// ago with everything they own, the sessions and their index included.
+// It returns the number of the accounts.
+func (s *Service) PurgeDeleted(ctx context.Context) (int, error) {
+	ids, err := s.users.Purge(storages.AllWorkspaces(ctx), time.Now().Add(-s.gracePeriod))
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	for _, id := range ids {
+		if err := s.sessions.DeleteAll(ctx, id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return len(ids), fmt.Errorf("failed to delete sessions of purged accounts: %w", err)
+	}
+
+	return len(ids), nil
+}
@@ -0,0 +1,93 @@
+package deletion
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+)
+
+// Purger erases the accounts whose deletion grace period is over.
+type Purger interface {
+	PurgeDeleted(ctx context.Context) (int, error)
+}
+
+type Option func(s *Service) error
+
+func WithLogger(log *slog.Logger) Option {
+	return func(s *Service) error {
+		if log == nil {
+			return errors.New("logger is nil")
+		}
+		s.log = log
+		return nil
+	}
+}
+
+func WithPurger(purger Purger) Option {
+	return func(s *Service) error {
+		if purger == nil {
+			return errors.New("purger could not be nil")
+		}
+		s.purger = purger
+		return nil
+	}
+}
+
+func WithCron(schedule string) Option {
+	return func(s *Service) error {
+		s.cron = cron.New()
+		s.schedule = schedule
+		return nil
+	}
+}
+
+// Service periodically erases the deleted accounts.
+type Service struct {
+	log *slog.Logger
+
+	cron     *cron.Cron
+	schedule string
+
+	purger Purger
+}
+
+func New(opts ...Option) (*Service, error) {
+	s := &Service{log: slog.Default()}
+
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Start schedules the purge job and returns immediately.
+func (s *Service) Start() error {
+	_, err := s.cron.AddFunc(s.schedule, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		n, err := s.purger.PurgeDeleted(ctx)
+		if err != nil {
+			s.log.Error("failed to purge deleted accounts", sl.Err(err))
+			return
+		}
+		s.log.Info("deleted accounts purged", slog.Int("users", n))
+	})
+	if err != nil {
+		return err
+	}
+	s.cron.Start()
+	return nil
+}
+
+// Stop stops the scheduler and waits for a running job to finish.
+func (s *Service) Stop() {
+	<-s.cron.Stop().Done()
+}
@@ -7,4 +7,10 @@ import (
 var (
 	ErrNilTasksStorage = errors.New("the tasks storage could not be nil")
 	ErrNilUsersStorage = errors.New("the users storage could not be nil")
+
+	ErrNilWorkspacesStorage = errors.New("the workspaces storage could not be nil")
+
+	ErrNilTemplatesStorage = errors.New("the templates storage could not be nil")
+	ErrNilTasksService     = errors.New("the tasks service could not be nil")
+	ErrNilSettingsStorage  = errors.New("the settings storage could not be nil")
 )
@@ -0,0 +1,207 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/envelope"
+)
+
+const (
+	// archiveKeyID is the ID of the key derived from the secret of the
+	// links in the keyring of the archives.
+	archiveKeyID = "export"
+	// sealedArchive is the first byte of an encrypted archive.
+	sealedArchive = 0x01
+)
+
+var errMalformedArchive = errors.New("the encrypted archive is malformed")
+
+type profile struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Username  string    `json:"username"`
+	Name      string    `json:"name"`
+	CreatedOn time.Time `json:"created_on"`
+}
+
+type task struct {
+	ID          string               `json:"id"`
+	WorkspaceID string               `json:"workspace_id,omitempty"`
+	Title       string               `json:"title"`
+	Description string               `json:"description"`
+	IsCompleted bool                 `json:"is_completed"`
+	IsDeleted   bool                 `json:"is_deleted"`
+	DueOn       *time.Time           `json:"due_on,omitempty"`
+	Tags        []string             `json:"tags"`
+	Priority    string               `json:"priority"`
+	Recurrence  string               `json:"recurrence,omitempty"`
+	Checklist   []data.ChecklistItem `json:"checklist"`
+	HiddenUntil *time.Time           `json:"hidden_until,omitempty"`
+	CompletedOn *time.Time           `json:"completed_on,omitempty"`
+	ArchivedOn  *time.Time           `json:"archived_on,omitempty"`
+	CreatedOn   time.Time            `json:"created_on"`
+}
+
+type session struct {
+	ID        string     `json:"id"`
+	ExpiresOn *time.Time `json:"expires_on,omitempty"`
+}
+
+type event struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"`
+	AggregateID string          `json:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedOn   time.Time       `json:"created_on"`
+}
+
+// newArchive returns the ZIP archive of the user data: the profile, all
+// the tasks including the deleted ones, the active sessions and the
+// domain events, one JSON file each.
+//
+// There is no audit log: the events kept in the outbox until they are
+// purged are the only record of what the user did, so they are exported
+// as events.json rather than presented as a complete audit trail.
+func newArchive(u data.User, tt []data.Task, ss []data.Session, ee []data.Event) ([]byte, error) {
+	tasks := make([]task, 0, len(tt))
+	for _, t := range tt {
+		tasks = append(tasks, task{
+			ID:          t.ID,
+			WorkspaceID: t.WorkspaceID,
+			Title:       t.Title,
+			Description: t.Description,
+			IsCompleted: t.IsCompleted,
+			IsDeleted:   t.IsDeleted,
+			DueOn:       optional(t.DueOn),
+			Tags:        t.Tags,
+			Priority:    t.Priority.String(),
+			Recurrence:  t.Recurrence,
+			Checklist:   t.Checklist,
+			HiddenUntil: optional(t.HiddenUntil),
+			CompletedOn: optional(t.CompletedOn),
+			ArchivedOn:  optional(t.ArchivedOn),
+			CreatedOn:   t.CreatedOn,
+		})
+	}
+
+	sessions := make([]session, 0, len(ss))
+	for _, s := range ss {
+		sessions = append(sessions, session{ID: s.ID, ExpiresOn: optional(s.ExpiresOn)})
+	}
+
+	events := make([]event, 0, len(ee))
+	for _, e := range ee {
+		events = append(events, event{
+			ID:          e.ID,
+			Type:        e.Type,
+			AggregateID: e.AggregateID,
+			Payload:     e.Payload,
+			CreatedOn:   e.CreatedOn,
+		})
+	}
+
+	files := []struct {
+		name string
+		v    any
+	}{
+		{"profile.json", profile{ID: u.ID, Email: u.Email, Username: u.Username, Name: u.Name, CreatedOn: u.CreatedOn}},
+		{"tasks.json", tasks},
+		{"sessions.json", sessions},
+		{"events.json", events},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.v); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func optional(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// archiveKeyring returns the keyring of the archives. Its only key is
+// derived from the secret of the links, so the archives need no key of
+// their own to be configured.
+func archiveKeyring(secret []byte) (*envelope.Keyring, error) {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("eldorado export archive"))
+
+	return envelope.NewKeyring(map[string]string{
+		archiveKeyID: base64.StdEncoding.EncodeToString(mac.Sum(nil)),
+	}, archiveKeyID)
+}
+
+// seal encrypts the archive of the export id with a new data key. The
+// ID is authenticated, so the archives of the exports can not be
+// swapped. The sealed archive is the sealedArchive byte, the length of
+// the wrapped data key as uint16, the wrapped data key and the
+// ciphertext.
+func (s *Service) seal(id string, archive []byte) ([]byte, error) {
+	dk, err := s.keyring.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := dk.SealBytes(id, archive)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 0, 3+len(dk.Wrapped)+len(ciphertext))
+	b = append(b, sealedArchive)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(dk.Wrapped)))
+	b = append(b, dk.Wrapped...)
+
+	return append(b, ciphertext...), nil
+}
+
+// open returns the archive of the export id sealed by seal.
+//
+// If the archive is not sealed returns errMalformedArchive.
+func (s *Service) open(id string, sealed []byte) ([]byte, error) {
+	if len(sealed) < 3 || sealed[0] != sealedArchive {
+		return nil, errMalformedArchive
+	}
+
+	n := int(binary.BigEndian.Uint16(sealed[1:3]))
+	if len(sealed) < 3+n {
+		return nil, errMalformedArchive
+	}
+
+	dk, err := s.keyring.Unwrap(archiveKeyID, sealed[3:3+n])
+	if err != nil {
+		return nil, err
+	}
+
+	return dk.OpenBytes(id, sealed[3+n:])
+}
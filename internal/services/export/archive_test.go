@@ -0,0 +1,45 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeal(t *testing.T) {
+	s := &Service{}
+	require.NoError(t, WithLinks("http://localhost:8080", "secret", time.Hour)(s))
+
+	archive, err := newArchive(data.User{ID: "user", Email: "user@example.com"}, nil, nil, nil)
+	require.NoError(t, err)
+
+	sealed, err := s.seal("first", archive)
+	require.NoError(t, err)
+	assert.Equal(t, byte(sealedArchive), sealed[0])
+	assert.False(t, bytes.Contains(sealed, archive[:4]), "the archive is stored in plaintext")
+
+	got, err := s.open("first", sealed)
+	require.NoError(t, err)
+	assert.Equal(t, archive, got)
+
+	// The archive of another export is not opened.
+	_, err = s.open("second", sealed)
+	assert.Error(t, err)
+
+	// Nor is it with another secret.
+	other := &Service{}
+	require.NoError(t, WithLinks("http://localhost:8080", "other", time.Hour)(other))
+	_, err = other.open("first", sealed)
+	assert.Error(t, err)
+
+	// Nor is an archive that is not sealed.
+	_, err = s.open("first", archive)
+	assert.ErrorIs(t, err, errMalformedArchive)
+
+	_, err = s.open("first", sealed[:2])
+	assert.ErrorIs(t, err, errMalformedArchive)
+}
@@ -0,0 +1,500 @@
+package export
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/envelope"
+	"github.com/romankravchuk/eldorado/internal/pkg/mail"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/services"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/exports"
+	exportspg "github.com/romankravchuk/eldorado/internal/storages/exports/pg"
+	exportssqlite "github.com/romankravchuk/eldorado/internal/storages/exports/sqlite"
+	"github.com/romankravchuk/eldorado/internal/storages/outbox"
+	outboxpg "github.com/romankravchuk/eldorado/internal/storages/outbox/pg"
+	outboxsqlite "github.com/romankravchuk/eldorado/internal/storages/outbox/sqlite"
+	"github.com/romankravchuk/eldorado/internal/storages/sessions"
+	"github.com/romankravchuk/eldorado/internal/storages/sessions/redis"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+	taskspg "github.com/romankravchuk/eldorado/internal/storages/tasks/pg"
+	taskssqlite "github.com/romankravchuk/eldorado/internal/storages/tasks/sqlite"
+	"github.com/romankravchuk/eldorado/internal/storages/users"
+	userspg "github.com/romankravchuk/eldorado/internal/storages/users/pg"
+	userssqlite "github.com/romankravchuk/eldorado/internal/storages/users/sqlite"
+)
+
+// purgeInterval is how often the expired exports are deleted.
+const purgeInterval = time.Hour
+
+// reclaimAfter is how long an export claimed by a worker is left to it.
+// The worker of an export not finished by then is considered dead and
+// the export is built again. It is well over the timeout of a build.
+const reclaimAfter = 10 * time.Minute
+
+var (
+	// ErrInvalidLink is returned by Download for a link that is not signed
+	// by the service, has expired or points to an export that is not ready.
+	ErrInvalidLink = errors.New("the download link is invalid or expired")
+	// ErrInProgress is returned by Request for a user whose previous
+	// export is not built yet.
+	ErrInProgress = errors.New("the previous export is not built yet")
+)
+
+// Mailer sends an email, see mail.Queue.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+type Option func(*Service) error
+
+// WithStorages sets the storages the exports are built from. The tasks
+// and the users are read on behalf of all workspaces.
+func WithStorages(exports exports.Storage, users users.Storage, tasks tasks.Storage, events outbox.Storage) Option {
+	return func(s *Service) error {
+		switch {
+		case exports == nil:
+			return errors.New("exports storage could not be nil")
+		case users == nil:
+			return services.ErrNilUsersStorage
+		case tasks == nil:
+			return services.ErrNilTasksStorage
+		case events == nil:
+			return errors.New("outbox storage could not be nil")
+		}
+
+		s.exports = exports
+		s.users = users
+		s.tasks = tasks
+		s.events = events
+		return nil
+	}
+}
+
+// WithDBStorage opens the storages of the driver, storages.DriverPostgres
+// or storages.DriverSQLite, on one db pool. The url of sqlite is the
+// database path. The tasks of postgres are decrypted with the keyring.
+func WithDBStorage(driver, url string, keyring *envelope.Keyring, poolOpts ...storages.PoolOption) Option {
+	return func(s *Service) error {
+		pool, err := storages.OpenDB(driver, url, poolOpts...)
+		if err != nil {
+			return err
+		}
+
+		return WithDBPool(driver, pool, keyring)(s)
+	}
+}
+
+// WithDBPool is WithDBStorage on the pool of the driver opened by the
+// caller, see storages.OpenDB. The pool is not closed by the service.
+func WithDBPool(driver string, pool *sql.DB, keyring *envelope.Keyring) Option {
+	return func(s *Service) error {
+		if pool == nil {
+			return storages.ErrNilDBPool
+		}
+
+		var (
+			ex     exports.Storage
+			us     users.Storage
+			ts     tasks.Storage
+			events outbox.Storage
+			errs   [4]error
+		)
+
+		switch driver {
+		case storages.DriverPostgres:
+			ex, errs[0] = exportspg.New(pool)
+			us, errs[1] = userspg.New(pool)
+			ts, errs[2] = taskspg.New(pool, taskspg.WithKeyring(keyring))
+			events, errs[3] = outboxpg.New(pool)
+		case storages.DriverSQLite:
+			ex, errs[0] = exportssqlite.New(pool)
+			us, errs[1] = userssqlite.New(pool)
+			ts, errs[2] = taskssqlite.New(pool)
+			events, errs[3] = outboxsqlite.New(pool)
+		default:
+			return fmt.Errorf("%w: %q", storages.ErrUnknownDriver, driver)
+		}
+
+		if err := errors.Join(errs[:]...); err != nil {
+			return err
+		}
+
+		return WithStorages(ex, us, ts, events)(s)
+	}
+}
+
+// WithSessionsStorage sets the storage the sessions of the users are
+// listed from. Without it the exports have no sessions.
+func WithSessionsStorage(sessions sessions.Storage) Option {
+	return func(s *Service) error {
+		s.sessions = sessions
+		return nil
+	}
+}
+
+// WithRedisSessionsStorage lists the sessions from the Redis of the auth
+// service.
+func WithRedisSessionsStorage(url string, redisOpts ...storages.RedisOption) Option {
+	return func(s *Service) error {
+		client, err := storages.NewRedisClient(url, redisOpts...)
+		if err != nil {
+			return err
+		}
+
+		return WithSessionsStorage(redis.New(client))(s)
+	}
+}
+
+func WithMailer(mailer Mailer) Option {
+	return func(s *Service) error {
+		if mailer == nil {
+			return errors.New("mailer could not be nil")
+		}
+		s.mailer = mailer
+		return nil
+	}
+}
+
+// WithRabbitMQ sends the links to the email queue of the email sender.
+func WithRabbitMQ(url, queueName string) Option {
+	return func(s *Service) error {
+		q, err := mail.New(url, queueName)
+		if err != nil {
+			return err
+		}
+
+		s.closeMailer = q.Close
+		return WithMailer(q)(s)
+	}
+}
+
+// WithLinks sets how the download links are made: the public url of the
+// api, the key they are signed with and how long they and the archives
+// are kept. The archives are encrypted with a key derived from the
+// secret, so changing it makes the archives unreadable as well as their
+// links invalid.
+func WithLinks(baseURL, secret string, ttl time.Duration) Option {
+	return func(s *Service) error {
+		if _, err := url.Parse(baseURL); err != nil {
+			return err
+		}
+		if secret == "" {
+			return errors.New("export signing secret could not be empty")
+		}
+		if ttl <= 0 {
+			return errors.New("export ttl must be positive")
+		}
+
+		k, err := archiveKeyring([]byte(secret))
+		if err != nil {
+			return err
+		}
+
+		s.baseURL = strings.TrimSuffix(baseURL, "/")
+		s.secret = []byte(secret)
+		s.keyring = k
+		s.ttl = ttl
+		return nil
+	}
+}
+
+// WithPolling sets how often the pending exports are looked for.
+// Defaults to every 5 seconds.
+func WithPolling(interval time.Duration) Option {
+	return func(s *Service) error {
+		if interval <= 0 {
+			return errors.New("polling interval must be positive")
+		}
+		s.interval = interval
+		return nil
+	}
+}
+
+func WithLogger(log *slog.Logger) Option {
+	return func(s *Service) error {
+		if log == nil {
+			return errors.New("logger is nil")
+		}
+		s.log = log
+		return nil
+	}
+}
+
+// Service builds the data exports of the users in background. An export
+// is requested with Request, built by Run into an encrypted ZIP archive
+// and its signed download link is emailed to the user. The archive is
+// deleted once the link expires.
+type Service struct {
+	exports  exports.Storage
+	users    users.Storage
+	tasks    tasks.Storage
+	events   outbox.Storage
+	sessions sessions.Storage
+
+	mailer      Mailer
+	closeMailer func() error
+
+	baseURL string
+	secret  []byte
+	keyring *envelope.Keyring
+	ttl     time.Duration
+
+	interval time.Duration
+	timeout  time.Duration
+	purgedAt time.Time
+
+	log *slog.Logger
+}
+
+func New(opts ...Option) (*Service, error) {
+	s := &Service{
+		interval: 5 * time.Second,
+		timeout:  time.Minute,
+		log:      slog.Default(),
+	}
+
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.exports == nil {
+		return nil, errors.New("exports storage is not set")
+	}
+	if s.mailer == nil {
+		return nil, errors.New("mailer is not set")
+	}
+	if len(s.secret) == 0 {
+		return nil, errors.New("export links are not set")
+	}
+
+	return s, nil
+}
+
+// Request queues an export of the user. The link is emailed once it is
+// built.
+//
+// If the previous export of the user is not built yet returns
+// ErrInProgress.
+func (s *Service) Request(ctx context.Context, userID string) (data.Export, error) {
+	e := data.Export{UserID: userID}
+	if err := s.exports.Create(ctx, &e); err != nil {
+		if errors.Is(err, exports.ErrInProgress) {
+			return data.Export{}, ErrInProgress
+		}
+		return data.Export{}, err
+	}
+
+	return e, nil
+}
+
+// Run builds the pending exports until ctx is done.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.drain(ctx)
+		s.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close closes the connection of the mailer opened by WithRabbitMQ.
+func (s *Service) Close() error {
+	if s.closeMailer == nil {
+		return nil
+	}
+	return s.closeMailer()
+}
+
+// drain builds the pending exports one by one until there are none. The
+// exports left by a dead worker are built as well.
+func (s *Service) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		e, err := s.exports.Claim(ctx, time.Now().Add(-reclaimAfter))
+		if err != nil {
+			if !errors.Is(err, exports.ErrNotFound) {
+				s.log.Error("failed to claim export", sl.Err(err))
+			}
+			return
+		}
+
+		s.process(ctx, e)
+	}
+}
+
+// process builds the export and emails its link. A failed export is kept
+// until it would have expired, so it is not built again.
+func (s *Service) process(ctx context.Context, e data.Export) {
+	log := s.log.With(slog.String("export_id", e.ID), slog.String("user_id", e.UserID))
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	e.ExpiresOn = time.Now().UTC().Add(s.ttl)
+
+	u, archive, err := s.build(ctx, e.UserID)
+	if err == nil {
+		archive, err = s.seal(e.ID, archive)
+	}
+	if err != nil {
+		log.Error("failed to build export", sl.Err(err))
+
+		e.Status = data.ExportFailed
+		if err := s.exports.Finish(ctx, e); err != nil {
+			log.Error("failed to mark export failed", sl.Err(err))
+		}
+		return
+	}
+
+	e.Status = data.ExportReady
+	e.Archive = archive
+	if err := s.exports.Finish(ctx, e); err != nil {
+		log.Error("failed to store export", sl.Err(err))
+		return
+	}
+
+	body := fmt.Sprintf(
+		"Hello, %s!\n\nThe export of your data is ready. Download it before %s:\n\n%s\n",
+		u.Name, e.ExpiresOn.Format(time.RFC1123), s.Link(e.ID, e.ExpiresOn),
+	)
+	if err := s.mailer.Send(ctx, u.Email, "Your data export is ready", body); err != nil {
+		log.Error("failed to send export link", sl.Err(err))
+		return
+	}
+
+	log.Info("export sent", slog.Int("size", len(archive)))
+}
+
+// build reads everything kept about the user and returns the archive.
+func (s *Service) build(ctx context.Context, userID string) (data.User, []byte, error) {
+	// The user is exported from all of their workspaces.
+	ctx = storages.AllWorkspaces(ctx)
+
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return data.User{}, nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	tt, err := s.tasks.FindByUserID(ctx, userID, tasks.Filter{All: true})
+	if err != nil {
+		return data.User{}, nil, fmt.Errorf("failed to find tasks: %w", err)
+	}
+
+	var ss []data.Session
+	if s.sessions != nil {
+		if ss, err = s.sessions.List(ctx, userID); err != nil {
+			return data.User{}, nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+	}
+
+	// The outbox keeps the events of the user and of their tasks.
+	ids := make([]string, 0, len(tt)+1)
+	ids = append(ids, userID)
+	for _, t := range tt {
+		ids = append(ids, t.ID)
+	}
+
+	ee, err := s.events.FindByAggregates(ctx, ids)
+	if err != nil {
+		return data.User{}, nil, fmt.Errorf("failed to find events: %w", err)
+	}
+
+	archive, err := newArchive(u, tt, ss, ee)
+	if err != nil {
+		return data.User{}, nil, err
+	}
+
+	return u, archive, nil
+}
+
+// purge deletes the expired exports, at most once per purgeInterval.
+func (s *Service) purge(ctx context.Context) {
+	if time.Since(s.purgedAt) < purgeInterval {
+		return
+	}
+
+	n, err := s.exports.Purge(ctx, time.Now())
+	if err != nil {
+		s.log.Error("failed to purge expired exports", sl.Err(err))
+		return
+	}
+
+	s.purgedAt = time.Now()
+	if n > 0 {
+		s.log.Info("expired exports purged", slog.Int64("count", n))
+	}
+}
+
+// Link returns the signed download link of the export valid until
+// expiresOn.
+func (s *Service) Link(id string, expiresOn time.Time) string {
+	expires := strconv.FormatInt(expiresOn.Unix(), 10)
+
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("signature", s.sign(id, expires))
+
+	return s.baseURL + "/api/exports/" + url.PathEscape(id) + "?" + q.Encode()
+}
+
+// Download returns the ready export of the link with the decrypted
+// archive.
+//
+// If the signature does not match, the link has expired or the export is
+// not ready returns ErrInvalidLink.
+func (s *Service) Download(ctx context.Context, id, expires, signature string) (data.Export, error) {
+	if !hmac.Equal([]byte(signature), []byte(s.sign(id, expires))) {
+		return data.Export{}, ErrInvalidLink
+	}
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !time.Now().Before(time.Unix(unix, 0)) {
+		return data.Export{}, ErrInvalidLink
+	}
+
+	e, err := s.exports.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, exports.ErrNotFound) {
+			return data.Export{}, ErrInvalidLink
+		}
+		return data.Export{}, err
+	}
+
+	if e.Status != data.ExportReady {
+		return data.Export{}, ErrInvalidLink
+	}
+
+	if e.Archive, err = s.open(e.ID, e.Archive); err != nil {
+		return data.Export{}, fmt.Errorf("failed to decrypt export: %w", err)
+	}
+
+	return e, nil
+}
+
+func (s *Service) sign(id, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(id + ":" + expires))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
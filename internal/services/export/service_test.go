@@ -0,0 +1,113 @@
+package export
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages/exports"
+	"github.com/romankravchuk/eldorado/internal/storages/exports/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseLink returns the export ID, the expiry and the signature of the
+// link.
+func parseLink(t *testing.T, link string) (id, expires, signature string) {
+	t.Helper()
+
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+
+	return path.Base(u.Path), u.Query().Get("expires"), u.Query().Get("signature")
+}
+
+func TestDownload(t *testing.T) {
+	storage := mocks.NewStorage(t)
+
+	s := &Service{exports: storage}
+	require.NoError(t, WithLinks("http://localhost:8080/", "secret", time.Hour)(s))
+
+	archive, err := newArchive(data.User{ID: "user", Email: "user@example.com"}, nil, nil, nil)
+	require.NoError(t, err)
+
+	sealed, err := s.seal("ready", archive)
+	require.NoError(t, err)
+
+	storage.On("FindByID", context.Background(), "ready").
+		Return(data.Export{ID: "ready", UserID: "user", Status: data.ExportReady, Archive: sealed}, nil)
+	storage.On("FindByID", context.Background(), "building").
+		Return(data.Export{ID: "building", UserID: "user", Status: data.ExportBuilding}, nil)
+	storage.On("FindByID", context.Background(), "purged").
+		Return(data.Export{}, exports.ErrNotFound)
+
+	expiresOn := time.Now().Add(time.Hour)
+
+	link := s.Link("ready", expiresOn)
+	assert.Contains(t, link, "http://localhost:8080/api/exports/ready?")
+
+	id, expires, signature := parseLink(t, link)
+	assert.Equal(t, "ready", id)
+	assert.Equal(t, strconv.FormatInt(expiresOn.Unix(), 10), expires)
+
+	t.Run("valid link", func(t *testing.T) {
+		e, err := s.Download(context.Background(), id, expires, signature)
+		require.NoError(t, err)
+		assert.Equal(t, "user", e.UserID)
+		assert.Equal(t, archive, e.Archive)
+	})
+
+	past := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		id        string
+		expires   string
+		signature string
+	}{
+		{name: "wrong signature", id: id, expires: expires, signature: s.sign(id, expires+"0")},
+		{name: "empty signature", id: id, expires: expires, signature: ""},
+		{name: "signed with another secret", id: id, expires: expires, signature: signWith("other", id, expires)},
+		{name: "tampered export id", id: "building", expires: expires, signature: signature},
+		{name: "tampered expiry", id: id, expires: strconv.FormatInt(expiresOn.Add(time.Hour).Unix(), 10), signature: signature},
+		{name: "expired", id: id, expires: past, signature: s.sign(id, past)},
+		{name: "unparsable expiry", id: id, expires: "tomorrow", signature: s.sign(id, "tomorrow")},
+		{name: "not ready", id: "building", expires: expires, signature: s.sign("building", expires)},
+		{name: "purged", id: "purged", expires: expires, signature: s.sign("purged", expires)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Download(context.Background(), tt.id, tt.expires, tt.signature)
+			assert.ErrorIs(t, err, ErrInvalidLink)
+		})
+	}
+}
+
+func TestDownloadMalformedArchive(t *testing.T) {
+	storage := mocks.NewStorage(t)
+
+	s := &Service{exports: storage}
+	require.NoError(t, WithLinks("http://localhost:8080", "secret", time.Hour)(s))
+
+	archive, err := newArchive(data.User{ID: "user"}, nil, nil, nil)
+	require.NoError(t, err)
+
+	// A plaintext archive is rejected rather than served.
+	storage.On("FindByID", context.Background(), "plain").
+		Return(data.Export{ID: "plain", Status: data.ExportReady, Archive: archive}, nil)
+
+	_, expires, signature := parseLink(t, s.Link("plain", time.Now().Add(time.Hour)))
+
+	_, err = s.Download(context.Background(), "plain", expires, signature)
+	assert.ErrorIs(t, err, errMalformedArchive)
+}
+
+func signWith(secret, id, expires string) string {
+	s := &Service{secret: []byte(secret)}
+	return s.sign(id, expires)
+}
@@ -0,0 +1,363 @@
+package relay
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/outbox"
+	"github.com/romankravchuk/eldorado/internal/storages/outbox/pg"
+	"github.com/romankravchuk/eldorado/internal/storages/outbox/sqlite"
+)
+
+// purgeInterval is how often the sent events are purged.
+const purgeInterval = time.Hour
+
+// ErrNacked is returned when the broker rejects some of the published events.
+var ErrNacked = errors.New("the broker did not confirm the events")
+
+// Publisher publishes the events to the broker.
+type Publisher interface {
+	// Publish publishes the message with the routing key and returns the
+	// confirmation of the broker, which is waited for after the batch is
+	// published.
+	Publish(ctx context.Context, key string, msg amqp.Publishing) (Confirmation, error)
+}
+
+// Confirmation reports whether the broker took the message, see
+// amqp.DeferredConfirmation.
+type Confirmation interface {
+	WaitContext(ctx context.Context) (bool, error)
+}
+
+// channelPublisher publishes to the exchange of a channel in confirm mode.
+type channelPublisher struct {
+	ch       *amqp.Channel
+	exchange string
+}
+
+func (p channelPublisher) Publish(ctx context.Context, key string, msg amqp.Publishing) (Confirmation, error) {
+	return p.ch.PublishWithDeferredConfirmWithContext(ctx, p.exchange, key, false, false, msg)
+}
+
+type Option func(*Relay) error
+
+// WithStorage sets the outbox and the transactor of its db pool.
+func WithStorage(events outbox.Storage, tx storages.Transactor) Option {
+	return func(r *Relay) error {
+		if events == nil || tx == nil {
+			return errors.New("outbox storage and transactor could not be nil")
+		}
+		r.events = events
+		r.tx = tx
+		return nil
+	}
+}
+
+// WithDBStorage opens the outbox of the driver, storages.DriverPostgres
+// or storages.DriverSQLite. The url of sqlite is the database path.
+func WithDBStorage(driver, url string, poolOpts ...storages.PoolOption) Option {
+	return func(r *Relay) error {
+		var (
+			pool   *sql.DB
+			events outbox.Storage
+			err    error
+		)
+
+		switch driver {
+		case storages.DriverPostgres:
+			if pool, err = storages.NewDBPool(driver, url, poolOpts...); err != nil {
+				return err
+			}
+			events, err = pg.New(pool)
+		case storages.DriverSQLite:
+			if pool, err = storages.NewSQLitePool(url, poolOpts...); err != nil {
+				return err
+			}
+			events, err = sqlite.New(pool)
+		default:
+			return fmt.Errorf("%w: %q", storages.ErrUnknownDriver, driver)
+		}
+		if err != nil {
+			return err
+		}
+
+		tx, err := storages.NewTransactor(pool)
+		if err != nil {
+			return err
+		}
+
+		return WithStorage(events, tx)(r)
+	}
+}
+
+// WithRabbitMQ connects to the broker and declares the durable topic
+// exchange the events are published to. The channel is put in confirm
+// mode, so an event is marked sent only after the broker has it.
+func WithRabbitMQ(url, exchange string) Option {
+	return func(r *Relay) error {
+		conn, err := amqp.Dial(url)
+		if err != nil {
+			return err
+		}
+
+		ch, err := conn.Channel()
+		if err != nil {
+			conn.Close()
+			return err
+		}
+
+		err = ch.ExchangeDeclare(exchange, amqp.ExchangeTopic, true, false, false, false, nil)
+		if err == nil {
+			err = ch.Confirm(false)
+		}
+		if err != nil {
+			conn.Close()
+			return err
+		}
+
+		r.conn = conn
+		return WithPublisher(channelPublisher{ch: ch, exchange: exchange})(r)
+	}
+}
+
+// WithPublisher sets the publisher of the events instead of WithRabbitMQ.
+func WithPublisher(p Publisher) Option {
+	return func(r *Relay) error {
+		if p == nil {
+			return errors.New("publisher could not be nil")
+		}
+		r.publisher = p
+		return nil
+	}
+}
+
+// WithPolling sets how often the outbox is polled and how many events are
+// published in one transaction. Defaults to every second by 100 events.
+func WithPolling(interval time.Duration, batch int) Option {
+	return func(r *Relay) error {
+		if interval <= 0 || batch <= 0 {
+			return errors.New("polling interval and batch size must be positive")
+		}
+		r.interval = interval
+		r.batch = batch
+		return nil
+	}
+}
+
+// WithRetention sets how long the sent events are kept in the outbox.
+// Defaults to 7 days, zero keeps them forever.
+func WithRetention(retention time.Duration) Option {
+	return func(r *Relay) error {
+		if retention < 0 {
+			return errors.New("retention could not be negative")
+		}
+		r.retention = retention
+		return nil
+	}
+}
+
+func WithLogger(log *slog.Logger) Option {
+	return func(r *Relay) error {
+		if log == nil {
+			return errors.New("logger is nil")
+		}
+		r.log = log
+		return nil
+	}
+}
+
+// Relay publishes the events of the outbox to a RabbitMQ exchange with the
+// event type as the routing key.
+//
+// An event is marked sent in the transaction that read it, after the
+// broker confirmed it. If the relay stops in between, the event is
+// published again, so the delivery is at least once and the consumers
+// should drop the duplicates by the event ID, see events.Envelope.
+type Relay struct {
+	events outbox.Storage
+	tx     storages.Transactor
+
+	conn      *amqp.Connection
+	publisher Publisher
+
+	interval  time.Duration
+	batch     int
+	retention time.Duration
+	timeout   time.Duration
+	purgedAt  time.Time
+
+	log *slog.Logger
+}
+
+func New(opts ...Option) (*Relay, error) {
+	r := &Relay{
+		interval:  time.Second,
+		batch:     100,
+		retention: 7 * 24 * time.Hour,
+		timeout:   10 * time.Second,
+		log:       slog.Default(),
+	}
+
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.events == nil {
+		return nil, errors.New("outbox storage is not set")
+	}
+
+	if r.publisher == nil {
+		return nil, errors.New("rabbitmq is not set")
+	}
+
+	return r, nil
+}
+
+// Run relays the events until ctx is done or the broker connection is lost.
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if r.conn != nil && r.conn.IsClosed() {
+			return amqp.ErrClosed
+		}
+
+		r.drain(ctx)
+		r.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close closes the broker connection opened by WithRabbitMQ.
+func (r *Relay) Close() error {
+	if r.conn == nil {
+		return nil
+	}
+	return r.conn.Close()
+}
+
+// drain publishes the batches of the pending events until the outbox is empty.
+func (r *Relay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		sent, err := r.Publish(ctx)
+		if err != nil {
+			r.log.Error("failed to relay events", sl.Err(err))
+			return
+		}
+
+		if sent > 0 {
+			r.log.Debug("events relayed", slog.Int("count", sent))
+		}
+
+		if sent < r.batch {
+			return
+		}
+	}
+}
+
+// Publish publishes one batch of the pending events and returns the number
+// of the events marked sent. The events the broker did not confirm stay
+// pending and ErrNacked is returned.
+func (r *Relay) Publish(ctx context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var sent int
+	var nacked error
+
+	err := r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		events, err := r.events.Pending(ctx, r.batch)
+		if err != nil {
+			return err
+		}
+
+		confirms := make([]Confirmation, 0, len(events))
+		for _, e := range events {
+			dc, err := r.publisher.Publish(ctx, e.Type, message(e))
+			if err != nil {
+				return err
+			}
+			confirms = append(confirms, dc)
+		}
+
+		ids := make([]int64, 0, len(events))
+		for i, dc := range confirms {
+			acked, err := dc.WaitContext(ctx)
+			if err != nil {
+				return err
+			}
+
+			if acked {
+				ids = append(ids, events[i].ID)
+			}
+		}
+
+		if len(ids) < len(events) {
+			nacked = fmt.Errorf("%w: %d of %d", ErrNacked, len(events)-len(ids), len(events))
+		}
+
+		if err = r.events.MarkSent(ctx, ids); err != nil {
+			return err
+		}
+
+		sent = len(ids)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return sent, nacked
+}
+
+// purge deletes the events sent before the retention once in purgeInterval.
+func (r *Relay) purge(ctx context.Context) {
+	if r.retention == 0 || time.Since(r.purgedAt) < purgeInterval {
+		return
+	}
+
+	r.purgedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	purged, err := r.events.Purge(ctx, time.Now().Add(-r.retention))
+	if err != nil {
+		r.log.Error("failed to purge sent events", sl.Err(err))
+		return
+	}
+
+	if purged > 0 {
+		r.log.Debug("sent events purged", slog.Int64("count", purged))
+	}
+}
+
+// message returns the persistent message of the event. The message ID is
+// the outbox ID of the event.
+func message(e data.Event) amqp.Publishing {
+	return amqp.Publishing{
+		MessageId:    strconv.FormatInt(e.ID, 10),
+		Type:         e.Type,
+		Timestamp:    e.CreatedOn,
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Body:         e.Payload,
+	}
+}
@@ -0,0 +1,168 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/outbox/sqlite"
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errBroker = errors.New("broker is unavailable")
+
+// fakePublisher keeps the published messages. The broker confirms the
+// messages unless their routing key is in nack, and fails every publish
+// while fail is set.
+type fakePublisher struct {
+	published []amqp.Publishing
+	keys      []string
+	nack      map[string]bool
+	fail      bool
+}
+
+type confirmation bool
+
+func (c confirmation) WaitContext(context.Context) (bool, error) {
+	return bool(c), nil
+}
+
+func (p *fakePublisher) Publish(_ context.Context, key string, msg amqp.Publishing) (Confirmation, error) {
+	if p.fail {
+		return nil, errBroker
+	}
+
+	p.published = append(p.published, msg)
+	p.keys = append(p.keys, key)
+
+	return confirmation(!p.nack[key]), nil
+}
+
+// newTestRelay returns a relay of a new sqlite outbox with the given
+// events, publishing in batches of two.
+func newTestRelay(t *testing.T, types ...string) (*Relay, *fakePublisher, *sqlite.OutboxStorage, []data.Event) {
+	db := storagetest.SQLite(t)
+
+	outbox, err := sqlite.New(db)
+	require.NoError(t, err)
+
+	tx, err := storages.NewTransactor(db)
+	require.NoError(t, err)
+
+	events := make([]data.Event, len(types))
+	for i, typ := range types {
+		e, err := data.NewEvent(typ, "aggregate", map[string]int{"n": i})
+		require.NoError(t, err)
+		require.NoError(t, outbox.Add(context.Background(), &e))
+		events[i] = e
+	}
+
+	p := &fakePublisher{nack: make(map[string]bool)}
+
+	r, err := New(
+		WithStorage(outbox, tx),
+		WithPublisher(p),
+		WithPolling(time.Second, 2),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	require.NoError(t, err)
+
+	return r, p, outbox, events
+}
+
+func pending(t *testing.T, outbox *sqlite.OutboxStorage) []data.Event {
+	t.Helper()
+
+	events, err := outbox.Pending(context.Background(), 100)
+	require.NoError(t, err)
+	return events
+}
+
+func TestPublish(t *testing.T) {
+	r, p, outbox, events := newTestRelay(t, "task.created", "task.completed", "task.deleted")
+	ctx := context.Background()
+
+	sent, err := r.Publish(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent, "the batch is not limited")
+	assert.Equal(t, []string{"task.created", "task.completed"}, p.keys, "the event type is not the routing key")
+
+	msg := p.published[0]
+	assert.Equal(t, strconv.FormatInt(events[0].ID, 10), msg.MessageId)
+	assert.Equal(t, "task.created", msg.Type)
+	assert.Equal(t, amqp.Persistent, msg.DeliveryMode)
+	assert.JSONEq(t, `{"n":0}`, string(msg.Body))
+
+	left := pending(t, outbox)
+	require.Len(t, left, 1)
+	assert.Equal(t, events[2].ID, left[0].ID)
+
+	sent, err = r.Publish(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Empty(t, pending(t, outbox))
+
+	sent, err = r.Publish(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Len(t, p.published, 3, "a sent event is published again")
+}
+
+func TestPublishFailure(t *testing.T) {
+	r, p, outbox, _ := newTestRelay(t, "task.created", "task.completed")
+	ctx := context.Background()
+
+	p.fail = true
+
+	sent, err := r.Publish(ctx)
+	assert.ErrorIs(t, err, errBroker)
+	assert.Equal(t, 0, sent)
+	assert.Len(t, pending(t, outbox), 2, "the events are marked sent after a failed publish")
+
+	// The events are published on the next attempt.
+	p.fail = false
+
+	sent, err = r.Publish(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	assert.Empty(t, pending(t, outbox))
+}
+
+func TestPublishNacked(t *testing.T) {
+	r, p, outbox, events := newTestRelay(t, "task.created", "task.completed")
+	ctx := context.Background()
+
+	p.nack["task.completed"] = true
+
+	sent, err := r.Publish(ctx)
+	assert.ErrorIs(t, err, ErrNacked)
+	assert.Equal(t, 1, sent)
+
+	left := pending(t, outbox)
+	require.Len(t, left, 1, "the confirmed event is not marked sent")
+	assert.Equal(t, events[1].ID, left[0].ID)
+
+	delete(p.nack, "task.completed")
+
+	sent, err = r.Publish(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Empty(t, pending(t, outbox))
+}
+
+func TestDrain(t *testing.T) {
+	r, p, outbox, _ := newTestRelay(t, "task.created", "task.updated", "task.completed", "task.snoozed", "task.deleted")
+
+	r.drain(context.Background())
+	assert.Len(t, p.published, 5, "the outbox is not drained batch by batch")
+	assert.Empty(t, pending(t, outbox))
+}
@@ -0,0 +1,154 @@
+package settings
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/services"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/settings"
+	"github.com/romankravchuk/eldorado/internal/storages/settings/pg"
+	"github.com/romankravchuk/eldorado/internal/storages/settings/sqlite"
+)
+
+type Option func(*Service) error
+
+func WithSettingsStorage(settings settings.Storage) Option {
+	return func(s *Service) error {
+		if settings == nil {
+			return services.ErrNilSettingsStorage
+		}
+
+		s.settings = settings
+		return nil
+	}
+}
+
+func WithSettingsPostgresStorage(url string, poolOpts ...storages.PoolOption) Option {
+	return func(s *Service) error {
+		conn, err := storages.NewDBPool("postgres", url, poolOpts...)
+		if err != nil {
+			return err
+		}
+
+		return withSettingsPostgresPool(conn)(s)
+	}
+}
+
+func withSettingsPostgresPool(conn *sql.DB) Option {
+	return func(s *Service) error {
+		settings, err := pg.New(conn)
+		if err != nil {
+			return err
+		}
+
+		return WithSettingsStorage(settings)(s)
+	}
+}
+
+// WithSettingsSQLiteStorage opens the settings storage of the sqlite
+// database at path.
+func WithSettingsSQLiteStorage(path string, poolOpts ...storages.PoolOption) Option {
+	return func(s *Service) error {
+		conn, err := storages.NewSQLitePool(path, poolOpts...)
+		if err != nil {
+			return err
+		}
+
+		return withSettingsSQLitePool(conn)(s)
+	}
+}
+
+func withSettingsSQLitePool(conn *sql.DB) Option {
+	return func(s *Service) error {
+		settings, err := sqlite.New(conn)
+		if err != nil {
+			return err
+		}
+
+		return WithSettingsStorage(settings)(s)
+	}
+}
+
+// WithSettingsDBStorage opens the settings storage of the driver,
+// storages.DriverPostgres or storages.DriverSQLite.
+func WithSettingsDBStorage(driver, url string, poolOpts ...storages.PoolOption) Option {
+	switch driver {
+	case storages.DriverPostgres:
+		return WithSettingsPostgresStorage(url, poolOpts...)
+	case storages.DriverSQLite:
+		return WithSettingsSQLiteStorage(url, poolOpts...)
+	}
+
+	return func(*Service) error {
+		return fmt.Errorf("%w: %q", storages.ErrUnknownDriver, driver)
+	}
+}
+
+// WithSettingsDBPool is WithSettingsDBStorage on the pool of the driver
+// opened by the caller, see storages.OpenDB. The pool is not closed by the
+// service.
+func WithSettingsDBPool(driver string, pool *sql.DB) Option {
+	switch {
+	case pool == nil:
+		return func(*Service) error {
+			return storages.ErrNilDBPool
+		}
+	case driver == storages.DriverPostgres:
+		return withSettingsPostgresPool(pool)
+	case driver == storages.DriverSQLite:
+		return withSettingsSQLitePool(pool)
+	}
+
+	return func(*Service) error {
+		return fmt.Errorf("%w: %q", storages.ErrUnknownDriver, driver)
+	}
+}
+
+// WithDefaults sets the settings returned for users that have not saved their own.
+func WithDefaults(defaults data.Settings) Option {
+	return func(s *Service) error {
+		s.defaults = defaults
+		return nil
+	}
+}
+
+type Service struct {
+	settings settings.Storage
+	defaults data.Settings
+}
+
+func New(opts ...Option) (*Service, error) {
+	s := &Service{}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Get returns the settings of the user, falling back to the defaults.
+func (s *Service) Get(ctx context.Context, userID string) (data.Settings, error) {
+	st, err := s.settings.Find(ctx, userID)
+	if errors.Is(err, settings.ErrNotFound) {
+		st = s.defaults
+		st.UserID = userID
+		return st, nil
+	}
+
+	return st, err
+}
+
+func (s *Service) Update(ctx context.Context, userID string, st data.Settings) (data.Settings, error) {
+	st.UserID = userID
+
+	if err := s.settings.Save(ctx, &st); err != nil {
+		return data.Settings{}, err
+	}
+
+	return st, nil
+}
@@ -12,6 +12,7 @@ import (
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/robfig/cron/v3"
+	"github.com/romankravchuk/eldorado/internal/pkg/envelope"
 	"github.com/romankravchuk/eldorado/internal/services"
 	"github.com/romankravchuk/eldorado/internal/storages"
 	"github.com/romankravchuk/eldorado/internal/storages/tasks"
@@ -29,7 +30,7 @@ func init() {
 	var err error
 	tmpl, err = template.ParseFiles("/email.html")
 	if err != nil {
-		slog.Error("failed to parse template", err)
+		slog.Error("failed to parse template", slog.String("error", err.Error()))
 		os.Exit(1)
 	}
 }
@@ -80,14 +81,17 @@ func WithTasksStorage(tasks tasks.Storage) Option {
 	}
 }
 
-func WithPosgresTasksStorage(url string) Option {
+// WithPosgresTasksStorage opens the tasks storage of the postgres database
+// at url. The statistic is read from the replicas, if they are not nil.
+// The titles are decrypted with the keyring.
+func WithPosgresTasksStorage(url string, replicas *storages.Replicas, keyring *envelope.Keyring, poolOpts ...storages.PoolOption) Option {
 	return func(s *Service) error {
-		db, err := storages.NewDBPool("postgres", url)
+		db, err := storages.NewDBPool("postgres", url, poolOpts...)
 		if err != nil {
 			return err
 		}
 
-		tasks, err := pg.New(db)
+		tasks, err := pg.New(db, pg.WithReplicas(replicas), pg.WithKeyring(keyring))
 		if err != nil {
 			return err
 		}
@@ -148,7 +152,8 @@ func (s *Service) Start() error {
 }
 
 func (s *Service) sendStatistic(ctx context.Context) error {
-	tasks, err := s.tasks.UncompletedStatistic(ctx)
+	// The statistic is sent to every user of every workspace.
+	tasks, err := s.tasks.UncompletedStatistic(storages.AllWorkspaces(ctx))
 	if err != nil {
 		return err
 	}
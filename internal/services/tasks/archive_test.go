@@ -0,0 +1,82 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// archivingStorage archives the tasks of owners by deleting them from the
+// storage behind the cache of the service.
+type archivingStorage struct {
+	tasks.Storage
+	owners []tasks.Owner
+	ids    []string
+	err    error
+
+	scoped bool
+	days   int
+}
+
+func (s *archivingStorage) Archive(ctx context.Context, defaultDays int) ([]tasks.Owner, error) {
+	_, s.scoped = storages.WorkspaceID(ctx)
+	s.days = defaultDays
+
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	for _, id := range s.ids {
+		if err := s.Storage.Delete(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.owners, nil
+}
+
+func TestArchive(t *testing.T) {
+	s, ctx, userID := newTestService(t, 3)
+
+	before, err := s.List(ctx, userID, true)
+	require.NoError(t, err)
+	require.Len(t, before, 3)
+
+	workspaceID, _ := storages.WorkspaceID(ctx)
+	storage := &archivingStorage{
+		Storage: s.tasks,
+		owners:  []tasks.Owner{{WorkspaceID: workspaceID, UserID: userID}, {WorkspaceID: uuid.NewString(), UserID: uuid.NewString()}},
+		ids:     []string{before[0].ID},
+	}
+	s.tasks = storage
+
+	users, err := s.Archive(ctx, 30)
+	require.NoError(t, err)
+	assert.Equal(t, 2, users)
+	assert.Equal(t, 30, storage.days)
+	assert.False(t, storage.scoped, "the tasks are archived in the workspace of the context only")
+
+	after, err := s.List(ctx, userID, true)
+	require.NoError(t, err)
+	assert.Len(t, after, 2, "the cached list of the owner is not invalidated")
+}
+
+func TestArchiveFailure(t *testing.T) {
+	s, ctx, userID := newTestService(t, 2)
+
+	_, err := s.List(ctx, userID, true)
+	require.NoError(t, err)
+
+	errFailed := errors.New("failed")
+	s.tasks = &archivingStorage{Storage: s.tasks, err: errFailed}
+
+	users, err := s.Archive(ctx, 30)
+	assert.ErrorIs(t, err, errFailed)
+	assert.Equal(t, 0, users)
+}
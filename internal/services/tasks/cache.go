@@ -0,0 +1,343 @@
+package tasks
+
+import (
+	"context"
+	"expvar"
+	"hash/fnv"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/cache/codec"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+)
+
+// maxPendingInvalidations bounds the invalidations kept for retry while
+// the cache is unavailable. Invalidations over the limit are dropped and
+// the cached lists expire by their TTL.
+const maxPendingInvalidations = 10000
+
+// refreshTimeout limits the loads shared by several requests and the
+// background refreshes, which are not bound to a single request.
+const refreshTimeout = 5 * time.Second
+
+// cacheStats counts the cache degradations, see /debug/vars.
+var cacheStats = expvar.NewMap("tasks_cache")
+
+// generationStripes is the number of the generation counters of the lists.
+const generationStripes = 1024
+
+// generations are bumped by the invalidations of the lists. A load caches
+// the list only if the generation of the list has not changed since it
+// started, so a load that read the list before a write does not cache it
+// after the write has dropped it. The lists share the counters by the hash
+// of their names, so the memory is bounded, and a collision only skips a
+// cache write.
+type generations [generationStripes]atomic.Uint64
+
+func (g *generations) of(list string) *atomic.Uint64 {
+	h := fnv.New32a()
+	h.Write([]byte(list))
+	return &g[h.Sum32()%generationStripes]
+}
+
+// listOf returns the task list of the user in the workspace of ctx,
+// workspace_id:user_id. The lists are cached, coalesced and invalidated
+// by it, since a user has a list in every workspace they are a member of.
+func listOf(ctx context.Context, userID string) string {
+	if workspaceID, ok := storages.WorkspaceID(ctx); ok {
+		return workspaceID + ":" + userID
+	}
+	return userID
+}
+
+func cacheKey(list string) string {
+	return "tasks:" + list
+}
+
+// cachedList returns the cached tasks of the user and whether they are stale.
+//
+// The cache is best-effort: errors are logged and reported as a miss.
+// Lists with a pending invalidation always miss, so a list that could not
+// be invalidated is never served.
+func (s *Service) cachedList(ctx context.Context, userID string) (tasks []data.Task, stale, found bool) {
+	list := listOf(ctx, userID)
+	if s.isPending(list) {
+		return nil, false, false
+	}
+
+	b, found, err := s.cache.Get(ctx, cacheKey(list))
+	if err != nil {
+		cacheStats.Add("get_errors", 1)
+		s.log.Warn("failed to read tasks from cache", sl.Err(err), slog.String("user_id", userID))
+		return nil, false, false
+	}
+	if !found {
+		return nil, false, false
+	}
+
+	entry, err := s.codec.Unmarshal(b)
+	if err != nil {
+		cacheStats.Add("decode_errors", 1)
+		s.log.Warn("failed to decode cached tasks", sl.Err(err), slog.String("user_id", userID))
+		return nil, false, false
+	}
+
+	// The list is cached for cacheTTL from the time it was read.
+	if reset := s.resetAt.Load(); reset != 0 && entry.FreshUntil.Add(-s.cacheTTL).UnixNano() < reset {
+		cacheStats.Add("reset_misses", 1)
+		return nil, false, false
+	}
+
+	if entry.Tasks == nil {
+		entry.Tasks = make([]data.Task, 0)
+	}
+
+	return entry.Tasks, time.Now().After(entry.FreshUntil), true
+}
+
+// load reads the tasks of the user from the storage and caches them.
+// Concurrent loads of the same list share one query if coalescing is on.
+func (s *Service) load(ctx context.Context, userID string) ([]data.Task, error) {
+	if !s.coalesce {
+		return s.fetch(ctx, userID)
+	}
+
+	ch := s.group.DoChan(listOf(ctx, userID), func() (any, error) {
+		// The query is shared, so it must not be canceled with the request
+		// that happened to start it.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), refreshTimeout)
+		defer cancel()
+
+		return s.fetch(ctx, userID)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]data.Task), nil
+	}
+}
+
+// revalidate refreshes the cached tasks of the user in background.
+// Only one refresh per list runs at a time.
+func (s *Service) revalidate(ctx context.Context, userID string) {
+	cacheStats.Add("stale_hits", 1)
+
+	s.group.DoChan(listOf(ctx, userID), func() (any, error) {
+		// The refresh outlives the request, but keeps its workspace.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), refreshTimeout)
+		defer cancel()
+
+		tasks, err := s.fetch(ctx, userID)
+		if err != nil {
+			s.log.Error("failed to refresh cached tasks", sl.Err(err), slog.String("user_id", userID))
+		}
+		return tasks, err
+	})
+}
+
+func (s *Service) fetch(ctx context.Context, userID string) ([]data.Task, error) {
+	gen := s.generations.of(listOf(ctx, userID)).Load()
+
+	tasks, err := s.tasks.FindByUserID(ctx, userID, tasks.Filter{IncludeSnoozed: true})
+	if err != nil {
+		return nil, err
+	}
+
+	s.cacheList(ctx, userID, tasks, gen)
+
+	return tasks, nil
+}
+
+// cacheList stores the tasks of the user read at the generation gen of the
+// list. If the list was invalidated since, the tasks are stale and are not
+// stored. Errors are logged and counted.
+func (s *Service) cacheList(ctx context.Context, userID string, tasks []data.Task, gen uint64) {
+	list := listOf(ctx, userID)
+	if s.isPending(list) {
+		return
+	}
+
+	b, err := s.codec.Marshal(codec.TaskList{
+		FreshUntil: time.Now().Add(s.cacheTTL),
+		Tasks:      tasks,
+	})
+	if err != nil {
+		cacheStats.Add("encode_errors", 1)
+		s.log.Warn("failed to encode tasks", sl.Err(err), slog.String("user_id", userID))
+		return
+	}
+
+	// Checked last, to narrow the window left for an invalidation to come
+	// between the check and the write.
+	if s.generations.of(list).Load() != gen {
+		cacheStats.Add("stale_sets", 1)
+		return
+	}
+
+	if err := s.cache.Set(ctx, cacheKey(list), b, s.cacheTTL+s.staleTTL); err != nil {
+		cacheStats.Add("set_errors", 1)
+		s.log.Warn("failed to write tasks to cache", sl.Err(err), slog.String("user_id", userID))
+	}
+}
+
+// invalidate drops the cached list of the user in the workspace of ctx.
+// If the cache is unavailable the invalidation is queued and retried in
+// background.
+func (s *Service) invalidate(ctx context.Context, userID string) {
+	list := listOf(ctx, userID)
+
+	// Requests coming after the write must not join a load started before
+	// it, and the loads in flight must not cache what they have read.
+	s.group.Forget(list)
+	s.generations.of(list).Add(1)
+
+	if err := s.cache.Del(ctx, cacheKey(list)); err != nil {
+		cacheStats.Add("del_errors", 1)
+		s.log.Warn("failed to invalidate cached tasks, queued for retry", sl.Err(err), slog.String("list", list))
+		s.addPending(list)
+	}
+}
+
+// CacheEntry describes the cached task list of a user.
+type CacheEntry struct {
+	Key   string
+	Found bool
+	// Size is the size of the cached payload in bytes.
+	Size       int
+	FreshUntil time.Time
+	Tasks      int
+	// PendingInvalidation is true if the entry failed to be invalidated
+	// and is not served until the retry succeeds.
+	PendingInvalidation bool
+}
+
+// InspectCache returns the cached task list entry of the user in the
+// workspace of ctx.
+func (s *Service) InspectCache(ctx context.Context, userID string) (CacheEntry, error) {
+	list := listOf(ctx, userID)
+
+	e := CacheEntry{
+		Key:                 cacheKey(list),
+		PendingInvalidation: s.isPending(list),
+	}
+
+	b, found, err := s.cache.Get(ctx, e.Key)
+	if err != nil || !found {
+		return e, err
+	}
+
+	e.Found = true
+	e.Size = len(b)
+
+	entry, err := s.codec.Unmarshal(b)
+	if err != nil {
+		return e, err
+	}
+
+	e.FreshUntil = entry.FreshUntil
+	e.Tasks = len(entry.Tasks)
+
+	return e, nil
+}
+
+// FlushCache drops the cached task list of the user in the workspace of
+// ctx. Unlike the invalidations on writes, cache errors are returned.
+func (s *Service) FlushCache(ctx context.Context, userID string) error {
+	list := listOf(ctx, userID)
+
+	s.group.Forget(list)
+	s.generations.of(list).Add(1)
+
+	return s.cache.Del(ctx, cacheKey(list))
+}
+
+// ResetCache drops all the cached task lists: the lists cached before the
+// call miss until they are cached again, and the loads in flight do not
+// cache what they have read. It is meant for the invalidations lost while
+// pg.Listen was disconnected, which could be of any list.
+func (s *Service) ResetCache() {
+	cacheStats.Add("resets", 1)
+
+	for i := range s.generations {
+		s.generations[i].Add(1)
+	}
+	s.resetAt.Store(time.Now().UnixNano())
+}
+
+// Invalidate drops the cached list of the user in the workspace of ctx.
+// It is meant for the changes made past the service, see pg.Listen. The
+// notifications do not tell who made the change, so the writes of the
+// service are invalidated by it once more and counted in
+// notified_invalidations as well.
+func (s *Service) Invalidate(ctx context.Context, userID string) {
+	cacheStats.Add("notified_invalidations", 1)
+	s.invalidate(ctx, userID)
+}
+
+func (s *Service) isPending(list string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.pending[list]
+	return ok
+}
+
+func (s *Service) addPending(list string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) >= maxPendingInvalidations {
+		cacheStats.Add("dropped_invalidations", 1)
+		return
+	}
+
+	s.pending[list] = struct{}{}
+	cacheStats.Add("pending_invalidations", 1)
+}
+
+// retryInvalidations retries the pending invalidations every interval
+// until Close is called.
+func (s *Service) retryInvalidations(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		lists := make([]string, 0, len(s.pending))
+		for list := range s.pending {
+			lists = append(lists, list)
+		}
+		s.mu.Unlock()
+
+		for _, list := range lists {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			err := s.cache.Del(ctx, cacheKey(list))
+			cancel()
+
+			if err != nil {
+				// The cache is still down, try the rest on the next tick.
+				break
+			}
+
+			s.mu.Lock()
+			delete(s.pending, list)
+			s.mu.Unlock()
+			cacheStats.Add("pending_invalidations", -1)
+		}
+	}
+}
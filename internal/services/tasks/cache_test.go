@@ -0,0 +1,227 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/cache/codec"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestService returns a service over the in-memory storage and cache
+// with n tasks of a user and the context of their workspace.
+func newTestService(tb testing.TB, n int, opts ...Option) (*Service, context.Context, string) {
+	ctx := storages.WithWorkspace(context.Background(), uuid.NewString())
+	userID := uuid.NewString()
+
+	storage := memory.New()
+	for i := 0; i < n; i++ {
+		t := data.Task{
+			UserID:      userID,
+			Title:       "Task number " + strconv.Itoa(i),
+			Description: "Some description of the task that is a bit longer than the title",
+			Tags:        []string{"work"},
+			Priority:    data.Priority(i % 4),
+			DueOn:       time.Now().Add(time.Duration(i) * time.Hour),
+		}
+		require.NoError(tb, storage.Save(ctx, &t))
+	}
+
+	opts = append([]Option{
+		WithTaskStorage(storage),
+		WithMemoryCache(0, time.Hour),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	}, opts...)
+
+	s, err := New(opts...)
+	require.NoError(tb, err)
+	tb.Cleanup(s.Close)
+
+	return s, ctx, userID
+}
+
+// blockingStorage holds FindByUserID until release is closed, once read
+// is signalled.
+type blockingStorage struct {
+	tasks.Storage
+	read    chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStorage) FindByUserID(ctx context.Context, userID string, f tasks.Filter) ([]data.Task, error) {
+	list, err := s.Storage.FindByUserID(ctx, userID, f)
+	close(s.read)
+	<-s.release
+	return list, err
+}
+
+func TestInvalidateDuringLoad(t *testing.T) {
+	s, ctx, userID := newTestService(t, 1)
+
+	storage := &blockingStorage{Storage: s.tasks, read: make(chan struct{}), release: make(chan struct{})}
+	s.tasks = storage
+
+	loaded := make(chan error)
+	go func() {
+		_, err := s.List(ctx, userID, true)
+		loaded <- err
+	}()
+
+	// The write lands after the load has read the list and before it is
+	// cached.
+	<-storage.read
+	s.tasks = storage.Storage
+	_, err := s.Create(ctx, userID, data.Task{Title: "Written during the load"})
+	require.NoError(t, err)
+
+	close(storage.release)
+	require.NoError(t, <-loaded)
+
+	_, found, err := s.cache.Get(ctx, cacheKey(listOf(ctx, userID)))
+	require.NoError(t, err)
+	assert.False(t, found, "the list read before the write is cached")
+
+	list, err := s.List(ctx, userID, true)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+}
+
+func TestListFarDatesCached(t *testing.T) {
+	hiddenUntil := time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+	dueOn := time.Date(2524, 5, 15, 9, 30, 0, 0, time.UTC)
+
+	for _, f := range []codec.Format{codec.FormatJSON, codec.FormatBinary} {
+		t.Run(strconv.Itoa(int(f)), func(t *testing.T) {
+			c, err := codec.New(f, false)
+			require.NoError(t, err)
+
+			s, ctx, userID := newTestService(t, 2, WithCodec(c))
+
+			list, err := s.List(ctx, userID, true)
+			require.NoError(t, err)
+			require.NoError(t, s.Snooze(ctx, userID, list[0].ID, hiddenUntil))
+
+			created, err := s.Create(ctx, userID, data.Task{Title: "In 500 years", DueOn: dueOn})
+			require.NoError(t, err)
+
+			// The first list is loaded and cached, the second is decoded.
+			for i := 0; i < 2; i++ {
+				visible, err := s.List(ctx, userID, false)
+				require.NoError(t, err)
+				require.Len(t, visible, 2, "the snoozed task is listed")
+
+				for _, task := range visible {
+					assert.NotEqual(t, list[0].ID, task.ID, "the snoozed task is listed")
+					if task.ID == created.ID {
+						assert.True(t, dueOn.Equal(task.DueOn), "got due %v, want %v", task.DueOn, dueOn)
+					}
+				}
+			}
+
+			_, found, err := s.cache.Get(ctx, cacheKey(listOf(ctx, userID)))
+			require.NoError(t, err)
+			assert.True(t, found, "the list is not cached")
+		})
+	}
+}
+
+// BenchmarkListCached measures a cache hit of List, the decoding of the
+// cached list dominates it.
+func BenchmarkListCached(b *testing.B) {
+	formats := []struct {
+		name     string
+		format   codec.Format
+		compress bool
+	}{
+		{"json", codec.FormatJSON, false},
+		{"json+deflate", codec.FormatJSON, true},
+		{"binary", codec.FormatBinary, false},
+		{"binary+deflate", codec.FormatBinary, true},
+	}
+
+	for _, n := range []int{100, 1000} {
+		for _, f := range formats {
+			b.Run(fmt.Sprintf("%s/%d", f.name, n), func(b *testing.B) {
+				c, err := codec.New(f.format, f.compress)
+				require.NoError(b, err)
+
+				s, ctx, userID := newTestService(b, n, WithCodec(c))
+
+				// Warm the cache.
+				_, err = s.List(ctx, userID, true)
+				require.NoError(b, err)
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := s.List(ctx, userID, true); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestResetCache(t *testing.T) {
+	s, ctx, userID := newTestService(t, 1)
+
+	_, err := s.List(ctx, userID, true)
+	require.NoError(t, err)
+
+	// A change made past the service, whose notification is lost.
+	require.NoError(t, s.tasks.Save(ctx, &data.Task{UserID: userID, Title: "Written by another replica"}))
+
+	list, err := s.List(ctx, userID, true)
+	require.NoError(t, err)
+	assert.Len(t, list, 1, "the list is not served from the cache")
+
+	s.ResetCache()
+
+	list, err = s.List(ctx, userID, true)
+	require.NoError(t, err)
+	assert.Len(t, list, 2, "the list cached before the reset is served")
+
+	// The list read after the reset is cached again.
+	_, found, err := s.cache.Get(ctx, cacheKey(listOf(ctx, userID)))
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	list, err = s.List(ctx, userID, true)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+}
+
+func TestResetCacheDuringLoad(t *testing.T) {
+	s, ctx, userID := newTestService(t, 1)
+
+	storage := &blockingStorage{Storage: s.tasks, read: make(chan struct{}), release: make(chan struct{})}
+	s.tasks = storage
+
+	loaded := make(chan error)
+	go func() {
+		_, err := s.List(ctx, userID, true)
+		loaded <- err
+	}()
+
+	<-storage.read
+	s.ResetCache()
+
+	close(storage.release)
+	require.NoError(t, <-loaded)
+
+	_, found, err := s.cache.Get(ctx, cacheKey(listOf(ctx, userID)))
+	require.NoError(t, err)
+	assert.False(t, found, "the list read before the reset is cached")
+}
@@ -2,18 +2,44 @@ package tasks
 
 import (
 	"context"
-	"encoding/json"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/envelope"
+	"github.com/romankravchuk/eldorado/internal/pkg/events"
+	"github.com/romankravchuk/eldorado/internal/pkg/sl"
 	"github.com/romankravchuk/eldorado/internal/server/http/api"
 	"github.com/romankravchuk/eldorado/internal/storages"
 	"github.com/romankravchuk/eldorado/internal/storages/cache"
+	"github.com/romankravchuk/eldorado/internal/storages/cache/codec"
+	"github.com/romankravchuk/eldorado/internal/storages/cache/instrumented"
+	"github.com/romankravchuk/eldorado/internal/storages/cache/memory"
 	"github.com/romankravchuk/eldorado/internal/storages/cache/redis"
+	"github.com/romankravchuk/eldorado/internal/storages/cache/tiered"
+	"github.com/romankravchuk/eldorado/internal/storages/dependencies"
+	deppg "github.com/romankravchuk/eldorado/internal/storages/dependencies/pg"
+	depsqlite "github.com/romankravchuk/eldorado/internal/storages/dependencies/sqlite"
+	"github.com/romankravchuk/eldorado/internal/storages/outbox"
+	outboxpg "github.com/romankravchuk/eldorado/internal/storages/outbox/pg"
+	outboxsqlite "github.com/romankravchuk/eldorado/internal/storages/outbox/sqlite"
+	"github.com/romankravchuk/eldorado/internal/storages/resilience"
 	"github.com/romankravchuk/eldorado/internal/storages/tasks"
 	"github.com/romankravchuk/eldorado/internal/storages/tasks/pg"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks/resilient"
+	tasksqlite "github.com/romankravchuk/eldorado/internal/storages/tasks/sqlite"
+	"golang.org/x/sync/singleflight"
 )
 
+// ErrBlocked is returned when a task is completed while some of its blockers are open.
+var ErrBlocked = errors.New("the task is blocked by uncompleted tasks")
+
 type Option func(*Service) error
 
 func WithTaskStorage(tasks tasks.Storage) Option {
@@ -23,33 +49,308 @@ func WithTaskStorage(tasks tasks.Storage) Option {
 	}
 }
 
-func WithTaskPostgresStorage(url string) Option {
+// WithTaskPostgresStorage opens the tasks, dependencies and outbox storages
+// of the postgres database at url. The task lists are read from the
+// replicas, if they are not nil. The task contents are encrypted with the
+// keyring, nil stores them in plaintext, see the WithKeyring option of the
+// tasks storage.
+func WithTaskPostgresStorage(url string, replicas *storages.Replicas, keyring *envelope.Keyring, poolOpts ...storages.PoolOption) Option {
+	return func(s *Service) error {
+		conn, err := storages.NewDBPool("postgres", url, poolOpts...)
+		if err != nil {
+			return err
+		}
+
+		return withTaskPostgresPool(conn, replicas, keyring)(s)
+	}
+}
+
+func withTaskPostgresPool(conn *sql.DB, replicas *storages.Replicas, keyring *envelope.Keyring) Option {
 	return func(s *Service) error {
-		conn, err := storages.NewDBPool("postgres", url)
+		tasks, err := pg.New(conn, pg.WithReplicas(replicas), pg.WithKeyring(keyring))
 		if err != nil {
 			return err
 		}
 
-		tasks, err := pg.New(conn)
+		deps, err := deppg.New(conn, deppg.WithReplicas(replicas), deppg.WithKeyring(keyring))
 		if err != nil {
 			return err
 		}
 
+		events, err := outboxpg.New(conn)
+		if err != nil {
+			return err
+		}
+
+		tx, err := storages.NewTransactor(conn)
+		if err != nil {
+			return err
+		}
+
+		if err = WithDependenciesStorage(deps)(s); err != nil {
+			return err
+		}
+
+		if err = WithOutbox(events)(s); err != nil {
+			return err
+		}
+
+		if err = WithTransactor(tx)(s); err != nil {
+			return err
+		}
+
 		return WithTaskStorage(tasks)(s)
 	}
 }
 
+// WithTaskSQLiteStorage opens the tasks, dependencies and outbox storages
+// of the sqlite database at path.
+func WithTaskSQLiteStorage(path string, poolOpts ...storages.PoolOption) Option {
+	return func(s *Service) error {
+		conn, err := storages.NewSQLitePool(path, poolOpts...)
+		if err != nil {
+			return err
+		}
+
+		return withTaskSQLitePool(conn)(s)
+	}
+}
+
+func withTaskSQLitePool(conn *sql.DB) Option {
+	return func(s *Service) error {
+		tasks, err := tasksqlite.New(conn)
+		if err != nil {
+			return err
+		}
+
+		deps, err := depsqlite.New(conn)
+		if err != nil {
+			return err
+		}
+
+		events, err := outboxsqlite.New(conn)
+		if err != nil {
+			return err
+		}
+
+		tx, err := storages.NewTransactor(conn)
+		if err != nil {
+			return err
+		}
+
+		if err = WithDependenciesStorage(deps)(s); err != nil {
+			return err
+		}
+
+		if err = WithOutbox(events)(s); err != nil {
+			return err
+		}
+
+		if err = WithTransactor(tx)(s); err != nil {
+			return err
+		}
+
+		return WithTaskStorage(tasks)(s)
+	}
+}
+
+// WithTaskDBStorage opens the storages of the driver, storages.DriverPostgres
+// or storages.DriverSQLite. The url of sqlite is the database path.
+// Only postgres supports the read replicas and the encryption, the keyring
+// is ignored by sqlite.
+func WithTaskDBStorage(driver, url string, replicas *storages.Replicas, keyring *envelope.Keyring, poolOpts ...storages.PoolOption) Option {
+	switch {
+	case driver == storages.DriverPostgres:
+		return WithTaskPostgresStorage(url, replicas, keyring, poolOpts...)
+	case replicas != nil:
+		return func(*Service) error {
+			return storages.ErrReplicasUnsupported
+		}
+	case driver == storages.DriverSQLite:
+		return WithTaskSQLiteStorage(url, poolOpts...)
+	}
+
+	return func(*Service) error {
+		return fmt.Errorf("%w: %q", storages.ErrUnknownDriver, driver)
+	}
+}
+
+// WithTaskDBPool is WithTaskDBStorage on the pool of the driver opened by
+// the caller, see storages.OpenDB, so the services of a process can share
+// one pool. The pool is not closed by the service.
+func WithTaskDBPool(driver string, pool *sql.DB, replicas *storages.Replicas, keyring *envelope.Keyring) Option {
+	switch {
+	case pool == nil:
+		return func(*Service) error {
+			return storages.ErrNilDBPool
+		}
+	case driver == storages.DriverPostgres:
+		return withTaskPostgresPool(pool, replicas, keyring)
+	case replicas != nil:
+		return func(*Service) error {
+			return storages.ErrReplicasUnsupported
+		}
+	case driver == storages.DriverSQLite:
+		return withTaskSQLitePool(pool)
+	}
+
+	return func(*Service) error {
+		return fmt.Errorf("%w: %q", storages.ErrUnknownDriver, driver)
+	}
+}
+
+func WithDependenciesStorage(deps dependencies.Storage) Option {
+	return func(s *Service) error {
+		s.deps = deps
+		return nil
+	}
+}
+
+// WithOutbox sets the storage the domain events are recorded in, see
+// WithEvents. It must share the transactions of the tasks storage, so the
+// storage options of a db pool set it themselves.
+func WithOutbox(events outbox.Storage) Option {
+	return func(s *Service) error {
+		s.outbox = events
+		return nil
+	}
+}
+
+// WithEvents makes the service record the task events in the outbox, in
+// the transaction of the change, see the events package. The relay
+// publishes them. Without an outbox nothing is recorded.
+func WithEvents(enabled bool) Option {
+	return func(s *Service) error {
+		s.events = enabled
+		return nil
+	}
+}
+
+// WithEventContents keeps the title and the description of the tasks in
+// the task events. They are left out by default, so the outbox and the
+// broker do not keep a plaintext copy of the contents encrypted in the
+// tasks table.
+func WithEventContents(enabled bool) Option {
+	return func(s *Service) error {
+		s.contents = enabled
+		return nil
+	}
+}
+
+// WithResilience retries the reads of the tasks storage failed on
+// transient errors and fails its calls fast while the database is down,
+// see resilience.Config. It applies to the storage of any option.
+func WithResilience(cfg resilience.Config) Option {
+	return func(s *Service) error {
+		s.resilience = &cfg
+		return nil
+	}
+}
+
+// WithTransactor sets the unit of work of the tasks and dependencies
+// storages. The storage options of a db pool set it themselves.
+// Without it the storage calls run one by one.
+func WithTransactor(tx storages.Transactor) Option {
+	return func(s *Service) error {
+		if tx == nil {
+			return errors.New("transactor is nil")
+		}
+		s.tx = tx
+		return nil
+	}
+}
+
+// WithCache sets the cache of the task lists. Cache operations are
+// recorded per key prefix, see instrumented.Cache.
 func WithCache(cache cache.Cache, ttl time.Duration) Option {
 	return func(s *Service) error {
-		s.cache = cache
+		s.cache = instrumented.New(cache)
 		s.cacheTTL = ttl
 		return nil
 	}
 }
 
-func WithRedisCache(url string, ttl time.Duration) Option {
+// WithMemoryCache caches the task lists in the process memory only.
+// It is meant for development without Redis and for a single replica.
+func WithMemoryCache(size int, ttl time.Duration) Option {
+	return WithCache(memory.New(size), ttl)
+}
+
+// WithTieredCache caches the task lists in the process memory in front of
+// Redis. Local copies are dropped on every replica through the Redis
+// pub/sub channel when a list is invalidated.
+func WithTieredCache(url, channel string, localSize int, localTTL, ttl time.Duration, redisOpts ...storages.RedisOption) Option {
 	return func(s *Service) error {
-		client, err := storages.NewRedisClient(url)
+		client, err := storages.NewRedisClient(url, redisOpts...)
+		if err != nil {
+			return err
+		}
+
+		c := tiered.New(memory.New(localSize), redis.New(client),
+			tiered.WithLocalTTL(localTTL),
+			tiered.WithBroker(redis.NewBroker(client, channel)),
+		)
+
+		return WithCache(c, ttl)(s)
+	}
+}
+
+// WithCodec sets the encoding of the cached task lists.
+// Defaults to the uncompressed binary format.
+func WithCodec(c codec.Codec) Option {
+	return func(s *Service) error {
+		if c == nil {
+			return errors.New("codec is nil")
+		}
+		s.codec = c
+		return nil
+	}
+}
+
+// WithCoalescing makes concurrent cache misses of the same user share
+// one storage query.
+func WithCoalescing(enabled bool) Option {
+	return func(s *Service) error {
+		s.coalesce = enabled
+		return nil
+	}
+}
+
+// WithStaleWhileRevalidate keeps cached lists for staleTTL after they
+// expire. An expired list is served while it is refreshed in background.
+// Zero staleTTL disables the mode.
+func WithStaleWhileRevalidate(staleTTL time.Duration) Option {
+	return func(s *Service) error {
+		if staleTTL < 0 {
+			return errors.New("stale ttl could not be negative")
+		}
+		s.staleTTL = staleTTL
+		return nil
+	}
+}
+
+// WithInvalidationRetry sets how often the invalidations that failed while
+// the cache was unavailable are retried. Defaults to 5 seconds.
+func WithInvalidationRetry(interval time.Duration) Option {
+	return func(s *Service) error {
+		s.retryInterval = interval
+		return nil
+	}
+}
+
+func WithLogger(log *slog.Logger) Option {
+	return func(s *Service) error {
+		if log == nil {
+			return errors.New("logger is nil")
+		}
+		s.log = log
+		return nil
+	}
+}
+
+func WithRedisCache(url string, ttl time.Duration, redisOpts ...storages.RedisOption) Option {
+	return func(s *Service) error {
+		client, err := storages.NewRedisClient(url, redisOpts...)
 		if err != nil {
 			return err
 		}
@@ -60,89 +361,362 @@ func WithRedisCache(url string, ttl time.Duration) Option {
 
 type Service struct {
 	tasks tasks.Storage
+	deps  dependencies.Storage
+	tx    storages.Transactor
+
+	outbox   outbox.Storage
+	events   bool
+	contents bool
+
+	resilience *resilience.Config
 
 	cache    cache.Cache
 	cacheTTL time.Duration
+	codec    codec.Codec
+	staleTTL time.Duration
+	coalesce bool
+	group    singleflight.Group
+
+	generations generations
+	// resetAt is when the cache was reset, in unix nanoseconds. The lists
+	// cached before miss, see ResetCache.
+	resetAt atomic.Int64
+
+	log *slog.Logger
+
+	mu            sync.Mutex
+	pending       map[string]struct{}
+	retryInterval time.Duration
+	done          chan struct{}
 }
 
 func New(opts ...Option) (*Service, error) {
-	s := &Service{}
+	binary, _ := codec.New(codec.FormatBinary, false)
+
+	s := &Service{
+		tx:            storages.NopTransactor{},
+		codec:         binary,
+		log:           slog.Default(),
+		pending:       make(map[string]struct{}),
+		retryInterval: 5 * time.Second,
+		done:          make(chan struct{}),
+	}
 	for _, opt := range opts {
 		if err := opt(s); err != nil {
 			return nil, err
 		}
 	}
+
+	if s.resilience != nil && s.tasks != nil {
+		s.tasks = resilient.New(s.tasks, resilience.New("tasks", *s.resilience))
+	}
+
+	go s.retryInvalidations(s.retryInterval)
+
 	return s, nil
 }
 
-func (s *Service) List(ctx context.Context, userID string) ([]data.Task, error) {
-	cache, found, err := s.cache.Get(ctx, userID)
+// Close stops retrying the pending cache invalidations and closes the cache.
+func (s *Service) Close() {
+	close(s.done)
+
+	if c, ok := s.cache.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			s.log.Error("failed to close cache", sl.Err(err))
+		}
+	}
+}
+
+// List returns the tasks of the user. Snoozed tasks are returned only if
+// includeSnoozed is true.
+//
+// The cache keeps the full list and snoozed tasks are filtered out on every
+// call, so a task shows up again as soon as its snooze expires.
+func (s *Service) List(ctx context.Context, userID string, includeSnoozed bool) ([]data.Task, error) {
+	all, err := s.list(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	if found {
-		tasks := make([]data.Task, 0)
-		if err := json.Unmarshal(cache, &tasks); err != nil {
-			return nil, err
+
+	if includeSnoozed {
+		return all, nil
+	}
+
+	now := time.Now()
+	visible := make([]data.Task, 0, len(all))
+	for _, t := range all {
+		if !t.IsSnoozed(now) {
+			visible = append(visible, t)
 		}
-		return tasks, nil
 	}
 
-	tasks, err := s.tasks.FindByUserID(ctx, userID)
+	return visible, nil
+}
+
+func (s *Service) list(ctx context.Context, userID string) ([]data.Task, error) {
+	cached, stale, found := s.cachedList(ctx, userID)
+	if found && !stale {
+		return cached, nil
+	}
+
+	if found && s.staleTTL > 0 {
+		s.revalidate(ctx, userID)
+		return cached, nil
+	}
+
+	return s.load(ctx, userID)
+}
+
+// Find returns the task of the given user.
+//
+// If the task belongs to another user returns tasks.ErrNotFound.
+func (s *Service) Find(ctx context.Context, userID, id string) (data.Task, error) {
+	t, err := s.tasks.FindByID(ctx, id)
 	if err != nil {
-		return nil, err
+		return data.Task{}, err
 	}
 
-	data, _ := json.Marshal(tasks)
-	if err := s.cache.Set(ctx, userID, data, s.cacheTTL); err != nil {
-		return nil, err
+	if t.UserID != userID {
+		return data.Task{}, tasks.ErrNotFound
 	}
 
-	return tasks, nil
+	return t, nil
 }
 
+// Create creates the task of the user and records task.created.
 func (s *Service) Create(ctx context.Context, userID string, t data.Task) (data.Task, error) {
 	t.UserID = userID
 
-	if err := s.tasks.Save(ctx, &t); err != nil {
-		return data.Task{}, err
-	}
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.tasks.Save(ctx, &t); err != nil {
+			return err
+		}
 
-	if err := s.cache.Del(ctx, userID); err != nil {
+		return s.record(ctx, events.TypeTaskCreated, events.TaskOf(t))
+	})
+	if err != nil {
 		return data.Task{}, err
 	}
 
+	s.invalidate(ctx, userID)
+
 	return t, nil
 }
 
+// Delete deletes the task and records task.deleted.
 func (s *Service) Delete(ctx context.Context, id string) error {
-	if err := s.tasks.Delete(ctx, id); err != nil {
-		return err
-	}
+	userID, _ := ctx.Value(api.UserIDKey).(string)
 
-	userID, ok := ctx.Value(api.UserIDKey).(string)
-	if ok {
-		if err := s.cache.Del(ctx, userID); err != nil {
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.tasks.Delete(ctx, id); err != nil {
 			return err
 		}
+
+		return s.record(ctx, events.TypeTaskDeleted, events.Task{ID: id, UserID: userID})
+	})
+	if err != nil {
+		return err
+	}
+
+	if userID != "" {
+		s.invalidate(ctx, userID)
 	}
 
 	return nil
 }
 
-func (s *Service) Update(ctx context.Context, id string, t data.Task) (data.Task, error) {
+// Update updates the task of t.UserID.
+//
+// If the task is being completed while some of its blockers are open
+// returns ErrBlocked, unless force is true. The check, the update and
+// the task.completed or task.updated event run in one transaction.
+func (s *Service) Update(ctx context.Context, id string, t data.Task, force bool) (data.Task, error) {
 	t.ID = id
 
-	if err := s.tasks.Update(ctx, &t); err != nil {
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := s.Find(ctx, t.UserID, id)
+		if err != nil {
+			return err
+		}
+
+		completed := t.IsCompleted && !current.IsCompleted
+
+		if completed && !force {
+			blocked, err := s.deps.HasOpenBlockers(ctx, id)
+			if err != nil {
+				return err
+			}
+
+			if blocked {
+				return ErrBlocked
+			}
+		}
+
+		if err = s.tasks.Update(ctx, &t); err != nil {
+			return err
+		}
+
+		if completed {
+			return s.record(ctx, events.TypeTaskCompleted, events.TaskOf(t))
+		}
+
+		return s.record(ctx, events.TypeTaskUpdated, events.TaskOf(t))
+	})
+	if err != nil {
 		return data.Task{}, err
 	}
 
-	userID, ok := ctx.Value(api.UserIDKey).(string)
-	if ok {
-		if err := s.cache.Del(ctx, userID); err != nil {
-			return data.Task{}, err
-		}
+	if userID, ok := ctx.Value(api.UserIDKey).(string); ok {
+		s.invalidate(ctx, userID)
 	}
 
 	return t, nil
 }
+
+// Dependencies returns the tasks the given task is blocked by
+// and the tasks it blocks.
+func (s *Service) Dependencies(ctx context.Context, userID, id string) (blockers, dependents []data.Task, err error) {
+	if _, err = s.Find(ctx, userID, id); err != nil {
+		return nil, nil, err
+	}
+
+	if blockers, err = s.deps.Blockers(ctx, id); err != nil {
+		return nil, nil, err
+	}
+
+	if dependents, err = s.deps.Dependents(ctx, id); err != nil {
+		return nil, nil, err
+	}
+
+	return blockers, dependents, nil
+}
+
+// AddDependency marks the task as blocked by the blocker.
+//
+// Both tasks must belong to the user. If the edge would create a cycle
+// returns dependencies.ErrCycle. The ownership checks and the insert run
+// in one transaction.
+func (s *Service) AddDependency(ctx context.Context, userID, id, blockerID string) error {
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := s.Find(ctx, userID, id); err != nil {
+			return err
+		}
+
+		if _, err := s.Find(ctx, userID, blockerID); err != nil {
+			return err
+		}
+
+		return s.deps.Add(ctx, id, blockerID)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, userID)
+
+	return nil
+}
+
+func (s *Service) RemoveDependency(ctx context.Context, userID, id, blockerID string) error {
+	if _, err := s.Find(ctx, userID, id); err != nil {
+		return err
+	}
+
+	if err := s.deps.Remove(ctx, id, blockerID); err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, userID)
+
+	return nil
+}
+
+// Snooze hides the task of the user from default lists until the given time.
+// Zero until shows the task again, and the task.snoozed event has no
+// hidden_until then.
+func (s *Service) Snooze(ctx context.Context, userID, id string, until time.Time) error {
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		t, err := s.Find(ctx, userID, id)
+		if err != nil {
+			return err
+		}
+
+		if err = s.tasks.Snooze(ctx, id, until); err != nil {
+			return err
+		}
+
+		t.HiddenUntil = until
+
+		return s.record(ctx, events.TypeTaskSnoozed, events.TaskOf(t))
+	})
+	if err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, userID)
+
+	return nil
+}
+
+// ListArchived returns the archived tasks of the user.
+// The archive is not cached.
+func (s *Service) ListArchived(ctx context.Context, userID string) ([]data.Task, error) {
+	return s.tasks.FindByUserID(ctx, userID, tasks.Filter{IncludeSnoozed: true, Archived: true})
+}
+
+// Unarchive brings the archived task of the user back to the task list.
+func (s *Service) Unarchive(ctx context.Context, userID, id string) error {
+	if _, err := s.Find(ctx, userID, id); err != nil {
+		return err
+	}
+
+	if err := s.tasks.Unarchive(ctx, id); err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, userID)
+
+	return nil
+}
+
+// Archive archives the old completed tasks of all users in all workspaces
+// and drops the cached lists of the affected users. Users without their
+// own setting keep completed tasks for defaultDays.
+//
+// Returns the number of affected lists.
+func (s *Service) Archive(ctx context.Context, defaultDays int) (int, error) {
+	owners, err := s.tasks.Archive(storages.AllWorkspaces(ctx), defaultDays)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, o := range owners {
+		s.invalidate(storages.WithWorkspace(ctx, o.WorkspaceID), o.UserID)
+	}
+
+	return len(owners), nil
+}
+
+// record adds the task event to the outbox, if the events are enabled.
+// It must run in the transaction of the change.
+func (s *Service) record(ctx context.Context, typ string, t events.Task) error {
+	if !s.events || s.outbox == nil {
+		return nil
+	}
+
+	if !s.contents {
+		t.Title, t.Description = "", ""
+	}
+
+	env, err := events.New(typ, events.TaskVersion, t)
+	if err != nil {
+		return err
+	}
+
+	e, err := data.NewEvent(typ, t.ID, env)
+	if err != nil {
+		return err
+	}
+
+	return s.outbox.Add(ctx, &e)
+}
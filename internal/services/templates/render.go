@@ -0,0 +1,39 @@
+package templates
+
+import (
+	"regexp"
+	"time"
+)
+
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// Builtins returns the placeholder values available in every template:
+//   - date - now in 2006-01-02 format
+//   - time - now in 15:04 format
+//   - datetime - now in 2006-01-02 15:04 format
+//   - weekday, month, year - parts of now
+//   - tomorrow, yesterday - adjacent dates in 2006-01-02 format
+func Builtins(now time.Time) map[string]string {
+	return map[string]string{
+		"date":      now.Format(time.DateOnly),
+		"time":      now.Format("15:04"),
+		"datetime":  now.Format("2006-01-02 15:04"),
+		"weekday":   now.Weekday().String(),
+		"month":     now.Month().String(),
+		"year":      now.Format("2006"),
+		"tomorrow":  now.AddDate(0, 0, 1).Format(time.DateOnly),
+		"yesterday": now.AddDate(0, 0, -1).Format(time.DateOnly),
+	}
+}
+
+// Render substitutes {{name}} placeholders in s with values.
+// Unknown placeholders are left as is.
+func Render(s string, values map[string]string) string {
+	return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+		name := placeholder.FindStringSubmatch(m)[1]
+		if v, ok := values[name]; ok {
+			return v
+		}
+		return m
+	})
+}
@@ -0,0 +1,51 @@
+package templates
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	values := map[string]string{"date": "2023-10-06", "project": "eldorado"}
+
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{name: "no placeholders", s: "Plan the day", want: "Plan the day"},
+		{name: "placeholder", s: "Report of {{date}}", want: "Report of 2023-10-06"},
+		{name: "spaces inside braces", s: "Report of {{ date }}", want: "Report of 2023-10-06"},
+		{name: "several placeholders", s: "{{project}}: {{date}}", want: "eldorado: 2023-10-06"},
+		{name: "repeated placeholder", s: "{{date}} / {{date}}", want: "2023-10-06 / 2023-10-06"},
+		{name: "unknown placeholder", s: "Call {{client}}", want: "Call {{client}}"},
+		{name: "invalid name", s: "{{1st}} and {{da-te}}", want: "{{1st}} and {{da-te}}"},
+		{name: "single braces", s: "{date}", want: "{date}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Render(tt.s, values))
+		})
+	}
+
+	// The substituted values are not rendered again.
+	assert.Equal(t, "{{date}}", Render("{{project}}", map[string]string{"project": "{{date}}", "date": "2023-10-06"}))
+}
+
+func TestBuiltins(t *testing.T) {
+	now := time.Date(2023, time.December, 31, 9, 5, 0, 0, time.UTC)
+
+	assert.Equal(t, map[string]string{
+		"date":      "2023-12-31",
+		"time":      "09:05",
+		"datetime":  "2023-12-31 09:05",
+		"weekday":   "Sunday",
+		"month":     "December",
+		"year":      "2023",
+		"tomorrow":  "2024-01-01",
+		"yesterday": "2023-12-30",
+	}, Builtins(now))
+}
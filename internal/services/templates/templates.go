@@ -0,0 +1,290 @@
+package templates
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/envelope"
+	"github.com/romankravchuk/eldorado/internal/services"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/templates"
+	"github.com/romankravchuk/eldorado/internal/storages/templates/pg"
+	"github.com/romankravchuk/eldorado/internal/storages/templates/sqlite"
+)
+
+var (
+	// ErrTitleTooLong is returned when the title of an instantiated task
+	// exceeds the tasks title limit after placeholder substitution.
+	ErrTitleTooLong = errors.New("the rendered title is longer than 100 characters")
+	// ErrDescriptionTooLong is returned when the description of an
+	// instantiated task exceeds the tasks description limit after
+	// placeholder substitution.
+	ErrDescriptionTooLong = errors.New("the rendered description is longer than 255 characters")
+	// ErrTitleTooShort is returned when the title of an instantiated task
+	// is shorter than the tasks title limit after placeholder
+	// substitution, e.g. a title of placeholders rendered to nothing.
+	ErrTitleTooShort = errors.New("the rendered title is shorter than 3 characters")
+	// ErrChecklistItemLength is returned when a checklist item of an
+	// instantiated task is empty or exceeds the item limit after
+	// placeholder substitution.
+	ErrChecklistItemLength = errors.New("a rendered checklist item is empty or longer than 255 characters")
+)
+
+const (
+	minTitleLen         = 3
+	maxTitleLen         = 100
+	maxDescriptionLen   = 255
+	maxChecklistItemLen = 255
+)
+
+// Tasks finds the tasks templates are saved from and creates the tasks
+// templates are instantiated to.
+type Tasks interface {
+	Find(ctx context.Context, userID, id string) (data.Task, error)
+	Create(ctx context.Context, userID string, t data.Task) (data.Task, error)
+}
+
+type Option func(*Service) error
+
+func WithTemplatesStorage(templates templates.Storage) Option {
+	return func(s *Service) error {
+		if templates == nil {
+			return services.ErrNilTemplatesStorage
+		}
+
+		s.templates = templates
+		return nil
+	}
+}
+
+// WithTemplatesPostgresStorage opens the templates storage of the postgres
+// database at url. The template contents are encrypted with the keyring,
+// nil stores them in plaintext.
+func WithTemplatesPostgresStorage(url string, keyring *envelope.Keyring, poolOpts ...storages.PoolOption) Option {
+	return func(s *Service) error {
+		conn, err := storages.NewDBPool("postgres", url, poolOpts...)
+		if err != nil {
+			return err
+		}
+
+		return withTemplatesPostgresPool(conn, keyring)(s)
+	}
+}
+
+func withTemplatesPostgresPool(conn *sql.DB, keyring *envelope.Keyring) Option {
+	return func(s *Service) error {
+		templates, err := pg.New(conn, pg.WithKeyring(keyring))
+		if err != nil {
+			return err
+		}
+
+		return WithTemplatesStorage(templates)(s)
+	}
+}
+
+// WithTemplatesSQLiteStorage opens the templates storage of the sqlite
+// database at path.
+func WithTemplatesSQLiteStorage(path string, poolOpts ...storages.PoolOption) Option {
+	return func(s *Service) error {
+		conn, err := storages.NewSQLitePool(path, poolOpts...)
+		if err != nil {
+			return err
+		}
+
+		return withTemplatesSQLitePool(conn)(s)
+	}
+}
+
+func withTemplatesSQLitePool(conn *sql.DB) Option {
+	return func(s *Service) error {
+		templates, err := sqlite.New(conn)
+		if err != nil {
+			return err
+		}
+
+		return WithTemplatesStorage(templates)(s)
+	}
+}
+
+// WithTemplatesDBStorage opens the templates storage of the driver,
+// storages.DriverPostgres or storages.DriverSQLite. The keyring is ignored
+// by sqlite.
+func WithTemplatesDBStorage(driver, url string, keyring *envelope.Keyring, poolOpts ...storages.PoolOption) Option {
+	switch driver {
+	case storages.DriverPostgres:
+		return WithTemplatesPostgresStorage(url, keyring, poolOpts...)
+	case storages.DriverSQLite:
+		return WithTemplatesSQLiteStorage(url, poolOpts...)
+	}
+
+	return func(*Service) error {
+		return fmt.Errorf("%w: %q", storages.ErrUnknownDriver, driver)
+	}
+}
+
+// WithTemplatesDBPool is WithTemplatesDBStorage on the pool of the driver
+// opened by the caller, see storages.OpenDB. The pool is not closed by the
+// service.
+func WithTemplatesDBPool(driver string, pool *sql.DB, keyring *envelope.Keyring) Option {
+	switch {
+	case pool == nil:
+		return func(*Service) error {
+			return storages.ErrNilDBPool
+		}
+	case driver == storages.DriverPostgres:
+		return withTemplatesPostgresPool(pool, keyring)
+	case driver == storages.DriverSQLite:
+		return withTemplatesSQLitePool(pool)
+	}
+
+	return func(*Service) error {
+		return fmt.Errorf("%w: %q", storages.ErrUnknownDriver, driver)
+	}
+}
+
+func WithTasks(tasks Tasks) Option {
+	return func(s *Service) error {
+		if tasks == nil {
+			return services.ErrNilTasksService
+		}
+
+		s.tasks = tasks
+		return nil
+	}
+}
+
+type Service struct {
+	templates templates.Storage
+	tasks     Tasks
+}
+
+func New(opts ...Option) (*Service, error) {
+	s := &Service{}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Service) List(ctx context.Context, userID string) ([]data.Template, error) {
+	return s.templates.FindByUserID(ctx, userID)
+}
+
+// Get returns the template of the given user.
+//
+// If the template belongs to another user returns templates.ErrNotFound.
+func (s *Service) Get(ctx context.Context, userID, id string) (data.Template, error) {
+	t, err := s.templates.FindByID(ctx, id)
+	if err != nil {
+		return data.Template{}, err
+	}
+
+	if t.UserID != userID {
+		return data.Template{}, templates.ErrNotFound
+	}
+
+	return t, nil
+}
+
+func (s *Service) Create(ctx context.Context, userID string, t data.Template) (data.Template, error) {
+	t.UserID = userID
+
+	if err := s.templates.Save(ctx, &t); err != nil {
+		return data.Template{}, err
+	}
+
+	return t, nil
+}
+
+// CreateFromTask saves the task of the given user as a named template.
+// Completion state of the checklist items is not kept.
+func (s *Service) CreateFromTask(ctx context.Context, userID, taskID, name string) (data.Template, error) {
+	task, err := s.tasks.Find(ctx, userID, taskID)
+	if err != nil {
+		return data.Template{}, err
+	}
+
+	checklist := make([]string, len(task.Checklist))
+	for i, item := range task.Checklist {
+		checklist[i] = item.Text
+	}
+
+	return s.Create(ctx, userID, data.Template{
+		Name:        name,
+		Title:       task.Title,
+		Description: task.Description,
+		Tags:        task.Tags,
+		Priority:    task.Priority,
+		Recurrence:  task.Recurrence,
+		Checklist:   checklist,
+	})
+}
+
+func (s *Service) Delete(ctx context.Context, userID, id string) error {
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return err
+	}
+
+	return s.templates.Delete(ctx, id)
+}
+
+// Instantiate creates a new task from the template of the given user.
+//
+// Placeholders in the title, description and checklist are substituted with
+// the built-in values computed for now and with vars, see Render. The
+// rendered task is checked against the limits of the created tasks.
+func (s *Service) Instantiate(
+	ctx context.Context,
+	userID, id string,
+	vars map[string]string,
+	now time.Time,
+) (data.Task, error) {
+	t, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return data.Task{}, err
+	}
+
+	values := Builtins(now)
+	for k, v := range vars {
+		values[k] = v
+	}
+
+	title := Render(t.Title, values)
+	if utf8.RuneCountInString(strings.TrimSpace(title)) < minTitleLen {
+		return data.Task{}, ErrTitleTooShort
+	}
+	if utf8.RuneCountInString(title) > maxTitleLen {
+		return data.Task{}, ErrTitleTooLong
+	}
+
+	description := Render(t.Description, values)
+	if utf8.RuneCountInString(description) > maxDescriptionLen {
+		return data.Task{}, ErrDescriptionTooLong
+	}
+
+	checklist := make([]data.ChecklistItem, len(t.Checklist))
+	for i, item := range t.Checklist {
+		text := Render(item, values)
+		if strings.TrimSpace(text) == "" || utf8.RuneCountInString(text) > maxChecklistItemLen {
+			return data.Task{}, ErrChecklistItemLength
+		}
+		checklist[i] = data.ChecklistItem{Text: text}
+	}
+
+	return s.tasks.Create(ctx, userID, data.Task{
+		Title:       title,
+		Description: description,
+		Tags:        t.Tags,
+		Priority:    t.Priority,
+		Recurrence:  t.Recurrence,
+		Checklist:   checklist,
+	})
+}
@@ -0,0 +1,217 @@
+package templates
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+	tasksstorage "github.com/romankravchuk/eldorado/internal/storages/tasks"
+	"github.com/romankravchuk/eldorado/internal/storages/templates"
+	"github.com/romankravchuk/eldorado/internal/storages/templates/sqlite"
+	userssqlite "github.com/romankravchuk/eldorado/internal/storages/users/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTasks keeps the tasks created from the templates in memory.
+type fakeTasks struct {
+	tasks map[string]data.Task
+}
+
+func (f *fakeTasks) Find(_ context.Context, userID, id string) (data.Task, error) {
+	t, ok := f.tasks[id]
+	if !ok || t.UserID != userID {
+		return data.Task{}, tasksstorage.ErrNotFound
+	}
+	return t, nil
+}
+
+func (f *fakeTasks) Create(_ context.Context, userID string, t data.Task) (data.Task, error) {
+	t.ID = uuid.NewString()
+	t.UserID = userID
+	t.CreatedOn = time.Now()
+	f.tasks[t.ID] = t
+	return t, nil
+}
+
+// newTestService returns a service over a new sqlite database with two
+// users, the owner of the templates and another one.
+func newTestService(t *testing.T) (s *Service, tasks *fakeTasks, owner, other string) {
+	db := storagetest.SQLite(t)
+
+	users, err := userssqlite.New(db)
+	require.NoError(t, err)
+
+	ids := make([]string, 2)
+	for i := range ids {
+		u := data.User{Email: uuid.NewString() + "@example.com", Username: uuid.NewString()[:8], EncryptedPassword: "hash"}
+		require.NoError(t, users.Save(storages.AllWorkspaces(context.Background()), &u))
+		ids[i] = u.ID
+	}
+
+	storage, err := sqlite.New(db)
+	require.NoError(t, err)
+
+	tasks = &fakeTasks{tasks: map[string]data.Task{}}
+
+	s, err = New(WithTemplatesStorage(storage), WithTasks(tasks))
+	require.NoError(t, err)
+
+	return s, tasks, ids[0], ids[1]
+}
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	s, _, owner, other := newTestService(t)
+
+	created, err := s.Create(ctx, owner, data.Template{UserID: other, Name: "weekly", Title: "Review"})
+	require.NoError(t, err)
+	assert.Equal(t, owner, created.UserID, "the template is not created for the caller")
+
+	got, err := s.Get(ctx, owner, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Review", got.Title)
+
+	_, err = s.Get(ctx, other, created.ID)
+	assert.ErrorIs(t, err, templates.ErrNotFound)
+
+	list, err := s.List(ctx, owner)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	list, err = s.List(ctx, other)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	assert.ErrorIs(t, s.Delete(ctx, other, created.ID), templates.ErrNotFound)
+	require.NoError(t, s.Delete(ctx, owner, created.ID))
+
+	_, err = s.Get(ctx, owner, created.ID)
+	assert.ErrorIs(t, err, templates.ErrNotFound)
+}
+
+func TestCreateFromTask(t *testing.T) {
+	ctx := context.Background()
+	s, tasks, owner, other := newTestService(t)
+
+	task, err := tasks.Create(ctx, owner, data.Task{
+		Title:       "Weekly review",
+		Description: "Look back at the week",
+		Tags:        []string{"work"},
+		Priority:    data.PriorityHigh,
+		Recurrence:  "every week",
+		Checklist:   []data.ChecklistItem{{Text: "Inbox", IsDone: true}, {Text: "Calendar"}},
+	})
+	require.NoError(t, err)
+
+	tpl, err := s.CreateFromTask(ctx, owner, task.ID, "weekly")
+	require.NoError(t, err)
+	assert.Equal(t, "Weekly review", tpl.Title)
+	assert.Equal(t, "Look back at the week", tpl.Description)
+	assert.Equal(t, []string{"work"}, tpl.Tags)
+	assert.Equal(t, data.PriorityHigh, tpl.Priority)
+	assert.Equal(t, "every week", tpl.Recurrence)
+	assert.Equal(t, []string{"Inbox", "Calendar"}, tpl.Checklist)
+
+	_, err = s.CreateFromTask(ctx, other, task.ID, "weekly")
+	assert.ErrorIs(t, err, tasksstorage.ErrNotFound)
+
+	_, err = s.CreateFromTask(ctx, owner, task.ID, "weekly")
+	assert.ErrorIs(t, err, templates.ErrAlreadyExists)
+}
+
+func TestInstantiate(t *testing.T) {
+	ctx := context.Background()
+	s, tasks, owner, other := newTestService(t)
+
+	now := time.Date(2023, time.October, 6, 9, 30, 0, 0, time.UTC)
+
+	tpl, err := s.Create(ctx, owner, data.Template{
+		Name:        "report",
+		Title:       "Report of {{date}} for {{client}}",
+		Description: "Due {{tomorrow}}, {{unknown}} is kept",
+		Tags:        []string{"work"},
+		Priority:    data.PriorityMedium,
+		Checklist:   []string{"Send to {{client}}"},
+	})
+	require.NoError(t, err)
+
+	task, err := s.Instantiate(ctx, owner, tpl.ID, map[string]string{"client": "ACME", "date": "today"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, "Report of today for ACME", task.Title, "the vars do not override the built-ins")
+	assert.Equal(t, "Due 2023-10-07, {{unknown}} is kept", task.Description)
+	assert.Equal(t, []data.ChecklistItem{{Text: "Send to ACME"}}, task.Checklist)
+	assert.Equal(t, []string{"work"}, task.Tags)
+	assert.Equal(t, data.PriorityMedium, task.Priority)
+	assert.Contains(t, tasks.tasks, task.ID)
+
+	_, err = s.Instantiate(ctx, other, tpl.ID, nil, now)
+	assert.ErrorIs(t, err, templates.ErrNotFound)
+
+	// The rendered title is 25 characters and the client.
+	_, err = s.Instantiate(ctx, owner, tpl.ID, map[string]string{"client": strings.Repeat("x", 76)}, now)
+	assert.ErrorIs(t, err, ErrTitleTooLong)
+
+	// The limits count characters, not bytes.
+	_, err = s.Instantiate(ctx, owner, tpl.ID, map[string]string{"client": strings.Repeat("ж", 75)}, now)
+	assert.NoError(t, err)
+
+	tpl, err = s.Create(ctx, owner, data.Template{Name: "notes", Title: "Notes", Description: "{{notes}}"})
+	require.NoError(t, err)
+
+	_, err = s.Instantiate(ctx, owner, tpl.ID, map[string]string{"notes": strings.Repeat("x", 256)}, now)
+	assert.ErrorIs(t, err, ErrDescriptionTooLong)
+
+	_, err = s.Instantiate(ctx, owner, tpl.ID, map[string]string{"notes": strings.Repeat("ж", 255)}, now)
+	assert.NoError(t, err)
+}
+
+func TestInstantiateRenderedLimits(t *testing.T) {
+	ctx := context.Background()
+	s, tasks, owner, _ := newTestService(t)
+
+	now := time.Date(2023, time.October, 6, 9, 30, 0, 0, time.UTC)
+
+	tpl, err := s.Create(ctx, owner, data.Template{
+		Name:      "errand",
+		Title:     "{{errand}}",
+		Checklist: []string{"{{first}}", "then {{second}}"},
+	})
+	require.NoError(t, err)
+
+	ok := map[string]string{"errand": "Gym", "first": "warm up", "second": "stretch"}
+
+	tests := []struct {
+		name string
+		vars map[string]string
+		want error
+	}{
+		{name: "valid", vars: ok},
+		{name: "empty title", vars: map[string]string{"errand": "", "first": "a", "second": "b"}, want: ErrTitleTooShort},
+		{name: "blank title", vars: map[string]string{"errand": "   ", "first": "a", "second": "b"}, want: ErrTitleTooShort},
+		{name: "short title", vars: map[string]string{"errand": "Go", "first": "a", "second": "b"}, want: ErrTitleTooShort},
+		{name: "empty checklist item", vars: map[string]string{"errand": "Gym", "first": "", "second": "b"}, want: ErrChecklistItemLength},
+		{name: "blank checklist item", vars: map[string]string{"errand": "Gym", "first": " ", "second": "b"}, want: ErrChecklistItemLength},
+		{name: "long checklist item", vars: map[string]string{"errand": "Gym", "first": "a", "second": strings.Repeat("x", 251)}, want: ErrChecklistItemLength},
+		{name: "longest checklist item", vars: map[string]string{"errand": "Gym", "first": "a", "second": strings.Repeat("ж", 250)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(tasks.tasks)
+
+			_, err := s.Instantiate(ctx, owner, tpl.ID, tt.vars, now)
+			if tt.want != nil {
+				assert.ErrorIs(t, err, tt.want)
+				assert.Len(t, tasks.tasks, before, "an invalid task is created")
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
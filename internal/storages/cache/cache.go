@@ -11,3 +11,11 @@ type Cache interface {
 	Get(context.Context, string) ([]byte, bool, error)
 	Del(context.Context, string) error
 }
+
+// TTLCache is a Cache that also tells how long an entry has left to live.
+type TTLCache interface {
+	Cache
+	// GetWithTTL is Get that also returns the time left to live of the
+	// entry, zero if it never expires.
+	GetWithTTL(context.Context, string) ([]byte, time.Duration, bool, error)
+}
@@ -0,0 +1,265 @@
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+)
+
+var errCorrupted = errors.New("codec: corrupted binary value")
+
+const (
+	flagCompleted = 1 << iota
+	flagDeleted
+	flagBlocked
+)
+
+// binaryFormat is a compact field-by-field encoding of the task list.
+// Integers are varints, strings and slices are prefixed with their length
+// and times are unix seconds followed by the nanoseconds of the second.
+//
+// The field order is the format, any change of it needs a new Format.
+// The workspace of a task follows its user since FormatBinaryV2. Before
+// FormatBinary the times were unix nanoseconds, zero time encoded as 0.
+type binaryFormat struct {
+	workspace bool
+	nanos     bool
+}
+
+func (f binaryFormat) marshal(l TaskList) ([]byte, error) {
+	w := writer{b: make([]byte, 0, 64+len(l.Tasks)*160), nanos: f.nanos}
+
+	w.time(l.FreshUntil)
+	w.uvarint(uint64(len(l.Tasks)))
+
+	for _, t := range l.Tasks {
+		w.string(t.ID)
+		w.string(t.UserID)
+		if f.workspace {
+			w.string(t.WorkspaceID)
+		}
+		w.string(t.Title)
+		w.string(t.Description)
+
+		var flags byte
+		if t.IsCompleted {
+			flags |= flagCompleted
+		}
+		if t.IsDeleted {
+			flags |= flagDeleted
+		}
+		if t.IsBlocked {
+			flags |= flagBlocked
+		}
+		w.b = append(w.b, flags, byte(t.Priority))
+
+		w.time(t.DueOn)
+		w.strings(t.Tags)
+		w.string(t.Recurrence)
+
+		w.uvarint(uint64(len(t.Checklist)))
+		for _, item := range t.Checklist {
+			w.string(item.Text)
+			w.bool(item.IsDone)
+		}
+
+		w.time(t.HiddenUntil)
+		w.time(t.CompletedOn)
+		w.time(t.ArchivedOn)
+		w.time(t.CreatedOn)
+	}
+
+	return w.b, nil
+}
+
+func (f binaryFormat) unmarshal(b []byte) (TaskList, error) {
+	r := reader{b: b, nanos: f.nanos}
+
+	l := TaskList{FreshUntil: r.time()}
+
+	n := r.length()
+	if r.err != nil {
+		return TaskList{}, r.err
+	}
+
+	l.Tasks = make([]data.Task, n)
+	for i := range l.Tasks {
+		t := &l.Tasks[i]
+
+		t.ID = r.string()
+		t.UserID = r.string()
+		if f.workspace {
+			t.WorkspaceID = r.string()
+		}
+		t.Title = r.string()
+		t.Description = r.string()
+
+		flags := r.byte()
+		t.IsCompleted = flags&flagCompleted != 0
+		t.IsDeleted = flags&flagDeleted != 0
+		t.IsBlocked = flags&flagBlocked != 0
+		t.Priority = data.Priority(int8(r.byte()))
+
+		t.DueOn = r.time()
+		t.Tags = r.strings()
+		t.Recurrence = r.string()
+
+		if m := r.length(); m > 0 {
+			t.Checklist = make([]data.ChecklistItem, m)
+			for j := range t.Checklist {
+				t.Checklist[j].Text = r.string()
+				t.Checklist[j].IsDone = r.byte() != 0
+			}
+		}
+
+		t.HiddenUntil = r.time()
+		t.CompletedOn = r.time()
+		t.ArchivedOn = r.time()
+		t.CreatedOn = r.time()
+
+		if r.err != nil {
+			return TaskList{}, r.err
+		}
+	}
+
+	if len(r.b) != 0 {
+		return TaskList{}, errCorrupted
+	}
+
+	return l, nil
+}
+
+type writer struct {
+	b     []byte
+	nanos bool
+}
+
+func (w *writer) uvarint(v uint64) {
+	w.b = binary.AppendUvarint(w.b, v)
+}
+
+func (w *writer) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.b = append(w.b, s...)
+}
+
+func (w *writer) strings(ss []string) {
+	w.uvarint(uint64(len(ss)))
+	for _, s := range ss {
+		w.string(s)
+	}
+}
+
+func (w *writer) bool(v bool) {
+	if v {
+		w.b = append(w.b, 1)
+		return
+	}
+	w.b = append(w.b, 0)
+}
+
+func (w *writer) time(t time.Time) {
+	if !w.nanos {
+		w.b = binary.AppendVarint(w.b, t.Unix())
+		w.uvarint(uint64(t.Nanosecond()))
+		return
+	}
+	if t.IsZero() {
+		w.b = binary.AppendVarint(w.b, 0)
+		return
+	}
+	w.b = binary.AppendVarint(w.b, t.UnixNano())
+}
+
+// reader decodes the values written by writer. The first error is kept
+// and every following read returns a zero value.
+type reader struct {
+	b     []byte
+	nanos bool
+	err   error
+}
+
+func (r *reader) fail() {
+	if r.err == nil {
+		r.err = errCorrupted
+	}
+	r.b = nil
+}
+
+func (r *reader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+// length reads a length prefix and checks it against the bytes left,
+// every element takes at least one byte.
+func (r *reader) length() int {
+	v := r.uvarint()
+	if v > uint64(len(r.b)) {
+		r.fail()
+		return 0
+	}
+	return int(v)
+}
+
+func (r *reader) string() string {
+	n := r.length()
+	s := string(r.b[:n])
+	r.b = r.b[n:]
+	return s
+}
+
+func (r *reader) strings() []string {
+	n := r.length()
+	if n == 0 {
+		return nil
+	}
+	ss := make([]string, n)
+	for i := range ss {
+		ss[i] = r.string()
+	}
+	return ss
+}
+
+func (r *reader) byte() byte {
+	if len(r.b) == 0 {
+		r.fail()
+		return 0
+	}
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+func (r *reader) time() time.Time {
+	v, n := binary.Varint(r.b)
+	if n <= 0 {
+		r.fail()
+		return time.Time{}
+	}
+	r.b = r.b[n:]
+
+	if !r.nanos {
+		nsec := r.uvarint()
+		if nsec >= uint64(time.Second) {
+			r.fail()
+			return time.Time{}
+		}
+		if t := time.Unix(v, int64(nsec)).UTC(); !t.IsZero() {
+			return t
+		}
+		return time.Time{}
+	}
+
+	if v == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, v).UTC()
+}
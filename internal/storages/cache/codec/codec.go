@@ -0,0 +1,157 @@
+// Package codec encodes the cached task lists.
+//
+// Every encoded value starts with a header byte: the low 7 bits are the
+// format version and the high bit marks a compressed payload. Values of
+// every known version are decoded whatever format is configured, so the
+// format can be changed without flushing the cache. Values written before
+// the header was introduced are plain JSON and are decoded as well.
+//
+// Encrypted seals the values of another codec under a header of its own.
+package codec
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+)
+
+// Format is the version of the encoding format.
+type Format byte
+
+const (
+	FormatJSON Format = 1
+	// FormatBinaryV1 is the binary format without the workspace of the
+	// tasks. It is only kept to decode the values written before.
+	FormatBinaryV1 Format = 2
+	// FormatBinaryV2 is the binary format with the times in unix
+	// nanoseconds, which only cover the years 1678 to 2262. It is only
+	// kept to decode the values written before.
+	FormatBinaryV2 Format = 3
+	FormatBinary   Format = 4
+)
+
+const compressedFlag = 0x80
+
+// minCompressSize is the payload size below which compression is skipped.
+const minCompressSize = 512
+
+var (
+	ErrEmpty         = errors.New("codec: empty value")
+	ErrUnknownFormat = errors.New("codec: unknown format")
+)
+
+// TaskList is the cached task list of a user. The entry outlives
+// FreshUntil by the stale TTL, so it can be served while it is being
+// refreshed.
+type TaskList struct {
+	FreshUntil time.Time   `json:"fresh_until"`
+	Tasks      []data.Task `json:"tasks"`
+}
+
+// Codec encodes and decodes the cached task lists.
+type Codec interface {
+	Marshal(l TaskList) ([]byte, error)
+	Unmarshal(b []byte) (TaskList, error)
+}
+
+type format interface {
+	marshal(l TaskList) ([]byte, error)
+	unmarshal(b []byte) (TaskList, error)
+}
+
+var formats = map[Format]format{
+	FormatJSON:     jsonFormat{},
+	FormatBinaryV1: binaryFormat{nanos: true},
+	FormatBinaryV2: binaryFormat{workspace: true, nanos: true},
+	FormatBinary:   binaryFormat{workspace: true},
+}
+
+// ParseFormat returns the format by its name: json or binary.
+func ParseFormat(name string) (Format, error) {
+	switch name {
+	case "json":
+		return FormatJSON, nil
+	case "binary":
+		return FormatBinary, nil
+	}
+	return 0, fmt.Errorf("codec: unknown format %q", name)
+}
+
+// Versioned writes values in the configured format, optionally compressed,
+// and reads values of every known format.
+type Versioned struct {
+	format   Format
+	compress bool
+}
+
+// New returns a Versioned codec. With compress, values larger than
+// 512 bytes are deflated.
+func New(f Format, compress bool) (*Versioned, error) {
+	if _, ok := formats[f]; !ok {
+		return nil, ErrUnknownFormat
+	}
+	return &Versioned{format: f, compress: compress}, nil
+}
+
+func (c *Versioned) Marshal(l TaskList) ([]byte, error) {
+	payload, err := formats[c.format].marshal(l)
+	if err != nil {
+		return nil, err
+	}
+
+	header := byte(c.format)
+
+	if c.compress && len(payload) >= minCompressSize {
+		var buf bytes.Buffer
+		buf.WriteByte(header | compressedFlag)
+
+		w, _ := flate.NewWriter(&buf, flate.BestSpeed)
+		if _, err := w.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	b := make([]byte, 0, len(payload)+1)
+	b = append(b, header)
+
+	return append(b, payload...), nil
+}
+
+func (c *Versioned) Unmarshal(b []byte) (TaskList, error) {
+	if len(b) == 0 {
+		return TaskList{}, ErrEmpty
+	}
+
+	// Values written before the header was introduced.
+	if b[0] == '{' {
+		return jsonFormat{}.unmarshal(b)
+	}
+
+	f, ok := formats[Format(b[0]&^compressedFlag)]
+	if !ok {
+		return TaskList{}, ErrUnknownFormat
+	}
+
+	payload := b[1:]
+	if b[0]&compressedFlag != 0 {
+		r := flate.NewReader(bytes.NewReader(payload))
+		defer r.Close()
+
+		var err error
+		if payload, err = io.ReadAll(r); err != nil {
+			return TaskList{}, err
+		}
+	}
+
+	return f.unmarshal(payload)
+}
@@ -0,0 +1,264 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/envelope"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// plainJSON is the encoding of the cached lists before the codecs.
+type plainJSON struct{}
+
+func (plainJSON) Marshal(l TaskList) ([]byte, error) {
+	return json.Marshal(l)
+}
+
+func (plainJSON) Unmarshal(b []byte) (TaskList, error) {
+	var l TaskList
+	err := json.Unmarshal(b, &l)
+	return l, err
+}
+
+func mustNew(t testing.TB, f Format, compress bool) Codec {
+	c, err := New(f, compress)
+	require.NoError(t, err)
+	return c
+}
+
+func mustKeyring(t testing.TB) *envelope.Keyring {
+	k, err := envelope.NewKeyring(map[string]string{
+		"k1": base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)),
+	}, "k1")
+	require.NoError(t, err)
+	return k
+}
+
+func codecs(t testing.TB) []struct {
+	name  string
+	codec Codec
+} {
+	return []struct {
+		name  string
+		codec Codec
+	}{
+		{"plain json", plainJSON{}},
+		{"json", mustNew(t, FormatJSON, false)},
+		{"json+deflate", mustNew(t, FormatJSON, true)},
+		{"binary", mustNew(t, FormatBinary, false)},
+		{"binary+deflate", mustNew(t, FormatBinary, true)},
+		{"binary+deflate+encrypted", NewEncrypted(mustNew(t, FormatBinary, true), mustKeyring(t))},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	list := generate(100)
+
+	for _, c := range codecs(t) {
+		t.Run(c.name, func(t *testing.T) {
+			b, err := c.codec.Marshal(list)
+			require.NoError(t, err)
+
+			got, err := c.codec.Unmarshal(b)
+			require.NoError(t, err)
+			assert.Equal(t, normalize(list), normalize(got))
+		})
+	}
+}
+
+func TestRoundTripFarDates(t *testing.T) {
+	list := generate(2)
+	list.Tasks[0].HiddenUntil = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+	list.Tasks[0].DueOn = time.Date(2524, 5, 15, 9, 30, 0, 123456789, time.UTC)
+	list.Tasks[1].DueOn = time.Date(1, 1, 1, 0, 0, 0, 1, time.UTC)
+	list.Tasks[1].CreatedOn = time.Date(1600, 2, 29, 12, 0, 0, 0, time.UTC)
+	list.Tasks[1].CompletedOn = time.Unix(0, 0).UTC()
+
+	for _, c := range codecs(t) {
+		t.Run(c.name, func(t *testing.T) {
+			b, err := c.codec.Marshal(list)
+			require.NoError(t, err)
+
+			got, err := c.codec.Unmarshal(b)
+			require.NoError(t, err)
+			assert.Equal(t, normalize(list), normalize(got))
+		})
+	}
+}
+
+func TestUnmarshalOtherFormats(t *testing.T) {
+	list := generate(10)
+	c := mustNew(t, FormatBinary, true)
+
+	// Values of the other formats and of the plain JSON are decoded.
+	for _, other := range []Codec{plainJSON{}, mustNew(t, FormatJSON, true)} {
+		b, err := other.Marshal(list)
+		require.NoError(t, err)
+
+		got, err := c.Unmarshal(b)
+		require.NoError(t, err)
+		assert.Equal(t, normalize(list), normalize(got))
+	}
+
+	// The first binary format has no workspace.
+	b, err := mustNew(t, FormatBinaryV1, false).Marshal(list)
+	require.NoError(t, err)
+
+	got, err := c.Unmarshal(b)
+	require.NoError(t, err)
+	for i := range got.Tasks {
+		assert.Empty(t, got.Tasks[i].WorkspaceID)
+		got.Tasks[i].WorkspaceID = list.Tasks[i].WorkspaceID
+	}
+	assert.Equal(t, normalize(list), normalize(got))
+
+	// The second binary format has the times in unix nanoseconds.
+	b, err = mustNew(t, FormatBinaryV2, false).Marshal(list)
+	require.NoError(t, err)
+
+	got, err = c.Unmarshal(b)
+	require.NoError(t, err)
+	assert.Equal(t, normalize(list), normalize(got))
+
+	_, err = c.Unmarshal(nil)
+	assert.ErrorIs(t, err, ErrEmpty)
+
+	_, err = c.Unmarshal([]byte{0x7e})
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestEncrypted(t *testing.T) {
+	list := generate(10)
+	c := NewEncrypted(mustNew(t, FormatBinary, false), mustKeyring(t))
+
+	b, err := c.Marshal(list)
+	require.NoError(t, err)
+	assert.False(t, bytes.Contains(b, []byte(list.Tasks[0].Title)), "the title is stored in plaintext")
+
+	_, err = mustNew(t, FormatBinary, false).Unmarshal(b)
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+
+	_, err = NewEncrypted(mustNew(t, FormatBinary, false), nil).Unmarshal(b)
+	assert.ErrorIs(t, err, envelope.ErrUnknownKey)
+
+	// The values written before the encryption are decoded.
+	plain, err := mustNew(t, FormatBinary, false).Marshal(list)
+	require.NoError(t, err)
+
+	got, err := c.Unmarshal(plain)
+	require.NoError(t, err)
+	assert.Equal(t, normalize(list), normalize(got))
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	for _, n := range []int{100, 1000} {
+		list := generate(n)
+
+		for _, c := range codecs(b) {
+			b.Run(fmt.Sprintf("%s/%d", c.name, n), func(b *testing.B) {
+				var payload []byte
+
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					var err error
+					if payload, err = c.codec.Marshal(list); err != nil {
+						b.Fatal(err)
+					}
+				}
+
+				b.ReportMetric(float64(len(payload)), "B/value")
+			})
+		}
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	for _, n := range []int{100, 1000} {
+		list := generate(n)
+
+		for _, c := range codecs(b) {
+			b.Run(fmt.Sprintf("%s/%d", c.name, n), func(b *testing.B) {
+				payload, err := c.codec.Marshal(list)
+				require.NoError(b, err)
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := c.codec.Unmarshal(payload); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// generate returns a list of n tasks resembling the real ones.
+func generate(n int) TaskList {
+	now := time.Unix(1696500000, 0).UTC()
+
+	l := TaskList{
+		FreshUntil: now.Add(30 * time.Minute),
+		Tasks:      make([]data.Task, n),
+	}
+
+	for i := range l.Tasks {
+		t := &l.Tasks[i]
+		t.ID = fmt.Sprintf("8673ce18-6bcc-4c02-9c9a-%012d", i)
+		t.UserID = "b0a1e7b6-1f43-4a4f-9d2f-3f7d3f7f4a11"
+		t.WorkspaceID = "5d1c2f0e-8a4b-4c6d-9e7f-0a1b2c3d4e5f"
+		t.Title = "Task number " + strconv.Itoa(i)
+		t.Description = "Some description of the task that is a bit longer than the title"
+		t.IsCompleted = i%3 == 0
+		t.IsBlocked = i%7 == 0
+		t.CreatedOn = now.Add(-time.Duration(i) * time.Hour)
+		t.Priority = data.Priority(i % 4)
+
+		if i%2 == 0 {
+			t.DueOn = now.Add(time.Duration(i) * time.Hour)
+			t.Tags = []string{"work", "project-" + strconv.Itoa(i%10)}
+		}
+		if i%5 == 0 {
+			t.Recurrence = "FREQ=WEEKLY;INTERVAL=1;BYDAY=MO"
+			t.Checklist = []data.ChecklistItem{
+				{Text: "first step"},
+				{Text: "second step", IsDone: true},
+			}
+		}
+		if t.IsCompleted {
+			t.CompletedOn = now.Add(-time.Duration(i) * time.Minute)
+		}
+	}
+
+	return l
+}
+
+// normalize drops the time zone differences, JSON keeps the offset and
+// the binary format always decodes to UTC.
+func normalize(l TaskList) TaskList {
+	utc := func(t time.Time) time.Time {
+		if t.IsZero() {
+			return time.Time{}
+		}
+		return t.UTC()
+	}
+
+	out := TaskList{FreshUntil: utc(l.FreshUntil), Tasks: make([]data.Task, len(l.Tasks))}
+	for i, t := range l.Tasks {
+		t.DueOn = utc(t.DueOn)
+		t.HiddenUntil = utc(t.HiddenUntil)
+		t.CompletedOn = utc(t.CompletedOn)
+		t.ArchivedOn = utc(t.ArchivedOn)
+		t.CreatedOn = utc(t.CreatedOn)
+		out.Tasks[i] = t
+	}
+	return out
+}
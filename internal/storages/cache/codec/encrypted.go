@@ -0,0 +1,83 @@
+package codec
+
+import (
+	"encoding/binary"
+
+	"github.com/romankravchuk/eldorado/internal/pkg/envelope"
+)
+
+// encryptedHeader is the header byte of the values sealed by Encrypted. It
+// is not a format version, so Versioned never mistakes it for one.
+const encryptedHeader = 0x7f
+
+// Encrypted seals the values of the inner codec with a new data key wrapped
+// with the primary key of the keyring, like the task contents are stored in
+// the database. The encrypted value is the header byte, the key ID and the
+// wrapped data key prefixed with their length and the ciphertext.
+//
+// Without the primary key the values are written as is. The values that
+// are not encrypted are decoded by the inner codec, so the encryption can
+// be turned on without flushing the cache.
+type Encrypted struct {
+	codec   Codec
+	keyring *envelope.Keyring
+}
+
+// NewEncrypted returns an Encrypted codec over c.
+func NewEncrypted(c Codec, k *envelope.Keyring) *Encrypted {
+	return &Encrypted{codec: c, keyring: k}
+}
+
+func (c *Encrypted) Marshal(l TaskList) ([]byte, error) {
+	b, err := c.codec.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.keyring == nil || c.keyring.Primary() == "" {
+		return b, nil
+	}
+
+	dk, err := c.keyring.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := dk.SealBytes("tasks", b)
+	if err != nil {
+		return nil, err
+	}
+
+	w := writer{b: make([]byte, 0, 1+2*binary.MaxVarintLen64+len(dk.KeyID)+len(dk.Wrapped)+len(sealed))}
+	w.b = append(w.b, encryptedHeader)
+	w.string(dk.KeyID)
+	w.string(string(dk.Wrapped))
+	w.b = append(w.b, sealed...)
+
+	return w.b, nil
+}
+
+func (c *Encrypted) Unmarshal(b []byte) (TaskList, error) {
+	if len(b) == 0 || b[0] != encryptedHeader {
+		return c.codec.Unmarshal(b)
+	}
+
+	r := reader{b: b[1:]}
+	keyID := r.string()
+	wrapped := []byte(r.string())
+	if r.err != nil {
+		return TaskList{}, r.err
+	}
+
+	dk, err := c.keyring.Unwrap(keyID, wrapped)
+	if err != nil {
+		return TaskList{}, err
+	}
+
+	plaintext, err := dk.OpenBytes("tasks", r.b)
+	if err != nil {
+		return TaskList{}, err
+	}
+
+	return c.codec.Unmarshal(plaintext)
+}
@@ -0,0 +1,17 @@
+package codec
+
+import "encoding/json"
+
+type jsonFormat struct{}
+
+func (jsonFormat) marshal(l TaskList) ([]byte, error) {
+	return json.Marshal(l)
+}
+
+func (jsonFormat) unmarshal(b []byte) (TaskList, error) {
+	var l TaskList
+	if err := json.Unmarshal(b, &l); err != nil {
+		return TaskList{}, err
+	}
+	return l, nil
+}
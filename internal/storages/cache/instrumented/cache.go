@@ -0,0 +1,112 @@
+package instrumented
+
+import (
+	"context"
+	"expvar"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/storages/cache"
+)
+
+// stats holds the counters per key prefix, see /debug/vars.
+//
+// Every prefix has hits, misses, errors, sets, dels, the total time spent
+// in get, set and del in nanoseconds (get_ns, set_ns, del_ns) and the
+// payload bytes read and written.
+var stats = expvar.NewMap("cache")
+
+var mu sync.Mutex
+
+// Cache is a cache.Cache decorator that records the metrics of the
+// underlying cache per key prefix. The prefix is the part of the key
+// before the first colon.
+type Cache struct {
+	next cache.Cache
+}
+
+func New(next cache.Cache) *Cache {
+	return &Cache{next: next}
+}
+
+func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m := prefixStats(key)
+	start := time.Now()
+
+	err := c.next.Set(ctx, key, value, ttl)
+
+	m.Add("set_ns", int64(time.Since(start)))
+	m.Add("sets", 1)
+	if err != nil {
+		m.Add("errors", 1)
+		return err
+	}
+	m.Add("bytes_written", int64(len(value)))
+
+	return nil
+}
+
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m := prefixStats(key)
+	start := time.Now()
+
+	value, found, err := c.next.Get(ctx, key)
+
+	m.Add("get_ns", int64(time.Since(start)))
+	switch {
+	case err != nil:
+		m.Add("errors", 1)
+	case found:
+		m.Add("hits", 1)
+		m.Add("bytes_read", int64(len(value)))
+	default:
+		m.Add("misses", 1)
+	}
+
+	return value, found, err
+}
+
+func (c *Cache) Del(ctx context.Context, key string) error {
+	m := prefixStats(key)
+	start := time.Now()
+
+	err := c.next.Del(ctx, key)
+
+	m.Add("del_ns", int64(time.Since(start)))
+	m.Add("dels", 1)
+	if err != nil {
+		m.Add("errors", 1)
+	}
+
+	return err
+}
+
+// Close closes the underlying cache if it is an io.Closer.
+func (c *Cache) Close() error {
+	if closer, ok := c.next.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func prefixStats(key string) *expvar.Map {
+	prefix, _, _ := strings.Cut(key, ":")
+
+	if m, ok := stats.Get(prefix).(*expvar.Map); ok {
+		return m
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if m, ok := stats.Get(prefix).(*expvar.Map); ok {
+		return m
+	}
+
+	m := new(expvar.Map).Init()
+	stats.Set(prefix, m)
+
+	return m
+}
@@ -0,0 +1,112 @@
+package instrumented
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/storages/cache/memory"
+	"github.com/romankravchuk/eldorado/internal/storages/cache/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// counter returns the value of the counter of the prefix, zero if it was
+// never added to.
+func counter(t *testing.T, prefix, name string) int64 {
+	t.Helper()
+
+	m, ok := stats.Get(prefix).(*expvar.Map)
+	require.True(t, ok, "no stats of the prefix %q", prefix)
+
+	v, ok := m.Get(name).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+	return v.Value()
+}
+
+func TestCounters(t *testing.T) {
+	// The stats are global, every test counts under its own prefix.
+	prefix := uuid.NewString()
+	key := prefix + ":user"
+
+	c := New(memory.New(10))
+	ctx := context.Background()
+
+	_, found, err := c.Get(ctx, key)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	require.NoError(t, c.Set(ctx, key, []byte("tasks"), time.Minute))
+
+	for i := 0; i < 2; i++ {
+		value, found, err := c.Get(ctx, key)
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, []byte("tasks"), value)
+	}
+
+	require.NoError(t, c.Del(ctx, key))
+
+	_, found, err = c.Get(ctx, key)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	assert.Equal(t, int64(2), counter(t, prefix, "hits"))
+	assert.Equal(t, int64(2), counter(t, prefix, "misses"))
+	assert.Equal(t, int64(0), counter(t, prefix, "errors"))
+	assert.Equal(t, int64(1), counter(t, prefix, "sets"))
+	assert.Equal(t, int64(1), counter(t, prefix, "dels"))
+	assert.Equal(t, int64(5), counter(t, prefix, "bytes_written"))
+	assert.Equal(t, int64(10), counter(t, prefix, "bytes_read"))
+}
+
+func TestCountersPerPrefix(t *testing.T) {
+	first, second := uuid.NewString(), uuid.NewString()
+
+	c := New(memory.New(10))
+	ctx := context.Background()
+
+	require.NoError(t, c.Set(ctx, first+":user", []byte("tasks"), time.Minute))
+	_, _, err := c.Get(ctx, first+":user")
+	require.NoError(t, err)
+	_, _, err = c.Get(ctx, second+":user")
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1), counter(t, first, "hits"))
+	assert.Equal(t, int64(0), counter(t, first, "misses"))
+	assert.Equal(t, int64(0), counter(t, second, "hits"))
+	assert.Equal(t, int64(1), counter(t, second, "misses"))
+}
+
+func TestErrors(t *testing.T) {
+	prefix := uuid.NewString()
+	key := prefix + ":user"
+
+	errCache := errors.New("cache is unavailable")
+
+	next := mocks.NewCache(t)
+	next.On("Get", mock.Anything, key).Return(nil, false, errCache)
+	next.On("Set", mock.Anything, key, mock.Anything, time.Minute).Return(errCache)
+	next.On("Del", mock.Anything, key).Return(errCache)
+
+	c := New(next)
+	ctx := context.Background()
+
+	_, _, err := c.Get(ctx, key)
+	assert.ErrorIs(t, err, errCache)
+	assert.ErrorIs(t, c.Set(ctx, key, []byte("tasks"), time.Minute), errCache)
+	assert.ErrorIs(t, c.Del(ctx, key), errCache)
+
+	assert.Equal(t, int64(3), counter(t, prefix, "errors"))
+	assert.Equal(t, int64(0), counter(t, prefix, "hits"))
+	assert.Equal(t, int64(0), counter(t, prefix, "misses"), "a failed get is not counted as a miss")
+	assert.Equal(t, int64(0), counter(t, prefix, "bytes_written"), "a failed set is not counted as written")
+	assert.Equal(t, int64(1), counter(t, prefix, "sets"))
+	assert.Equal(t, int64(1), counter(t, prefix, "dels"))
+}
@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// Cache is an in-process LRU implementation of cache.Cache with per-entry TTL.
+type Cache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+
+	now func() time.Time
+}
+
+// New returns a Cache holding at most capacity entries. When the cache is
+// full the least recently used entry is evicted. Non-positive capacity
+// means no limit.
+func New(capacity int) *Cache {
+	return &Cache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// Set stores a copy of value. Non-positive ttl means the entry never expires.
+func (c *Cache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	v := make([]byte, len(value))
+	copy(v, value)
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = c.now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry)
+		e.value = v
+		e.expiresAt = expiresAt
+		c.ll.MoveToFront(el)
+		return nil
+	}
+
+	c.items[key] = c.ll.PushFront(&entry{key: key, value: v, expiresAt: expiresAt})
+
+	if c.capacity > 0 && c.ll.Len() > c.capacity {
+		c.remove(c.ll.Back())
+	}
+
+	return nil
+}
+
+// Get returns the value of the key. Expired entries are removed lazily.
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	v, _, found, err := c.GetWithTTL(ctx, key)
+	return v, found, err
+}
+
+// GetWithTTL returns the value of the key and the time it has left, zero
+// if it never expires.
+func (c *Cache) GetWithTTL(_ context.Context, key string) ([]byte, time.Duration, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, 0, false, nil
+	}
+
+	e := el.Value.(*entry)
+
+	var ttl time.Duration
+	if !e.expiresAt.IsZero() {
+		if ttl = e.expiresAt.Sub(c.now()); ttl <= 0 {
+			c.remove(el)
+			return nil, 0, false, nil
+		}
+	}
+
+	c.ll.MoveToFront(el)
+
+	return e.value, ttl, true, nil
+}
+
+func (c *Cache) Del(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+
+	return nil
+}
+
+// Flush removes all entries.
+func (c *Cache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+// Len returns the number of entries, including the expired ones
+// not removed yet.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*entry).key)
+}
@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEviction(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		capacity int
+		do       func(c *Cache)
+		want     []string
+		evicted  []string
+	}{
+		{
+			name:     "least recently set",
+			capacity: 2,
+			do: func(c *Cache) {
+				_ = c.Set(ctx, "a", []byte("a"), 0)
+				_ = c.Set(ctx, "b", []byte("b"), 0)
+				_ = c.Set(ctx, "c", []byte("c"), 0)
+			},
+			want:    []string{"b", "c"},
+			evicted: []string{"a"},
+		},
+		{
+			name:     "get keeps the entry",
+			capacity: 2,
+			do: func(c *Cache) {
+				_ = c.Set(ctx, "a", []byte("a"), 0)
+				_ = c.Set(ctx, "b", []byte("b"), 0)
+				_, _, _ = c.Get(ctx, "a")
+				_ = c.Set(ctx, "c", []byte("c"), 0)
+			},
+			want:    []string{"a", "c"},
+			evicted: []string{"b"},
+		},
+		{
+			name:     "overwrite keeps the entry",
+			capacity: 2,
+			do: func(c *Cache) {
+				_ = c.Set(ctx, "a", []byte("a"), 0)
+				_ = c.Set(ctx, "b", []byte("b"), 0)
+				_ = c.Set(ctx, "a", []byte("a"), 0)
+				_ = c.Set(ctx, "c", []byte("c"), 0)
+			},
+			want:    []string{"a", "c"},
+			evicted: []string{"b"},
+		},
+		{
+			name:     "no limit",
+			capacity: 0,
+			do: func(c *Cache) {
+				_ = c.Set(ctx, "a", []byte("a"), 0)
+				_ = c.Set(ctx, "b", []byte("b"), 0)
+				_ = c.Set(ctx, "c", []byte("c"), 0)
+			},
+			want: []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(tt.capacity)
+			tt.do(c)
+
+			assert.Equal(t, len(tt.want), c.Len())
+			for _, key := range tt.want {
+				v, found, err := c.Get(ctx, key)
+				require.NoError(t, err)
+				assert.True(t, found, key)
+				assert.Equal(t, []byte(key), v)
+			}
+			for _, key := range tt.evicted {
+				_, found, _ := c.Get(ctx, key)
+				assert.False(t, found, key)
+			}
+		})
+	}
+}
+
+func TestTTL(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, time.May, 15, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		elapsed time.Duration
+		want    bool
+	}{
+		{name: "fresh", ttl: time.Minute, elapsed: 59 * time.Second, want: true},
+		{name: "expired", ttl: time.Minute, elapsed: time.Minute},
+		{name: "no ttl", ttl: 0, elapsed: 24 * time.Hour, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := start
+			c := New(0)
+			c.now = func() time.Time { return now }
+
+			require.NoError(t, c.Set(ctx, "key", []byte("value"), tt.ttl))
+
+			now = now.Add(tt.elapsed)
+
+			_, found, err := c.Get(ctx, "key")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, found)
+
+			// Expired entries are removed when they are read.
+			if !tt.want {
+				assert.Equal(t, 0, c.Len())
+			}
+		})
+	}
+}
+
+func TestSetCopiesValue(t *testing.T) {
+	ctx := context.Background()
+	c := New(0)
+
+	value := []byte("value")
+	require.NoError(t, c.Set(ctx, "key", value, 0))
+	value[0] = 'V'
+
+	v, _, _ := c.Get(ctx, "key")
+	assert.Equal(t, []byte("value"), v)
+}
+
+func TestGetWithTTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	c := New(0)
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, c.Set(ctx, "expiring", []byte("v"), time.Minute))
+	require.NoError(t, c.Set(ctx, "forever", []byte("v"), 0))
+
+	now = now.Add(20 * time.Second)
+
+	_, ttl, found, err := c.GetWithTTL(ctx, "expiring")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, 40*time.Second, ttl)
+
+	_, ttl, found, _ = c.GetWithTTL(ctx, "forever")
+	require.True(t, found)
+	assert.Zero(t, ttl, "an entry without an expiry has a ttl")
+
+	now = now.Add(40 * time.Second)
+
+	_, _, found, _ = c.GetWithTTL(ctx, "expiring")
+	assert.False(t, found)
+	assert.Equal(t, 1, c.Len(), "the expired entry is not removed")
+}
@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Broker spreads cache invalidations between processes over a Redis
+// pub/sub channel.
+type Broker struct {
+	client  *redis.Client
+	channel string
+}
+
+func NewBroker(client *redis.Client, channel string) *Broker {
+	return &Broker{client: client, channel: channel}
+}
+
+// Publish notifies the subscribers that the key was deleted.
+func (b *Broker) Publish(ctx context.Context, key string) error {
+	return b.client.Publish(ctx, b.channel, key).Err()
+}
+
+// Subscribe calls onKey for every published key until ctx is done.
+//
+// Messages published while the connection is down are lost, so onReset is
+// called every time the subscription is (re)established and after every
+// receive error. Subscribers are expected to drop everything they hold.
+func (b *Broker) Subscribe(ctx context.Context, onKey func(key string), onReset func()) error {
+	sub := b.client.Subscribe(ctx, b.channel)
+	defer sub.Close()
+
+	for {
+		msg, err := sub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			onReset()
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			onReset()
+		case *redis.Message:
+			onKey(m.Payload)
+		}
+	}
+}
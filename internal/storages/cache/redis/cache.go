@@ -35,6 +35,40 @@ func (s *Cache) Get(ctx context.Context, key string) ([]byte, bool, error) {
 	return res, true, nil
 }
 
+// GetWithTTL returns the value of the key and its PTTL, read in one
+// transaction. A key without an expiry has zero ttl.
+func (s *Cache) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, bool, error) {
+	var (
+		get  *redis.StringCmd
+		pttl *redis.DurationCmd
+	)
+
+	_, err := s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		get = p.Get(ctx, key)
+		pttl = p.PTTL(ctx, key)
+		return nil
+	})
+	if errors.Is(get.Err(), redis.Nil) {
+		return nil, 0, false, nil
+	}
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	res, err := get.Bytes()
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	// PTTL is negative for the keys without an expiry.
+	ttl := pttl.Val()
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	return res, ttl, true, nil
+}
+
 func (s *Cache) Del(ctx context.Context, key string) error {
 	if err := s.client.Del(ctx, key).Err(); err != nil {
 		if errors.Is(err, redis.Nil) {
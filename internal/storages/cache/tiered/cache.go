@@ -0,0 +1,204 @@
+package tiered
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/storages/cache"
+	"github.com/romankravchuk/eldorado/internal/storages/cache/memory"
+)
+
+// Broker spreads the deleted keys to the other processes sharing the remote cache.
+type Broker interface {
+	Publish(ctx context.Context, key string) error
+	Subscribe(ctx context.Context, onKey func(key string), onReset func()) error
+}
+
+// epochStripes is the number of the epoch counters of the keys.
+const epochStripes = 1024
+
+// epochs are bumped whenever the local copy of a key is written or
+// dropped. A remote hit is copied to local memory only if the epoch of its
+// key has not changed since the remote read started, so a read that began
+// before a Del does not bring the deleted value back. The keys share the
+// counters by their hash, a collision only skips a local fill.
+type epochs [epochStripes]atomic.Uint64
+
+func (e *epochs) of(key string) *atomic.Uint64 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &e[h.Sum32()%epochStripes]
+}
+
+type Option func(*Cache)
+
+// WithLocalTTL limits how long an entry is kept in local memory.
+// Defaults to 30 seconds.
+func WithLocalTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.localTTL = ttl
+	}
+}
+
+// WithBroker drops local entries deleted by the other processes.
+// Without a broker local entries live until they expire.
+func WithBroker(b Broker) Option {
+	return func(c *Cache) {
+		c.broker = b
+	}
+}
+
+// Cache is a two-tier cache.Cache: the in-process memory in front of
+// a shared remote cache.
+type Cache struct {
+	local    *memory.Cache
+	remote   cache.Cache
+	localTTL time.Duration
+	broker   Broker
+
+	// mu makes the epoch check and the local fill of Get atomic with the
+	// bump and the local write of the others. resets counts the flushes.
+	mu     sync.Mutex
+	epochs epochs
+	resets atomic.Uint64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New returns a two-tier cache and, if a broker is given, subscribes to
+// the invalidations of the other processes until Close is called.
+func New(local *memory.Cache, remote cache.Cache, opts ...Option) *Cache {
+	c := &Cache{
+		local:    local,
+		remote:   remote,
+		localTTL: 30 * time.Second,
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	go func() {
+		defer close(c.done)
+
+		if c.broker == nil {
+			return
+		}
+
+		_ = c.broker.Subscribe(ctx,
+			func(key string) { c.dropLocal(ctx, key) },
+			c.flushLocal,
+		)
+	}()
+
+	return c
+}
+
+func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := c.remote.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epochs.of(key).Add(1)
+
+	return c.local.Set(ctx, key, value, c.localTTLFor(ttl))
+}
+
+// Get returns the value from local memory or, on a local miss, from the
+// remote cache. Remote hits are copied to local memory, unless the key
+// was written or deleted while the remote read was in flight. The local
+// copy expires with the remote entry if it has less than the local TTL
+// left, which is known if the remote cache is a cache.TTLCache.
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if v, found, _ := c.local.Get(ctx, key); found {
+		return v, true, nil
+	}
+
+	epoch := c.epoch(key)
+
+	v, ttl, found, err := c.remoteGet(ctx, key)
+	if err != nil || !found {
+		return nil, false, err
+	}
+
+	c.mu.Lock()
+	if c.epoch(key) == epoch {
+		_ = c.local.Set(ctx, key, v, c.localTTLFor(ttl))
+	}
+	c.mu.Unlock()
+
+	return v, true, nil
+}
+
+// Del deletes the key from both tiers and tells the other processes to
+// drop their local copies.
+func (c *Cache) Del(ctx context.Context, key string) error {
+	c.dropLocal(ctx, key)
+
+	if err := c.remote.Del(ctx, key); err != nil {
+		return err
+	}
+
+	if c.broker != nil {
+		return c.broker.Publish(ctx, key)
+	}
+
+	return nil
+}
+
+// Close stops receiving the invalidations.
+func (c *Cache) Close() error {
+	c.cancel()
+	<-c.done
+	return nil
+}
+
+// remoteGet reads the key from the remote cache with its time left to
+// live, zero if it is not known.
+func (c *Cache) remoteGet(ctx context.Context, key string) ([]byte, time.Duration, bool, error) {
+	if r, ok := c.remote.(cache.TTLCache); ok {
+		return r.GetWithTTL(ctx, key)
+	}
+
+	v, found, err := c.remote.Get(ctx, key)
+	return v, 0, found, err
+}
+
+// epoch changes whenever the local copy of the key is written or dropped.
+// Both counters only grow, so their sum does too.
+func (c *Cache) epoch(key string) uint64 {
+	return c.epochs.of(key).Load() + c.resets.Load()
+}
+
+func (c *Cache) dropLocal(ctx context.Context, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epochs.of(key).Add(1)
+	_ = c.local.Del(ctx, key)
+}
+
+func (c *Cache) flushLocal() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.resets.Add(1)
+	c.local.Flush()
+}
+
+func (c *Cache) localTTLFor(ttl time.Duration) time.Duration {
+	if ttl > 0 && ttl < c.localTTL {
+		return ttl
+	}
+	return c.localTTL
+}
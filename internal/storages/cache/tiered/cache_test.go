@@ -0,0 +1,185 @@
+package tiered
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/storages/cache/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingRemote is a remote cache whose reads, once blocked, wait for
+// release after they have read the value.
+type blockingRemote struct {
+	*memory.Cache
+	block   bool
+	read    chan struct{}
+	release chan struct{}
+}
+
+func newBlockingRemote() *blockingRemote {
+	return &blockingRemote{
+		Cache:   memory.New(0),
+		read:    make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (r *blockingRemote) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	v, _, found, err := r.GetWithTTL(ctx, key)
+	return v, found, err
+}
+
+func (r *blockingRemote) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, bool, error) {
+	v, ttl, found, err := r.Cache.GetWithTTL(ctx, key)
+	if r.block {
+		close(r.read)
+		<-r.release
+	}
+	return v, ttl, found, err
+}
+
+// resetBroker hands out the callbacks of the subscription.
+type resetBroker struct {
+	subscribed chan struct{}
+	onKey      func(string)
+	onReset    func()
+}
+
+func (b *resetBroker) Publish(context.Context, string) error {
+	return nil
+}
+
+func (b *resetBroker) Subscribe(ctx context.Context, onKey func(string), onReset func()) error {
+	b.onKey, b.onReset = onKey, onReset
+	close(b.subscribed)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestGetDuringInvalidation(t *testing.T) {
+	const key = "tasks:user"
+
+	tests := []struct {
+		name       string
+		invalidate func(t *testing.T, c *Cache, b *resetBroker)
+		wantLocal  bool
+	}{
+		{
+			name:       "no invalidation",
+			invalidate: func(*testing.T, *Cache, *resetBroker) {},
+			wantLocal:  true,
+		},
+		{
+			name: "del",
+			invalidate: func(t *testing.T, c *Cache, _ *resetBroker) {
+				require.NoError(t, c.Del(context.Background(), key))
+			},
+		},
+		{
+			name:       "del by another process",
+			invalidate: func(_ *testing.T, _ *Cache, b *resetBroker) { b.onKey(key) },
+		},
+		{
+			name:       "reset",
+			invalidate: func(_ *testing.T, _ *Cache, b *resetBroker) { b.onReset() },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			local := memory.New(0)
+			remote := newBlockingRemote()
+			broker := &resetBroker{subscribed: make(chan struct{})}
+
+			c := New(local, remote, WithBroker(broker))
+			defer c.Close()
+			<-broker.subscribed
+
+			require.NoError(t, remote.Set(ctx, key, []byte("old"), time.Minute))
+			remote.block = true
+
+			got := make(chan []byte)
+			go func() {
+				v, _, _ := c.Get(ctx, key)
+				got <- v
+			}()
+
+			// The value is read, the local fill has not happened yet.
+			<-remote.read
+			tt.invalidate(t, c, broker)
+			close(remote.release)
+
+			assert.Equal(t, []byte("old"), <-got)
+
+			_, found, _ := local.Get(ctx, key)
+			assert.Equal(t, tt.wantLocal, found)
+		})
+	}
+}
+
+func TestSetDuringGet(t *testing.T) {
+	const key = "tasks:user"
+	ctx := context.Background()
+
+	local := memory.New(0)
+	remote := newBlockingRemote()
+	c := New(local, remote)
+	defer c.Close()
+
+	require.NoError(t, remote.Set(ctx, key, []byte("old"), time.Minute))
+	remote.block = true
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _, _ = c.Get(ctx, key)
+	}()
+
+	<-remote.read
+	require.NoError(t, c.Set(ctx, key, []byte("new"), time.Minute))
+	close(remote.release)
+	<-done
+
+	v, found, _ := local.Get(ctx, key)
+	require.True(t, found)
+	assert.Equal(t, []byte("new"), v)
+}
+
+func TestLocalTTL(t *testing.T) {
+	const key = "tasks:user"
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		remoteTTL time.Duration
+		wantMax   time.Duration
+	}{
+		{name: "remote expires first", remoteTTL: 5 * time.Second, wantMax: 5 * time.Second},
+		{name: "local expires first", remoteTTL: time.Hour, wantMax: time.Minute},
+		{name: "remote never expires", remoteTTL: 0, wantMax: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local, remote := memory.New(0), memory.New(0)
+			c := New(local, remote, WithLocalTTL(time.Minute))
+			defer c.Close()
+
+			require.NoError(t, remote.Set(ctx, key, []byte("list"), tt.remoteTTL))
+
+			_, found, err := c.Get(ctx, key)
+			require.NoError(t, err)
+			require.True(t, found)
+
+			_, ttl, found, _ := local.GetWithTTL(ctx, key)
+			require.True(t, found, "the remote hit is not copied to local memory")
+			assert.LessOrEqual(t, ttl, tt.wantMax, "the local copy outlives the remote entry")
+			assert.Greater(t, ttl, tt.wantMax-time.Second)
+		})
+	}
+}
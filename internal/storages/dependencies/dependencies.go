@@ -0,0 +1,25 @@
+package dependencies
+
+import (
+	"context"
+	"errors"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+)
+
+var (
+	ErrNotFound      = errors.New("the dependency not found")
+	ErrAlreadyExists = errors.New("the dependency already exists")
+	ErrCycle         = errors.New("the dependency would create a cycle")
+)
+
+// Storage keeps "task is blocked by task" edges.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name Storage
+type Storage interface {
+	Add(ctx context.Context, taskID, blockerID string) error
+	Remove(ctx context.Context, taskID, blockerID string) error
+	Blockers(ctx context.Context, taskID string) ([]data.Task, error)
+	Dependents(ctx context.Context, taskID string) ([]data.Task, error)
+	HasOpenBlockers(ctx context.Context, taskID string) (bool, error)
+}
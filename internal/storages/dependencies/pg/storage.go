@@ -0,0 +1,279 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/envelope"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/dependencies"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+)
+
+const checkViolationCode = "23514"
+
+// DependenciesStorage is a postgres implementation of dependencies.Storage.
+//
+// The dependencies are not under the row-level security themselves, but
+// the tasks they join are, so every call is scoped to the workspace of the
+// context, see storages.Scoped.
+type DependenciesStorage struct {
+	db       *sql.DB
+	stmts    *storages.Statements
+	replicas *storages.Replicas
+	keyring  *envelope.Keyring
+}
+
+type Option func(*DependenciesStorage)
+
+// WithReplicas pins the reads to the primary after the dependencies
+// change, see storages.WithReadYourWrites. The dependencies themselves
+// are always read from the primary.
+func WithReplicas(replicas *storages.Replicas) Option {
+	return func(s *DependenciesStorage) {
+		s.replicas = replicas
+	}
+}
+
+// WithKeyring decrypts the titles of the tasks encrypted by the tasks
+// storage.
+func WithKeyring(k *envelope.Keyring) Option {
+	return func(s *DependenciesStorage) {
+		s.keyring = k
+	}
+}
+
+// New returns new DependenciesStorage instance with postgres db pool.
+//
+// If db is nil returns storages.ErrNilDBPool. If there is no keyring and
+// some tasks are encrypted returns storages.ErrNoKeyring.
+func New(db *sql.DB, opts ...Option) (*DependenciesStorage, error) {
+	if db == nil {
+		return nil, storages.ErrNilDBPool
+	}
+
+	s := &DependenciesStorage{db: db, stmts: storages.NewStatements(db)}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := storages.CheckKeyring(db, s.keyring, "tasks"); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Add marks the task as blocked by the blocker. It joins the transaction
+// of ctx, if there is one, see storages.RunInTx.
+//
+// Both tasks must belong to the same user, otherwise returns tasks.ErrNotFound.
+// If the blocker already depends on the task, directly or transitively,
+// returns dependencies.ErrCycle. The check and the insert run in one
+// transaction under an advisory lock of the owner, so concurrent requests
+// can not create a cycle either.
+func (s *DependenciesStorage) Add(ctx context.Context, taskID, blockerID string) error {
+	if taskID == blockerID {
+		return dependencies.ErrCycle
+	}
+
+	err := storages.ScopedTx(ctx, s.stmts, func(ctx context.Context, tx *sql.Tx) error {
+		return s.add(ctx, tx, taskID, blockerID)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.replicas.Wrote(ctx)
+
+	return nil
+}
+
+// add checks and inserts the dependency in tx.
+func (s *DependenciesStorage) add(ctx context.Context, tx *sql.Tx, taskID, blockerID string) error {
+	const (
+		ownerQuery  = "SELECT user_id FROM tasks WHERE id IN ($1, $2) AND is_deleted = false"
+		lockQuery   = "SELECT pg_advisory_xact_lock(hashtext($1))"
+		cycleQuery  = "WITH RECURSIVE reach (id) AS (SELECT blocked_by_id FROM task_dependencies WHERE task_id = $1 UNION SELECT d.blocked_by_id FROM task_dependencies d JOIN reach r ON d.task_id = r.id) SELECT EXISTS (SELECT 1 FROM reach WHERE id = $2)"
+		insertQuery = "INSERT INTO task_dependencies (task_id, blocked_by_id) VALUES ($1, $2)"
+	)
+
+	rows, err := tx.QueryContext(ctx, ownerQuery, taskID, blockerID)
+	if err != nil {
+		return err
+	}
+
+	var owners []string
+	for rows.Next() {
+		var owner string
+		if err = rows.Scan(&owner); err != nil {
+			break
+		}
+		owners = append(owners, owner)
+	}
+
+	if closeErr := rows.Close(); closeErr != nil {
+		return closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	// A missing task, or one of another workspace hidden by the row-level
+	// security, or two different owners.
+	if len(owners) != 2 || owners[0] != owners[1] {
+		return tasks.ErrNotFound
+	}
+
+	if _, err = tx.ExecContext(ctx, lockQuery, owners[0]); err != nil {
+		return err
+	}
+
+	var cycle bool
+	if err = tx.QueryRowContext(ctx, cycleQuery, blockerID, taskID).Scan(&cycle); err != nil {
+		return err
+	}
+
+	if cycle {
+		return dependencies.ErrCycle
+	}
+
+	if _, err = tx.ExecContext(ctx, insertQuery, taskID, blockerID); err != nil {
+		if psqlErr, ok := err.(*pq.Error); ok {
+			switch psqlErr.Code {
+			case storages.UniqueViolationCode:
+				return dependencies.ErrAlreadyExists
+			case checkViolationCode:
+				return dependencies.ErrCycle
+			}
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Remove removes the dependency.
+//
+// If count of affected rows is not 1 returns dependencies.ErrNotFound.
+func (s *DependenciesStorage) Remove(ctx context.Context, taskID, blockerID string) error {
+	const query = "DELETE FROM task_dependencies WHERE task_id = $1 AND blocked_by_id = $2"
+
+	err := storages.Scoped(ctx, s.stmts, func(ctx context.Context, _ *sql.Tx) error {
+		stmt, err := s.stmts.Prepare(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		res, err := stmt.ExecContext(ctx, taskID, blockerID)
+		if err != nil {
+			return err
+		}
+
+		count, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if count != 1 {
+			return dependencies.ErrNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.replicas.Wrote(ctx)
+
+	return nil
+}
+
+// Blockers returns the tasks the given task is blocked by.
+func (s *DependenciesStorage) Blockers(ctx context.Context, taskID string) ([]data.Task, error) {
+	const query = "SELECT t.id, t.user_id, t.title, t.key_id, t.data_key, t.is_completed, t.created_on FROM task_dependencies d JOIN tasks t ON t.id = d.blocked_by_id WHERE d.task_id = $1 AND t.is_deleted = false ORDER BY t.created_on"
+
+	return s.findTasks(ctx, query, taskID)
+}
+
+// Dependents returns the tasks blocked by the given task.
+func (s *DependenciesStorage) Dependents(ctx context.Context, taskID string) ([]data.Task, error) {
+	const query = "SELECT t.id, t.user_id, t.title, t.key_id, t.data_key, t.is_completed, t.created_on FROM task_dependencies d JOIN tasks t ON t.id = d.task_id WHERE d.blocked_by_id = $1 AND t.is_deleted = false ORDER BY t.created_on"
+
+	return s.findTasks(ctx, query, taskID)
+}
+
+// HasOpenBlockers reports whether the task is blocked by an uncompleted task.
+func (s *DependenciesStorage) HasOpenBlockers(ctx context.Context, taskID string) (blocked bool, err error) {
+	const query = "SELECT EXISTS (SELECT 1 FROM task_dependencies d JOIN tasks t ON t.id = d.blocked_by_id WHERE d.task_id = $1 AND t.is_completed = false AND t.is_deleted = false)"
+
+	err = storages.Scoped(ctx, s.stmts, func(ctx context.Context, _ *sql.Tx) error {
+		stmt, err := s.stmts.Prepare(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		return stmt.QueryRowContext(ctx, taskID).Scan(&blocked)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return blocked, nil
+}
+
+func (s *DependenciesStorage) findTasks(ctx context.Context, query, taskID string) (tt []data.Task, err error) {
+	err = storages.Scoped(ctx, s.stmts, func(ctx context.Context, _ *sql.Tx) error {
+		stmt, err := s.stmts.Prepare(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		rows, err := stmt.QueryContext(ctx, taskID)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var (
+				t       data.Task
+				keyID   sql.NullString
+				dataKey []byte
+			)
+			if err = rows.Scan(&t.ID, &t.UserID, &t.Title, &keyID, &dataKey, &t.IsCompleted, &t.CreatedOn); err != nil {
+				break
+			}
+			c := storages.Contents{Title: t.Title, KeyID: keyID.String, DataKey: dataKey}
+			if t.Title, _, err = c.Open(s.keyring); err != nil {
+				err = fmt.Errorf("task %s: %w", t.ID, err)
+				break
+			}
+			tt = append(tt, t)
+		}
+
+		if closeErr := rows.Close(); closeErr != nil {
+			return closeErr
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tt, nil
+}
@@ -0,0 +1,208 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/dependencies"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// DependenciesStorage is a sqlite implementation of dependencies.Storage.
+type DependenciesStorage struct {
+	db    *sql.DB
+	stmts *storages.Statements
+}
+
+// New returns new DependenciesStorage instance with sqlite db pool,
+// see storages.NewSQLitePool.
+//
+// If db is nil returns storages.ErrNilDBPool.
+func New(db *sql.DB) (*DependenciesStorage, error) {
+	if db == nil {
+		return nil, storages.ErrNilDBPool
+	}
+
+	return &DependenciesStorage{db: db, stmts: storages.NewStatements(db)}, nil
+}
+
+// Add marks the task as blocked by the blocker. It joins the transaction
+// of ctx, if there is one, see storages.RunInTx.
+//
+// Both tasks must belong to the same user, otherwise returns tasks.ErrNotFound.
+// If the blocker already depends on the task, directly or transitively,
+// returns dependencies.ErrCycle. The check and the insert run in one
+// transaction, which holds the database write lock from its start, so
+// concurrent requests can not create a cycle either.
+func (s *DependenciesStorage) Add(ctx context.Context, taskID, blockerID string) error {
+	if taskID == blockerID {
+		return dependencies.ErrCycle
+	}
+
+	return storages.RunInTx(ctx, s.db, func(ctx context.Context, tx *sql.Tx) error {
+		return s.add(ctx, tx, taskID, blockerID)
+	})
+}
+
+// add checks and inserts the dependency in tx.
+func (s *DependenciesStorage) add(ctx context.Context, tx *sql.Tx, taskID, blockerID string) error {
+	const (
+		ownerQuery  = "SELECT user_id FROM tasks WHERE id IN ($1, $2) AND is_deleted = false"
+		cycleQuery  = "WITH RECURSIVE reach (id) AS (SELECT blocked_by_id FROM task_dependencies WHERE task_id = $1 UNION SELECT d.blocked_by_id FROM task_dependencies d JOIN reach r ON d.task_id = r.id) SELECT EXISTS (SELECT 1 FROM reach WHERE id = $2)"
+		insertQuery = "INSERT INTO task_dependencies (task_id, blocked_by_id) VALUES ($1, $2)"
+	)
+
+	rows, err := tx.QueryContext(ctx, ownerQuery, taskID, blockerID)
+	if err != nil {
+		return err
+	}
+
+	var owners []string
+	for rows.Next() {
+		var owner string
+		if err = rows.Scan(&owner); err != nil {
+			break
+		}
+		owners = append(owners, owner)
+	}
+
+	if closeErr := rows.Close(); closeErr != nil {
+		return closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	// A missing task or two different owners.
+	if len(owners) != 2 || owners[0] != owners[1] {
+		return tasks.ErrNotFound
+	}
+
+	var cycle bool
+	if err = tx.QueryRowContext(ctx, cycleQuery, blockerID, taskID).Scan(&cycle); err != nil {
+		return err
+	}
+
+	if cycle {
+		return dependencies.ErrCycle
+	}
+
+	if _, err = tx.ExecContext(ctx, insertQuery, taskID, blockerID); err != nil {
+		switch {
+		case storages.IsSQLiteUniqueViolation(err):
+			return dependencies.ErrAlreadyExists
+		case storages.IsSQLiteConstraint(err, sqlite3.SQLITE_CONSTRAINT_CHECK):
+			return dependencies.ErrCycle
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Remove removes the dependency.
+//
+// If count of affected rows is not 1 returns dependencies.ErrNotFound.
+func (s *DependenciesStorage) Remove(ctx context.Context, taskID, blockerID string) error {
+	const query = "DELETE FROM task_dependencies WHERE task_id = $1 AND blocked_by_id = $2"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	res, err := stmt.ExecContext(ctx, taskID, blockerID)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count != 1 {
+		return dependencies.ErrNotFound
+	}
+
+	return nil
+}
+
+// Blockers returns the tasks the given task is blocked by.
+func (s *DependenciesStorage) Blockers(ctx context.Context, taskID string) ([]data.Task, error) {
+	const query = "SELECT t.id, t.user_id, t.title, t.is_completed, t.created_on FROM task_dependencies d JOIN tasks t ON t.id = d.blocked_by_id WHERE d.task_id = $1 AND t.is_deleted = false ORDER BY t.created_on"
+
+	return s.findTasks(ctx, query, taskID)
+}
+
+// Dependents returns the tasks blocked by the given task.
+func (s *DependenciesStorage) Dependents(ctx context.Context, taskID string) ([]data.Task, error) {
+	const query = "SELECT t.id, t.user_id, t.title, t.is_completed, t.created_on FROM task_dependencies d JOIN tasks t ON t.id = d.task_id WHERE d.blocked_by_id = $1 AND t.is_deleted = false ORDER BY t.created_on"
+
+	return s.findTasks(ctx, query, taskID)
+}
+
+// HasOpenBlockers reports whether the task is blocked by an uncompleted task.
+func (s *DependenciesStorage) HasOpenBlockers(ctx context.Context, taskID string) (bool, error) {
+	const query = "SELECT EXISTS (SELECT 1 FROM task_dependencies d JOIN tasks t ON t.id = d.blocked_by_id WHERE d.task_id = $1 AND t.is_completed = false AND t.is_deleted = false)"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return false, err
+	}
+
+	var blocked bool
+	if err = stmt.QueryRowContext(ctx, taskID).Scan(&blocked); err != nil {
+		return false, err
+	}
+
+	return blocked, nil
+}
+
+func (s *DependenciesStorage) findTasks(ctx context.Context, query, taskID string) ([]data.Task, error) {
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := stmt.QueryContext(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	var tt []data.Task
+	for rows.Next() {
+		var (
+			t         data.Task
+			createdOn storages.SQLiteTime
+		)
+		if err = rows.Scan(&t.ID, &t.UserID, &t.Title, &t.IsCompleted, &createdOn); err != nil {
+			break
+		}
+		t.CreatedOn = createdOn.Time
+		tt = append(tt, t)
+	}
+
+	if closeErr := rows.Close(); closeErr != nil {
+		return nil, closeErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tt, nil
+}
@@ -0,0 +1,37 @@
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/romankravchuk/eldorado/internal/storages/dependencies/sqlite"
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+	taskssqlite "github.com/romankravchuk/eldorado/internal/storages/tasks/sqlite"
+	userssqlite "github.com/romankravchuk/eldorado/internal/storages/users/sqlite"
+	workspacessqlite "github.com/romankravchuk/eldorado/internal/storages/workspaces/sqlite"
+)
+
+func TestConformance(t *testing.T) {
+	db := storagetest.SQLite(t)
+
+	users, err := userssqlite.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workspaces, err := workspacessqlite.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := taskssqlite.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deps, err := sqlite.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storagetest.Dependencies(t, deps, tasks, users, workspaces)
+}
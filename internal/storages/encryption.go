@@ -0,0 +1,218 @@
+package storages
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/romankravchuk/eldorado/internal/pkg/envelope"
+)
+
+// CheckKeyring is called by the constructors of the storages of the
+// encrypted contents. A storage without a keyring would store the contents
+// in plaintext and fail to read the encrypted ones, so if k is nil and
+// the table has an encrypted row it returns ErrNoKeyring. The rows of all
+// the workspaces are checked.
+func CheckKeyring(db *sql.DB, k *envelope.Keyring, table string) error {
+	if k != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(AllWorkspaces(context.Background()), PrepareTimeout)
+	defer cancel()
+
+	var encrypted bool
+
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM "+table+" WHERE key_id IS NOT NULL)").Scan(&encrypted)
+	if err != nil {
+		return fmt.Errorf("failed to check the encryption of %s: %w", table, err)
+	}
+
+	if encrypted {
+		return fmt.Errorf("%w: %s", ErrNoKeyring, table)
+	}
+
+	return nil
+}
+
+// Contents are the title and the description of a task or a template as
+// they are stored. An empty KeyID means they are stored in plaintext,
+// otherwise they are sealed with the data key DataKey wrapped with the
+// master key KeyID.
+type Contents struct {
+	Title       string
+	Description string
+	KeyID       string
+	DataKey     []byte
+}
+
+// SealContents encrypts the title and the description with a new data key
+// wrapped with the primary key of k. Without the primary key they are
+// stored in plaintext.
+func SealContents(k *envelope.Keyring, title, description string) (Contents, error) {
+	if k == nil || k.Primary() == "" {
+		return Contents{Title: title, Description: description}, nil
+	}
+
+	dk, err := k.NewDataKey()
+	if err != nil {
+		return Contents{}, err
+	}
+
+	c := Contents{KeyID: dk.KeyID, DataKey: dk.Wrapped}
+
+	if c.Title, err = dk.Seal("title", title); err != nil {
+		return Contents{}, err
+	}
+
+	if c.Description, err = dk.Seal("description", description); err != nil {
+		return Contents{}, err
+	}
+
+	return c, nil
+}
+
+// Open returns the decrypted title and description. The plaintext contents
+// are returned as is, as well as the empty fields, that were not selected.
+//
+// If k has not the key of the contents returns envelope.ErrUnknownKey.
+func (c Contents) Open(k *envelope.Keyring) (title, description string, err error) {
+	if c.KeyID == "" {
+		return c.Title, c.Description, nil
+	}
+
+	dk, err := k.Unwrap(c.KeyID, c.DataKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	if title = c.Title; title != "" {
+		if title, err = dk.Open("title", title); err != nil {
+			return "", "", err
+		}
+	}
+
+	if description = c.Description; description != "" {
+		if description, err = dk.Open("description", description); err != nil {
+			return "", "", err
+		}
+	}
+
+	return title, description, nil
+}
+
+// RecryptContents is the rewrite of RewriteContents that encrypts the
+// contents under the primary key of k. The plaintext contents are
+// encrypted with a new data key, the data keys wrapped with the other keys
+// are rewrapped with the primary key.
+//
+// If k has no primary key returns envelope.ErrNoPrimaryKey.
+func RecryptContents(k *envelope.Keyring) (func(c *Contents) error, error) {
+	if k == nil || k.Primary() == "" {
+		return nil, envelope.ErrNoPrimaryKey
+	}
+
+	return func(c *Contents) error {
+		if c.KeyID == "" {
+			sealed, err := SealContents(k, c.Title, c.Description)
+			*c = sealed
+			return err
+		}
+
+		dk, err := k.Rewrap(c.KeyID, c.DataKey)
+		if err != nil {
+			return err
+		}
+		c.KeyID, c.DataKey = dk.KeyID, dk.Wrapped
+
+		return nil
+	}, nil
+}
+
+// DecryptContents is the rewrite of RewriteContents that stores the
+// contents in plaintext.
+func DecryptContents(k *envelope.Keyring) func(c *Contents) error {
+	return func(c *Contents) error {
+		title, description, err := c.Open(k)
+		*c = Contents{Title: title, Description: description}
+		return err
+	}
+}
+
+// RewriteContents selects the contents of the rows with selectQuery,
+// changes them with rewrite and stores them back with updateQuery, in one
+// transaction of the pool of stmts. selectQuery returns the id, title,
+// description, key_id and data_key columns, updateQuery takes the title,
+// the description, the key ID, empty for NULL, the data key and the id.
+//
+// Returns the number of rewritten rows.
+func RewriteContents(ctx context.Context, stmts *Statements, selectQuery, updateQuery string, args []any, rewrite func(c *Contents) error) (int, error) {
+	var count int
+
+	err := RunInTx(ctx, stmts.db, func(ctx context.Context, _ *sql.Tx) error {
+		stmt, err := stmts.Prepare(ctx, selectQuery)
+		if err != nil {
+			return err
+		}
+
+		rows, err := stmt.QueryContext(ctx, args...)
+		if err != nil {
+			return err
+		}
+
+		var (
+			ids      []string
+			contents []Contents
+		)
+		for rows.Next() {
+			var (
+				id    string
+				c     Contents
+				keyID sql.NullString
+			)
+			if err = rows.Scan(&id, &c.Title, &c.Description, &keyID, &c.DataKey); err != nil {
+				break
+			}
+			c.KeyID = keyID.String
+			ids = append(ids, id)
+			contents = append(contents, c)
+		}
+
+		if closeErr := rows.Close(); closeErr != nil {
+			return closeErr
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		update, err := stmts.Prepare(ctx, updateQuery)
+		if err != nil {
+			return err
+		}
+
+		for i := range contents {
+			if err = rewrite(&contents[i]); err != nil {
+				return fmt.Errorf("failed to rewrite %s: %w", ids[i], err)
+			}
+
+			c := contents[i]
+			if _, err = update.ExecContext(ctx, c.Title, c.Description, c.KeyID, c.DataKey, ids[i]); err != nil {
+				return err
+			}
+		}
+
+		count = len(contents)
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -2,4 +2,13 @@ package storages
 
 import "errors"
 
-var ErrNilDBPool = errors.New("the db pool could not be nil")
+var (
+	ErrNilDBPool     = errors.New("the db pool could not be nil")
+	ErrUnknownDriver = errors.New("unknown database driver")
+	// ErrReplicasUnsupported is returned when read replicas are set for
+	// a driver other than postgres.
+	ErrReplicasUnsupported = errors.New("read replicas are supported by postgres only")
+	// ErrNoKeyring is returned by the constructors of the storages without
+	// a keyring for a table that has encrypted contents.
+	ErrNoKeyring = errors.New("the contents are encrypted but the encryption keys are not set")
+)
@@ -0,0 +1,41 @@
+package exports
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+)
+
+var (
+	ErrNotFound = errors.New("the export not found")
+	// ErrInProgress is returned by Create for a user that has an export
+	// pending or being built.
+	ErrInProgress = errors.New("the export is in progress")
+)
+
+// Storage keeps the data exports of the users until they expire.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name Storage
+type Storage interface {
+	// Create stores a pending export and fills its ID and CreatedOn. A
+	// user has at most one export pending or being built.
+	//
+	// If the user has one returns ErrInProgress.
+	Create(ctx context.Context, e *data.Export) error
+	// Claim marks the oldest pending export as building and returns it.
+	// The exports claimed before staleBefore and not finished are claimed
+	// again, their worker is considered dead. Concurrent workers never
+	// claim the same export.
+	//
+	// If there is no export to claim returns ErrNotFound.
+	Claim(ctx context.Context, staleBefore time.Time) (data.Export, error)
+	// Finish stores the status, the archive and the expiry of the export.
+	Finish(ctx context.Context, e data.Export) error
+	// FindByID returns the export with its archive.
+	FindByID(ctx context.Context, id string) (data.Export, error)
+	// Purge deletes the exports expired before the given time and returns
+	// their number.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
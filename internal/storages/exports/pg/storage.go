@@ -0,0 +1,150 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/exports"
+)
+
+// ExportsStorage is a postgres implementation of exports.Storage.
+type ExportsStorage struct {
+	stmts *storages.Statements
+}
+
+// New returns new ExportsStorage instance with postgres db pool.
+//
+// If db is nil returns storages.ErrNilDBPool.
+func New(db *sql.DB) (*ExportsStorage, error) {
+	if db == nil {
+		return nil, storages.ErrNilDBPool
+	}
+
+	return &ExportsStorage{stmts: storages.NewStatements(db)}, nil
+}
+
+// Create stores a pending export and fills its ID, Status and CreatedOn.
+//
+// If the user has an export pending or being built returns
+// exports.ErrInProgress.
+func (s *ExportsStorage) Create(ctx context.Context, e *data.Export) error {
+	const query = "INSERT INTO exports (user_id) VALUES ($1) RETURNING id, status, created_on"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	err = stmt.QueryRowContext(ctx, e.UserID).Scan(&e.ID, &e.Status, &e.CreatedOn)
+	if err != nil {
+		if psqlErr, ok := err.(*pq.Error); ok && psqlErr.Code == storages.UniqueViolationCode {
+			return exports.ErrInProgress
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Claim marks the oldest pending export, or building one claimed before
+// staleBefore, as building and returns it without the archive. The rows
+// locked by another worker are skipped.
+//
+// If there is no export to claim returns exports.ErrNotFound.
+func (s *ExportsStorage) Claim(ctx context.Context, staleBefore time.Time) (e data.Export, err error) {
+	const query = "UPDATE exports SET status = 'building', claimed_on = $1 WHERE id = (SELECT id FROM exports WHERE status = 'pending' OR (status = 'building' AND claimed_on < $2) ORDER BY created_on LIMIT 1 FOR UPDATE SKIP LOCKED) RETURNING id, user_id, status, created_on"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return data.Export{}, err
+	}
+
+	err = stmt.QueryRowContext(ctx, time.Now().UTC(), staleBefore.UTC()).Scan(&e.ID, &e.UserID, &e.Status, &e.CreatedOn)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data.Export{}, exports.ErrNotFound
+		}
+
+		return data.Export{}, err
+	}
+
+	return e, nil
+}
+
+// Finish stores the status, the archive and the expiry of the export.
+//
+// If the export is not found returns exports.ErrNotFound.
+func (s *ExportsStorage) Finish(ctx context.Context, e data.Export) error {
+	const query = "UPDATE exports SET status = $2, archive = $3, expires_on = $4 WHERE id = $1"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	res, err := stmt.ExecContext(ctx, e.ID, e.Status, e.Archive, e.ExpiresOn.UTC())
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n != 1 {
+		return exports.ErrNotFound
+	}
+
+	return nil
+}
+
+// FindByID returns the export with its archive.
+//
+// If the export is not found returns exports.ErrNotFound.
+func (s *ExportsStorage) FindByID(ctx context.Context, id string) (e data.Export, err error) {
+	const query = "SELECT id, user_id, status, archive, created_on, expires_on FROM exports WHERE id = $1"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return data.Export{}, err
+	}
+
+	var expiresOn sql.NullTime
+
+	err = stmt.QueryRowContext(ctx, id).Scan(&e.ID, &e.UserID, &e.Status, &e.Archive, &e.CreatedOn, &expiresOn)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data.Export{}, exports.ErrNotFound
+		}
+
+		return data.Export{}, err
+	}
+
+	e.ExpiresOn = expiresOn.Time
+
+	return e, nil
+}
+
+// Purge deletes the exports expired before the given time.
+func (s *ExportsStorage) Purge(ctx context.Context, before time.Time) (int64, error) {
+	const query = "DELETE FROM exports WHERE expires_on < $1"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := stmt.ExecContext(ctx, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,25 @@
+package pg_test
+
+import (
+	"testing"
+
+	"github.com/romankravchuk/eldorado/internal/storages/exports/pg"
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+	userspg "github.com/romankravchuk/eldorado/internal/storages/users/pg"
+)
+
+func TestConformance(t *testing.T) {
+	db := storagetest.Postgres(t)
+
+	users, err := userspg.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exports, err := pg.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storagetest.Exports(t, exports, users)
+}
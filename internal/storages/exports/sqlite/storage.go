@@ -0,0 +1,165 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/exports"
+)
+
+// ExportsStorage is a sqlite implementation of exports.Storage.
+type ExportsStorage struct {
+	stmts *storages.Statements
+}
+
+// New returns new ExportsStorage instance with sqlite db pool,
+// see storages.NewSQLitePool.
+//
+// If db is nil returns storages.ErrNilDBPool.
+func New(db *sql.DB) (*ExportsStorage, error) {
+	if db == nil {
+		return nil, storages.ErrNilDBPool
+	}
+
+	return &ExportsStorage{stmts: storages.NewStatements(db)}, nil
+}
+
+// Create stores a pending export and fills its ID, Status and CreatedOn.
+// SQLite has no uuid generator, so the ID is generated here.
+//
+// If the user has an export pending or being built returns
+// exports.ErrInProgress.
+func (s *ExportsStorage) Create(ctx context.Context, e *data.Export) error {
+	const query = "INSERT INTO exports (id, user_id, status, created_on) VALUES ($1, $2, $3, $4)"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	var (
+		id        = uuid.NewString()
+		createdOn = time.Now().UTC().Truncate(time.Microsecond)
+	)
+
+	if _, err = stmt.ExecContext(ctx, id, e.UserID, data.ExportPending, createdOn); err != nil {
+		if storages.IsSQLiteUniqueViolation(err) {
+			return exports.ErrInProgress
+		}
+
+		return err
+	}
+
+	e.ID = id
+	e.Status = data.ExportPending
+	e.CreatedOn = createdOn
+
+	return nil
+}
+
+// Claim marks the oldest pending export, or building one claimed before
+// staleBefore, as building and returns it without the archive. A write
+// takes the database lock, so concurrent workers take turns.
+//
+// If there is no export to claim returns exports.ErrNotFound.
+func (s *ExportsStorage) Claim(ctx context.Context, staleBefore time.Time) (e data.Export, err error) {
+	const query = "UPDATE exports SET status = 'building', claimed_on = $1 WHERE id = (SELECT id FROM exports WHERE status = 'pending' OR (status = 'building' AND julianday(claimed_on) < julianday($2)) ORDER BY julianday(created_on) LIMIT 1) RETURNING id, user_id, status, created_on"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return data.Export{}, err
+	}
+
+	var createdOn storages.SQLiteTime
+
+	err = stmt.QueryRowContext(ctx, time.Now().UTC(), staleBefore.UTC()).Scan(&e.ID, &e.UserID, &e.Status, &createdOn)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data.Export{}, exports.ErrNotFound
+		}
+
+		return data.Export{}, err
+	}
+
+	e.CreatedOn = createdOn.Time
+
+	return e, nil
+}
+
+// Finish stores the status, the archive and the expiry of the export.
+//
+// If the export is not found returns exports.ErrNotFound.
+func (s *ExportsStorage) Finish(ctx context.Context, e data.Export) error {
+	const query = "UPDATE exports SET status = $2, archive = $3, expires_on = $4 WHERE id = $1"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	res, err := stmt.ExecContext(ctx, e.ID, e.Status, e.Archive, e.ExpiresOn.UTC())
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n != 1 {
+		return exports.ErrNotFound
+	}
+
+	return nil
+}
+
+// FindByID returns the export with its archive.
+//
+// If the export is not found returns exports.ErrNotFound.
+func (s *ExportsStorage) FindByID(ctx context.Context, id string) (e data.Export, err error) {
+	const query = "SELECT id, user_id, status, archive, created_on, expires_on FROM exports WHERE id = $1"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return data.Export{}, err
+	}
+
+	var createdOn, expiresOn storages.SQLiteTime
+
+	err = stmt.QueryRowContext(ctx, id).Scan(&e.ID, &e.UserID, &e.Status, &e.Archive, &createdOn, &expiresOn)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data.Export{}, exports.ErrNotFound
+		}
+
+		return data.Export{}, err
+	}
+
+	e.CreatedOn = createdOn.Time
+	e.ExpiresOn = expiresOn.Time
+
+	return e, nil
+}
+
+// Purge deletes the exports expired before the given time.
+func (s *ExportsStorage) Purge(ctx context.Context, before time.Time) (int64, error) {
+	const query = "DELETE FROM exports WHERE julianday(expires_on) < julianday($1)"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := stmt.ExecContext(ctx, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,60 @@
+package storages
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Lock is a session advisory lock of the database, held by one process at
+// a time. The jobs that every replica of the api schedules but that must
+// run once take it before they run.
+type Lock struct {
+	db     *sql.DB
+	driver string
+	key    int64
+}
+
+// NewLock returns the lock of the key in the database of db.
+func NewLock(db *sql.DB, driver string, key int64) (*Lock, error) {
+	if db == nil {
+		return nil, ErrNilDBPool
+	}
+
+	switch driver {
+	case DriverPostgres, DriverSQLite:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDriver, driver)
+	}
+
+	return &Lock{db: db, driver: driver, key: key}, nil
+}
+
+// TryLock takes the lock without waiting. If the lock is held by another
+// process returns false. The lock is held on a connection of the pool
+// until release is called.
+//
+// SQLite has no advisory locks and its database is served by one process,
+// there the lock is always taken.
+func (l *Lock) TryLock(ctx context.Context) (release func(), ok bool, err error) {
+	if l.driver == DriverSQLite {
+		return func() {}, true, nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&ok); err != nil || !ok {
+		conn.Close()
+		return nil, false, err
+	}
+
+	release = func() {
+		conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", l.key)
+		conn.Close()
+	}
+
+	return release, true, nil
+}
@@ -0,0 +1,58 @@
+package storages_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testLockID int64 = 1934620175530481122
+
+func TestLockSQLite(t *testing.T) {
+	lock, err := storages.NewLock(storagetest.SQLite(t), storages.DriverSQLite, testLockID)
+	require.NoError(t, err)
+
+	release, ok, err := lock.TryLock(context.Background())
+	require.NoError(t, err)
+	assert.True(t, ok)
+	release()
+}
+
+func TestLockPostgres(t *testing.T) {
+	db := storagetest.Postgres(t)
+	ctx := context.Background()
+
+	first, err := storages.NewLock(db, storages.DriverPostgres, testLockID)
+	require.NoError(t, err)
+	second, err := storages.NewLock(db, storages.DriverPostgres, testLockID)
+	require.NoError(t, err)
+
+	release, ok, err := first.TryLock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, ok, err = second.TryLock(ctx)
+	require.NoError(t, err)
+	assert.False(t, ok, "the lock is taken twice")
+
+	release()
+
+	release, ok, err = second.TryLock(ctx)
+	require.NoError(t, err)
+	assert.True(t, ok, "the lock is not released")
+	release()
+
+	assert.Equal(t, 0, db.Stats().InUse, "the connection of the lock is not returned to the pool")
+}
+
+func TestNewLock(t *testing.T) {
+	_, err := storages.NewLock(nil, storages.DriverPostgres, testLockID)
+	assert.ErrorIs(t, err, storages.ErrNilDBPool)
+
+	_, err = storages.NewLock(storagetest.SQLite(t), "mysql", testLockID)
+	assert.ErrorIs(t, err, storages.ErrUnknownDriver)
+}
@@ -0,0 +1,469 @@
+// Package migrations applies the SQL migrations embedded in the db package.
+//
+// The applied version is kept in the schema_migrations table, the same one
+// golang-migrate uses, so databases migrated by it are picked up as is.
+//
+// Postgres and SQLite have their own migrations, see WithDriver.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	schema "github.com/romankravchuk/eldorado/db"
+	"github.com/romankravchuk/eldorado/internal/storages"
+)
+
+// lockID is the key of the advisory lock held while migrating, so
+// concurrent runs wait for each other.
+const lockID int64 = 4315982740117392481
+
+var (
+	ErrDirty          = errors.New("the database is dirty, fix it and run force")
+	ErrNoChange       = errors.New("no change")
+	ErrSchemaMismatch = errors.New("the database schema version does not match the migrations")
+	ErrUnknownVersion = errors.New("unknown migration version")
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is a pair of the up and down SQL scripts of one version.
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is the state of the database schema.
+type Status struct {
+	// Version is the applied version, zero if none was applied.
+	Version uint64
+	Dirty   bool
+	// Latest is the version of the last known migration.
+	Latest  uint64
+	Applied []Migration
+	Pending []Migration
+}
+
+type Option func(*Migrator) error
+
+// WithFS loads the migrations from the dir of fsys instead of the
+// embedded ones.
+func WithFS(fsys fs.FS, dir string) Option {
+	return func(m *Migrator) error {
+		migrations, err := Load(fsys, dir)
+		if err != nil {
+			return err
+		}
+		m.migrations = migrations
+		return nil
+	}
+}
+
+// WithDriver sets the dialect of the database, storages.DriverPostgres or
+// storages.DriverSQLite, and loads its embedded migrations.
+// Defaults to postgres.
+func WithDriver(driver string) Option {
+	return func(m *Migrator) error {
+		dir, ok := dirs[driver]
+		if !ok {
+			return fmt.Errorf("%w: %q", storages.ErrUnknownDriver, driver)
+		}
+		m.driver = driver
+		return WithFS(schema.Migrations, dir)(m)
+	}
+}
+
+// dirs are the directories of the embedded migrations by driver.
+var dirs = map[string]string{
+	storages.DriverPostgres: "migrations",
+	storages.DriverSQLite:   "migrations/sqlite",
+}
+
+// Migrator applies the migrations to a postgres or sqlite database.
+type Migrator struct {
+	db         *sql.DB
+	driver     string
+	migrations []Migration
+}
+
+// New returns a Migrator of the embedded postgres migrations.
+//
+// If db is nil returns storages.ErrNilDBPool.
+func New(db *sql.DB, opts ...Option) (*Migrator, error) {
+	if db == nil {
+		return nil, storages.ErrNilDBPool
+	}
+
+	m := &Migrator{db: db}
+	if err := WithDriver(storages.DriverPostgres)(m); err != nil {
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		if err := opt(m); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Load reads the migrations from the dir of fsys. Files must be named
+// like 000001_name.up.sql and 000001_name.down.sql.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, e := range entries {
+		match := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", e.Name(), err)
+		}
+
+		b, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+
+		if match[3] == "up" {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies all pending migrations and returns the number of applied ones.
+// Every migration runs in its own transaction together with the version
+// update.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		version, dirty, err := readVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return ErrDirty
+		}
+
+		for _, mig := range m.migrations {
+			if mig.Version <= version {
+				continue
+			}
+
+			if err := apply(ctx, conn, mig.Up, mig.Version, true); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			applied++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return applied, err
+	}
+
+	if applied == 0 {
+		return 0, ErrNoChange
+	}
+
+	return applied, nil
+}
+
+// Down reverts the last n applied migrations.
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	if n <= 0 {
+		return 0, ErrNoChange
+	}
+
+	reverted := 0
+
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		version, dirty, err := readVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return ErrDirty
+		}
+
+		for reverted < n && version > 0 {
+			i := m.index(version)
+			if i < 0 {
+				return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+			}
+
+			var prev uint64
+			if i > 0 {
+				prev = m.migrations[i-1].Version
+			}
+
+			mig := m.migrations[i]
+			if err := apply(ctx, conn, mig.Down, prev, prev > 0); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+
+			version = prev
+			reverted++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return reverted, err
+	}
+
+	if reverted == 0 {
+		return 0, ErrNoChange
+	}
+
+	return reverted, nil
+}
+
+// Force sets the version without running any migration and clears the
+// dirty flag. Zero version marks the database as not migrated.
+func (m *Migrator) Force(ctx context.Context, version uint64) error {
+	if version != 0 && m.index(version) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		return apply(ctx, conn, "", version, version > 0)
+	})
+}
+
+// Status returns the applied version and the applied and pending migrations.
+// It only reads the database, without the schema_migrations table every
+// migration is pending.
+func (m *Migrator) Status(ctx context.Context) (Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+	defer conn.Close()
+
+	exists, err := m.tableExists(ctx, conn)
+	if err != nil {
+		return Status{}, err
+	}
+
+	var s Status
+	if exists {
+		if s.Version, s.Dirty, err = readVersion(ctx, conn); err != nil {
+			return Status{}, err
+		}
+	}
+
+	for _, mig := range m.migrations {
+		if mig.Version <= s.Version {
+			s.Applied = append(s.Applied, mig)
+		} else {
+			s.Pending = append(s.Pending, mig)
+		}
+	}
+
+	if len(m.migrations) > 0 {
+		s.Latest = m.migrations[len(m.migrations)-1].Version
+	}
+
+	return s, nil
+}
+
+// Check returns ErrSchemaMismatch if the database is dirty or its version
+// is not the latest one.
+func (m *Migrator) Check(ctx context.Context) error {
+	s, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	if s.Dirty || s.Version != s.Latest {
+		return fmt.Errorf("%w: database is at %d (dirty: %t), migrations are at %d",
+			ErrSchemaMismatch, s.Version, s.Dirty, s.Latest)
+	}
+
+	return nil
+}
+
+// CheckSchema checks the database of the driver at url against the
+// embedded migrations, see Migrator.Check.
+func CheckSchema(ctx context.Context, driver, url string, opts ...storages.PoolOption) error {
+	pool, err := storages.OpenDB(driver, url, opts...)
+	if err != nil {
+		return err
+	}
+	defer storages.ClosePool(pool)
+
+	m, err := New(pool, WithDriver(driver))
+	if err != nil {
+		return err
+	}
+
+	return m.Check(ctx)
+}
+
+func (m *Migrator) index(version uint64) int {
+	for i, mig := range m.migrations {
+		if mig.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// locked runs fn on a single connection holding the migrations advisory lock.
+// SQLite has no advisory locks, there every migration takes the database
+// write lock when its transaction begins, see storages.NewSQLitePool.
+func (m *Migrator) locked(ctx context.Context, fn func(*sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.driver == storages.DriverPostgres {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+			return err
+		}
+		defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+	}
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) tableExists(ctx context.Context, conn *sql.Conn) (bool, error) {
+	query := "SELECT to_regclass('schema_migrations') IS NOT NULL"
+	if m.driver == storages.DriverSQLite {
+		query = "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')"
+	}
+
+	var exists bool
+	err := conn.QueryRowContext(ctx, query).Scan(&exists)
+	return exists, err
+}
+
+func ensureTable(ctx context.Context, conn *sql.Conn) error {
+	const query = "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)"
+
+	_, err := conn.ExecContext(ctx, query)
+	return err
+}
+
+func readVersion(ctx context.Context, conn *sql.Conn) (version uint64, dirty bool, err error) {
+	const query = "SELECT version, dirty FROM schema_migrations LIMIT 1"
+
+	err = conn.QueryRowContext(ctx, query).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+
+	return version, dirty, err
+}
+
+// apply runs script and sets the version in one transaction.
+// If set is false the version row is removed.
+//
+// Before the script runs the version is marked dirty on its own, so a
+// script that fails halfway, or is not transactional, leaves the database
+// dirty until it is fixed and forced.
+func apply(ctx context.Context, conn *sql.Conn, script string, version uint64, set bool) error {
+	if script != "" {
+		if err := markDirty(ctx, conn, version); err != nil {
+			return err
+		}
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if script != "" {
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			return err
+		}
+	}
+
+	if set {
+		if err := setVersion(ctx, tx, version, false); err != nil {
+			return err
+		}
+	} else if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// markDirty sets the version dirty in its own transaction.
+func markDirty(ctx context.Context, conn *sql.Conn, version uint64) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setVersion(ctx, tx, version, true); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// setVersion replaces the version row of schema_migrations.
+func setVersion(ctx context.Context, db execer, version uint64, dirty bool) error {
+	if _, err := db.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+
+	_, err := db.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)", int64(version), dirty)
+	return err
+}
@@ -0,0 +1,203 @@
+package migrations_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testMigrations create the first and the second table.
+var testMigrations = fstest.MapFS{
+	"sql/000001_create_first.up.sql":    {Data: []byte("CREATE TABLE first (id integer PRIMARY KEY);")},
+	"sql/000001_create_first.down.sql":  {Data: []byte("DROP TABLE first;")},
+	"sql/000002_create_second.up.sql":   {Data: []byte("CREATE TABLE second (id integer PRIMARY KEY);")},
+	"sql/000002_create_second.down.sql": {Data: []byte("DROP TABLE second;")},
+	"sql/readme.md":                     {Data: []byte("not a migration")},
+}
+
+// newTestDB returns the path and the pool of a new sqlite database.
+func newTestDB(t *testing.T) (string, *sql.DB) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "eldorado.db")
+
+	db, err := storages.NewSQLitePool(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { storages.ClosePool(db) })
+
+	return path, db
+}
+
+func newTestMigrator(t *testing.T, db *sql.DB, fsys fstest.MapFS) *migrations.Migrator {
+	t.Helper()
+
+	m, err := migrations.New(db, migrations.WithDriver(storages.DriverSQLite), migrations.WithFS(fsys, "sql"))
+	require.NoError(t, err)
+
+	return m
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = $1)", name).Scan(&exists)
+	require.NoError(t, err)
+
+	return exists
+}
+
+func TestUpDown(t *testing.T) {
+	ctx := context.Background()
+	_, db := newTestDB(t)
+	m := newTestMigrator(t, db, testMigrations)
+
+	s, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), s.Version)
+	assert.Equal(t, uint64(2), s.Latest)
+	assert.Len(t, s.Pending, 2)
+	assert.False(t, tableExists(t, db, "schema_migrations"), "status created the version table")
+
+	n, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.True(t, tableExists(t, db, "first"))
+	assert.True(t, tableExists(t, db, "second"))
+
+	_, err = m.Up(ctx)
+	assert.ErrorIs(t, err, migrations.ErrNoChange)
+
+	s, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), s.Version)
+	assert.False(t, s.Dirty)
+	assert.Len(t, s.Applied, 2)
+	assert.Empty(t, s.Pending)
+	assert.NoError(t, m.Check(ctx))
+
+	n, err = m.Down(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.True(t, tableExists(t, db, "first"))
+	assert.False(t, tableExists(t, db, "second"))
+
+	s, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), s.Version)
+	assert.ErrorIs(t, m.Check(ctx), migrations.ErrSchemaMismatch)
+
+	// Down stops at the first migration.
+	n, err = m.Down(ctx, 5)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.False(t, tableExists(t, db, "first"))
+
+	s, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), s.Version)
+	assert.Empty(t, s.Applied)
+
+	_, err = m.Down(ctx, 1)
+	assert.ErrorIs(t, err, migrations.ErrNoChange)
+
+	_, err = m.Down(ctx, 0)
+	assert.ErrorIs(t, err, migrations.ErrNoChange)
+}
+
+func TestFailedMigration(t *testing.T) {
+	ctx := context.Background()
+	_, db := newTestDB(t)
+
+	fsys := fstest.MapFS{
+		"sql/000003_broken.up.sql":   {Data: []byte("CREATE TABLE third (id integer PRIMARY KEY); CREATE TABLE;")},
+		"sql/000003_broken.down.sql": {Data: []byte("DROP TABLE third;")},
+	}
+	for name, f := range testMigrations {
+		fsys[name] = f
+	}
+
+	m := newTestMigrator(t, db, fsys)
+
+	n, err := m.Up(ctx)
+	require.Error(t, err)
+	assert.Equal(t, 2, n, "the migrations before the broken one are not applied")
+	assert.False(t, tableExists(t, db, "third"), "the broken migration is not rolled back")
+
+	s, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), s.Version)
+	assert.True(t, s.Dirty)
+	assert.ErrorIs(t, m.Check(ctx), migrations.ErrSchemaMismatch)
+
+	_, err = m.Up(ctx)
+	assert.ErrorIs(t, err, migrations.ErrDirty)
+
+	_, err = m.Down(ctx, 1)
+	assert.ErrorIs(t, err, migrations.ErrDirty)
+
+	// The database is fixed by hand and forced to the last good version.
+	assert.ErrorIs(t, m.Force(ctx, 4), migrations.ErrUnknownVersion)
+	require.NoError(t, m.Force(ctx, 2))
+
+	s, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), s.Version)
+	assert.False(t, s.Dirty)
+
+	n, err = m.Down(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// Zero marks the database as not migrated.
+	require.NoError(t, m.Force(ctx, 2))
+	require.NoError(t, m.Force(ctx, 0))
+
+	s, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), s.Version)
+}
+
+func TestCheckSchema(t *testing.T) {
+	ctx := context.Background()
+	path, db := newTestDB(t)
+
+	assert.ErrorIs(t, migrations.CheckSchema(ctx, storages.DriverSQLite, path), migrations.ErrSchemaMismatch)
+
+	m, err := migrations.New(db, migrations.WithDriver(storages.DriverSQLite))
+	require.NoError(t, err)
+
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+
+	assert.NoError(t, migrations.CheckSchema(ctx, storages.DriverSQLite, path))
+
+	// A migration that failed halfway leaves the latest version dirty.
+	_, err = db.Exec("UPDATE schema_migrations SET dirty = true")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, migrations.CheckSchema(ctx, storages.DriverSQLite, path), migrations.ErrSchemaMismatch)
+
+	assert.ErrorIs(t, migrations.CheckSchema(ctx, "mysql", path), storages.ErrUnknownDriver)
+}
+
+func TestLoad(t *testing.T) {
+	ms, err := migrations.Load(testMigrations, "sql")
+	require.NoError(t, err)
+	require.Len(t, ms, 2)
+	assert.Equal(t, uint64(1), ms[0].Version)
+	assert.Equal(t, "create_first", ms[0].Name)
+	assert.Equal(t, "DROP TABLE first;", ms[0].Down)
+
+	_, err = migrations.Load(fstest.MapFS{
+		"sql/000001_only_down.down.sql": {Data: []byte("SELECT 1;")},
+	}, "sql")
+	assert.Error(t, err)
+}
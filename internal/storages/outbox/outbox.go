@@ -0,0 +1,31 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+)
+
+// Storage keeps the domain events until they are published. The events
+// are added in the transaction of the change they describe, see
+// storages.Transactor, so an event is stored if and only if the change
+// is committed.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name Storage
+type Storage interface {
+	// Add stores the event and sets its ID and creation time.
+	Add(ctx context.Context, e *data.Event) error
+	// Pending returns up to limit unsent events, oldest first. Within a
+	// transaction the returned events are locked until it ends, so that
+	// concurrent relays do not publish them twice.
+	Pending(ctx context.Context, limit int) ([]data.Event, error)
+	// MarkSent marks the events as published.
+	MarkSent(ctx context.Context, ids []int64) error
+	// Purge deletes the events sent before the given time and returns
+	// their number.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	// FindByAggregates returns the kept events of the aggregates, sent or
+	// not, oldest first.
+	FindByAggregates(ctx context.Context, aggregateIDs []string) ([]data.Event, error)
+}
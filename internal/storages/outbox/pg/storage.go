@@ -0,0 +1,128 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+)
+
+// OutboxStorage is a postgres implementation of outbox.Storage.
+type OutboxStorage struct {
+	db    *sql.DB
+	stmts *storages.Statements
+}
+
+// New returns new OutboxStorage instance with postgres db pool.
+//
+// If db is nil returns storages.ErrNilDBPool.
+func New(db *sql.DB) (*OutboxStorage, error) {
+	if db == nil {
+		return nil, storages.ErrNilDBPool
+	}
+
+	return &OutboxStorage{db: db, stmts: storages.NewStatements(db)}, nil
+}
+
+// Add stores the event. It joins the transaction of ctx, if there is one.
+func (s *OutboxStorage) Add(ctx context.Context, e *data.Event) error {
+	const query = "INSERT INTO outbox (event_type, aggregate_id, payload) VALUES ($1, $2, $3) RETURNING id, created_on"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	return stmt.QueryRowContext(ctx, e.Type, e.AggregateID, e.Payload).Scan(&e.ID, &e.CreatedOn)
+}
+
+// Pending returns up to limit unsent events. The rows locked by another
+// transaction are skipped.
+func (s *OutboxStorage) Pending(ctx context.Context, limit int) ([]data.Event, error) {
+	const query = "SELECT id, event_type, aggregate_id, payload, created_on FROM outbox WHERE sent_on IS NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED"
+
+	return s.findEvents(ctx, query, limit)
+}
+
+// FindByAggregates returns the kept events of the aggregates, oldest first.
+func (s *OutboxStorage) FindByAggregates(ctx context.Context, aggregateIDs []string) ([]data.Event, error) {
+	const query = "SELECT id, event_type, aggregate_id, payload, created_on FROM outbox WHERE aggregate_id = ANY($1) ORDER BY id"
+
+	if len(aggregateIDs) == 0 {
+		return nil, nil
+	}
+
+	return s.findEvents(ctx, query, pq.Array(aggregateIDs))
+}
+
+func (s *OutboxStorage) findEvents(ctx context.Context, query string, args ...any) ([]data.Event, error) {
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var ee []data.Event
+	for rows.Next() {
+		var e data.Event
+		if err = rows.Scan(&e.ID, &e.Type, &e.AggregateID, &e.Payload, &e.CreatedOn); err != nil {
+			break
+		}
+		ee = append(ee, e)
+	}
+
+	if closeErr := rows.Close(); closeErr != nil {
+		return nil, closeErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ee, nil
+}
+
+// MarkSent marks the events as published. The send times are in UTC.
+func (s *OutboxStorage) MarkSent(ctx context.Context, ids []int64) error {
+	const query = "UPDATE outbox SET sent_on = (now() AT TIME ZONE 'utc') WHERE id = ANY($1)"
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	_, err = stmt.ExecContext(ctx, pq.Array(ids))
+	return err
+}
+
+// Purge deletes the events sent before the given time.
+func (s *OutboxStorage) Purge(ctx context.Context, before time.Time) (int64, error) {
+	const query = "DELETE FROM outbox WHERE sent_on < $1"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := stmt.ExecContext(ctx, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
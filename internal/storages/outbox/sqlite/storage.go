@@ -0,0 +1,155 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+)
+
+// OutboxStorage is a sqlite implementation of outbox.Storage.
+type OutboxStorage struct {
+	db    *sql.DB
+	stmts *storages.Statements
+}
+
+// New returns new OutboxStorage instance with sqlite db pool,
+// see storages.NewSQLitePool.
+//
+// If db is nil returns storages.ErrNilDBPool.
+func New(db *sql.DB) (*OutboxStorage, error) {
+	if db == nil {
+		return nil, storages.ErrNilDBPool
+	}
+
+	return &OutboxStorage{db: db, stmts: storages.NewStatements(db)}, nil
+}
+
+// Add stores the event. It joins the transaction of ctx, if there is one.
+func (s *OutboxStorage) Add(ctx context.Context, e *data.Event) error {
+	const query = "INSERT INTO outbox (event_type, aggregate_id, payload, created_on) VALUES ($1, $2, $3, $4) RETURNING id"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	createdOn := time.Now().UTC()
+	if err = stmt.QueryRowContext(ctx, e.Type, e.AggregateID, string(e.Payload), createdOn).Scan(&e.ID); err != nil {
+		return err
+	}
+
+	e.CreatedOn = createdOn
+
+	return nil
+}
+
+// Pending returns up to limit unsent events. A transaction takes the
+// database write lock when it begins, so the relays of one database
+// take turns and do not need row locks.
+func (s *OutboxStorage) Pending(ctx context.Context, limit int) ([]data.Event, error) {
+	const query = "SELECT id, event_type, aggregate_id, payload, created_on FROM outbox WHERE sent_on IS NULL ORDER BY id LIMIT $1"
+
+	return s.findEvents(ctx, query, limit)
+}
+
+// FindByAggregates returns the kept events of the aggregates, oldest first.
+func (s *OutboxStorage) FindByAggregates(ctx context.Context, aggregateIDs []string) ([]data.Event, error) {
+	const query = "SELECT id, event_type, aggregate_id, payload, created_on FROM outbox WHERE aggregate_id IN (SELECT value FROM json_each($1)) ORDER BY id"
+
+	if len(aggregateIDs) == 0 {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(aggregateIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.findEvents(ctx, query, string(b))
+}
+
+func (s *OutboxStorage) findEvents(ctx context.Context, query string, args ...any) ([]data.Event, error) {
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var ee []data.Event
+	for rows.Next() {
+		var (
+			e         data.Event
+			payload   string
+			createdOn storages.SQLiteTime
+		)
+		if err = rows.Scan(&e.ID, &e.Type, &e.AggregateID, &payload, &createdOn); err != nil {
+			break
+		}
+		e.Payload = []byte(payload)
+		e.CreatedOn = createdOn.Time
+		ee = append(ee, e)
+	}
+
+	if closeErr := rows.Close(); closeErr != nil {
+		return nil, closeErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ee, nil
+}
+
+// MarkSent marks the events as published. The send times are in UTC.
+func (s *OutboxStorage) MarkSent(ctx context.Context, ids []int64) error {
+	const query = "UPDATE outbox SET sent_on = " + nowUTC + " WHERE id IN (SELECT value FROM json_each($1))"
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	b, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	_, err = stmt.ExecContext(ctx, string(b))
+	return err
+}
+
+// Purge deletes the events sent before the given time.
+func (s *OutboxStorage) Purge(ctx context.Context, before time.Time) (int64, error) {
+	const query = "DELETE FROM outbox WHERE julianday(sent_on) < julianday($1)"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := stmt.ExecContext(ctx, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+const nowUTC = "strftime('%Y-%m-%d %H:%M:%f', 'now')"
@@ -1,23 +1,176 @@
 package storages
 
 import (
+	"context"
 	"database/sql"
+	"log/slog"
+	"net"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 const UniqueViolationCode = "23505"
 
-// NewDBPool returns a connection pool for databsae.
-func NewDBPool(driver, url string) (*sql.DB, error) {
-	db, err := sql.Open(driver, url)
+// PoolConfig is the configuration of a database connection pool, see
+// WithPoolConfig. Zero values keep the defaults of database/sql.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// DialTimeout limits the time a postgres connection takes to open,
+	// unless the url sets connect_timeout.
+	DialTimeout time.Duration
+	// PingTimeout limits every ping of the startup check.
+	PingTimeout time.Duration
+	// ConnectAttempts is how many times the startup check pings the
+	// database before it gives up. The delay between the attempts starts
+	// at ConnectBackoff and doubles up to maxConnectBackoff.
+	ConnectAttempts int
+	ConnectBackoff  time.Duration
+}
+
+// PoolOption configures the pool opened by NewDBPool, NewSQLitePool
+// or OpenDB.
+type PoolOption func(*PoolConfig)
+
+// WithPoolConfig sets the configuration of the pool. Defaults to one ping
+// of up to 5 seconds and the database/sql defaults.
+func WithPoolConfig(cfg PoolConfig) PoolOption {
+	return func(c *PoolConfig) {
+		*c = cfg
+	}
+}
+
+func newPoolConfig(opts []PoolOption) PoolConfig {
+	cfg := PoolConfig{
+		PingTimeout:     5 * time.Second,
+		ConnectAttempts: 1,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+// NewDBPool returns a connection pool for databsae. The pool is checked
+// with a ping and registered in the pool statistics, see PoolStats.
+func NewDBPool(driver, url string, opts ...PoolOption) (*sql.DB, error) {
+	cfg := newPoolConfig(opts)
+
+	db, err := openDB(driver, url, cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	if err = db.Ping(); err != nil {
+	err = connect(cfg.ConnectAttempts, cfg.ConnectBackoff, cfg.PingTimeout, db.PingContext)
+	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
-	return db, err
+	registerDB(driver, db)
+
+	return db, nil
+}
+
+// openDB opens the pool without connecting and applies the cfg.
+func openDB(driver, url string, cfg PoolConfig) (*sql.DB, error) {
+	var (
+		db  *sql.DB
+		err error
+	)
+
+	if driver == DriverPostgres {
+		db, err = openPostgres(url, cfg)
+	} else {
+		db, err = sql.Open(driver, url)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+
+	return db, nil
+}
+
+func openPostgres(url string, cfg PoolConfig) (*sql.DB, error) {
+	connector, err := pq.NewConnector(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.DialTimeout > 0 {
+		connector.Dialer(&pqDialer{net.Dialer{Timeout: cfg.DialTimeout}})
+	}
+
+	return sql.OpenDB(scopeConnector{connector}), nil
+}
+
+// pqDialer is a pq.Dialer with a dial timeout.
+type pqDialer struct {
+	net.Dialer
+}
+
+func (d *pqDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	dialer := d.Dialer
+	dialer.Timeout = timeout
+	return dialer.Dial(network, address)
+}
+
+// maxConnectBackoff is the longest delay between the startup pings.
+const maxConnectBackoff = 30 * time.Second
+
+// connect pings until the ping succeeds or the attempts run out.
+func connect(attempts int, backoff, timeout time.Duration, ping func(context.Context) error) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = pingWithTimeout(timeout, ping)
+		if err == nil || attempt == attempts {
+			return err
+		}
+
+		slog.Warn("failed to connect, retrying",
+			slog.String("error", err.Error()),
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+		)
+
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+}
+
+func pingWithTimeout(timeout time.Duration, ping func(context.Context) error) error {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	return ping(ctx)
 }
@@ -2,20 +2,84 @@ package storages
 
 import (
 	"context"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-func NewRedisClient(url string) (*redis.Client, error) {
+// RedisConfig is the configuration of a Redis client, see WithRedisConfig.
+// Zero values keep the values of the url and the defaults of go-redis.
+type RedisConfig struct {
+	PoolSize        int
+	MinIdleConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	DialTimeout     time.Duration
+	// PingTimeout, ConnectAttempts and ConnectBackoff configure the
+	// startup check as in PoolConfig.
+	PingTimeout     time.Duration
+	ConnectAttempts int
+	ConnectBackoff  time.Duration
+}
+
+// RedisOption configures the client opened by NewRedisClient.
+type RedisOption func(*RedisConfig)
+
+// WithRedisConfig sets the configuration of the client. Defaults to one
+// ping of up to 5 seconds and the values of the url.
+func WithRedisConfig(cfg RedisConfig) RedisOption {
+	return func(c *RedisConfig) {
+		*c = cfg
+	}
+}
+
+// NewRedisClient returns a client of the Redis at url. The client is
+// checked with a ping and registered in the pool statistics, see PoolStats.
+func NewRedisClient(url string, redisOpts ...RedisOption) (*redis.Client, error) {
 	opts, err := redis.ParseURL(url)
 	if err != nil {
 		return nil, err
 	}
 
+	cfg := RedisConfig{
+		PingTimeout:     5 * time.Second,
+		ConnectAttempts: 1,
+	}
+	for _, opt := range redisOpts {
+		opt(&cfg)
+	}
+
+	if cfg.PoolSize > 0 {
+		opts.PoolSize = cfg.PoolSize
+	}
+	if cfg.MinIdleConns > 0 {
+		opts.MinIdleConns = cfg.MinIdleConns
+	}
+	if cfg.MaxIdleConns > 0 {
+		opts.MaxIdleConns = cfg.MaxIdleConns
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		opts.ConnMaxLifetime = cfg.ConnMaxLifetime
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		opts.ConnMaxIdleTime = cfg.ConnMaxIdleTime
+	}
+	if cfg.DialTimeout > 0 {
+		opts.DialTimeout = cfg.DialTimeout
+	}
+
 	client := redis.NewClient(opts)
 
-	if err := client.Ping(context.Background()).Err(); err != nil {
+	err = connect(cfg.ConnectAttempts, cfg.ConnectBackoff, cfg.PingTimeout, func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	})
+	if err != nil {
+		client.Close()
 		return nil, err
 	}
+
+	registerRedis(client)
+
 	return client, nil
 }
@@ -0,0 +1,275 @@
+package storages
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Replicas routes the read-only queries of the postgres storages to the
+// read replicas by round robin. A replica that fails its health check is
+// skipped until it passes again, and without healthy replicas the reads
+// go to the primary.
+//
+// The methods of a nil *Replicas route everything to the primary, so the
+// storages do not check whether replicas are configured.
+type Replicas struct {
+	nodes []*replica
+	next  atomic.Uint64
+
+	interval time.Duration
+	timeout  time.Duration
+	pool     PoolConfig
+	// ping is the health check of a replica.
+	ping func(ctx context.Context, db *sql.DB) error
+
+	// window is how long the reads stay on the primary after a write,
+	// zero disables read-your-writes. key returns the owner of the pin,
+	// without it a write pins the reads of the whole process.
+	window time.Duration
+	key    func(context.Context) string
+
+	mu        sync.Mutex
+	pins      map[string]time.Time
+	pinnedAll time.Time
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+type replica struct {
+	db      *sql.DB
+	stmts   *Statements
+	healthy atomic.Bool
+}
+
+type ReplicasOption func(*Replicas) error
+
+// WithHealthCheck sets how often the replicas are pinged and how long a ping
+// may take. Defaults to every 5 seconds with a 1 second timeout.
+func WithHealthCheck(interval, timeout time.Duration) ReplicasOption {
+	return func(r *Replicas) error {
+		if interval <= 0 || timeout <= 0 {
+			return errors.New("health check interval and timeout must be positive")
+		}
+		r.interval = interval
+		r.timeout = timeout
+		return nil
+	}
+}
+
+// WithReadYourWrites keeps the reads on the primary for window after a
+// write, so a client sees its own changes while the replicas catch up.
+// The key returns the client of the context, e.g. the user ID. Nil key
+// pins the reads of all clients.
+func WithReadYourWrites(window time.Duration, key func(context.Context) string) ReplicasOption {
+	return func(r *Replicas) error {
+		if window < 0 {
+			return errors.New("read-your-writes window could not be negative")
+		}
+		r.window = window
+		r.key = key
+		return nil
+	}
+}
+
+// WithReplicasPool sets the configuration of the pools of the replicas,
+// the startup ping is not used. Defaults to the database/sql defaults.
+func WithReplicasPool(cfg PoolConfig) ReplicasOption {
+	return func(r *Replicas) error {
+		r.pool = cfg
+		return nil
+	}
+}
+
+// NewReplicas opens the replicas at urls, see WithReplicasPool, and checks
+// their health in background until Close. Unreachable replicas do not
+// fail the call, they are skipped until they come up.
+func NewReplicas(urls []string, opts ...ReplicasOption) (*Replicas, error) {
+	r := &Replicas{
+		interval: 5 * time.Second,
+		timeout:  time.Second,
+		pins:     make(map[string]time.Time),
+		done:     make(chan struct{}),
+		ping: func(ctx context.Context, db *sql.DB) error {
+			return db.PingContext(ctx)
+		},
+	}
+
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, url := range urls {
+		db, err := openDB(DriverPostgres, url, r.pool)
+		if err != nil {
+			r.closeDBs()
+			return nil, err
+		}
+		registerDB(DriverPostgres+"_replica", db)
+		r.nodes = append(r.nodes, &replica{db: db, stmts: NewStatements(db)})
+	}
+
+	r.check()
+
+	r.wg.Add(1)
+	go r.run()
+
+	return r, nil
+}
+
+// Reader returns the statements of the next healthy replica, or nil if the
+// reads of ctx must go to the primary. The reads of a transaction always
+// go to the primary.
+func (r *Replicas) Reader(ctx context.Context) *Statements {
+	if r == nil || len(r.nodes) == 0 || InTx(ctx) || r.pinned(ctx) {
+		return nil
+	}
+
+	n := uint64(len(r.nodes))
+	start := r.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		node := r.nodes[(start+i)%n]
+		if node.healthy.Load() {
+			return node.stmts
+		}
+	}
+
+	return nil
+}
+
+// Wrote pins the reads of the client of ctx to the primary for the
+// read-your-writes window.
+func (r *Replicas) Wrote(ctx context.Context) {
+	if r == nil || r.window == 0 {
+		return
+	}
+
+	until := time.Now().Add(r.window)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.key == nil {
+		r.pinnedAll = until
+		return
+	}
+
+	if key := r.key(ctx); key != "" {
+		r.pins[key] = until
+	}
+}
+
+// Healthy returns the number of the replicas that passed the last check.
+func (r *Replicas) Healthy() int {
+	if r == nil {
+		return 0
+	}
+
+	healthy := 0
+	for _, node := range r.nodes {
+		if node.healthy.Load() {
+			healthy++
+		}
+	}
+	return healthy
+}
+
+// Close stops the health checks and closes the replicas.
+func (r *Replicas) Close() error {
+	if r == nil {
+		return nil
+	}
+
+	close(r.done)
+	r.wg.Wait()
+
+	return r.closeDBs()
+}
+
+func (r *Replicas) pinned(ctx context.Context) bool {
+	if r.window == 0 {
+		return false
+	}
+
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.key == nil {
+		return now.Before(r.pinnedAll)
+	}
+
+	key := r.key(ctx)
+	if key == "" {
+		return false
+	}
+
+	return now.Before(r.pins[key])
+}
+
+func (r *Replicas) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			r.check()
+			r.dropExpiredPins()
+		}
+	}
+}
+
+// check pings all replicas concurrently.
+func (r *Replicas) check() {
+	var wg sync.WaitGroup
+	for _, node := range r.nodes {
+		wg.Add(1)
+		go func(node *replica) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+			defer cancel()
+
+			node.healthy.Store(r.ping(ctx, node.db) == nil)
+		}(node)
+	}
+	wg.Wait()
+}
+
+func (r *Replicas) dropExpiredPins() {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, until := range r.pins {
+		if !now.Before(until) {
+			delete(r.pins, key)
+		}
+	}
+}
+
+func (r *Replicas) closeDBs() error {
+	var errs []error
+	for _, node := range r.nodes {
+		if err := node.stmts.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		if err := ClosePool(node.db); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
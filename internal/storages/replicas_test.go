@@ -0,0 +1,168 @@
+package storages
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHealth is the health check of the replicas of a test, the replicas
+// are never connected to.
+type fakeHealth struct {
+	mu   sync.Mutex
+	down map[*sql.DB]bool
+}
+
+func (h *fakeHealth) ping(_ context.Context, db *sql.DB) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.down[db] {
+		return errors.New("replica is down")
+	}
+	return nil
+}
+
+func (h *fakeHealth) set(db *sql.DB, down bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.down[db] = down
+}
+
+// newTestReplicas returns n replicas checked by the fake health check
+// only when check is called.
+func newTestReplicas(t *testing.T, n int, opts ...ReplicasOption) (*Replicas, *fakeHealth) {
+	t.Helper()
+
+	health := &fakeHealth{down: make(map[*sql.DB]bool)}
+
+	urls := make([]string, n)
+	for i := range urls {
+		urls[i] = "postgres://replica/eldorado?sslmode=disable"
+	}
+
+	opts = append([]ReplicasOption{
+		WithHealthCheck(time.Hour, time.Second),
+		func(r *Replicas) error {
+			r.ping = health.ping
+			return nil
+		},
+	}, opts...)
+
+	r, err := NewReplicas(urls, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { r.Close() })
+
+	return r, health
+}
+
+func TestReplicasRoundRobin(t *testing.T) {
+	r, _ := newTestReplicas(t, 3)
+	require.Equal(t, 3, r.Healthy())
+
+	ctx := context.Background()
+
+	counts := make(map[*Statements]int)
+	for i := 0; i < 30; i++ {
+		stmts := r.Reader(ctx)
+		require.NotNil(t, stmts)
+		counts[stmts]++
+	}
+
+	assert.Len(t, counts, 3)
+	for _, node := range r.nodes {
+		assert.Equal(t, 10, counts[node.stmts], "the reads are not spread evenly")
+	}
+}
+
+func TestReplicasUnhealthy(t *testing.T) {
+	r, health := newTestReplicas(t, 3)
+	ctx := context.Background()
+
+	down := r.nodes[1]
+	health.set(down.db, true)
+	r.check()
+	assert.Equal(t, 2, r.Healthy())
+
+	for i := 0; i < 10; i++ {
+		stmts := r.Reader(ctx)
+		require.NotNil(t, stmts)
+		assert.NotSame(t, down.stmts, stmts, "the read went to the unhealthy replica")
+	}
+
+	for _, node := range r.nodes {
+		health.set(node.db, true)
+	}
+	r.check()
+
+	assert.Equal(t, 0, r.Healthy())
+	assert.Nil(t, r.Reader(ctx), "the read does not go to the primary without healthy replicas")
+
+	// A replica is used again once it passes the check.
+	health.set(down.db, false)
+	r.check()
+	assert.Same(t, down.stmts, r.Reader(ctx))
+}
+
+func TestReplicasReadYourWrites(t *testing.T) {
+	type userKey struct{}
+
+	const window = 50 * time.Millisecond
+
+	r, _ := newTestReplicas(t, 2, WithReadYourWrites(window, func(ctx context.Context) string {
+		user, _ := ctx.Value(userKey{}).(string)
+		return user
+	}))
+
+	writer := context.WithValue(context.Background(), userKey{}, "writer")
+	reader := context.WithValue(context.Background(), userKey{}, "reader")
+
+	r.Wrote(writer)
+	assert.Nil(t, r.Reader(writer), "the reads of the writer are not pinned to the primary")
+	assert.NotNil(t, r.Reader(reader), "the reads of another user are pinned to the primary")
+
+	// The contexts without a key are never pinned.
+	r.Wrote(context.Background())
+	assert.NotNil(t, r.Reader(context.Background()))
+
+	time.Sleep(window)
+	assert.NotNil(t, r.Reader(writer), "the pin outlives the window")
+
+	r.dropExpiredPins()
+	assert.Empty(t, r.pins)
+}
+
+func TestReplicasReadYourWritesWithoutKey(t *testing.T) {
+	r, _ := newTestReplicas(t, 2, WithReadYourWrites(time.Hour, nil))
+
+	ctx := context.Background()
+	assert.NotNil(t, r.Reader(ctx))
+
+	r.Wrote(ctx)
+	assert.Nil(t, r.Reader(ctx), "a write does not pin the reads of the process")
+}
+
+func TestReplicasInTx(t *testing.T) {
+	r, _ := newTestReplicas(t, 2)
+
+	ctx := context.WithValue(context.Background(), txKey{}, ctxTx{})
+	assert.Nil(t, r.Reader(ctx), "the read of a transaction goes to a replica")
+}
+
+func TestNilReplicas(t *testing.T) {
+	var r *Replicas
+
+	ctx := context.Background()
+	r.Wrote(ctx)
+
+	assert.Nil(t, r.Reader(ctx))
+	assert.Equal(t, 0, r.Healthy())
+	assert.NoError(t, r.Close())
+}
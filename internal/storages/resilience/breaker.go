@@ -0,0 +1,111 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the storage while the
+// circuit breaker is open.
+var ErrCircuitOpen = errors.New("the storage is unavailable, the circuit breaker is open")
+
+type state int
+
+const (
+	stateClosed state = iota
+	stateOpen
+	stateHalfOpen
+)
+
+func (s state) String() string {
+	switch s {
+	case stateOpen:
+		return "open"
+	case stateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// breaker opens after threshold consecutive failures and fails the calls
+// fast for cooldown. Then it lets one probe call through: its success
+// closes the breaker and its failure opens it again.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    state
+	failures int
+	openedAt time.Time
+	probing  bool
+
+	// onChange is called with the new state under mu.
+	onChange func(state)
+}
+
+// allow returns ErrCircuitOpen if the call must not be made.
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.set(stateHalfOpen)
+		b.probing = true
+		return nil
+	case stateHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	}
+
+	return nil
+}
+
+// done records the result of an allowed call. Transient errors, an
+// unreachable database and the expiry of the timeout of the policy,
+// timedOut, are failures. The other errors mean that the database has
+// answered, so they count as successes, except the errors of the contexts:
+// the cancellation or the deadline of the caller tells nothing of the
+// database, a slow request must not open the breaker for everyone.
+func (b *breaker) done(err error, timedOut bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasProbe := b.state == stateHalfOpen
+	b.probing = false
+
+	switch {
+	case timedOut || IsTransient(err) || isUnreachable(err):
+		b.failures++
+		if wasProbe || b.failures >= b.threshold {
+			b.openedAt = time.Now()
+			b.set(stateOpen)
+		}
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return
+	default:
+		b.failures = 0
+		b.set(stateClosed)
+	}
+}
+
+func (b *breaker) set(s state) {
+	if b.state == s {
+		return
+	}
+
+	b.state = s
+	if b.onChange != nil {
+		b.onChange(s)
+	}
+}
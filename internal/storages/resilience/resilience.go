@@ -0,0 +1,151 @@
+// Package resilience retries the storage calls that failed on transient
+// errors and fails them fast while the database is down.
+package resilience
+
+import (
+	"context"
+	"expvar"
+	"math/rand"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/storages"
+)
+
+// stats holds the counters per storage, see /debug/vars. Every storage
+// has retries, the failures after the last retry (exhausted), the calls
+// rejected by the open breaker (rejected), the times the breaker opened
+// (opens) and its current state (breaker).
+var stats = expvar.NewMap("storage_resilience")
+
+// Config is the retry and circuit breaker policy of a storage.
+type Config struct {
+	// Retries is how many times a failed idempotent read is made again.
+	// Zero disables the retries.
+	Retries int
+	// BaseDelay is the delay before the first retry. It doubles on every
+	// retry up to MaxDelay, and a random half of it is taken off, so the
+	// clients do not retry in step.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// FailureThreshold is how many consecutive calls must fail to open
+	// the breaker. Zero disables the breaker.
+	FailureThreshold int
+	// OpenTimeout is how long the open breaker fails the calls before it
+	// lets a probe call through.
+	OpenTimeout time.Duration
+	// CallTimeout limits every call, a call that runs out of it counts as
+	// a failure of the breaker. The deadline of the caller never counts,
+	// so zero opens the breaker on errors only.
+	CallTimeout time.Duration
+}
+
+// Policy runs the calls of one storage under its Config.
+type Policy struct {
+	cfg     Config
+	breaker *breaker
+	metrics *expvar.Map
+}
+
+// New returns the policy of the named storage. The name keys its metrics.
+func New(name string, cfg Config) *Policy {
+	p := &Policy{cfg: cfg, metrics: new(expvar.Map).Init()}
+	stats.Set(name, p.metrics)
+
+	if cfg.FailureThreshold > 0 {
+		current := new(expvar.String)
+		current.Set(stateClosed.String())
+		p.metrics.Set("breaker", current)
+
+		p.breaker = &breaker{
+			threshold: cfg.FailureThreshold,
+			cooldown:  cfg.OpenTimeout,
+			onChange: func(s state) {
+				current.Set(s.String())
+				if s == stateOpen {
+					p.metrics.Add("opens", 1)
+				}
+			},
+		}
+	}
+
+	return p
+}
+
+// Read runs the idempotent fn and retries it on transient errors.
+// Within a transaction fn is not retried, since the transaction is
+// aborted by the error.
+func (p *Policy) Read(ctx context.Context, fn func(ctx context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		err := p.call(ctx, fn)
+		if err == nil || !IsTransient(err) || storages.InTx(ctx) {
+			return err
+		}
+
+		if attempt == p.cfg.Retries {
+			if p.cfg.Retries > 0 {
+				p.metrics.Add("exhausted", 1)
+			}
+			return err
+		}
+
+		timer := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		p.metrics.Add("retries", 1)
+	}
+}
+
+// Write runs fn once, since a write could have been applied before the
+// error.
+func (p *Policy) Write(ctx context.Context, fn func(ctx context.Context) error) error {
+	return p.call(ctx, fn)
+}
+
+func (p *Policy) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	callCtx := ctx
+	if p.cfg.CallTimeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, p.cfg.CallTimeout)
+		defer cancel()
+	}
+
+	if p.breaker == nil {
+		return fn(callCtx)
+	}
+
+	if err := p.breaker.allow(); err != nil {
+		p.metrics.Add("rejected", 1)
+		return err
+	}
+
+	err := fn(callCtx)
+
+	if ctx.Err() != nil {
+		// The caller is gone, whatever the call has failed with.
+		p.breaker.done(ctx.Err(), false)
+	} else {
+		p.breaker.done(err, err != nil && callCtx.Err() != nil)
+	}
+
+	return err
+}
+
+// backoff returns the delay before the retry after the given attempt.
+func (p *Policy) backoff(attempt int) time.Duration {
+	d := p.cfg.BaseDelay << attempt
+	if d <= 0 || (p.cfg.MaxDelay > 0 && d > p.cfg.MaxDelay) {
+		d = p.cfg.MaxDelay
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	half := int64(d / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
@@ -0,0 +1,111 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"other", errors.New("syntax error"), false},
+		{"serialization failure", &pq.Error{Code: "40001"}, true},
+		{"connection class", &pq.Error{Code: "08006"}, true},
+		{"unique violation", &pq.Error{Code: "23505"}, false},
+		{"reset", &net.OpError{Op: "read", Err: syscall.ECONNRESET}, true},
+		{"read timeout", &net.OpError{Op: "read", Err: timeoutError{}}, true},
+		{"refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, false},
+		{"unknown host", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}, false},
+		{"other read error", &net.OpError{Op: "read", Err: errors.New("oops")}, false},
+		{"canceled", fmt.Errorf("query: %w", context.Canceled), false},
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsTransient(tt.err))
+		})
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	refused := func(context.Context) error {
+		return &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+	}
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		timeout  time.Duration
+		fn       func(ctx context.Context) error
+		wantOpen bool
+	}{
+		{
+			name:    "deadline of the caller",
+			cfg:     Config{FailureThreshold: 2, OpenTimeout: time.Minute},
+			timeout: time.Millisecond,
+			fn:      slow,
+		},
+		{
+			name:    "deadline of the caller before the call timeout",
+			cfg:     Config{FailureThreshold: 2, OpenTimeout: time.Minute, CallTimeout: time.Minute},
+			timeout: time.Millisecond,
+			fn:      slow,
+		},
+		{
+			name:     "call timeout",
+			cfg:      Config{FailureThreshold: 2, OpenTimeout: time.Minute, CallTimeout: time.Millisecond},
+			timeout:  time.Minute,
+			fn:       slow,
+			wantOpen: true,
+		},
+		{
+			name:     "unreachable database",
+			cfg:      Config{FailureThreshold: 2, OpenTimeout: time.Minute},
+			timeout:  time.Minute,
+			fn:       refused,
+			wantOpen: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(t.Name(), tt.cfg)
+
+			for i := 0; i < tt.cfg.FailureThreshold; i++ {
+				ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+				_ = p.Write(ctx, tt.fn)
+				cancel()
+			}
+
+			err := p.Write(context.Background(), func(context.Context) error { return nil })
+			if tt.wantOpen {
+				assert.ErrorIs(t, err, ErrCircuitOpen)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
@@ -0,0 +1,157 @@
+package storages
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/lib/pq"
+)
+
+// scopeConnector opens the postgres connections that apply the workspace
+// scope of the context to every statement, see Scoped.
+//
+// The scope is kept in the app.workspace_id and app.all_workspaces session
+// settings. A connection remembers the scope it has set and sets it again
+// only when a statement comes with another one, so a statement costs one
+// extra round trip when the connection changes hands between workspaces
+// and none otherwise. The statements of unscoped contexts clear the scope,
+// so they never see the rows of the previous request.
+type scopeConnector struct {
+	driver.Connector
+}
+
+func (c scopeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &scopeConn{Conn: conn}, nil
+}
+
+// scopeConn is a pq connection that sets the scope before the statements.
+// Like any driver connection it is used by one goroutine at a time.
+type scopeConn struct {
+	driver.Conn
+
+	// scope is the scope set in the session, valid unless unknown.
+	scope   string
+	unknown bool
+}
+
+// setScope sets the scope of ctx in the session, unless it is set already.
+// The settings are sent as literals in a simple query, so it takes a single
+// round trip.
+func (c *scopeConn) setScope(ctx context.Context) error {
+	scope, _ := ctx.Value(workspaceKey{}).(string)
+	if !c.unknown && c.scope == scope {
+		return nil
+	}
+
+	workspaceID, all := scope, "off"
+	if scope == allWorkspaces {
+		workspaceID, all = "", "on"
+	}
+
+	query := "SELECT set_config('app.workspace_id', " + pq.QuoteLiteral(workspaceID) + ", false), " +
+		"set_config('app.all_workspaces', '" + all + "', false)"
+
+	if _, err := c.Conn.(driver.ExecerContext).ExecContext(ctx, query, nil); err != nil {
+		c.unknown = true
+		return err
+	}
+
+	c.scope, c.unknown = scope, false
+
+	return nil
+}
+
+func (c *scopeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.setScope(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c *scopeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.setScope(ctx); err != nil {
+		return nil, err
+	}
+
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *scopeConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &scopeStmt{Stmt: stmt, conn: c}, nil
+}
+
+// BeginTx begins a transaction. A session setting changed in a transaction
+// is reverted by its rollback, so after a failed transaction the scope is
+// set again.
+func (c *scopeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	tx, err := c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &scopeTx{Tx: tx, conn: c}, nil
+}
+
+func (c *scopeConn) Ping(ctx context.Context) error {
+	return c.Conn.(driver.Pinger).Ping(ctx)
+}
+
+func (c *scopeConn) ResetSession(ctx context.Context) error {
+	return c.Conn.(driver.SessionResetter).ResetSession(ctx)
+}
+
+func (c *scopeConn) IsValid() bool {
+	return c.Conn.(driver.Validator).IsValid()
+}
+
+type scopeStmt struct {
+	driver.Stmt
+	conn *scopeConn
+}
+
+func (s *scopeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if err := s.conn.setScope(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.Stmt.(driver.StmtQueryContext).QueryContext(ctx, args)
+}
+
+func (s *scopeStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if err := s.conn.setScope(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.Stmt.(driver.StmtExecContext).ExecContext(ctx, args)
+}
+
+type scopeTx struct {
+	driver.Tx
+	conn *scopeConn
+}
+
+func (t *scopeTx) Commit() error {
+	// A failed transaction is rolled back on commit.
+	if err := t.Tx.Commit(); err != nil {
+		t.conn.unknown = true
+		return err
+	}
+
+	return nil
+}
+
+func (t *scopeTx) Rollback() error {
+	t.conn.unknown = true
+	return t.Tx.Rollback()
+}
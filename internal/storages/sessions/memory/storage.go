@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages/sessions"
+)
+
+type entry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// Storage is an in-memory implementation of sessions.Storage.
+// It is safe for concurrent use. Expired values are removed lazily.
+type Storage struct {
+	mu     sync.Mutex
+	values map[string]entry
+	users  map[string]map[string]struct{}
+}
+
+func New() *Storage {
+	return &Storage{
+		values: make(map[string]entry),
+		users:  make(map[string]map[string]struct{}),
+	}
+}
+
+// Set stores a copy of value. Like in Redis, zero ttl means the value
+// never expires.
+func (s *Storage) Set(_ context.Context, userID, key string, value []byte, ttl time.Duration) error {
+	v := make([]byte, len(value))
+	copy(v, value)
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = entry{value: v, expiresAt: expiresAt}
+
+	if s.users[userID] == nil {
+		s.users[userID] = make(map[string]struct{})
+	}
+	s.users[userID][key] = struct{}{}
+
+	return nil
+}
+
+// Get returns the value of the key.
+//
+// If the key is missing or expired returns sessions.ErrNotFound.
+func (s *Storage) Get(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.values[key]
+	if !ok {
+		return nil, sessions.ErrNotFound
+	}
+
+	if !e.expiresAt.IsZero() && !time.Now().Before(e.expiresAt) {
+		delete(s.values, key)
+		return nil, sessions.ErrNotFound
+	}
+
+	v := make([]byte, len(e.value))
+	copy(v, e.value)
+
+	return v, nil
+}
+
+// List returns the sessions of the user that have not expired yet, the
+// soonest to expire first.
+func (s *Storage) List(_ context.Context, userID string) ([]data.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	list := make([]data.Session, 0, len(s.users[userID]))
+	for key := range s.users[userID] {
+		e, ok := s.values[key]
+		if !ok || (!e.expiresAt.IsZero() && !now.Before(e.expiresAt)) {
+			delete(s.users[userID], key)
+			continue
+		}
+
+		list = append(list, data.Session{ID: key, ExpiresOn: e.expiresAt})
+	}
+
+	// Like in Redis, the sessions that never expire are the last.
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i].ExpiresOn, list[j].ExpiresOn
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return list[i].ID < list[j].ID
+	})
+
+	return list, nil
+}
+
+// DeleteAll deletes all the sessions of the user.
+func (s *Storage) DeleteAll(_ context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.users[userID] {
+		delete(s.values, key)
+	}
+	delete(s.users, userID)
+
+	return nil
+}
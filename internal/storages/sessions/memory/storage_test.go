@@ -0,0 +1,12 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/romankravchuk/eldorado/internal/storages/sessions/memory"
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Sessions(t, memory.New())
+}
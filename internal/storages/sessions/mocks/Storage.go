@@ -5,6 +5,7 @@ package mocks
 import (
 	context "context"
 
+	data "github.com/romankravchuk/eldorado/internal/data"
 	mock "github.com/stretchr/testify/mock"
 
 	time "time"
@@ -15,6 +16,20 @@ type Storage struct {
 	mock.Mock
 }
 
+// DeleteAll provides a mock function with given fields: ctx, userID
+func (_m *Storage) DeleteAll(ctx context.Context, userID string) error {
+	ret := _m.Called(ctx, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Get provides a mock function with given fields: ctx, key
 func (_m *Storage) Get(ctx context.Context, key string) ([]byte, error) {
 	ret := _m.Called(ctx, key)
@@ -41,13 +56,39 @@ func (_m *Storage) Get(ctx context.Context, key string) ([]byte, error) {
 	return r0, r1
 }
 
-// Set provides a mock function with given fields: ctx, key, value, ttl
-func (_m *Storage) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
-	ret := _m.Called(ctx, key, value, ttl)
+// List provides a mock function with given fields: ctx, userID
+func (_m *Storage) List(ctx context.Context, userID string) ([]data.Session, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []data.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]data.Session, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []data.Session); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]data.Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Set provides a mock function with given fields: ctx, userID, key, value, ttl
+func (_m *Storage) Set(ctx context.Context, userID string, key string, value []byte, ttl time.Duration) error {
+	ret := _m.Called(ctx, userID, key, value, ttl)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, []byte, time.Duration) error); ok {
-		r0 = rf(ctx, key, value, ttl)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []byte, time.Duration) error); ok {
+		r0 = rf(ctx, userID, key, value, ttl)
 	} else {
 		r0 = ret.Error(0)
 	}
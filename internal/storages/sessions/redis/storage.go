@@ -3,12 +3,19 @@ package redis
 import (
 	"context"
 	"errors"
+	"math"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/romankravchuk/eldorado/internal/data"
 	"github.com/romankravchuk/eldorado/internal/storages/sessions"
 )
 
+// Storage is a Redis implementation of sessions.Storage. The keys of the
+// sessions of a user are indexed in a sorted set scored by their expiry.
+// The expired members are trimmed on every write and the index expires
+// with its latest session.
 type Storage struct {
 	client *redis.Client
 }
@@ -17,11 +24,42 @@ func New(client *redis.Client) *Storage {
 	return &Storage{client: client}
 }
 
-func (s *Storage) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
-	if err := s.client.Set(ctx, key, value, ttl).Err(); err != nil {
-		return err
+func userKey(userID string) string {
+	return "sessions:user:" + userID
+}
+
+// trimIndex removes the members of the index KEYS[1] expired before
+// ARGV[1] and sets the expiry of the index to the one of its latest
+// member. A member without expiry keeps the index.
+var trimIndex = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', '(' .. ARGV[1])
+local last = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+if #last == 0 then
+	return 0
+end
+if last[2] == 'inf' then
+	return redis.call('PERSIST', KEYS[1])
+end
+return redis.call('EXPIREAT', KEYS[1], last[2])
+`)
+
+func (s *Storage) Set(ctx context.Context, userID, key string, value []byte, ttl time.Duration) error {
+	now := time.Now()
+
+	score := math.Inf(1)
+	if ttl > 0 {
+		score = float64(now.Add(ttl).Unix())
 	}
-	return nil
+
+	_, err := s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, key, value, ttl)
+		p.ZAdd(ctx, userKey(userID), redis.Z{Score: score, Member: key})
+		// EVAL, not EVALSHA, the script may not be loaded yet.
+		trimIndex.Eval(ctx, p, []string{userKey(userID)}, now.Unix())
+		return nil
+	})
+
+	return err
 }
 
 func (s *Storage) Get(ctx context.Context, key string) ([]byte, error) {
@@ -34,3 +72,36 @@ func (s *Storage) Get(ctx context.Context, key string) ([]byte, error) {
 	}
 	return res, nil
 }
+
+func (s *Storage) List(ctx context.Context, userID string) ([]data.Session, error) {
+	res, err := s.client.ZRangeByScoreWithScores(ctx, userKey(userID), &redis.ZRangeBy{
+		Min: strconv.FormatInt(time.Now().Unix(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]data.Session, 0, len(res))
+	for _, z := range res {
+		var expiresOn time.Time
+		if !math.IsInf(z.Score, 1) {
+			expiresOn = time.Unix(int64(z.Score), 0).UTC()
+		}
+
+		list = append(list, data.Session{ID: z.Member.(string), ExpiresOn: expiresOn})
+	}
+
+	return list, nil
+}
+
+// DeleteAll deletes the indexed sessions of the user and the index. It
+// is not atomic: a session set after the index is read survives.
+func (s *Storage) DeleteAll(ctx context.Context, userID string) error {
+	keys, err := s.client.ZRange(ctx, userKey(userID), 0, -1).Result()
+	if err != nil {
+		return err
+	}
+
+	return s.client.Del(ctx, append(keys, userKey(userID))...).Err()
+}
@@ -0,0 +1,12 @@
+package redis_test
+
+import (
+	"testing"
+
+	"github.com/romankravchuk/eldorado/internal/storages/sessions/redis"
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Sessions(t, redis.New(storagetest.Redis(t)))
+}
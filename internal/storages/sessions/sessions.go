@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
 )
 
 var ErrNotFound = errors.New("the value in sessions storage not found")
@@ -11,5 +13,12 @@ var ErrNotFound = errors.New("the value in sessions storage not found")
 //go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name Storage
 type Storage interface {
 	Get(ctx context.Context, key string) ([]byte, error)
-	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Set stores the session of the user. Zero ttl means the session
+	// never expires.
+	Set(ctx context.Context, userID, key string, value []byte, ttl time.Duration) error
+	// List returns the sessions of the user that have not expired yet,
+	// the soonest to expire first.
+	List(ctx context.Context, userID string) ([]data.Session, error)
+	// DeleteAll revokes all the sessions of the user.
+	DeleteAll(ctx context.Context, userID string) error
 }
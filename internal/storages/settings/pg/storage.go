@@ -0,0 +1,67 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/settings"
+)
+
+// SettingsStorage is a postgres implementation of settings.Storage.
+type SettingsStorage struct {
+	db    *sql.DB
+	stmts *storages.Statements
+}
+
+// New returns new SettingsStorage instance with postgres db pool.
+//
+// If db is nil returns storages.ErrNilDBPool.
+func New(db *sql.DB) (*SettingsStorage, error) {
+	if db == nil {
+		return nil, storages.ErrNilDBPool
+	}
+
+	return &SettingsStorage{db: db, stmts: storages.NewStatements(db)}, nil
+}
+
+// Find returns the settings of the user.
+//
+// If the user has never saved settings returns settings.ErrNotFound.
+func (s *SettingsStorage) Find(ctx context.Context, userID string) (data.Settings, error) {
+	const query = "SELECT user_id, archive_after_days FROM user_settings WHERE user_id = $1 AND archive_after_days IS NOT NULL"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return data.Settings{}, err
+	}
+
+	var st data.Settings
+	if err = stmt.QueryRowContext(ctx, userID).Scan(&st.UserID, &st.ArchiveAfterDays); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data.Settings{}, settings.ErrNotFound
+		}
+		return data.Settings{}, err
+	}
+
+	return st, nil
+}
+
+// Save creates or replaces the settings of st.UserID.
+func (s *SettingsStorage) Save(ctx context.Context, st *data.Settings) error {
+	const query = "INSERT INTO user_settings (user_id, archive_after_days) VALUES ($1, $2) " +
+		"ON CONFLICT (user_id) DO UPDATE SET archive_after_days = EXCLUDED.archive_after_days, updated_on = CURRENT_TIMESTAMP"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	if _, err = stmt.ExecContext(ctx, st.UserID, st.ArchiveAfterDays); err != nil {
+		return err
+	}
+
+	return nil
+}
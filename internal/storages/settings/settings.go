@@ -0,0 +1,16 @@
+package settings
+
+import (
+	"context"
+	"errors"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+)
+
+var ErrNotFound = errors.New("the settings not found")
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name Storage
+type Storage interface {
+	Find(ctx context.Context, userID string) (data.Settings, error)
+	Save(ctx context.Context, s *data.Settings) error
+}
@@ -0,0 +1,84 @@
+package storages
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// NewSQLitePool returns a connection pool for the SQLite database file at path.
+//
+// Foreign keys are enforced and the journal is switched to WAL, so readers
+// do not wait for the writer. Transactions take the write lock when they
+// begin and concurrent writers wait for it up to 5 seconds. Times are
+// written in the format the SQLite date functions understand.
+func NewSQLitePool(path string, opts ...PoolOption) (*sql.DB, error) {
+	q := url.Values{}
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Add("_pragma", "journal_mode(WAL)")
+	q.Add("_pragma", "busy_timeout(5000)")
+	q.Set("_time_format", "sqlite")
+	q.Set("_txlock", "immediate")
+
+	return NewDBPool(DriverSQLite, "file:"+path+"?"+q.Encode(), opts...)
+}
+
+// IsSQLiteConstraint reports whether err is a violation of the SQLite
+// constraint of the given extended result code, e.g.
+// sqlite3.SQLITE_CONSTRAINT_UNIQUE.
+func IsSQLiteConstraint(err error, code int) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == code
+}
+
+// IsSQLiteUniqueViolation reports whether err is a violation of a unique
+// index or a primary key.
+func IsSQLiteUniqueViolation(err error) bool {
+	return IsSQLiteConstraint(err, sqlite3.SQLITE_CONSTRAINT_UNIQUE) ||
+		IsSQLiteConstraint(err, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY)
+}
+
+// SQLiteTime scans a nullable datetime column in UTC. The driver parses
+// only the columns declared as datetime, so the text results of
+// expressions and CTEs are parsed here. NULL is the zero time.
+type SQLiteTime struct {
+	Time time.Time
+}
+
+// sqliteTimeFormats are the layouts of the times written by the driver
+// and by the SQLite date functions.
+var sqliteTimeFormats = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+func (t *SQLiteTime) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		t.Time = time.Time{}
+	case time.Time:
+		t.Time = v.UTC()
+	case string:
+		for _, layout := range sqliteTimeFormats {
+			if parsed, err := time.Parse(layout, v); err == nil {
+				t.Time = parsed.UTC()
+				return nil
+			}
+		}
+		return fmt.Errorf("unsupported datetime %q", v)
+	default:
+		return fmt.Errorf("unsupported datetime value of type %T", src)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package storages
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// Statements lazily prepares the queries of a db pool and keeps the
+// prepared statements for reuse.
+//
+// A *sql.Stmt is bound to the pool, not to a connection: it is prepared
+// again on every connection it runs on for the first time, including the
+// connections opened after the old ones were lost. So a statement prepared
+// once stays usable for the lifetime of the pool.
+type Statements struct {
+	db *sql.DB
+
+	mu    sync.RWMutex
+	stmts map[string]*sql.Stmt
+}
+
+func NewStatements(db *sql.DB) *Statements {
+	return &Statements{
+		db:    db,
+		stmts: make(map[string]*sql.Stmt),
+	}
+}
+
+// Prepare returns the prepared statement of the query, preparing it
+// within PrepareTimeout on the first call. The statement must not be
+// closed by the caller.
+//
+// If ctx holds a transaction of the pool, see RunInTx, the statement runs
+// in it. Such a statement is closed with the transaction.
+func (s *Statements) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := s.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if tx, ok := Tx(ctx, s.db); ok {
+		return tx.StmtContext(ctx, stmt), nil
+	}
+
+	return stmt, nil
+}
+
+func (s *Statements) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	s.mu.RLock()
+	stmt, ok := s.stmts[query]
+	s.mu.RUnlock()
+
+	if ok {
+		return stmt, nil
+	}
+
+	prepareCtx, cancel := context.WithTimeout(ctx, PrepareTimeout)
+	defer cancel()
+
+	stmt, err := s.db.PrepareContext(prepareCtx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Another goroutine could prepare the same query meanwhile.
+	if prepared, ok := s.stmts[query]; ok {
+		stmt.Close()
+		return prepared, nil
+	}
+
+	s.stmts[query] = stmt
+
+	return stmt, nil
+}
+
+// Close closes all prepared statements.
+func (s *Statements) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for query, stmt := range s.stmts {
+		if err := stmt.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(s.stmts, query)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,145 @@
+package storages_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+	taskspg "github.com/romankravchuk/eldorado/internal/storages/tasks/pg"
+	"github.com/romankravchuk/eldorado/internal/storages/users"
+	userspg "github.com/romankravchuk/eldorado/internal/storages/users/pg"
+)
+
+// missingID is looked up so the benchmarks need no data, the cost is
+// dominated by the round trips anyway.
+const missingID = "00000000-0000-0000-0000-000000000000"
+
+// BenchmarkStatements compares preparing a statement on every call, as the
+// postgres storages did before, with the statements cached by
+// storages.Statements. It needs a migrated database, see
+// storagetest.Postgres.
+func BenchmarkStatements(b *testing.B) {
+	db := storagetest.Postgres(b)
+
+	tasksStorage, err := taskspg.New(db)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	usersStorage, err := userspg.New(db)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ctx := storages.AllWorkspaces(context.Background())
+
+	bench(b, "users.FindByEmail/prepare per call", func() error {
+		return perCall(ctx, db, "SELECT id, email, username, encrypted_password, name, created_on FROM users WHERE email = $1 AND deleted_on IS NULL", "bench@example.com")
+	})
+	bench(b, "users.FindByEmail/cached statement", func() error {
+		_, err := usersStorage.FindByEmail(ctx, "bench@example.com")
+		if errors.Is(err, users.ErrNotFound) {
+			return nil
+		}
+		return err
+	})
+	bench(b, "tasks.FindByID/prepare per call", func() error {
+		return perCall(ctx, db, "SELECT id, user_id, title FROM tasks WHERE id = $1 AND is_deleted = false", missingID)
+	})
+	bench(b, "tasks.FindByID/cached statement", func() error {
+		_, err := tasksStorage.FindByID(ctx, missingID)
+		if errors.Is(err, tasks.ErrNotFound) {
+			return nil
+		}
+		return err
+	})
+}
+
+// BenchmarkScopedRead measures a read in a workspace. The connections set
+// the scope only when it changes, which the alternating workspaces force
+// on every call. A transaction per call is the way the scope was set
+// before.
+func BenchmarkScopedRead(b *testing.B) {
+	db := storagetest.Postgres(b)
+
+	tasksStorage, err := taskspg.New(db)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	findByID := func(ctx context.Context) error {
+		_, err := tasksStorage.FindByID(ctx, missingID)
+		if errors.Is(err, tasks.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	workspaces := []context.Context{
+		storages.WithWorkspace(context.Background(), uuid.NewString()),
+		storages.WithWorkspace(context.Background(), uuid.NewString()),
+	}
+
+	bench(b, "same workspace", func() error {
+		return findByID(workspaces[0])
+	})
+
+	var i int
+	bench(b, "alternating workspaces", func() error {
+		i++
+		return findByID(workspaces[i%2])
+	})
+
+	bench(b, "transaction per call", func() error {
+		ctx := workspaces[0]
+		workspaceID, _ := storages.WorkspaceID(ctx)
+
+		return storages.RunInTx(ctx, db, func(ctx context.Context, tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, "SELECT set_config('app.workspace_id', $1, true)", workspaceID); err != nil {
+				return err
+			}
+			return findByID(ctx)
+		})
+	})
+}
+
+func bench(b *testing.B, name string, fn func() error) {
+	b.Run(name, func(b *testing.B) {
+		if err := fn(); err != nil {
+			b.Fatal(err)
+		}
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := fn(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// perCall runs the query the way the storages did before: prepare,
+// query and close on every call.
+func perCall(ctx context.Context, db *sql.DB, query, arg string) error {
+	prepareCtx, cancel := context.WithTimeout(ctx, storages.PrepareTimeout)
+	defer cancel()
+
+	stmt, err := db.PrepareContext(prepareCtx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, arg)
+	if err != nil {
+		return err
+	}
+
+	return rows.Close()
+}
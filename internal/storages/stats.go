@@ -0,0 +1,110 @@
+package storages
+
+import (
+	"database/sql"
+	"expvar"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// The statistics of the pools are published in db_pools and redis_pools
+// of /debug/vars. The pools are named by the driver and the order they
+// were opened in, e.g. postgres_1, postgres_replica_1 or redis_1. The
+// pools closed with ClosePool and CloseRedisClient are left out.
+func init() {
+	expvar.Publish("db_pools", expvar.Func(func() any { return PoolStats() }))
+	expvar.Publish("redis_pools", expvar.Func(func() any { return RedisPoolStats() }))
+}
+
+var (
+	poolsMu    sync.Mutex
+	dbPools    []namedDB
+	redisPools []*redis.Client
+)
+
+type namedDB struct {
+	name string
+	db   *sql.DB
+}
+
+func registerDB(name string, db *sql.DB) {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	dbPools = append(dbPools, namedDB{name: name, db: db})
+}
+
+func registerRedis(client *redis.Client) {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	redisPools = append(redisPools, client)
+}
+
+func unregisterDB(db *sql.DB) {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	for i, p := range dbPools {
+		if p.db == db {
+			dbPools = append(dbPools[:i], dbPools[i+1:]...)
+			return
+		}
+	}
+}
+
+func unregisterRedis(client *redis.Client) {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	for i, c := range redisPools {
+		if c == client {
+			redisPools = append(redisPools[:i], redisPools[i+1:]...)
+			return
+		}
+	}
+}
+
+// ClosePool closes the pool opened by NewDBPool and removes it from the
+// pool statistics.
+func ClosePool(db *sql.DB) error {
+	unregisterDB(db)
+	return db.Close()
+}
+
+// CloseRedisClient closes the client opened by NewRedisClient and removes
+// it from the pool statistics.
+func CloseRedisClient(client *redis.Client) error {
+	unregisterRedis(client)
+	return client.Close()
+}
+
+// PoolStats returns the statistics of the database pools by their names.
+func PoolStats() map[string]sql.DBStats {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	counts := make(map[string]int, len(dbPools))
+	stats := make(map[string]sql.DBStats, len(dbPools))
+	for _, p := range dbPools {
+		counts[p.name]++
+		stats[fmt.Sprintf("%s_%d", p.name, counts[p.name])] = p.db.Stats()
+	}
+
+	return stats
+}
+
+// RedisPoolStats returns the statistics of the Redis pools by their names.
+func RedisPoolStats() map[string]*redis.PoolStats {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	stats := make(map[string]*redis.PoolStats, len(redisPools))
+	for i, client := range redisPools {
+		stats[fmt.Sprintf("redis_%d", i+1)] = client.PoolStats()
+	}
+
+	return stats
+}
@@ -0,0 +1,72 @@
+package storages_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/romankravchuk/eldorado/internal/storages"
+)
+
+// sqlitePools returns the names of the sqlite pools published in
+// db_pools.
+func sqlitePools(t *testing.T) []string {
+	t.Helper()
+
+	var stats map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(expvar.Get("db_pools").String()), &stats); err != nil {
+		t.Fatalf("failed to decode db_pools: %v", err)
+	}
+
+	var names []string
+	for name := range stats {
+		if strings.HasPrefix(name, storages.DriverSQLite+"_") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func TestPoolStats(t *testing.T) {
+	before := len(sqlitePools(t))
+
+	first, err := storages.NewSQLitePool(filepath.Join(t.TempDir(), "first.db"), storages.WithPoolConfig(storages.PoolConfig{MaxOpenConns: 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storages.ClosePool(first)
+
+	second, err := storages.NewSQLitePool(filepath.Join(t.TempDir(), "second.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(sqlitePools(t)); got != before+2 {
+		t.Fatalf("got %d sqlite pools, want %d", got, before+2)
+	}
+
+	stats := storages.PoolStats()
+	name := fmt.Sprintf("%s_%d", storages.DriverSQLite, before+1)
+	if s, ok := stats[name]; !ok || s.MaxOpenConnections != 3 {
+		t.Errorf("got %s %+v, want the first pool with 3 max open connections", name, s)
+	}
+
+	if err := storages.ClosePool(second); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(sqlitePools(t)); got != before+1 {
+		t.Errorf("got %d sqlite pools after close, want %d", got, before+1)
+	}
+
+	// A pool closed twice is not removed twice.
+	if err := storages.ClosePool(second); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(sqlitePools(t)); got != before+1 {
+		t.Errorf("got %d sqlite pools after a second close, want %d", got, before+1)
+	}
+}
@@ -1,5 +1,22 @@
 package storages
 
-import "time"
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
 
 const PrepareTimeout = 1 * time.Second
+
+// OpenDB returns a connection pool of the driver, DriverPostgres or
+// DriverSQLite. The url of the sqlite driver is the path to the database file.
+func OpenDB(driver, url string, opts ...PoolOption) (*sql.DB, error) {
+	switch driver {
+	case DriverPostgres:
+		return NewDBPool(DriverPostgres, url, opts...)
+	case DriverSQLite:
+		return NewSQLitePool(url, opts...)
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownDriver, driver)
+}
@@ -0,0 +1,91 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/dependencies"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+	"github.com/romankravchuk/eldorado/internal/storages/users"
+	"github.com/romankravchuk/eldorado/internal/storages/workspaces"
+)
+
+// Dependencies checks the dependencies.Storage implementation. The tasks
+// are created in ts, their owners in us and the workspaces in ws, which
+// must share the database of ds.
+func Dependencies(t *testing.T, ds dependencies.Storage, ts tasks.Storage, us users.Storage, ws workspaces.Storage) {
+	ctx := context.Background()
+
+	owner := data.User{Email: unique("conformance") + "@example.com", Username: unique("conformance"), EncryptedPassword: "hash"}
+	other := data.User{Email: unique("conformance") + "@example.com", Username: unique("conformance"), EncryptedPassword: "hash"}
+	if !ok(t, "users.Save", us.Save(storages.AllWorkspaces(ctx), &owner)) || !ok(t, "users.Save", us.Save(storages.AllWorkspaces(ctx), &other)) {
+		return
+	}
+
+	workspace := data.Workspace{Name: unique("conformance")}
+	foreign := data.Workspace{Name: unique("conformance")}
+	if !ok(t, "workspaces.Create", ws.Create(ctx, &workspace, owner.ID)) || !ok(t, "workspaces.Create", ws.Create(ctx, &foreign, other.ID)) {
+		return
+	}
+
+	foreignCtx := storages.WithWorkspace(ctx, foreign.ID)
+	ctx = storages.WithWorkspace(ctx, workspace.ID)
+
+	newTask := func(ctx context.Context, userID string) string {
+		t.Helper()
+
+		task := data.Task{UserID: userID, Title: unique("task ")}
+		if !ok(t, "tasks.Save", ts.Save(ctx, &task)) {
+			t.FailNow()
+		}
+		return task.ID
+	}
+
+	a, b, c := newTask(ctx, owner.ID), newTask(ctx, owner.ID), newTask(ctx, owner.ID)
+	foreignTask := newTask(foreignCtx, other.ID)
+
+	// a is blocked by b, b is blocked by c.
+	ok(t, "Add", ds.Add(ctx, a, b))
+	ok(t, "Add", ds.Add(ctx, b, c))
+
+	tests := []struct {
+		name      string
+		taskID    string
+		blockerID string
+		want      error
+	}{
+		{name: "self dependency", taskID: a, blockerID: a, want: dependencies.ErrCycle},
+		{name: "direct cycle", taskID: b, blockerID: a, want: dependencies.ErrCycle},
+		{name: "transitive cycle", taskID: c, blockerID: a, want: dependencies.ErrCycle},
+		{name: "duplicate", taskID: a, blockerID: b, want: dependencies.ErrAlreadyExists},
+		{name: "blocker of another owner", taskID: a, blockerID: foreignTask, want: tasks.ErrNotFound},
+		{name: "task of another owner", taskID: foreignTask, blockerID: a, want: tasks.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		is(t, "Add: "+tt.name, ds.Add(ctx, tt.taskID, tt.blockerID), tt.want)
+	}
+
+	blockers, err := ds.Blockers(ctx, a)
+	if ok(t, "Blockers", err) && (len(blockers) != 1 || blockers[0].ID != b) {
+		t.Errorf("Blockers: got %+v, want only %s", blockers, b)
+	}
+
+	dependents, err := ds.Dependents(ctx, c)
+	if ok(t, "Dependents", err) && (len(dependents) != 1 || dependents[0].ID != b) {
+		t.Errorf("Dependents: got %+v, want only %s", dependents, b)
+	}
+
+	blocked, err := ds.HasOpenBlockers(ctx, a)
+	if ok(t, "HasOpenBlockers", err) && !blocked {
+		t.Error("HasOpenBlockers: got false for a task with an open blocker")
+	}
+
+	ok(t, "Remove", ds.Remove(ctx, a, b))
+	is(t, "Remove removed", ds.Remove(ctx, a, b), dependencies.ErrNotFound)
+
+	// Without the edge a->b the reverse one does not close a cycle.
+	ok(t, "Add after Remove", ds.Add(ctx, b, a))
+}
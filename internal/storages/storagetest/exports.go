@@ -0,0 +1,99 @@
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/exports"
+	"github.com/romankravchuk/eldorado/internal/storages/users"
+)
+
+// maxClaims bounds the claims looking for the export of the suite, the
+// exports of the other runs in a shared database are claimed as well.
+const maxClaims = 100
+
+// Exports checks the exports.Storage implementation. The user is created
+// in us, which must be the users of es.
+func Exports(t *testing.T, es exports.Storage, us users.Storage) {
+	ctx := context.Background()
+
+	u := data.User{Email: unique("conformance") + "@example.com", Username: unique("conformance"), EncryptedPassword: "hash"}
+	if !ok(t, "users.Save", us.Save(storages.AllWorkspaces(ctx), &u)) {
+		return
+	}
+
+	e := data.Export{UserID: u.ID}
+	if !ok(t, "Create", es.Create(ctx, &e)) {
+		return
+	}
+	if e.ID == "" || e.Status != data.ExportPending || e.CreatedOn.IsZero() {
+		t.Errorf("Create: ID, Status and CreatedOn are not filled: %+v", e)
+	}
+
+	is(t, "Create of a second export in progress", es.Create(ctx, &data.Export{UserID: u.ID}), exports.ErrInProgress)
+
+	// claim claims the exports until the one of the suite.
+	claim := func(staleBefore time.Time) (data.Export, error) {
+		for i := 0; i < maxClaims; i++ {
+			c, err := es.Claim(ctx, staleBefore)
+			if err != nil || c.ID == e.ID {
+				return c, err
+			}
+		}
+		return data.Export{}, errors.New("the export is not claimed")
+	}
+
+	c, err := claim(time.Now().Add(-time.Hour))
+	if ok(t, "Claim", err) && (c.UserID != u.ID || c.Status != data.ExportBuilding) {
+		t.Errorf("Claim: got %+v, want the building export of %s", c, u.ID)
+	}
+
+	_, err = claim(time.Now().Add(-time.Hour))
+	is(t, "Claim of a claimed export", err, exports.ErrNotFound)
+
+	is(t, "Create while the export is building", es.Create(ctx, &data.Export{UserID: u.ID}), exports.ErrInProgress)
+
+	c, err = claim(time.Now().Add(time.Second))
+	if ok(t, "Claim of a stale export", err) && c.Status != data.ExportBuilding {
+		t.Errorf("Claim of a stale export: got %+v, want it building again", c)
+	}
+
+	e.Status = data.ExportReady
+	e.Archive = []byte("archive")
+	e.ExpiresOn = time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	if ok(t, "Finish", es.Finish(ctx, e)) {
+		got, err := es.FindByID(ctx, e.ID)
+		if ok(t, "FindByID", err) && (got.Status != data.ExportReady || !bytes.Equal(got.Archive, e.Archive) || !got.ExpiresOn.Equal(e.ExpiresOn)) {
+			t.Errorf("FindByID: got %+v, want %+v", got, e)
+		}
+	}
+
+	expired := data.Export{UserID: u.ID}
+	if ok(t, "Create after the export is ready", es.Create(ctx, &expired)) {
+		expired.Status = data.ExportFailed
+		expired.ExpiresOn = time.Now().Add(-time.Minute)
+		if ok(t, "Finish of a failed export", es.Finish(ctx, expired)) {
+			n, err := es.Purge(ctx, time.Now())
+			if ok(t, "Purge", err) && n < 1 {
+				t.Errorf("Purge: got %d, want at least the expired export", n)
+			}
+
+			_, err = es.FindByID(ctx, expired.ID)
+			is(t, "FindByID of a purged export", err, exports.ErrNotFound)
+
+			_, err = es.FindByID(ctx, e.ID)
+			ok(t, "FindByID of an export not expired", err)
+		}
+	}
+
+	_, err = es.FindByID(ctx, uuid.NewString())
+	is(t, "FindByID of a missing export", err, exports.ErrNotFound)
+
+	is(t, "Finish of a missing export", es.Finish(ctx, data.Export{ID: uuid.NewString(), Status: data.ExportFailed}), exports.ErrNotFound)
+}
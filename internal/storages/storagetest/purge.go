@@ -0,0 +1,56 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/outbox"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+	"github.com/romankravchuk/eldorado/internal/storages/users"
+	"github.com/romankravchuk/eldorado/internal/storages/workspaces"
+)
+
+// PurgedEvents checks that users.Storage.Purge deletes the events of the
+// user and of their tasks from the outbox. The storages must share the
+// database.
+func PurgedEvents(t *testing.T, us users.Storage, ws workspaces.Storage, ts tasks.Storage, es outbox.Storage) {
+	ctx := storages.AllWorkspaces(context.Background())
+
+	u := data.User{Email: unique("conformance") + "@example.com", Username: unique("conformance"), EncryptedPassword: "hash"}
+	if !ok(t, "users.Save", us.Save(ctx, &u)) {
+		return
+	}
+
+	workspace := data.Workspace{Name: unique("conformance")}
+	if !ok(t, "workspaces.Create", ws.Create(ctx, &workspace, u.ID)) {
+		return
+	}
+
+	task := data.Task{UserID: u.ID, Title: unique("task ")}
+	if !ok(t, "tasks.Save", ts.Save(storages.WithWorkspace(ctx, workspace.ID), &task)) {
+		return
+	}
+
+	for _, id := range []string{u.ID, task.ID} {
+		e, err := data.NewEvent("conformance", id, map[string]string{"email": u.Email})
+		if !ok(t, "data.NewEvent", err) || !ok(t, "outbox.Add", es.Add(ctx, &e)) {
+			return
+		}
+	}
+
+	if !ok(t, "Delete", us.Delete(ctx, u.ID)) {
+		return
+	}
+
+	if _, err := us.Purge(ctx, time.Now().Add(time.Hour)); !ok(t, "Purge", err) {
+		return
+	}
+
+	ee, err := es.FindByAggregates(ctx, []string{u.ID, task.ID})
+	if ok(t, "outbox.FindByAggregates", err) && len(ee) != 0 {
+		t.Errorf("Purge: the events of the user are kept: %+v", ee)
+	}
+}
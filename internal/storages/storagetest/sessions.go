@@ -0,0 +1,72 @@
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/storages/sessions"
+)
+
+// Sessions checks the sessions.Storage implementation.
+// It takes a bit longer than 200ms to check the expiry.
+func Sessions(t *testing.T, s sessions.Storage) {
+	ctx := context.Background()
+
+	userID := unique("conformance")
+	key := unique("conformance:")
+
+	_, err := s.Get(ctx, key)
+	is(t, "Get of a missing key", err, sessions.ErrNotFound)
+
+	if ok(t, "Set", s.Set(ctx, userID, key, []byte("first"), time.Minute)) {
+		v, err := s.Get(ctx, key)
+		if ok(t, "Get", err) && !bytes.Equal(v, []byte("first")) {
+			t.Errorf("Get: got %q, want %q", v, "first")
+		}
+	}
+
+	if ok(t, "Set of an existing key", s.Set(ctx, userID, key, []byte("second"), time.Minute)) {
+		v, err := s.Get(ctx, key)
+		if ok(t, "Get of an overwritten key", err) && !bytes.Equal(v, []byte("second")) {
+			t.Errorf("Get of an overwritten key: got %q, want %q", v, "second")
+		}
+	}
+
+	expiring := unique("conformance:")
+	if ok(t, "Set with a short ttl", s.Set(ctx, userID, expiring, []byte("value"), 100*time.Millisecond)) {
+		time.Sleep(200 * time.Millisecond)
+
+		_, err := s.Get(ctx, expiring)
+		is(t, "Get of an expired key", err, sessions.ErrNotFound)
+	}
+
+	later := unique("conformance:")
+	if ok(t, "Set of a second session", s.Set(ctx, userID, later, []byte("value"), time.Hour)) {
+		list, err := s.List(ctx, userID)
+		if ok(t, "List", err) {
+			if len(list) != 2 || list[0].ID != key || list[1].ID != later {
+				t.Errorf("List: got %+v, want %s and %s, the soonest to expire first", list, key, later)
+			} else if list[0].ExpiresOn.IsZero() || !list[0].ExpiresOn.Before(list[1].ExpiresOn) {
+				t.Errorf("List: expiry is not filled: %+v", list)
+			}
+		}
+	}
+
+	if ok(t, "DeleteAll", s.DeleteAll(ctx, userID)) {
+		_, err := s.Get(ctx, later)
+		is(t, "Get of a revoked session", err, sessions.ErrNotFound)
+
+		list, err := s.List(ctx, userID)
+		if ok(t, "List after DeleteAll", err) && len(list) != 0 {
+			t.Errorf("List after DeleteAll: got %d sessions, want none", len(list))
+		}
+	}
+
+	list, err := s.List(ctx, unique("conformance"))
+	if ok(t, "List of an unknown user", err) && len(list) != 0 {
+		t.Errorf("List of an unknown user: got %d sessions, want none", len(list))
+	}
+
+}
@@ -0,0 +1,111 @@
+// Package storagetest is the conformance suite of the storages. Every
+// implementation of tasks.Storage, dependencies.Storage, users.Storage,
+// workspaces.Storage, sessions.Storage, exports.Storage and
+// templates.Storage must pass it, the tests of the implementations run it.
+//
+// The in-memory and the sqlite storages are always checked, the sqlite
+// ones in a new database. The postgres and the redis ones are checked
+// when STORAGETEST_PG_URL and STORAGETEST_REDIS_URL are set, the postgres
+// database must be migrated.
+//
+// The suites create their own data with random names, so they can run
+// against a shared database, and do not clean it up.
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/migrations"
+)
+
+// Postgres returns the pool of the database at STORAGETEST_PG_URL. The
+// test is skipped if it is not set.
+func Postgres(t testing.TB) *sql.DB {
+	t.Helper()
+
+	url := os.Getenv("STORAGETEST_PG_URL")
+	if url == "" {
+		t.Skip("STORAGETEST_PG_URL is not set")
+	}
+
+	db, err := storages.NewDBPool(storages.DriverPostgres, url)
+	if err != nil {
+		t.Fatalf("failed to connect to postgres: %v", err)
+	}
+	t.Cleanup(func() { storages.ClosePool(db) })
+
+	return db
+}
+
+// SQLite returns the pool of a new migrated sqlite database.
+func SQLite(t testing.TB) *sql.DB {
+	t.Helper()
+
+	db, err := storages.NewSQLitePool(filepath.Join(t.TempDir(), "eldorado.db"))
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	t.Cleanup(func() { storages.ClosePool(db) })
+
+	m, err := migrations.New(db, migrations.WithDriver(storages.DriverSQLite))
+	if err == nil {
+		_, err = m.Up(context.Background())
+	}
+	if err != nil && !errors.Is(err, migrations.ErrNoChange) {
+		t.Fatalf("failed to migrate sqlite: %v", err)
+	}
+
+	return db
+}
+
+// Redis returns the client of the redis at STORAGETEST_REDIS_URL. The test
+// is skipped if it is not set.
+func Redis(t testing.TB) *redis.Client {
+	t.Helper()
+
+	url := os.Getenv("STORAGETEST_REDIS_URL")
+	if url == "" {
+		t.Skip("STORAGETEST_REDIS_URL is not set")
+	}
+
+	client, err := storages.NewRedisClient(url)
+	if err != nil {
+		t.Fatalf("failed to connect to redis: %v", err)
+	}
+	t.Cleanup(func() { storages.CloseRedisClient(client) })
+
+	return client
+}
+
+// is checks that err matches target.
+func is(t *testing.T, name string, err, target error) {
+	t.Helper()
+
+	if !errors.Is(err, target) {
+		t.Errorf("%s: got error %v, want %v", name, err, target)
+	}
+}
+
+// ok checks that err is nil and reports whether it is.
+func ok(t *testing.T, name string, err error) bool {
+	t.Helper()
+
+	if err != nil {
+		t.Errorf("%s: unexpected error: %v", name, err)
+		return false
+	}
+	return true
+}
+
+// unique returns a random name with the given prefix.
+func unique(prefix string) string {
+	return prefix + uuid.NewString()[:8]
+}
@@ -0,0 +1,208 @@
+package storagetest
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+	"github.com/romankravchuk/eldorado/internal/storages/users"
+	"github.com/romankravchuk/eldorado/internal/storages/workspaces"
+)
+
+// Tasks checks the tasks.Storage implementation. The owners of the tasks
+// are created in us and their workspaces in ws, which must be the users
+// and the workspaces of ts. The tasks are checked in the workspace of the
+// owner, and must not be seen from the workspace of the other user.
+func Tasks(t *testing.T, ts tasks.Storage, us users.Storage, ws workspaces.Storage) {
+	ctx := context.Background()
+
+	owner := data.User{Email: unique("conformance") + "@example.com", Username: unique("conformance"), EncryptedPassword: "hash"}
+	other := data.User{Email: unique("conformance") + "@example.com", Username: unique("conformance"), EncryptedPassword: "hash"}
+	if !ok(t, "users.Save", us.Save(storages.AllWorkspaces(ctx), &owner)) || !ok(t, "users.Save", us.Save(storages.AllWorkspaces(ctx), &other)) {
+		return
+	}
+
+	workspace := data.Workspace{Name: unique("conformance")}
+	foreign := data.Workspace{Name: unique("conformance")}
+	if !ok(t, "workspaces.Create", ws.Create(ctx, &workspace, owner.ID)) || !ok(t, "workspaces.Create", ws.Create(ctx, &foreign, other.ID)) {
+		return
+	}
+
+	foreignCtx := storages.WithWorkspace(ctx, foreign.ID)
+	ctx = storages.WithWorkspace(ctx, workspace.ID)
+
+	// Timestamp columns keep microseconds.
+	due := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+
+	task := data.Task{
+		UserID:      owner.ID,
+		Title:       unique("task "),
+		Description: "description",
+		DueOn:       due,
+		Tags:        []string{"work", "home"},
+		Priority:    data.PriorityHigh,
+		Recurrence:  "FREQ=DAILY;INTERVAL=1",
+		Checklist:   []data.ChecklistItem{{Text: "step"}, {Text: "done", IsDone: true}},
+	}
+
+	if !ok(t, "Save", ts.Save(ctx, &task)) {
+		return
+	}
+	if task.ID == "" || task.CreatedOn.IsZero() || task.IsCompleted {
+		t.Errorf("Save: ID, CreatedOn and IsCompleted are not filled: %+v", task)
+	}
+	if task.WorkspaceID != workspace.ID {
+		t.Errorf("Save: got workspace %q, want the workspace of the context %q", task.WorkspaceID, workspace.ID)
+	}
+
+	got, err := ts.FindByID(ctx, task.ID)
+	if ok(t, "FindByID", err) {
+		switch {
+		case got.UserID != task.UserID, got.WorkspaceID != workspace.ID, got.Title != task.Title, got.Description != task.Description,
+			got.Priority != task.Priority, got.Recurrence != task.Recurrence:
+			t.Errorf("FindByID: got %+v, want %+v", got, task)
+		case !got.DueOn.Equal(due):
+			t.Errorf("FindByID: got due %v, want %v", got.DueOn, due)
+		case !slices.Equal(got.Tags, task.Tags):
+			t.Errorf("FindByID: got tags %v, want %v", got.Tags, task.Tags)
+		case !slices.Equal(got.Checklist, task.Checklist):
+			t.Errorf("FindByID: got checklist %v, want %v", got.Checklist, task.Checklist)
+		case got.IsCompleted, got.IsDeleted, !got.HiddenUntil.IsZero(), !got.CompletedOn.IsZero(), !got.ArchivedOn.IsZero():
+			t.Errorf("FindByID: a new task has state %+v", got)
+		}
+	}
+
+	missingID := uuid.NewString()
+
+	_, err = ts.FindByID(ctx, missingID)
+	is(t, "FindByID of a missing task", err, tasks.ErrNotFound)
+
+	listed := func(name, userID string, f tasks.Filter, want bool) {
+		tt, err := ts.FindByUserID(ctx, userID, f)
+		if !ok(t, name, err) {
+			return
+		}
+		if has := containsTask(tt, task.ID); has != want {
+			t.Errorf("%s: task listed %t, want %t", name, has, want)
+		}
+	}
+
+	listed("FindByUserID", owner.ID, tasks.Filter{}, true)
+	listed("FindByUserID of another user", other.ID, tasks.Filter{}, false)
+	listed("FindByUserID of archived tasks", owner.ID, tasks.Filter{Archived: true}, false)
+
+	// Isolation of the workspaces.
+	tt, err := ts.FindByUserID(foreignCtx, owner.ID, tasks.Filter{IncludeSnoozed: true})
+	if ok(t, "FindByUserID in another workspace", err) && containsTask(tt, task.ID) {
+		t.Errorf("FindByUserID: the task is listed in another workspace")
+	}
+
+	_, err = ts.FindByID(foreignCtx, task.ID)
+	is(t, "FindByID in another workspace", err, tasks.ErrNotFound)
+
+	stats, err := ts.UncompletedStatistic(ctx)
+	if ok(t, "UncompletedStatistic", err) && !containsStatistic(stats, owner.Email, task.Title) {
+		t.Errorf("UncompletedStatistic: the task of %s is missing", owner.Email)
+	}
+
+	// Snoozing.
+	if ok(t, "Snooze", ts.Snooze(ctx, task.ID, time.Now().Add(time.Hour))) {
+		listed("FindByUserID of a snoozed task", owner.ID, tasks.Filter{}, false)
+		listed("FindByUserID with snoozed tasks", owner.ID, tasks.Filter{IncludeSnoozed: true}, true)
+	}
+	if ok(t, "Snooze with zero time", ts.Snooze(ctx, task.ID, time.Time{})) {
+		listed("FindByUserID of an unsnoozed task", owner.ID, tasks.Filter{}, true)
+	}
+	is(t, "Snooze of a missing task", ts.Snooze(ctx, missingID, time.Now().Add(time.Hour)), tasks.ErrNotFound)
+
+	// Completion.
+	update := task
+	update.Title = unique("updated ")
+	update.IsCompleted = true
+	if ok(t, "Update", ts.Update(ctx, &update)) {
+		got, err := ts.FindByID(ctx, task.ID)
+		if ok(t, "FindByID of an updated task", err) {
+			if got.Title != update.Title || !got.IsCompleted || got.CompletedOn.IsZero() {
+				t.Errorf("Update: got %+v, want completed task titled %q", got, update.Title)
+			}
+		}
+	}
+
+	update.IsCompleted = false
+	if ok(t, "Update reopening the task", ts.Update(ctx, &update)) {
+		got, err := ts.FindByID(ctx, task.ID)
+		if ok(t, "FindByID of a reopened task", err) && (got.IsCompleted || !got.CompletedOn.IsZero()) {
+			t.Errorf("Update: a reopened task has state %+v", got)
+		}
+	}
+
+	missing := update
+	missing.ID = missingID
+	is(t, "Update of a missing task", ts.Update(ctx, &missing), tasks.ErrNotFound)
+
+	// Archiving.
+	is(t, "Unarchive of a task not archived", ts.Unarchive(ctx, task.ID), tasks.ErrNotFound)
+
+	archived, err := ts.Archive(ctx, 0)
+	if ok(t, "Archive", err) && slices.Contains(archived, tasks.Owner{WorkspaceID: workspace.ID, UserID: owner.ID}) {
+		t.Errorf("Archive: archived the tasks of %s with archiving disabled", owner.ID)
+	}
+
+	// Deletion.
+	if ok(t, "Delete", ts.Delete(ctx, task.ID)) {
+		_, err := ts.FindByID(ctx, task.ID)
+		is(t, "FindByID of a deleted task", err, tasks.ErrNotFound)
+
+		listed("FindByUserID of a deleted task", owner.ID, tasks.Filter{IncludeSnoozed: true}, false)
+
+		all, err := ts.FindByUserID(ctx, owner.ID, tasks.Filter{All: true})
+		if ok(t, "FindByUserID of all tasks", err) {
+			found := false
+			for _, v := range all {
+				if v.ID == task.ID {
+					found = true
+					if !v.IsDeleted {
+						t.Errorf("FindByUserID of all tasks: the deleted task %s is not marked deleted", task.ID)
+					}
+				}
+			}
+			if !found {
+				t.Errorf("FindByUserID of all tasks: the deleted task %s is not listed", task.ID)
+			}
+		}
+
+		stats, err := ts.UncompletedStatistic(ctx)
+		if ok(t, "UncompletedStatistic", err) && containsStatistic(stats, owner.Email, update.Title) {
+			t.Errorf("UncompletedStatistic: a deleted task of %s is listed", owner.Email)
+		}
+
+		is(t, "Delete of a deleted task", ts.Delete(ctx, task.ID), tasks.ErrNotFound)
+		is(t, "Update of a deleted task", ts.Update(ctx, &update), tasks.ErrNotFound)
+		is(t, "Snooze of a deleted task", ts.Snooze(ctx, task.ID, time.Now().Add(time.Hour)), tasks.ErrNotFound)
+	}
+	is(t, "Delete of a missing task", ts.Delete(ctx, missingID), tasks.ErrNotFound)
+
+}
+
+func containsTask(tt []data.Task, id string) bool {
+	for _, t := range tt {
+		if t.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsStatistic(stats []data.StatisticTask, email, title string) bool {
+	for _, st := range stats {
+		if st.Email == email && st.Title == title {
+			return true
+		}
+	}
+	return false
+}
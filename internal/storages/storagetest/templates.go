@@ -0,0 +1,89 @@
+package storagetest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/templates"
+	"github.com/romankravchuk/eldorado/internal/storages/users"
+)
+
+// Templates checks the templates.Storage implementation. The owners are
+// created in us, which must be the users of ts.
+func Templates(t *testing.T, ts templates.Storage, us users.Storage) {
+	ctx := context.Background()
+
+	owner := data.User{Email: unique("conformance") + "@example.com", Username: unique("conformance"), EncryptedPassword: "hash"}
+	other := data.User{Email: unique("conformance") + "@example.com", Username: unique("conformance"), EncryptedPassword: "hash"}
+	if !ok(t, "users.Save", us.Save(storages.AllWorkspaces(ctx), &owner)) || !ok(t, "users.Save", us.Save(storages.AllWorkspaces(ctx), &other)) {
+		return
+	}
+
+	weekly := data.Template{
+		UserID:      owner.ID,
+		Name:        "weekly",
+		Title:       "Review of {{date}}",
+		Description: "What was done on {{weekday}}",
+		Tags:        []string{"work", "review"},
+		Priority:    data.PriorityHigh,
+		Recurrence:  "every week",
+		Checklist:   []string{"Inbox", "Calendar"},
+	}
+	if !ok(t, "Save", ts.Save(ctx, &weekly)) {
+		return
+	}
+	if weekly.ID == "" || weekly.CreatedOn.IsZero() {
+		t.Errorf("Save: ID and CreatedOn are not filled: %+v", weekly)
+	}
+
+	got, err := ts.FindByID(ctx, weekly.ID)
+	if ok(t, "FindByID", err) {
+		if !got.CreatedOn.Equal(weekly.CreatedOn) {
+			t.Errorf("FindByID: got CreatedOn %v, want %v", got.CreatedOn, weekly.CreatedOn)
+		}
+		got.CreatedOn = weekly.CreatedOn
+		if !reflect.DeepEqual(got, weekly) {
+			t.Errorf("FindByID: got %+v, want %+v", got, weekly)
+		}
+	}
+
+	_, err = ts.FindByID(ctx, uuid.NewString())
+	is(t, "FindByID of a missing template", err, templates.ErrNotFound)
+
+	is(t, "Save of a duplicate name", ts.Save(ctx, &data.Template{UserID: owner.ID, Name: "weekly", Title: "Other"}), templates.ErrAlreadyExists)
+
+	// The names are unique per user.
+	foreign := data.Template{UserID: other.ID, Name: "weekly", Title: "Foreign"}
+	ok(t, "Save of the name of another user", ts.Save(ctx, &foreign))
+
+	// A template without tags and checklist is read back with empty ones.
+	daily := data.Template{UserID: owner.ID, Name: "daily", Title: "Plan the day"}
+	if !ok(t, "Save without tags and checklist", ts.Save(ctx, &daily)) {
+		return
+	}
+
+	tt, err := ts.FindByUserID(ctx, owner.ID)
+	if ok(t, "FindByUserID", err) {
+		if len(tt) != 2 || tt[0].ID != daily.ID || tt[1].ID != weekly.ID {
+			t.Errorf("FindByUserID: got %+v, want %s and %s ordered by name", tt, daily.ID, weekly.ID)
+		} else if len(tt[0].Tags) != 0 || len(tt[0].Checklist) != 0 {
+			t.Errorf("FindByUserID: got tags %v and checklist %v, want none", tt[0].Tags, tt[0].Checklist)
+		}
+	}
+
+	tt, err = ts.FindByUserID(ctx, uuid.NewString())
+	if ok(t, "FindByUserID of a user without templates", err) && len(tt) != 0 {
+		t.Errorf("FindByUserID of a user without templates: got %+v", tt)
+	}
+
+	if ok(t, "Delete", ts.Delete(ctx, daily.ID)) {
+		_, err = ts.FindByID(ctx, daily.ID)
+		is(t, "FindByID of a deleted template", err, templates.ErrNotFound)
+	}
+
+	is(t, "Delete of a deleted template", ts.Delete(ctx, daily.ID), templates.ErrNotFound)
+}
@@ -0,0 +1,111 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/users"
+)
+
+// Users checks the users.Storage implementation.
+func Users(t *testing.T, s users.Storage) {
+	// The users are created and signed in on behalf of no workspace.
+	ctx := storages.AllWorkspaces(context.Background())
+
+	u := data.User{
+		Email:             unique("conformance") + "@example.com",
+		Username:          unique("conformance"),
+		Name:              "Conformance",
+		EncryptedPassword: "hash",
+	}
+
+	if !ok(t, "Save", s.Save(ctx, &u)) {
+		return
+	}
+	if u.ID == "" {
+		t.Errorf("Save: ID is not filled")
+	}
+
+	byEmail, err := s.FindByEmail(ctx, u.Email)
+	if ok(t, "FindByEmail", err) {
+		if byEmail.ID != u.ID || byEmail.Username != u.Username || byEmail.Name != u.Name || byEmail.EncryptedPassword != u.EncryptedPassword {
+			t.Errorf("FindByEmail: got %+v, want %+v", byEmail, u)
+		}
+		if byEmail.CreatedOn.IsZero() {
+			t.Errorf("FindByEmail: CreatedOn is not filled")
+		}
+	}
+
+	byID, err := s.FindByID(ctx, u.ID)
+	if ok(t, "FindByID", err) && byID.Email != u.Email {
+		t.Errorf("FindByID: got user %s, want %s", byID.Email, u.Email)
+	}
+
+	byUsername, err := s.FindByUsername(ctx, u.Username)
+	if ok(t, "FindByUsername", err) && byUsername.ID != u.ID {
+		t.Errorf("FindByUsername: got user %s, want %s", byUsername.ID, u.ID)
+	}
+
+	_, err = s.FindByEmail(ctx, unique("missing")+"@example.com")
+	is(t, "FindByEmail of a missing user", err, users.ErrNotFound)
+
+	_, err = s.FindByID(ctx, uuid.NewString())
+	is(t, "FindByID of a missing user", err, users.ErrNotFound)
+
+	_, err = s.FindByUsername(ctx, unique("missing"))
+	is(t, "FindByUsername of a missing user", err, users.ErrNotFound)
+
+	sameEmail := data.User{Email: u.Email, Username: unique("conformance"), EncryptedPassword: "hash"}
+	is(t, "Save with a taken email", s.Save(ctx, &sameEmail), users.ErrAlreadyExists)
+
+	sameUsername := data.User{Email: unique("conformance") + "@example.com", Username: u.Username, EncryptedPassword: "hash"}
+	is(t, "Save with a taken username", s.Save(ctx, &sameUsername), users.ErrAlreadyExists)
+
+	if ok(t, "Delete", s.Delete(ctx, u.ID)) {
+		_, err = s.FindByID(ctx, u.ID)
+		is(t, "FindByID of a deleted user", err, users.ErrNotFound)
+
+		deleted, err := s.FindByEmail(ctx, u.Email)
+		if ok(t, "FindByEmail of a deleted user", err) && deleted.DeletedOn.IsZero() {
+			t.Errorf("FindByEmail of a deleted user: DeletedOn is not filled")
+		}
+
+		is(t, "Delete of a deleted user", s.Delete(ctx, u.ID), users.ErrNotFound)
+
+		if ok(t, "Restore", s.Restore(ctx, u.ID)) {
+			_, err = s.FindByID(ctx, u.ID)
+			ok(t, "FindByID of a restored user", err)
+		}
+	}
+	is(t, "Restore of a user not deleted", s.Restore(ctx, u.ID), users.ErrNotFound)
+
+	purged := data.User{Email: unique("conformance") + "@example.com", Username: unique("conformance"), EncryptedPassword: "hash"}
+	if ok(t, "Save", s.Save(ctx, &purged)) && ok(t, "Delete", s.Delete(ctx, purged.ID)) {
+		ids, err := s.Purge(ctx, time.Now().Add(-time.Hour))
+		if ok(t, "Purge", err) && contains(ids, purged.ID) {
+			t.Errorf("Purge: deleted user %s before the given time", purged.ID)
+		}
+
+		ids, err = s.Purge(ctx, time.Now().Add(time.Hour))
+		if ok(t, "Purge", err) && (!contains(ids, purged.ID) || contains(ids, u.ID)) {
+			t.Errorf("Purge: got %v, want %s and not %s", ids, purged.ID, u.ID)
+		}
+
+		_, err = s.FindByEmail(ctx, purged.Email)
+		is(t, "FindByEmail of a purged user", err, users.ErrNotFound)
+	}
+
+}
+
+func contains(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/users"
+	"github.com/romankravchuk/eldorado/internal/storages/workspaces"
+)
+
+// Workspaces checks the workspaces.Storage implementation. The members are
+// created in us, which must be the users of ws.
+func Workspaces(t *testing.T, ws workspaces.Storage, us users.Storage) {
+	ctx := context.Background()
+
+	owner := data.User{Email: unique("conformance") + "@example.com", Username: unique("conformance"), EncryptedPassword: "hash"}
+	if !ok(t, "users.Save", us.Save(storages.AllWorkspaces(ctx), &owner)) {
+		return
+	}
+
+	first := data.Workspace{Name: unique("conformance")}
+	if !ok(t, "Create", ws.Create(ctx, &first, owner.ID)) {
+		return
+	}
+	if first.ID == "" || first.CreatedOn.IsZero() {
+		t.Errorf("Create: ID and CreatedOn are not filled: %+v", first)
+	}
+
+	m, err := ws.FindMembership(ctx, first.ID, owner.ID)
+	if ok(t, "FindMembership", err) && (m.WorkspaceID != first.ID || m.UserID != owner.ID || m.Role != data.RoleOwner) {
+		t.Errorf("FindMembership: got %+v, want the owner of %s", m, first.ID)
+	}
+
+	_, err = ws.FindMembership(ctx, uuid.NewString(), owner.ID)
+	is(t, "FindMembership of a missing workspace", err, workspaces.ErrNotFound)
+
+	second := data.Workspace{Name: unique("conformance")}
+	if !ok(t, "Create of a second workspace", ws.Create(ctx, &second, owner.ID)) {
+		return
+	}
+
+	mm, err := ws.Memberships(ctx, owner.ID)
+	if ok(t, "Memberships", err) && (len(mm) != 2 || mm[0].WorkspaceID != first.ID || mm[1].WorkspaceID != second.ID) {
+		t.Errorf("Memberships: got %+v, want %s and %s, the oldest first", mm, first.ID, second.ID)
+	}
+
+	mm, err = ws.Memberships(ctx, uuid.NewString())
+	if ok(t, "Memberships of a user without workspaces", err) && len(mm) != 0 {
+		t.Errorf("Memberships of a user without workspaces: got %+v", mm)
+	}
+
+}
@@ -0,0 +1,355 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/settings"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+)
+
+// Emails finds the emails of the users for UncompletedStatistic.
+type Emails interface {
+	EmailByID(userID string) (string, bool)
+}
+
+type Option func(*TasksStorage)
+
+// WithEmails sets the emails of the users. Without it UncompletedStatistic
+// returns nothing, as the postgres storage does for unknown users.
+func WithEmails(emails Emails) Option {
+	return func(s *TasksStorage) {
+		s.emails = emails
+	}
+}
+
+// WithSettings sets the per-user archive_after_days used by Archive.
+func WithSettings(settings settings.Storage) Option {
+	return func(s *TasksStorage) {
+		s.settings = settings
+	}
+}
+
+// TasksStorage is an in-memory implementation of tasks.Storage.
+// It is safe for concurrent use.
+//
+// Task dependencies are not kept, so IsBlocked is always false. The
+// lookups filter the tasks by the workspace of the context, as the sqlite
+// storage does.
+type TasksStorage struct {
+	mu    sync.RWMutex
+	tasks map[string]data.Task
+
+	emails   Emails
+	settings settings.Storage
+}
+
+func New(opts ...Option) *TasksStorage {
+	s := &TasksStorage{tasks: make(map[string]data.Task)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// UncompletedStatistic returns 5 or low uncompleted tasks for each user.
+//
+// Deleted and snoozed tasks are skipped.
+func (s *TasksStorage) UncompletedStatistic(_ context.Context) ([]data.StatisticTask, error) {
+	if s.emails == nil {
+		return nil, nil
+	}
+
+	now := time.Now()
+
+	s.mu.RLock()
+	byUser := make(map[string][]data.Task)
+	for _, t := range s.tasks {
+		if !t.IsCompleted && !t.IsDeleted && !t.IsSnoozed(now) {
+			byUser[t.UserID] = append(byUser[t.UserID], t)
+		}
+	}
+	s.mu.RUnlock()
+
+	type ranked struct {
+		email string
+		tasks []data.Task
+	}
+
+	users := make([]ranked, 0, len(byUser))
+	for userID, tt := range byUser {
+		email, ok := s.emails.EmailByID(userID)
+		if !ok {
+			continue
+		}
+
+		sort.Slice(tt, func(i, j int) bool { return tt[i].CreatedOn.After(tt[j].CreatedOn) })
+		if len(tt) > 5 {
+			tt = tt[:5]
+		}
+
+		users = append(users, ranked{email: email, tasks: tt})
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].email < users[j].email })
+
+	var stats []data.StatisticTask
+	for _, u := range users {
+		for _, t := range u.tasks {
+			stats = append(stats, data.StatisticTask{Email: u.email, Title: t.Title, CreatedOn: t.CreatedOn})
+		}
+	}
+
+	return stats, nil
+}
+
+// FindByUserID returns a list of tasks for a given user ordered by creation.
+//
+// Snoozed tasks are excluded unless f.IncludeSnoozed is set.
+// Archived tasks are returned only if f.Archived is set.
+func (s *TasksStorage) FindByUserID(ctx context.Context, userID string, f tasks.Filter) ([]data.Task, error) {
+	now := time.Now()
+	workspaceID, scoped := storages.WorkspaceID(ctx)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tt []data.Task
+	for _, t := range s.tasks {
+		if t.UserID != userID || (scoped && t.WorkspaceID != workspaceID) {
+			continue
+		}
+		if f.All {
+			tt = append(tt, clone(t))
+			continue
+		}
+		if t.IsDeleted {
+			continue
+		}
+		if !f.IncludeSnoozed && t.IsSnoozed(now) {
+			continue
+		}
+		if !t.ArchivedOn.IsZero() != f.Archived {
+			continue
+		}
+		tt = append(tt, clone(t))
+	}
+
+	sort.Slice(tt, func(i, j int) bool { return tt[i].CreatedOn.Before(tt[j].CreatedOn) })
+
+	return tt, nil
+}
+
+// FindByID returns a task by given id.
+//
+// If task is not found or deleted returns tasks.ErrNotFound.
+func (s *TasksStorage) FindByID(ctx context.Context, id string) (data.Task, error) {
+	workspaceID, scoped := storages.WorkspaceID(ctx)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tasks[id]
+	if !ok || t.IsDeleted || (scoped && t.WorkspaceID != workspaceID) {
+		return data.Task{}, tasks.ErrNotFound
+	}
+
+	return clone(t), nil
+}
+
+// Save saves a task in t.WorkspaceID, or in the workspace of ctx if it is
+// empty.
+//
+// If save succeeds ID, IsCompleted and CreatedOn fields are filled.
+func (s *TasksStorage) Save(ctx context.Context, t *data.Task) error {
+	if t.WorkspaceID == "" {
+		t.WorkspaceID, _ = storages.WorkspaceID(ctx)
+	}
+
+	t.ID = uuid.NewString()
+	t.IsCompleted = false
+	t.CreatedOn = time.Now().UTC()
+
+	stored := clone(*t)
+	stored.IsDeleted = false
+	stored.IsBlocked = false
+	stored.HiddenUntil = time.Time{}
+	stored.CompletedOn = time.Time{}
+	stored.ArchivedOn = time.Time{}
+	stored.DueOn = utc(stored.DueOn)
+	if stored.Tags == nil {
+		stored.Tags = []string{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tasks[t.ID] = stored
+
+	return nil
+}
+
+// Delete marks a task as deleted.
+//
+// If the task is not found or already deleted returns tasks.ErrNotFound.
+func (s *TasksStorage) Delete(_ context.Context, id string) error {
+	return s.update(id, func(t *data.Task) error {
+		t.IsDeleted = true
+		return nil
+	})
+}
+
+// Update updates the title, description and completion of a task.
+//
+// Completing a task stamps CompletedOn, reopening it clears CompletedOn
+// and brings the task back from the archive.
+// If the task is not found or deleted returns tasks.ErrNotFound.
+func (s *TasksStorage) Update(_ context.Context, u *data.Task) error {
+	return s.update(u.ID, func(t *data.Task) error {
+		switch {
+		case !u.IsCompleted:
+			t.CompletedOn = time.Time{}
+			t.ArchivedOn = time.Time{}
+		case !t.IsCompleted:
+			t.CompletedOn = time.Now().UTC()
+		}
+
+		t.Title = u.Title
+		t.Description = u.Description
+		t.IsCompleted = u.IsCompleted
+
+		return nil
+	})
+}
+
+// Snooze hides a task from default lists until the given time.
+// Zero until shows the task again.
+//
+// If the task is not found or deleted returns tasks.ErrNotFound.
+func (s *TasksStorage) Snooze(_ context.Context, id string, until time.Time) error {
+	return s.update(id, func(t *data.Task) error {
+		t.HiddenUntil = utc(until)
+		return nil
+	})
+}
+
+// Archive archives the tasks completed more than archive_after_days ago.
+// Users without settings use defaultDays. Zero days disables archiving.
+//
+// Returns the users whose tasks were archived with their workspaces.
+func (s *TasksStorage) Archive(ctx context.Context, defaultDays int) ([]tasks.Owner, error) {
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	days := make(map[string]int)
+	var owners []tasks.Owner
+
+	for id, t := range s.tasks {
+		if !t.IsCompleted || t.IsDeleted || !t.ArchivedOn.IsZero() || t.CompletedOn.IsZero() {
+			continue
+		}
+
+		d, ok := days[t.UserID]
+		if !ok {
+			var err error
+			if d, err = s.archiveAfterDays(ctx, t.UserID, defaultDays); err != nil {
+				return nil, err
+			}
+			days[t.UserID] = d
+		}
+
+		if d <= 0 || t.CompletedOn.After(now.AddDate(0, 0, -d)) {
+			continue
+		}
+
+		t.ArchivedOn = now
+		s.tasks[id] = t
+
+		o := tasks.Owner{WorkspaceID: t.WorkspaceID, UserID: t.UserID}
+		if !slices.Contains(owners, o) {
+			owners = append(owners, o)
+		}
+	}
+
+	return owners, nil
+}
+
+// Unarchive brings an archived task back to the task list.
+// CompletedOn is reset, so the archive countdown starts over.
+//
+// If the task is not archived returns tasks.ErrNotFound.
+func (s *TasksStorage) Unarchive(_ context.Context, id string) error {
+	return s.update(id, func(t *data.Task) error {
+		if t.ArchivedOn.IsZero() {
+			return tasks.ErrNotFound
+		}
+
+		t.ArchivedOn = time.Time{}
+		t.CompletedOn = time.Now().UTC()
+
+		return nil
+	})
+}
+
+func (s *TasksStorage) update(id string, fn func(*data.Task) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tasks[id]
+	if !ok || t.IsDeleted {
+		return tasks.ErrNotFound
+	}
+
+	if err := fn(&t); err != nil {
+		return err
+	}
+
+	s.tasks[id] = t
+
+	return nil
+}
+
+func (s *TasksStorage) archiveAfterDays(ctx context.Context, userID string, defaultDays int) (int, error) {
+	if s.settings == nil {
+		return defaultDays, nil
+	}
+
+	st, err := s.settings.Find(ctx, userID)
+	if errors.Is(err, settings.ErrNotFound) {
+		return defaultDays, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return st.ArchiveAfterDays, nil
+}
+
+// clone copies the slices of the task, so callers can not change the
+// stored one.
+func clone(t data.Task) data.Task {
+	if t.Tags != nil {
+		t.Tags = append([]string{}, t.Tags...)
+	}
+	if t.Checklist != nil {
+		t.Checklist = append([]data.ChecklistItem{}, t.Checklist...)
+	}
+	return t
+}
+
+// utc drops the location like the timestamp columns of the postgres storage do.
+func utc(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.UTC()
+}
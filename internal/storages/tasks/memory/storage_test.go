@@ -0,0 +1,16 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks/memory"
+	usersmemory "github.com/romankravchuk/eldorado/internal/storages/users/memory"
+	workspacesmemory "github.com/romankravchuk/eldorado/internal/storages/workspaces/memory"
+)
+
+func TestConformance(t *testing.T) {
+	users := usersmemory.New()
+
+	storagetest.Tasks(t, memory.New(memory.WithEmails(users)), users, workspacesmemory.New())
+}
@@ -7,6 +7,10 @@ import (
 
 	data "github.com/romankravchuk/eldorado/internal/data"
 	mock "github.com/stretchr/testify/mock"
+
+	tasks "github.com/romankravchuk/eldorado/internal/storages/tasks"
+
+	time "time"
 )
 
 // Storage is an autogenerated mock type for the Storage type
@@ -14,6 +18,32 @@ type Storage struct {
 	mock.Mock
 }
 
+// Archive provides a mock function with given fields: ctx, defaultDays
+func (_m *Storage) Archive(ctx context.Context, defaultDays int) ([]tasks.Owner, error) {
+	ret := _m.Called(ctx, defaultDays)
+
+	var r0 []tasks.Owner
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]tasks.Owner, error)); ok {
+		return rf(ctx, defaultDays)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []tasks.Owner); ok {
+		r0 = rf(ctx, defaultDays)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]tasks.Owner)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, defaultDays)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Delete provides a mock function with given fields: ctx, id
 func (_m *Storage) Delete(ctx context.Context, id string) error {
 	ret := _m.Called(ctx, id)
@@ -28,25 +58,49 @@ func (_m *Storage) Delete(ctx context.Context, id string) error {
 	return r0
 }
 
-// FindByUserID provides a mock function with given fields: ctx, userID
-func (_m *Storage) FindByUserID(ctx context.Context, userID string) ([]data.Task, error) {
-	ret := _m.Called(ctx, userID)
+// FindByID provides a mock function with given fields: ctx, id
+func (_m *Storage) FindByID(ctx context.Context, id string) (data.Task, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 data.Task
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (data.Task, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) data.Task); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(data.Task)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindByUserID provides a mock function with given fields: ctx, userID, f
+func (_m *Storage) FindByUserID(ctx context.Context, userID string, f tasks.Filter) ([]data.Task, error) {
+	ret := _m.Called(ctx, userID, f)
 
 	var r0 []data.Task
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) ([]data.Task, error)); ok {
-		return rf(ctx, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, tasks.Filter) ([]data.Task, error)); ok {
+		return rf(ctx, userID, f)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) []data.Task); ok {
-		r0 = rf(ctx, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, tasks.Filter) []data.Task); ok {
+		r0 = rf(ctx, userID, f)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]data.Task)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, userID)
+	if rf, ok := ret.Get(1).(func(context.Context, string, tasks.Filter) error); ok {
+		r1 = rf(ctx, userID, f)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -68,6 +122,34 @@ func (_m *Storage) Save(ctx context.Context, task *data.Task) error {
 	return r0
 }
 
+// Snooze provides a mock function with given fields: ctx, id, until
+func (_m *Storage) Snooze(ctx context.Context, id string, until time.Time) error {
+	ret := _m.Called(ctx, id, until)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = rf(ctx, id, until)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Unarchive provides a mock function with given fields: ctx, id
+func (_m *Storage) Unarchive(ctx context.Context, id string) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UncompletedStatistic provides a mock function with given fields: ctx
 func (_m *Storage) UncompletedStatistic(ctx context.Context) ([]data.StatisticTask, error) {
 	ret := _m.Called(ctx)
@@ -0,0 +1,97 @@
+package pg
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// The backoff between the restarts of a failed listener, see ListenForever.
+const (
+	minListenBackoff = time.Second
+	maxListenBackoff = time.Minute
+)
+
+// ChangesChannel is the channel the tasks triggers notify with the
+// workspace and the user whose tasks were changed, workspace_id:user_id.
+const ChangesChannel = "tasks_changed"
+
+// Listen calls onChange with the workspace and the user ID for every change
+// of the tasks until ctx is done, whoever made it.
+//
+// Notifications sent while the connection is down are lost, so onReset is
+// called every time the connection is re-established.
+func Listen(ctx context.Context, url string, onChange func(workspaceID, userID string), onReset func()) error {
+	l := pq.NewListener(url, time.Second, time.Minute, nil)
+	defer l.Close()
+
+	if err := l.Listen(ChangesChannel); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case n := <-l.Notify:
+			// pq sends nil after reconnecting.
+			if n == nil {
+				onReset()
+				continue
+			}
+			workspaceID, userID, ok := strings.Cut(n.Extra, ":")
+			if !ok {
+				// Sent by the triggers of the previous migration.
+				workspaceID, userID = "", n.Extra
+			}
+			onChange(workspaceID, userID)
+		case <-time.After(90 * time.Second):
+			go l.Ping()
+		}
+	}
+}
+
+// ListenForever runs Listen until ctx is done. A failed listener is
+// restarted after a backoff that starts at a second and doubles up to a
+// minute, and onReset is called then, as the notifications sent while it
+// was down are lost. onError is called with every failure.
+func ListenForever(ctx context.Context, url string, onChange func(workspaceID, userID string), onReset func(), onError func(error)) {
+	restart(ctx, func(ctx context.Context) error {
+		return Listen(ctx, url, onChange, onReset)
+	}, onReset, onError, minListenBackoff, maxListenBackoff)
+}
+
+// restart runs listen until ctx is done, see ListenForever. The backoff
+// starts over once listen has run for longer than maxBackoff.
+func restart(ctx context.Context, listen func(ctx context.Context) error, onReset func(), onError func(error), minBackoff, maxBackoff time.Duration) {
+	backoff := minBackoff
+
+	for {
+		started := time.Now()
+
+		err := listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		onError(err)
+
+		if time.Since(started) > maxBackoff {
+			backoff = minBackoff
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = 2 * backoff
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+
+		onReset()
+	}
+}
@@ -0,0 +1,92 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRestart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errListen := errors.New("connection refused")
+
+	var (
+		runs, resets int
+		errs         []error
+		starts       []time.Time
+	)
+
+	listen := func(ctx context.Context) error {
+		runs++
+		starts = append(starts, time.Now())
+		if runs <= 4 {
+			return errListen
+		}
+
+		// The fifth listener runs until the shutdown.
+		cancel()
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		restart(ctx, listen, func() { resets++ }, func(err error) { errs = append(errs, err) }, 10*time.Millisecond, 20*time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the listener is not stopped on shutdown")
+	}
+
+	if runs != 5 {
+		t.Fatalf("got %d runs, want 5", runs)
+	}
+	if resets != 4 {
+		t.Errorf("got %d cache resets, want one per restart, 4", resets)
+	}
+	if len(errs) != 4 {
+		t.Errorf("got %d errors, want 4", len(errs))
+	}
+	for _, err := range errs {
+		if !errors.Is(err, errListen) {
+			t.Errorf("got error %v, want %v", err, errListen)
+		}
+	}
+
+	// The backoff doubles from 10ms and stops at 20ms.
+	for i, want := range []time.Duration{10, 20, 20, 20} {
+		if got := starts[i+1].Sub(starts[i]); got < want*time.Millisecond {
+			t.Errorf("restart %d after %v, want at least %v", i+1, got, want*time.Millisecond)
+		}
+	}
+}
+
+func TestRestartStopsDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	resets := 0
+	done := make(chan struct{})
+	go func() {
+		restart(ctx, func(context.Context) error {
+			cancel()
+			return errors.New("connection refused")
+		}, func() { resets++ }, func(error) {}, time.Hour, time.Hour)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the backoff outlives the shutdown")
+	}
+
+	if resets != 0 {
+		t.Errorf("the cache is reset after the shutdown")
+	}
+}
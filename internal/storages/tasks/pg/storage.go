@@ -3,133 +3,242 @@ package pg
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/envelope"
 	"github.com/romankravchuk/eldorado/internal/storages"
 	"github.com/romankravchuk/eldorado/internal/storages/tasks"
 )
 
 // TasksStorage is a postgres implementation of tasks.Storage.
+//
+// Every call is scoped to the workspace of the context, see
+// storages.Scoped, and the row-level security of the tasks table hides
+// the tasks of the other workspaces. If the context is not scoped the calls
+// return storages.ErrNoWorkspace.
+//
+// With a keyring the title and the description are encrypted at rest, see
+// WithKeyring.
 type TasksStorage struct {
-	db *sql.DB
+	db       *sql.DB
+	stmts    *storages.Statements
+	replicas *storages.Replicas
+	keyring  *envelope.Keyring
+}
+
+type Option func(*TasksStorage)
+
+// WithReplicas routes the task lists and the statistic to the read
+// replicas. The rest of the queries go to the primary.
+func WithReplicas(replicas *storages.Replicas) Option {
+	return func(s *TasksStorage) {
+		s.replicas = replicas
+	}
+}
+
+// WithKeyring encrypts the title and the description of the saved and
+// updated tasks with a new data key wrapped with the primary key of the
+// keyring. The key ID and the wrapped data key are stored in the row.
+// The tasks stored in plaintext or under the other keys of the keyring
+// are still read, see Recrypt. A keyring without the primary key only
+// decrypts.
+func WithKeyring(k *envelope.Keyring) Option {
+	return func(s *TasksStorage) {
+		s.keyring = k
+	}
 }
 
 // New returns new TasksStorage instance with postgres db pool.
 //
-// If db is nil returns storages.ErrNilDBPool.
-func New(db *sql.DB) (*TasksStorage, error) {
+// If db is nil returns storages.ErrNilDBPool. If there is no keyring and
+// some tasks are encrypted returns storages.ErrNoKeyring.
+func New(db *sql.DB, opts ...Option) (*TasksStorage, error) {
 	if db == nil {
 		return nil, storages.ErrNilDBPool
 	}
 
-	return &TasksStorage{db: db}, nil
+	s := &TasksStorage{db: db, stmts: storages.NewStatements(db)}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := storages.CheckKeyring(db, s.keyring, "tasks"); err != nil {
+		return nil, err
+	}
+
+	return s, nil
 }
 
 // UncompletedStatistic returns 5 or low uncompleted tasks for each user.
-func (s *TasksStorage) UncompletedStatistic(ctx context.Context) ([]data.StatisticTask, error) {
-	const query = "WITH ranked_tasks AS (SELECT id, user_id, title, created_on, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_on DESC) AS rank FROM tasks WHERE is_completed = false) SELECT u.email, rt.title, rt.created_on FROM users u JOIN ranked_tasks rt ON rt.user_id = u.id WHERE rt.rank <= 5 ORDER BY u.email, rt.rank"
-
-	prepareCtx, cancel := context.WithTimeout(ctx, storages.PrepareTimeout)
-	defer cancel()
+//
+// Deleted and snoozed tasks are skipped. Only the tasks of the workspace
+// of ctx are counted, see storages.AllWorkspaces. The titles are
+// decrypted.
+func (s *TasksStorage) UncompletedStatistic(ctx context.Context) (tasks []data.StatisticTask, err error) {
+	const query = "WITH ranked_tasks AS (SELECT id, user_id, title, key_id, data_key, created_on, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_on DESC) AS rank FROM tasks WHERE is_completed = false AND is_deleted = false AND " + notSnoozed + ") SELECT u.email, rt.title, rt.key_id, rt.data_key, rt.created_on FROM users u JOIN ranked_tasks rt ON rt.user_id = u.id WHERE rt.rank <= 5 ORDER BY u.email, rt.rank"
+
+	stmts := s.reader(ctx)
+
+	err = storages.Scoped(ctx, stmts, func(ctx context.Context, _ *sql.Tx) error {
+		stmt, err := stmts.Prepare(ctx, query)
+		if err != nil {
+			return err
+		}
 
-	stmt, err := s.db.PrepareContext(prepareCtx, query)
-	if err != nil {
-		return nil, err
-	}
-	defer stmt.Close()
+		rows, err := stmt.QueryContext(ctx)
+		if err != nil {
+			return err
+		}
 
-	rows, err := stmt.QueryContext(ctx)
-	if err != nil {
-		return nil, err
-	}
+		for rows.Next() {
+			var (
+				st      data.StatisticTask
+				keyID   sql.NullString
+				dataKey []byte
+			)
+			if err = rows.Scan(&st.Email, &st.Title, &keyID, &dataKey, &st.CreatedOn); err != nil {
+				break
+			}
+			c := storages.Contents{Title: st.Title, KeyID: keyID.String, DataKey: dataKey}
+			if st.Title, _, err = c.Open(s.keyring); err != nil {
+				break
+			}
+			tasks = append(tasks, st)
+		}
 
-	var tasks []data.StatisticTask
-	for rows.Next() {
-		var st data.StatisticTask
-		if err = rows.Scan(&st.Email, &st.Title, &st.CreatedOn); err != nil {
-			break
+		if closeErr := rows.Close(); closeErr != nil {
+			return closeErr
 		}
-		tasks = append(tasks, st)
-	}
 
-	if closeErr := rows.Close(); closeErr != nil {
-		return nil, closeErr
-	}
+		if err != nil {
+			return err
+		}
 
+		return rows.Err()
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-
 	return tasks, nil
 }
 
 // FindByUserID returns a list of tasks for a given user.
-func (s *TasksStorage) FindByUserID(ctx context.Context, userID string) ([]data.Task, error) {
-	const query = "SELECT id, user_id, title, description, is_completed, created_on FROM tasks WHERE user_id = $1 AND is_deleted = false"
+//
+// Snoozed tasks are excluded unless f.IncludeSnoozed is set.
+// Archived tasks are returned only if f.Archived is set.
+func (s *TasksStorage) FindByUserID(ctx context.Context, userID string, f tasks.Filter) (tasks []data.Task, err error) {
+	const query = "SELECT " + taskColumns + " FROM tasks WHERE user_id = $1 AND ($4 OR (is_deleted = false AND ($2 OR " + notSnoozed + ") AND (archived_on IS NOT NULL) = $3))"
 
-	prepareCtx, cancel := context.WithTimeout(ctx, storages.PrepareTimeout)
-	defer cancel()
+	stmts := s.reader(ctx)
 
-	stmt, err := s.db.PrepareContext(prepareCtx, query)
-	if err != nil {
-		return nil, err
-	}
-	defer stmt.Close()
+	err = storages.Scoped(ctx, stmts, func(ctx context.Context, _ *sql.Tx) error {
+		stmt, err := stmts.Prepare(ctx, query)
+		if err != nil {
+			return err
+		}
 
-	rows, err := stmt.QueryContext(ctx, userID)
-	if err != nil {
-		return nil, err
-	}
+		rows, err := stmt.QueryContext(ctx, userID, f.IncludeSnoozed, f.Archived, f.All)
+		if err != nil {
+			return err
+		}
 
-	var tasks []data.Task
-	for rows.Next() {
-		var task data.Task
-		if err = rows.Scan(&task.ID, &task.UserID, &task.Title, &task.Description, &task.IsCompleted, &task.CreatedOn); err != nil {
-			break
+		for rows.Next() {
+			var task data.Task
+			if task, err = s.scanTask(rows); err != nil {
+				break
+			}
+			tasks = append(tasks, task)
 		}
-		tasks = append(tasks, task)
-	}
 
-	if closeErr := rows.Close(); closeErr != nil {
-		return nil, closeErr
-	}
+		if closeErr := rows.Close(); closeErr != nil {
+			return closeErr
+		}
+
+		if err != nil {
+			return err
+		}
 
+		return rows.Err()
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+	return tasks, nil
+}
+
+// FindByID returns a task by given id.
+//
+// If task is not found or deleted returns tasks.ErrNotFound.
+func (s *TasksStorage) FindByID(ctx context.Context, id string) (t data.Task, err error) {
+	const query = "SELECT " + taskColumns + " FROM tasks WHERE id = $1 AND is_deleted = false"
+
+	err = storages.Scoped(ctx, s.stmts, func(ctx context.Context, _ *sql.Tx) error {
+		stmt, err := s.stmts.Prepare(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		t, err = s.scanTask(stmt.QueryRowContext(ctx, id))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data.Task{}, tasks.ErrNotFound
+		}
+
+		return data.Task{}, err
 	}
 
-	return tasks, nil
+	return t, nil
 }
 
 // Save saves a tasks to the database.
 //
-// If save succeeds ID, IsCompleted and CreatedOn fields are filled.
+// The task is saved in t.WorkspaceID, or in the workspace of ctx if it is
+// empty. If save succeeds ID, WorkspaceID, IsCompleted and CreatedOn fields
+// are filled.
 func (s *TasksStorage) Save(ctx context.Context, t *data.Task) error {
-	const query = "INSERT INTO tasks (user_id, title, description) VALUES ($1, $2, $3) RETURNING id, is_completed, created_on"
+	const query = "INSERT INTO tasks (user_id, workspace_id, title, description, due_on, tags, priority, recurrence, checklist, key_id, data_key) VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $5, COALESCE($6, '{}'), $7, $8, $9, NULLIF($10, ''), $11) RETURNING id, workspace_id, is_completed, created_on"
 
-	prepareCtx, cancel := context.WithTimeout(ctx, storages.PrepareTimeout)
-	defer cancel()
+	checklist, err := marshalChecklist(t.Checklist)
+	if err != nil {
+		return err
+	}
 
-	stmt, err := s.db.PrepareContext(prepareCtx, query)
+	sealed, err := storages.SealContents(s.keyring, t.Title, t.Description)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
 
-	err = stmt.QueryRowContext(ctx, t.UserID, t.Title, t.Description).
-		Scan(&t.ID, &t.IsCompleted, &t.CreatedOn)
+	workspaceID := t.WorkspaceID
+	if workspaceID == "" {
+		workspaceID, _ = storages.WorkspaceID(ctx)
+	}
+
+	err = storages.Scoped(ctx, s.stmts, func(ctx context.Context, _ *sql.Tx) error {
+		stmt, err := s.stmts.Prepare(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		return stmt.QueryRowContext(ctx,
+			t.UserID, workspaceID, sealed.Title, sealed.Description, nullTime(t.DueOn), pq.Array(t.Tags), t.Priority, t.Recurrence, checklist,
+			sealed.KeyID, sealed.DataKey,
+		).Scan(&t.ID, &t.WorkspaceID, &t.IsCompleted, &t.CreatedOn)
+	})
 	if err != nil {
 		return err
 	}
 
+	s.replicas.Wrote(ctx)
+
 	return nil
 }
 
@@ -138,62 +247,274 @@ func (s *TasksStorage) Save(ctx context.Context, t *data.Task) error {
 // Actually set is_delete = true.
 // If count of affected rows is not 1 returns tasks.ErrNotFound.
 func (s *TasksStorage) Delete(ctx context.Context, id string) error {
-	const query = "UPDATE tasks SET is_deleted = true WHERE id = $1"
+	const query = "UPDATE tasks SET is_deleted = true WHERE id = $1 AND is_deleted = false"
 
-	prepareCtx, cancel := context.WithTimeout(ctx, storages.PrepareTimeout)
-	defer cancel()
+	return s.exec(ctx, query, id)
+}
 
-	stmt, err := s.db.PrepareContext(prepareCtx, query)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
+// Update updates a task in the database.
+//
+// Completing a task stamps completed_on, reopening it clears completed_on
+// and brings the task back from the archive. The title and the description
+// are encrypted with a new data key.
+// If count of affected rows is not 1 returns tasks.ErrNotFound.
+func (s *TasksStorage) Update(ctx context.Context, t *data.Task) error {
+	const query = "UPDATE tasks SET title = $1, description = $2, key_id = NULLIF($5, ''), data_key = $6, " +
+		"completed_on = CASE WHEN NOT $3 THEN NULL WHEN is_completed THEN completed_on ELSE " + nowUTC + " END, " +
+		"archived_on = CASE WHEN $3 THEN archived_on END, " +
+		"is_completed = $3 WHERE id = $4 AND is_deleted = false"
 
-	res, err := stmt.ExecContext(ctx, id)
+	sealed, err := storages.SealContents(s.keyring, t.Title, t.Description)
 	if err != nil {
 		return err
 	}
 
-	count, err := res.RowsAffected()
+	return s.exec(ctx, query, sealed.Title, sealed.Description, t.IsCompleted, t.ID, sealed.KeyID, sealed.DataKey)
+}
+
+// Snooze hides a task from default lists until the given time.
+// Zero until shows the task again.
+//
+// If count of affected rows is not 1 returns tasks.ErrNotFound.
+func (s *TasksStorage) Snooze(ctx context.Context, id string, until time.Time) error {
+	const query = "UPDATE tasks SET hidden_until = $1 WHERE id = $2 AND is_deleted = false"
+
+	return s.exec(ctx, query, nullTime(until), id)
+}
+
+// Archive archives the tasks completed more than archive_after_days ago.
+// Users without settings use defaultDays. Zero days disables archiving.
+//
+// Returns the users whose tasks were archived with their workspaces.
+// Only the tasks of the workspace of ctx are archived, see
+// storages.AllWorkspaces.
+func (s *TasksStorage) Archive(ctx context.Context, defaultDays int) (owners []tasks.Owner, err error) {
+	const query = "WITH due AS (SELECT t.id FROM tasks t LEFT JOIN user_settings us ON us.user_id = t.user_id " +
+		"WHERE t.is_completed = true AND t.is_deleted = false AND t.archived_on IS NULL AND t.completed_on IS NOT NULL " +
+		"AND COALESCE(us.archive_after_days, $1) > 0 " +
+		"AND t.completed_on <= " + nowUTC + " - make_interval(days => COALESCE(us.archive_after_days, $1))), " +
+		"archived AS (UPDATE tasks SET archived_on = " + nowUTC + " WHERE id IN (SELECT id FROM due) RETURNING workspace_id, user_id) " +
+		"SELECT DISTINCT workspace_id, user_id FROM archived"
+
+	err = storages.Scoped(ctx, s.stmts, func(ctx context.Context, _ *sql.Tx) error {
+		stmt, err := s.stmts.Prepare(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		rows, err := stmt.QueryContext(ctx, defaultDays)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var o tasks.Owner
+			if err = rows.Scan(&o.WorkspaceID, &o.UserID); err != nil {
+				break
+			}
+			owners = append(owners, o)
+		}
+
+		if closeErr := rows.Close(); closeErr != nil {
+			return closeErr
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return rows.Err()
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if count != 1 {
-		return tasks.ErrNotFound
-	}
+	s.replicas.Wrote(ctx)
 
-	return nil
+	return owners, nil
 }
 
-// Update updates a task in the database.
+// Unarchive brings an archived task back to the task list.
+// Completed_on is reset, so the archive countdown starts over.
 //
-// If count of affected rows is not 1 returns tasks.ErrNotFound.
-func (s *TasksStorage) Update(ctx context.Context, t *data.Task) error {
-	query := "UPDATE tasks SET title = $1, description = $2, is_completed = $3 WHERE id = $4"
+// If the task is not archived returns tasks.ErrNotFound.
+func (s *TasksStorage) Unarchive(ctx context.Context, id string) error {
+	const query = "UPDATE tasks SET archived_on = NULL, completed_on = " + nowUTC + " WHERE id = $1 AND archived_on IS NOT NULL AND is_deleted = false"
 
-	prepareCtx, cancel := context.WithTimeout(ctx, storages.PrepareTimeout)
-	defer cancel()
+	return s.exec(ctx, query, id)
+}
 
-	stmt, err := s.db.PrepareContext(prepareCtx, query)
+// Recrypt encrypts up to limit tasks that are not encrypted under the
+// primary key of the keyring, see storages.RecryptContents. The rows
+// locked by another transaction are skipped.
+//
+// Returns the number of recrypted tasks, zero when there are no such tasks
+// left. Only the tasks of the workspace of ctx are recrypted, see
+// storages.AllWorkspaces. If the keyring has no primary key returns
+// envelope.ErrNoPrimaryKey.
+func (s *TasksStorage) Recrypt(ctx context.Context, limit int) (int, error) {
+	const (
+		selectQuery = "SELECT id, title, description, key_id, data_key FROM tasks WHERE key_id IS DISTINCT FROM $1 ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED"
+		updateQuery = "UPDATE tasks SET title = $1, description = $2, key_id = $3, data_key = $4 WHERE id = $5"
+	)
+
+	recrypt, err := storages.RecryptContents(s.keyring)
 	if err != nil {
+		return 0, err
+	}
+
+	return s.rewrite(ctx, selectQuery, updateQuery, []any{s.keyring.Primary(), limit}, recrypt)
+}
+
+// Decrypt stores up to limit encrypted tasks in plaintext. It is meant to
+// be run before the encryption migration is rolled back.
+//
+// Returns the number of decrypted tasks, zero when there are no encrypted
+// tasks left. Only the tasks of the workspace of ctx are decrypted, see
+// storages.AllWorkspaces.
+func (s *TasksStorage) Decrypt(ctx context.Context, limit int) (int, error) {
+	const (
+		selectQuery = "SELECT id, title, description, key_id, data_key FROM tasks WHERE key_id IS NOT NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED"
+		updateQuery = "UPDATE tasks SET title = $1, description = $2, key_id = NULLIF($3, ''), data_key = $4 WHERE id = $5"
+	)
+
+	return s.rewrite(ctx, selectQuery, updateQuery, []any{limit}, storages.DecryptContents(s.keyring))
+}
+
+// rewrite rewrites the contents of the tasks, see storages.RewriteContents.
+func (s *TasksStorage) rewrite(ctx context.Context, selectQuery, updateQuery string, args []any, fn func(c *storages.Contents) error) (count int, err error) {
+	err = storages.ScopedTx(ctx, s.stmts, func(ctx context.Context, _ *sql.Tx) error {
+		count, err = storages.RewriteContents(ctx, s.stmts, selectQuery, updateQuery, args, fn)
 		return err
+	})
+	if err != nil {
+		return 0, err
 	}
-	defer stmt.Close()
 
-	res, err := stmt.ExecContext(ctx, t.Title, t.Description, t.IsCompleted, t.ID)
+	s.replicas.Wrote(ctx)
+
+	return count, nil
+}
+
+// exec runs the update query in the workspace of ctx. If count of
+// affected rows is not 1 returns tasks.ErrNotFound.
+func (s *TasksStorage) exec(ctx context.Context, query string, args ...any) error {
+	err := storages.Scoped(ctx, s.stmts, func(ctx context.Context, _ *sql.Tx) error {
+		stmt, err := s.stmts.Prepare(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		res, err := stmt.ExecContext(ctx, args...)
+		if err != nil {
+			return err
+		}
+
+		count, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if count != 1 {
+			return tasks.ErrNotFound
+		}
+
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	count, err := res.RowsAffected()
+	s.replicas.Wrote(ctx)
+
+	return nil
+}
+
+// reader returns the statements of a replica for a read-only query,
+// or of the primary, see storages.Replicas.
+func (s *TasksStorage) reader(ctx context.Context) *storages.Statements {
+	if stmts := s.replicas.Reader(ctx); stmts != nil {
+		return stmts
+	}
+	return s.stmts
+}
+
+// nullTime converts zero time to NULL and stores the rest in UTC,
+// because timestamp columns drop the offset.
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// notSnoozed matches the tasks that are not hidden at the moment.
+// Times are stored in UTC, see nullTime.
+const notSnoozed = "(hidden_until IS NULL OR hidden_until <= " + nowUTC + ")"
+
+const nowUTC = "(now() AT TIME ZONE 'utc')"
+
+// taskColumns selects a task from the unaliased tasks table. is_blocked is true
+// while any of the blockers is not completed.
+const taskColumns = "id, user_id, workspace_id, title, description, is_completed, is_deleted, due_on, tags, priority, recurrence, checklist, created_on, hidden_until, completed_on, archived_on, key_id, data_key, " +
+	"EXISTS (SELECT 1 FROM task_dependencies d JOIN tasks b ON b.id = d.blocked_by_id WHERE d.task_id = tasks.id AND b.is_completed = false AND b.is_deleted = false) AS is_blocked"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTask scans a row selected with taskColumns and decrypts the title
+// and the description.
+func (s *TasksStorage) scanTask(row scanner) (data.Task, error) {
+	var (
+		t           data.Task
+		dueOn       sql.NullTime
+		hiddenUntil sql.NullTime
+		completedOn sql.NullTime
+		archivedOn  sql.NullTime
+		checklist   []byte
+		keyID       sql.NullString
+		dataKey     []byte
+	)
+
+	err := row.Scan(
+		&t.ID, &t.UserID, &t.WorkspaceID, &t.Title, &t.Description, &t.IsCompleted, &t.IsDeleted,
+		&dueOn, pq.Array(&t.Tags), &t.Priority, &t.Recurrence, &checklist, &t.CreatedOn, &hiddenUntil, &completedOn, &archivedOn,
+		&keyID, &dataKey, &t.IsBlocked,
+	)
 	if err != nil {
-		return err
+		return data.Task{}, err
 	}
 
-	if count != 1 {
-		return tasks.ErrNotFound
+	c := storages.Contents{Title: t.Title, Description: t.Description, KeyID: keyID.String, DataKey: dataKey}
+	if t.Title, t.Description, err = c.Open(s.keyring); err != nil {
+		return data.Task{}, fmt.Errorf("task %s: %w", t.ID, err)
 	}
 
-	return nil
+	t.DueOn = dueOn.Time
+	t.HiddenUntil = hiddenUntil.Time
+	t.CompletedOn = completedOn.Time
+	t.ArchivedOn = archivedOn.Time
+
+	if err = json.Unmarshal(checklist, &t.Checklist); err != nil {
+		return data.Task{}, err
+	}
+
+	return t, nil
+}
+
+// marshalChecklist returns checklist as a JSON string,
+// since pq sends []byte parameters as bytea.
+func marshalChecklist(items []data.ChecklistItem) (string, error) {
+	if items == nil {
+		items = []data.ChecklistItem{}
+	}
+
+	b, err := json.Marshal(items)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
 }
@@ -0,0 +1,31 @@
+package pg_test
+
+import (
+	"testing"
+
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks/pg"
+	userspg "github.com/romankravchuk/eldorado/internal/storages/users/pg"
+	workspacespg "github.com/romankravchuk/eldorado/internal/storages/workspaces/pg"
+)
+
+func TestConformance(t *testing.T) {
+	db := storagetest.Postgres(t)
+
+	users, err := userspg.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workspaces, err := workspacespg.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := pg.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storagetest.Tasks(t, tasks, users, workspaces)
+}
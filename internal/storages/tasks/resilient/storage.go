@@ -0,0 +1,84 @@
+package resilient
+
+import (
+	"context"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages/resilience"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+)
+
+// Storage is a tasks.Storage decorator that retries the reads failed on
+// transient errors and fails all calls fast while the breaker is open,
+// see resilience.Policy.
+type Storage struct {
+	next   tasks.Storage
+	policy *resilience.Policy
+}
+
+func New(next tasks.Storage, policy *resilience.Policy) *Storage {
+	return &Storage{next: next, policy: policy}
+}
+
+func (s *Storage) FindByUserID(ctx context.Context, userID string, f tasks.Filter) (tt []data.Task, err error) {
+	err = s.policy.Read(ctx, func(ctx context.Context) error {
+		tt, err = s.next.FindByUserID(ctx, userID, f)
+		return err
+	})
+	return tt, err
+}
+
+func (s *Storage) FindByID(ctx context.Context, id string) (t data.Task, err error) {
+	err = s.policy.Read(ctx, func(ctx context.Context) error {
+		t, err = s.next.FindByID(ctx, id)
+		return err
+	})
+	return t, err
+}
+
+func (s *Storage) UncompletedStatistic(ctx context.Context) (tt []data.StatisticTask, err error) {
+	err = s.policy.Read(ctx, func(ctx context.Context) error {
+		tt, err = s.next.UncompletedStatistic(ctx)
+		return err
+	})
+	return tt, err
+}
+
+func (s *Storage) Save(ctx context.Context, task *data.Task) error {
+	return s.policy.Write(ctx, func(ctx context.Context) error {
+		return s.next.Save(ctx, task)
+	})
+}
+
+func (s *Storage) Delete(ctx context.Context, id string) error {
+	return s.policy.Write(ctx, func(ctx context.Context) error {
+		return s.next.Delete(ctx, id)
+	})
+}
+
+func (s *Storage) Update(ctx context.Context, task *data.Task) error {
+	return s.policy.Write(ctx, func(ctx context.Context) error {
+		return s.next.Update(ctx, task)
+	})
+}
+
+func (s *Storage) Snooze(ctx context.Context, id string, until time.Time) error {
+	return s.policy.Write(ctx, func(ctx context.Context) error {
+		return s.next.Snooze(ctx, id, until)
+	})
+}
+
+func (s *Storage) Archive(ctx context.Context, defaultDays int) (owners []tasks.Owner, err error) {
+	err = s.policy.Write(ctx, func(ctx context.Context) error {
+		owners, err = s.next.Archive(ctx, defaultDays)
+		return err
+	})
+	return owners, err
+}
+
+func (s *Storage) Unarchive(ctx context.Context, id string) error {
+	return s.policy.Write(ctx, func(ctx context.Context) error {
+		return s.next.Unarchive(ctx, id)
+	})
+}
@@ -0,0 +1,392 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/tasks"
+)
+
+// TasksStorage is a sqlite implementation of tasks.Storage.
+//
+// SQLite has no row-level security, so the lookups filter the tasks by the
+// workspace of the context themselves, see storages.WithWorkspace. The
+// contexts that are not scoped see the tasks of all workspaces.
+type TasksStorage struct {
+	db    *sql.DB
+	stmts *storages.Statements
+}
+
+// New returns new TasksStorage instance with sqlite db pool,
+// see storages.NewSQLitePool.
+//
+// If db is nil returns storages.ErrNilDBPool.
+func New(db *sql.DB) (*TasksStorage, error) {
+	if db == nil {
+		return nil, storages.ErrNilDBPool
+	}
+
+	return &TasksStorage{db: db, stmts: storages.NewStatements(db)}, nil
+}
+
+// UncompletedStatistic returns 5 or low uncompleted tasks for each user.
+//
+// Deleted and snoozed tasks are skipped.
+func (s *TasksStorage) UncompletedStatistic(ctx context.Context) ([]data.StatisticTask, error) {
+	const query = "WITH ranked_tasks AS (SELECT id, user_id, title, created_on, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_on DESC) AS rank FROM tasks WHERE is_completed = false AND is_deleted = false AND " + notSnoozed + ") SELECT u.email, rt.title, rt.created_on FROM users u JOIN ranked_tasks rt ON rt.user_id = u.id WHERE rt.rank <= 5 ORDER BY u.email, rt.rank"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []data.StatisticTask
+	for rows.Next() {
+		var (
+			st        data.StatisticTask
+			createdOn storages.SQLiteTime
+		)
+		if err = rows.Scan(&st.Email, &st.Title, &createdOn); err != nil {
+			break
+		}
+		st.CreatedOn = createdOn.Time
+		tasks = append(tasks, st)
+	}
+
+	if closeErr := rows.Close(); closeErr != nil {
+		return nil, closeErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// FindByUserID returns a list of tasks for a given user.
+//
+// Snoozed tasks are excluded unless f.IncludeSnoozed is set.
+// Archived tasks are returned only if f.Archived is set.
+func (s *TasksStorage) FindByUserID(ctx context.Context, userID string, f tasks.Filter) ([]data.Task, error) {
+	const query = "SELECT " + taskColumns + " FROM tasks WHERE user_id = $1 AND ($5 OR (is_deleted = false AND ($2 OR " + notSnoozed + ") AND (archived_on IS NOT NULL) = $3)) AND ($4 = '' OR workspace_id = $4)"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaceID, _ := storages.WorkspaceID(ctx)
+
+	rows, err := stmt.QueryContext(ctx, userID, f.IncludeSnoozed, f.Archived, workspaceID, f.All)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []data.Task
+	for rows.Next() {
+		var task data.Task
+		if task, err = scanTask(rows); err != nil {
+			break
+		}
+		tasks = append(tasks, task)
+	}
+
+	if closeErr := rows.Close(); closeErr != nil {
+		return nil, closeErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// FindByID returns a task by given id.
+//
+// If task is not found or deleted returns tasks.ErrNotFound.
+func (s *TasksStorage) FindByID(ctx context.Context, id string) (data.Task, error) {
+	const query = "SELECT " + taskColumns + " FROM tasks WHERE id = $1 AND is_deleted = false AND ($2 = '' OR workspace_id = $2)"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return data.Task{}, err
+	}
+
+	workspaceID, _ := storages.WorkspaceID(ctx)
+
+	t, err := scanTask(stmt.QueryRowContext(ctx, id, workspaceID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data.Task{}, tasks.ErrNotFound
+		}
+
+		return data.Task{}, err
+	}
+
+	return t, nil
+}
+
+// Save saves a tasks to the database.
+//
+// The task is saved in t.WorkspaceID, or in the workspace of ctx if it is
+// empty. If save succeeds ID, IsCompleted and CreatedOn fields are filled.
+// SQLite has no uuid generator, so the ID is generated here.
+func (s *TasksStorage) Save(ctx context.Context, t *data.Task) error {
+	const query = "INSERT INTO tasks (id, user_id, workspace_id, title, description, due_on, tags, priority, recurrence, checklist, created_on) VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10, $11)"
+
+	tags, err := marshalArray(t.Tags)
+	if err != nil {
+		return err
+	}
+
+	checklist, err := marshalArray(t.Checklist)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	var (
+		id        = uuid.NewString()
+		createdOn = time.Now().UTC().Truncate(time.Microsecond)
+	)
+
+	if t.WorkspaceID == "" {
+		t.WorkspaceID, _ = storages.WorkspaceID(ctx)
+	}
+
+	_, err = stmt.ExecContext(ctx,
+		id, t.UserID, t.WorkspaceID, t.Title, t.Description, nullTime(t.DueOn), tags, t.Priority, t.Recurrence, checklist, createdOn,
+	)
+	if err != nil {
+		return err
+	}
+
+	t.ID = id
+	t.IsCompleted = false
+	t.CreatedOn = createdOn
+
+	return nil
+}
+
+// Delete deletes a task from the database.
+//
+// Actually set is_delete = true.
+// If count of affected rows is not 1 returns tasks.ErrNotFound.
+func (s *TasksStorage) Delete(ctx context.Context, id string) error {
+	const query = "UPDATE tasks SET is_deleted = true WHERE id = $1 AND is_deleted = false"
+
+	return s.exec(ctx, query, id)
+}
+
+// Update updates a task in the database.
+//
+// Completing a task stamps completed_on, reopening it clears completed_on
+// and brings the task back from the archive.
+// If count of affected rows is not 1 returns tasks.ErrNotFound.
+func (s *TasksStorage) Update(ctx context.Context, t *data.Task) error {
+	const query = "UPDATE tasks SET title = $1, description = $2, " +
+		"completed_on = CASE WHEN NOT $3 THEN NULL WHEN is_completed THEN completed_on ELSE " + nowUTC + " END, " +
+		"archived_on = CASE WHEN $3 THEN archived_on END, " +
+		"is_completed = $3 WHERE id = $4 AND is_deleted = false"
+
+	return s.exec(ctx, query, t.Title, t.Description, t.IsCompleted, t.ID)
+}
+
+// Snooze hides a task from default lists until the given time.
+// Zero until shows the task again.
+//
+// If count of affected rows is not 1 returns tasks.ErrNotFound.
+func (s *TasksStorage) Snooze(ctx context.Context, id string, until time.Time) error {
+	const query = "UPDATE tasks SET hidden_until = $1 WHERE id = $2 AND is_deleted = false"
+
+	return s.exec(ctx, query, nullTime(until), id)
+}
+
+// Archive archives the tasks completed more than archive_after_days ago.
+// Users without settings use defaultDays. Zero days disables archiving.
+//
+// Returns the users whose tasks were archived with their workspaces.
+func (s *TasksStorage) Archive(ctx context.Context, defaultDays int) ([]tasks.Owner, error) {
+	const query = "UPDATE tasks SET archived_on = " + nowUTC + " WHERE id IN (SELECT t.id FROM tasks t LEFT JOIN user_settings us ON us.user_id = t.user_id " +
+		"WHERE t.is_completed = true AND t.is_deleted = false AND t.archived_on IS NULL AND t.completed_on IS NOT NULL " +
+		"AND COALESCE(us.archive_after_days, $1) > 0 " +
+		"AND julianday(t.completed_on) <= julianday('now') - COALESCE(us.archive_after_days, $1)) " +
+		"RETURNING COALESCE(workspace_id, ''), user_id"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := stmt.QueryContext(ctx, defaultDays)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		owners []tasks.Owner
+		seen   = make(map[tasks.Owner]struct{})
+	)
+	for rows.Next() {
+		var o tasks.Owner
+		if err = rows.Scan(&o.WorkspaceID, &o.UserID); err != nil {
+			break
+		}
+		if _, ok := seen[o]; ok {
+			continue
+		}
+		seen[o] = struct{}{}
+		owners = append(owners, o)
+	}
+
+	if closeErr := rows.Close(); closeErr != nil {
+		return nil, closeErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return owners, nil
+}
+
+// Unarchive brings an archived task back to the task list.
+// Completed_on is reset, so the archive countdown starts over.
+//
+// If the task is not archived returns tasks.ErrNotFound.
+func (s *TasksStorage) Unarchive(ctx context.Context, id string) error {
+	const query = "UPDATE tasks SET archived_on = NULL, completed_on = " + nowUTC + " WHERE id = $1 AND archived_on IS NOT NULL AND is_deleted = false"
+
+	return s.exec(ctx, query, id)
+}
+
+// exec runs the update query. If count of affected rows is not 1
+// returns tasks.ErrNotFound.
+func (s *TasksStorage) exec(ctx context.Context, query string, args ...any) error {
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	res, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count != 1 {
+		return tasks.ErrNotFound
+	}
+
+	return nil
+}
+
+// nullTime converts zero time to NULL and stores the rest in UTC,
+// so the stored times compare as text and by the date functions alike.
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// notSnoozed matches the tasks that are not hidden at the moment.
+// Times are stored in UTC, see nullTime.
+const notSnoozed = "(hidden_until IS NULL OR julianday(hidden_until) <= julianday('now'))"
+
+const nowUTC = "strftime('%Y-%m-%d %H:%M:%f', 'now')"
+
+// taskColumns selects a task from the unaliased tasks table. is_blocked is true
+// while any of the blockers is not completed.
+const taskColumns = "id, user_id, COALESCE(workspace_id, ''), title, description, is_completed, is_deleted, due_on, tags, priority, recurrence, checklist, created_on, hidden_until, completed_on, archived_on, " +
+	"EXISTS (SELECT 1 FROM task_dependencies d JOIN tasks b ON b.id = d.blocked_by_id WHERE d.task_id = tasks.id AND b.is_completed = false AND b.is_deleted = false) AS is_blocked"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTask scans a row selected with taskColumns.
+func scanTask(row scanner) (data.Task, error) {
+	var (
+		t           data.Task
+		createdOn   storages.SQLiteTime
+		dueOn       storages.SQLiteTime
+		hiddenUntil storages.SQLiteTime
+		completedOn storages.SQLiteTime
+		archivedOn  storages.SQLiteTime
+		tags        string
+		checklist   string
+	)
+
+	err := row.Scan(
+		&t.ID, &t.UserID, &t.WorkspaceID, &t.Title, &t.Description, &t.IsCompleted, &t.IsDeleted,
+		&dueOn, &tags, &t.Priority, &t.Recurrence, &checklist, &createdOn, &hiddenUntil, &completedOn, &archivedOn, &t.IsBlocked,
+	)
+	if err != nil {
+		return data.Task{}, err
+	}
+
+	t.CreatedOn = createdOn.Time
+	t.DueOn = dueOn.Time
+	t.HiddenUntil = hiddenUntil.Time
+	t.CompletedOn = completedOn.Time
+	t.ArchivedOn = archivedOn.Time
+
+	if err = json.Unmarshal([]byte(tags), &t.Tags); err != nil {
+		return data.Task{}, err
+	}
+
+	if err = json.Unmarshal([]byte(checklist), &t.Checklist); err != nil {
+		return data.Task{}, err
+	}
+
+	return t, nil
+}
+
+// marshalArray returns items as a JSON array, nil items as an empty one.
+func marshalArray[T any](items []T) (string, error) {
+	if items == nil {
+		items = []T{}
+	}
+
+	b, err := json.Marshal(items)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
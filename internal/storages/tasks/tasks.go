@@ -3,17 +3,39 @@ package tasks
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/romankravchuk/eldorado/internal/data"
 )
 
 var ErrNotFound = errors.New("the task not found")
 
+// Filter narrows down the tasks returned by FindByUserID.
+type Filter struct {
+	// IncludeSnoozed includes the tasks hidden until a future time.
+	IncludeSnoozed bool
+	// Archived returns only the archived tasks instead of the active ones.
+	Archived bool
+	// All returns every task of the user, including the deleted, snoozed
+	// and archived ones. The other fields are ignored.
+	All bool
+}
+
+// Owner is the user whose task list in the workspace was changed.
+type Owner struct {
+	WorkspaceID string
+	UserID      string
+}
+
 //go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name Storage
 type Storage interface {
-	FindByUserID(ctx context.Context, userID string) ([]data.Task, error)
+	FindByUserID(ctx context.Context, userID string, f Filter) ([]data.Task, error)
+	FindByID(ctx context.Context, id string) (data.Task, error)
 	UncompletedStatistic(ctx context.Context) ([]data.StatisticTask, error)
 	Save(ctx context.Context, task *data.Task) error
 	Delete(ctx context.Context, id string) error
 	Update(ctx context.Context, task *data.Task) error
+	Snooze(ctx context.Context, id string, until time.Time) error
+	Archive(ctx context.Context, defaultDays int) ([]Owner, error)
+	Unarchive(ctx context.Context, id string) error
 }
@@ -0,0 +1,258 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/pkg/envelope"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/templates"
+)
+
+const templateColumns = "id, user_id, name, title, description, tags, priority, recurrence, checklist, created_on, key_id, data_key"
+
+// TemplatesStorage is a postgres implementation of templates.Storage.
+//
+// With a keyring the title and the description are encrypted at rest like
+// the ones of the tasks, see WithKeyring.
+type TemplatesStorage struct {
+	db      *sql.DB
+	stmts   *storages.Statements
+	keyring *envelope.Keyring
+}
+
+type Option func(*TemplatesStorage)
+
+// WithKeyring encrypts the title and the description of the saved
+// templates, see the WithKeyring option of the tasks storage.
+func WithKeyring(k *envelope.Keyring) Option {
+	return func(s *TemplatesStorage) {
+		s.keyring = k
+	}
+}
+
+// New returns new TemplatesStorage instance with postgres db pool.
+//
+// If db is nil returns storages.ErrNilDBPool. If there is no keyring and
+// some templates are encrypted returns storages.ErrNoKeyring.
+func New(db *sql.DB, opts ...Option) (*TemplatesStorage, error) {
+	if db == nil {
+		return nil, storages.ErrNilDBPool
+	}
+
+	s := &TemplatesStorage{db: db, stmts: storages.NewStatements(db)}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := storages.CheckKeyring(db, s.keyring, "task_templates"); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// FindByUserID returns a list of templates for a given user ordered by name.
+func (s *TemplatesStorage) FindByUserID(ctx context.Context, userID string) ([]data.Template, error) {
+	const query = "SELECT " + templateColumns + " FROM task_templates WHERE user_id = $1 ORDER BY name"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := stmt.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var tt []data.Template
+	for rows.Next() {
+		var t data.Template
+		if t, err = s.scanTemplate(rows); err != nil {
+			break
+		}
+		tt = append(tt, t)
+	}
+
+	if closeErr := rows.Close(); closeErr != nil {
+		return nil, closeErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tt, nil
+}
+
+// FindByID returns a template by given id.
+//
+// If template is not found returns templates.ErrNotFound.
+func (s *TemplatesStorage) FindByID(ctx context.Context, id string) (data.Template, error) {
+	const query = "SELECT " + templateColumns + " FROM task_templates WHERE id = $1"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return data.Template{}, err
+	}
+
+	t, err := s.scanTemplate(stmt.QueryRowContext(ctx, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data.Template{}, templates.ErrNotFound
+		}
+
+		return data.Template{}, err
+	}
+
+	return t, nil
+}
+
+// Save saves a template to the database.
+//
+// If save succeeds ID and CreatedOn fields are filled.
+// If user already has a template with the same name returns templates.ErrAlreadyExists.
+func (s *TemplatesStorage) Save(ctx context.Context, t *data.Template) error {
+	const query = "INSERT INTO task_templates (user_id, name, title, description, tags, priority, recurrence, checklist, key_id, data_key) VALUES ($1, $2, $3, $4, COALESCE($5, '{}'), $6, $7, $8, NULLIF($9, ''), $10) RETURNING id, created_on"
+
+	sealed, err := storages.SealContents(s.keyring, t.Title, t.Description)
+	if err != nil {
+		return err
+	}
+
+	checklist := t.Checklist
+	if checklist == nil {
+		checklist = []string{}
+	}
+
+	b, err := json.Marshal(checklist)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	err = stmt.QueryRowContext(ctx,
+		t.UserID, t.Name, sealed.Title, sealed.Description, pq.Array(t.Tags), t.Priority, t.Recurrence, string(b),
+		sealed.KeyID, sealed.DataKey,
+	).Scan(&t.ID, &t.CreatedOn)
+	if err != nil {
+		if psqlErr, ok := err.(*pq.Error); ok && psqlErr.Code == storages.UniqueViolationCode {
+			return templates.ErrAlreadyExists
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Recrypt encrypts up to limit templates that are not encrypted under the
+// primary key of the keyring, see storages.RecryptContents. The rows
+// locked by another transaction are skipped.
+//
+// Returns the number of recrypted templates, zero when there are no such
+// templates left. If the keyring has no primary key returns
+// envelope.ErrNoPrimaryKey.
+func (s *TemplatesStorage) Recrypt(ctx context.Context, limit int) (int, error) {
+	const (
+		selectQuery = "SELECT id, title, description, key_id, data_key FROM task_templates WHERE key_id IS DISTINCT FROM $1 ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED"
+		updateQuery = "UPDATE task_templates SET title = $1, description = $2, key_id = $3, data_key = $4 WHERE id = $5"
+	)
+
+	recrypt, err := storages.RecryptContents(s.keyring)
+	if err != nil {
+		return 0, err
+	}
+
+	return storages.RewriteContents(ctx, s.stmts, selectQuery, updateQuery, []any{s.keyring.Primary(), limit}, recrypt)
+}
+
+// Decrypt stores up to limit encrypted templates in plaintext. It is meant
+// to be run before the encryption migration is rolled back.
+//
+// Returns the number of decrypted templates, zero when there are no
+// encrypted templates left.
+func (s *TemplatesStorage) Decrypt(ctx context.Context, limit int) (int, error) {
+	const (
+		selectQuery = "SELECT id, title, description, key_id, data_key FROM task_templates WHERE key_id IS NOT NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED"
+		updateQuery = "UPDATE task_templates SET title = $1, description = $2, key_id = NULLIF($3, ''), data_key = $4 WHERE id = $5"
+	)
+
+	return storages.RewriteContents(ctx, s.stmts, selectQuery, updateQuery, []any{limit}, storages.DecryptContents(s.keyring))
+}
+
+// Delete deletes a template from the database.
+//
+// If count of affected rows is not 1 returns templates.ErrNotFound.
+func (s *TemplatesStorage) Delete(ctx context.Context, id string) error {
+	const query = "DELETE FROM task_templates WHERE id = $1"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count != 1 {
+		return templates.ErrNotFound
+	}
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTemplate scans a row selected with templateColumns and decrypts the
+// title and the description.
+func (s *TemplatesStorage) scanTemplate(row scanner) (data.Template, error) {
+	var (
+		t         data.Template
+		checklist []byte
+		contents  storages.Contents
+		keyID     sql.NullString
+	)
+
+	err := row.Scan(
+		&t.ID, &t.UserID, &t.Name, &contents.Title, &contents.Description,
+		pq.Array(&t.Tags), &t.Priority, &t.Recurrence, &checklist, &t.CreatedOn,
+		&keyID, &contents.DataKey,
+	)
+	if err != nil {
+		return data.Template{}, err
+	}
+
+	contents.KeyID = keyID.String
+	if t.Title, t.Description, err = contents.Open(s.keyring); err != nil {
+		return data.Template{}, err
+	}
+
+	if err = json.Unmarshal(checklist, &t.Checklist); err != nil {
+		return data.Template{}, err
+	}
+
+	return t, nil
+}
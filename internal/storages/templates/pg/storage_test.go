@@ -0,0 +1,25 @@
+package pg_test
+
+import (
+	"testing"
+
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+	"github.com/romankravchuk/eldorado/internal/storages/templates/pg"
+	userspg "github.com/romankravchuk/eldorado/internal/storages/users/pg"
+)
+
+func TestConformance(t *testing.T) {
+	db := storagetest.Postgres(t)
+
+	users, err := userspg.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := pg.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storagetest.Templates(t, templates, users)
+}
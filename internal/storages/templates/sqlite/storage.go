@@ -0,0 +1,215 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/templates"
+)
+
+const templateColumns = "id, user_id, name, title, description, tags, priority, recurrence, checklist, created_on"
+
+// TemplatesStorage is a sqlite implementation of templates.Storage.
+type TemplatesStorage struct {
+	db    *sql.DB
+	stmts *storages.Statements
+}
+
+// New returns new TemplatesStorage instance with sqlite db pool,
+// see storages.NewSQLitePool.
+//
+// If db is nil returns storages.ErrNilDBPool.
+func New(db *sql.DB) (*TemplatesStorage, error) {
+	if db == nil {
+		return nil, storages.ErrNilDBPool
+	}
+
+	return &TemplatesStorage{db: db, stmts: storages.NewStatements(db)}, nil
+}
+
+// FindByUserID returns a list of templates for a given user ordered by name.
+func (s *TemplatesStorage) FindByUserID(ctx context.Context, userID string) ([]data.Template, error) {
+	const query = "SELECT " + templateColumns + " FROM task_templates WHERE user_id = $1 ORDER BY name"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := stmt.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var tt []data.Template
+	for rows.Next() {
+		var t data.Template
+		if t, err = scanTemplate(rows); err != nil {
+			break
+		}
+		tt = append(tt, t)
+	}
+
+	if closeErr := rows.Close(); closeErr != nil {
+		return nil, closeErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tt, nil
+}
+
+// FindByID returns a template by given id.
+//
+// If template is not found returns templates.ErrNotFound.
+func (s *TemplatesStorage) FindByID(ctx context.Context, id string) (data.Template, error) {
+	const query = "SELECT " + templateColumns + " FROM task_templates WHERE id = $1"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return data.Template{}, err
+	}
+
+	t, err := scanTemplate(stmt.QueryRowContext(ctx, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data.Template{}, templates.ErrNotFound
+		}
+
+		return data.Template{}, err
+	}
+
+	return t, nil
+}
+
+// Save saves a template to the database.
+//
+// If save succeeds ID and CreatedOn fields are filled.
+// If user already has a template with the same name returns templates.ErrAlreadyExists.
+func (s *TemplatesStorage) Save(ctx context.Context, t *data.Template) error {
+	const query = "INSERT INTO task_templates (id, user_id, name, title, description, tags, priority, recurrence, checklist, created_on) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)"
+
+	tags, err := marshalArray(t.Tags)
+	if err != nil {
+		return err
+	}
+
+	checklist, err := marshalArray(t.Checklist)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	var (
+		id        = uuid.NewString()
+		createdOn = time.Now().UTC().Truncate(time.Microsecond)
+	)
+
+	_, err = stmt.ExecContext(ctx,
+		id, t.UserID, t.Name, t.Title, t.Description, tags, t.Priority, t.Recurrence, checklist, createdOn,
+	)
+	if err != nil {
+		if storages.IsSQLiteUniqueViolation(err) {
+			return templates.ErrAlreadyExists
+		}
+
+		return err
+	}
+
+	t.ID = id
+	t.CreatedOn = createdOn
+
+	return nil
+}
+
+// Delete deletes a template from the database.
+//
+// If count of affected rows is not 1 returns templates.ErrNotFound.
+func (s *TemplatesStorage) Delete(ctx context.Context, id string) error {
+	const query = "DELETE FROM task_templates WHERE id = $1"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count != 1 {
+		return templates.ErrNotFound
+	}
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTemplate scans a row selected with templateColumns.
+func scanTemplate(row scanner) (data.Template, error) {
+	var (
+		t         data.Template
+		createdOn storages.SQLiteTime
+		tags      string
+		checklist string
+	)
+
+	err := row.Scan(
+		&t.ID, &t.UserID, &t.Name, &t.Title, &t.Description,
+		&tags, &t.Priority, &t.Recurrence, &checklist, &createdOn,
+	)
+	if err != nil {
+		return data.Template{}, err
+	}
+
+	t.CreatedOn = createdOn.Time
+
+	if err = json.Unmarshal([]byte(tags), &t.Tags); err != nil {
+		return data.Template{}, err
+	}
+
+	if err = json.Unmarshal([]byte(checklist), &t.Checklist); err != nil {
+		return data.Template{}, err
+	}
+
+	return t, nil
+}
+
+// marshalArray returns items as a JSON array, nil items as an empty one.
+func marshalArray[T any](items []T) (string, error) {
+	if items == nil {
+		items = []T{}
+	}
+
+	b, err := json.Marshal(items)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
@@ -0,0 +1,25 @@
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+	"github.com/romankravchuk/eldorado/internal/storages/templates/sqlite"
+	userssqlite "github.com/romankravchuk/eldorado/internal/storages/users/sqlite"
+)
+
+func TestConformance(t *testing.T) {
+	db := storagetest.SQLite(t)
+
+	users, err := userssqlite.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := sqlite.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storagetest.Templates(t, templates, users)
+}
@@ -0,0 +1,21 @@
+package templates
+
+import (
+	"context"
+	"errors"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+)
+
+var (
+	ErrNotFound      = errors.New("the template not found")
+	ErrAlreadyExists = errors.New("the template with given name already exists")
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name Storage
+type Storage interface {
+	FindByUserID(ctx context.Context, userID string) ([]data.Template, error)
+	FindByID(ctx context.Context, id string) (data.Template, error)
+	Save(ctx context.Context, t *data.Template) error
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,105 @@
+package storages
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Transactor runs several storage calls as a unit of work.
+type Transactor interface {
+	// WithinTx runs fn in a transaction. The storage calls made with the
+	// context passed to fn join the transaction. It is committed if fn
+	// returns nil and rolled back if fn returns an error or panics.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+// ctxTx is the transaction of a db pool kept in a context.
+type ctxTx struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+// TxTransactor is a Transactor of a postgres or sqlite db pool. The
+// storages of the same pool join its transactions, see Statements.Prepare.
+type TxTransactor struct {
+	db *sql.DB
+}
+
+// NewTransactor returns a Transactor of db.
+//
+// If db is nil returns ErrNilDBPool.
+func NewTransactor(db *sql.DB) (*TxTransactor, error) {
+	if db == nil {
+		return nil, ErrNilDBPool
+	}
+
+	return &TxTransactor{db: db}, nil
+}
+
+// WithinTx runs fn in a transaction, see Transactor. If ctx already
+// holds a transaction of the pool, fn joins it and the outermost call
+// commits. A panic of fn is raised again after the rollback.
+//
+// The transaction must not be used concurrently, so fn must not share
+// its context with other goroutines.
+func (t *TxTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return RunInTx(ctx, t.db, func(ctx context.Context, _ *sql.Tx) error {
+		return fn(ctx)
+	})
+}
+
+// RunInTx runs fn in the transaction of db held by ctx, or in a new one
+// that is committed when fn returns nil. The context passed to fn holds
+// the transaction.
+func RunInTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context, tx *sql.Tx) error) (err error) {
+	if tx, ok := Tx(ctx, db); ok {
+		return fn(ctx, tx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+
+		err = tx.Commit()
+	}()
+
+	return fn(context.WithValue(ctx, txKey{}, ctxTx{db: db, tx: tx}), tx)
+}
+
+// Tx returns the transaction of db held by ctx.
+func Tx(ctx context.Context, db *sql.DB) (*sql.Tx, bool) {
+	t, ok := ctx.Value(txKey{}).(ctxTx)
+	if !ok || t.db != db {
+		return nil, false
+	}
+
+	return t.tx, true
+}
+
+// InTx reports whether ctx holds a transaction of any db pool.
+func InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(ctxTx)
+	return ok
+}
+
+// NopTransactor runs the calls without a transaction. It is meant for the
+// storages that have no transactions, such as the in-memory ones.
+type NopTransactor struct{}
+
+func (NopTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
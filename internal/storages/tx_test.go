@@ -0,0 +1,152 @@
+package storages_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+	"github.com/romankravchuk/eldorado/internal/storages/users"
+	userssqlite "github.com/romankravchuk/eldorado/internal/storages/users/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTxTest returns the transactor and the users storage of a new sqlite
+// database, and the context of the calls.
+func newTxTest(t *testing.T) (*storages.TxTransactor, *userssqlite.UsersStorage, *sql.DB, context.Context) {
+	db := storagetest.SQLite(t)
+
+	tx, err := storages.NewTransactor(db)
+	require.NoError(t, err)
+
+	us, err := userssqlite.New(db)
+	require.NoError(t, err)
+
+	return tx, us, db, storages.AllWorkspaces(context.Background())
+}
+
+func newUser() *data.User {
+	name := uuid.NewString()[:8]
+	return &data.User{Email: name + "@example.com", Username: name, EncryptedPassword: "hash"}
+}
+
+func TestWithinTxCommit(t *testing.T) {
+	tx, us, _, ctx := newTxTest(t)
+
+	u := newUser()
+	err := tx.WithinTx(ctx, func(ctx context.Context) error {
+		assert.True(t, storages.InTx(ctx))
+		return us.Save(ctx, u)
+	})
+	require.NoError(t, err)
+
+	_, err = us.FindByID(ctx, u.ID)
+	assert.NoError(t, err)
+}
+
+func TestWithinTxRollback(t *testing.T) {
+	tx, us, _, ctx := newTxTest(t)
+
+	errFailed := errors.New("failed")
+
+	u := newUser()
+	err := tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := us.Save(ctx, u); err != nil {
+			return err
+		}
+
+		// The call made in the transaction sees its own write.
+		_, err := us.FindByID(ctx, u.ID)
+		require.NoError(t, err)
+
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+
+	_, err = us.FindByID(ctx, u.ID)
+	assert.ErrorIs(t, err, users.ErrNotFound, "the write of the failed transaction is kept")
+}
+
+func TestWithinTxPanic(t *testing.T) {
+	tx, us, db, ctx := newTxTest(t)
+
+	u := newUser()
+	assert.PanicsWithValue(t, "failed", func() {
+		tx.WithinTx(ctx, func(ctx context.Context) error {
+			if err := us.Save(ctx, u); err != nil {
+				return err
+			}
+			panic("failed")
+		})
+	})
+
+	_, err := us.FindByID(ctx, u.ID)
+	assert.ErrorIs(t, err, users.ErrNotFound, "the write of the panicked transaction is kept")
+
+	// The connection is released, a sqlite database has one writer.
+	assert.Equal(t, 0, db.Stats().InUse)
+}
+
+func TestWithinTxNested(t *testing.T) {
+	tx, us, db, ctx := newTxTest(t)
+
+	errFailed := errors.New("failed")
+
+	outer, inner := newUser(), newUser()
+	err := tx.WithinTx(ctx, func(ctx context.Context) error {
+		outerTx, ok := storages.Tx(ctx, db)
+		require.True(t, ok)
+
+		if err := us.Save(ctx, outer); err != nil {
+			return err
+		}
+
+		err := storages.RunInTx(ctx, db, func(ctx context.Context, innerTx *sql.Tx) error {
+			assert.Same(t, outerTx, innerTx, "the nested call does not join the outer transaction")
+			return us.Save(ctx, inner)
+		})
+		require.NoError(t, err)
+
+		// The nested call does not commit, the outer one rolls back both.
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+
+	_, err = us.FindByID(ctx, outer.ID)
+	assert.ErrorIs(t, err, users.ErrNotFound)
+	_, err = us.FindByID(ctx, inner.ID)
+	assert.ErrorIs(t, err, users.ErrNotFound)
+}
+
+func TestTx(t *testing.T) {
+	db := storagetest.SQLite(t)
+	other := storagetest.SQLite(t)
+
+	ctx := context.Background()
+	assert.False(t, storages.InTx(ctx))
+
+	_, ok := storages.Tx(ctx, db)
+	assert.False(t, ok)
+
+	err := storages.RunInTx(ctx, db, func(ctx context.Context, tx *sql.Tx) error {
+		got, ok := storages.Tx(ctx, db)
+		assert.True(t, ok)
+		assert.Same(t, tx, got)
+
+		// The transaction of one pool is not joined by another.
+		_, ok = storages.Tx(ctx, other)
+		assert.False(t, ok)
+		assert.True(t, storages.InTx(ctx))
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, err = storages.NewTransactor(nil)
+	assert.ErrorIs(t, err, storages.ErrNilDBPool)
+}
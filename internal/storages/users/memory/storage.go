@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages/users"
+)
+
+// UsersStorage is an in-memory implementation of users.Storage.
+// It is safe for concurrent use.
+type UsersStorage struct {
+	mu    sync.RWMutex
+	users map[string]data.User
+}
+
+func New() *UsersStorage {
+	return &UsersStorage{users: make(map[string]data.User)}
+}
+
+// FindByUsername returns user by given username.
+//
+// If user is not found returns users.ErrNotFound.
+func (s *UsersStorage) FindByUsername(_ context.Context, username string) (data.User, error) {
+	return s.find(func(u data.User) bool { return u.Username == username })
+}
+
+// FindByID returns user by given ID.
+//
+// If user is not found returns users.ErrNotFound.
+func (s *UsersStorage) FindByID(_ context.Context, id string) (data.User, error) {
+	return s.find(func(u data.User) bool { return u.ID == id })
+}
+
+// FindByEmail returns user by given email, the users marked deleted
+// included.
+//
+// If user is not found returns users.ErrNotFound.
+func (s *UsersStorage) FindByEmail(_ context.Context, email string) (data.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+
+	return data.User{}, users.ErrNotFound
+}
+
+// Save saves a given user and fills its ID and CreatedOn.
+//
+// If user with given email or username already exists returns users.ErrAlreadyExists.
+func (s *UsersStorage) Save(_ context.Context, u *data.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Email == u.Email || existing.Username == u.Username {
+			return users.ErrAlreadyExists
+		}
+	}
+
+	u.ID = uuid.NewString()
+	u.CreatedOn = time.Now().UTC()
+	u.DeletedOn = time.Time{}
+
+	s.users[u.ID] = *u
+
+	return nil
+}
+
+// Delete marks the user deleted.
+//
+// If the user is not found or is already marked returns users.ErrNotFound.
+func (s *UsersStorage) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok || !u.DeletedOn.IsZero() {
+		return users.ErrNotFound
+	}
+
+	u.DeletedOn = time.Now().UTC()
+	s.users[id] = u
+
+	return nil
+}
+
+// Restore clears the deletion mark of the user.
+//
+// If the user is not found or is not marked returns users.ErrNotFound.
+func (s *UsersStorage) Restore(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok || u.DeletedOn.IsZero() {
+		return users.ErrNotFound
+	}
+
+	u.DeletedOn = time.Time{}
+	s.users[id] = u
+
+	return nil
+}
+
+// Purge deletes the users marked deleted before the given time. The
+// storage keeps nothing else of the users.
+func (s *UsersStorage) Purge(_ context.Context, before time.Time) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	for id, u := range s.users {
+		if !u.DeletedOn.IsZero() && u.DeletedOn.Before(before) {
+			ids = append(ids, id)
+			delete(s.users, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// EmailByID returns the email of the user, deleted users included.
+func (s *UsersStorage) EmailByID(id string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[id]
+	return u.Email, ok
+}
+
+func (s *UsersStorage) find(match func(data.User) bool) (data.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.DeletedOn.IsZero() && match(u) {
+			return u, nil
+		}
+	}
+
+	return data.User{}, users.ErrNotFound
+}
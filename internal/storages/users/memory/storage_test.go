@@ -0,0 +1,12 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+	"github.com/romankravchuk/eldorado/internal/storages/users/memory"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Users(t, memory.New())
+}
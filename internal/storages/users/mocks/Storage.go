@@ -7,6 +7,8 @@ import (
 
 	data "github.com/romankravchuk/eldorado/internal/data"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // Storage is an autogenerated mock type for the Storage type
@@ -14,6 +16,20 @@ type Storage struct {
 	mock.Mock
 }
 
+// Delete provides a mock function with given fields: ctx, id
+func (_m *Storage) Delete(ctx context.Context, id string) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // FindByEmail provides a mock function with given fields: ctx, email
 func (_m *Storage) FindByEmail(ctx context.Context, email string) (data.User, error) {
 	ret := _m.Called(ctx, email)
@@ -38,6 +54,30 @@ func (_m *Storage) FindByEmail(ctx context.Context, email string) (data.User, er
 	return r0, r1
 }
 
+// FindByID provides a mock function with given fields: ctx, id
+func (_m *Storage) FindByID(ctx context.Context, id string) (data.User, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 data.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (data.User, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) data.User); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(data.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // FindByUsername provides a mock function with given fields: ctx, username
 func (_m *Storage) FindByUsername(ctx context.Context, username string) (data.User, error) {
 	ret := _m.Called(ctx, username)
@@ -62,6 +102,46 @@ func (_m *Storage) FindByUsername(ctx context.Context, username string) (data.Us
 	return r0, r1
 }
 
+// Purge provides a mock function with given fields: ctx, before
+func (_m *Storage) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	ret := _m.Called(ctx, before)
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) ([]string, error)); ok {
+		return rf(ctx, before)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) []string); ok {
+		r0 = rf(ctx, before)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Restore provides a mock function with given fields: ctx, id
+func (_m *Storage) Restore(ctx context.Context, id string) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Save provides a mock function with given fields: ctx, u
 func (_m *Storage) Save(ctx context.Context, u *data.User) error {
 	ret := _m.Called(ctx, u)
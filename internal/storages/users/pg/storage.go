@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/lib/pq"
 	"github.com/romankravchuk/eldorado/internal/data"
@@ -12,37 +13,69 @@ import (
 )
 
 // UsersStorage is a postgres implementation of users.Storage.
+//
+// Every call is scoped to the workspace of the context, see
+// storages.Scoped, and the row-level security of the users table hides
+// the users that are not members of the workspace. The users are created
+// and signed in on behalf of no workspace, see storages.AllWorkspaces.
 type UsersStorage struct {
-	db *sql.DB
+	db       *sql.DB
+	stmts    *storages.Statements
+	replicas *storages.Replicas
+}
+
+type Option func(*UsersStorage)
+
+// WithReplicas routes the lookups of the users to the read replicas.
+func WithReplicas(replicas *storages.Replicas) Option {
+	return func(s *UsersStorage) {
+		s.replicas = replicas
+	}
 }
 
 // New retuns new UsersStorage instance with postgres db pool.
 //
 // If db is nil returns storages.ErrNilDBPool.
-func New(db *sql.DB) (*UsersStorage, error) {
+func New(db *sql.DB, opts ...Option) (*UsersStorage, error) {
 	if db == nil {
 		return nil, storages.ErrNilDBPool
 	}
 
-	return &UsersStorage{
-		db: db,
-	}, nil
+	s := &UsersStorage{
+		db:    db,
+		stmts: storages.NewStatements(db),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+// FindByID returns user by given ID.
+//
+// If user is not found returns users.ErrNotFound.
+func (s *UsersStorage) FindByID(ctx context.Context, id string) (data.User, error) {
+	const query = "SELECT id, email, username, encrypted_password, name, created_on, deleted_on FROM users WHERE id = $1 AND deleted_on IS NULL"
+
+	return s.findUser(ctx, query, id)
 }
 
 // FindByUsername returns user by given username.
 //
 // If user is not found returns users.ErrNotFound.
 func (s *UsersStorage) FindByUsername(ctx context.Context, username string) (data.User, error) {
-	const query = "SELECT id, email, username, encrypted_password, name, created_on FROM users WHERE username = $1 AND deleted_on IS NULL"
+	const query = "SELECT id, email, username, encrypted_password, name, created_on, deleted_on FROM users WHERE username = $1 AND deleted_on IS NULL"
 
 	return s.findUser(ctx, query, username)
 }
 
-// FindByUsername returns user by given username.
+// FindByEmail returns user by given email, the users marked deleted
+// included.
 //
 // If user is not found returns users.ErrNotFound.
 func (s *UsersStorage) FindByEmail(ctx context.Context, email string) (data.User, error) {
-	const query = "SELECT id, email, username, encrypted_password, name, created_on FROM users WHERE email = $1 AND deleted_on IS NULL"
+	const query = "SELECT id, email, username, encrypted_password, name, created_on, deleted_on FROM users WHERE email = $1"
 
 	return s.findUser(ctx, query, email)
 }
@@ -53,16 +86,14 @@ func (s *UsersStorage) FindByEmail(ctx context.Context, email string) (data.User
 func (s *UsersStorage) Save(ctx context.Context, u *data.User) error {
 	const query = "INSERT INTO users (email, username, name, encrypted_password) VALUES ($1, $2, $3, $4) RETURNING id"
 
-	prepareCtx, cancel := context.WithTimeout(ctx, storages.PrepareTimeout)
-	defer cancel()
-
-	stmt, err := s.db.PrepareContext(prepareCtx, query)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
+	err := storages.Scoped(ctx, s.stmts, func(ctx context.Context, _ *sql.Tx) error {
+		stmt, err := s.stmts.Prepare(ctx, query)
+		if err != nil {
+			return err
+		}
 
-	err = stmt.QueryRowContext(ctx, u.Email, u.Username, u.Name, u.EncryptedPassword).Scan(&u.ID)
+		return stmt.QueryRowContext(ctx, u.Email, u.Username, u.Name, u.EncryptedPassword).Scan(&u.ID)
+	})
 	if err != nil {
 		if psqlErr, ok := err.(*pq.Error); ok && psqlErr.Code == storages.UniqueViolationCode {
 			return users.ErrAlreadyExists
@@ -71,22 +102,161 @@ func (s *UsersStorage) Save(ctx context.Context, u *data.User) error {
 		return err
 	}
 
+	s.replicas.Wrote(ctx)
+
 	return nil
 }
 
-func (s *UsersStorage) findUser(ctx context.Context, query, param string) (data.User, error) {
-	prepareCtx, cancel := context.WithTimeout(ctx, storages.PrepareTimeout)
-	defer cancel()
+// Delete marks the user deleted.
+//
+// If the user is not found or is already marked returns users.ErrNotFound.
+func (s *UsersStorage) Delete(ctx context.Context, id string) error {
+	const query = "UPDATE users SET deleted_on = (now() AT TIME ZONE 'utc') WHERE id = $1 AND deleted_on IS NULL"
+
+	return s.exec(ctx, query, id)
+}
+
+// Restore clears the deletion mark of the user.
+//
+// If the user is not found or is not marked returns users.ErrNotFound.
+func (s *UsersStorage) Restore(ctx context.Context, id string) error {
+	const query = "UPDATE users SET deleted_on = NULL WHERE id = $1 AND deleted_on IS NOT NULL"
+
+	return s.exec(ctx, query, id)
+}
+
+// Purge deletes the users marked deleted before the given time and
+// everything they own in one transaction, their events in the outbox
+// included. The dependencies of the tasks and the memberships are deleted
+// by the cascades.
+func (s *UsersStorage) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	const (
+		selectQuery = "SELECT id FROM users WHERE deleted_on < $1 FOR UPDATE"
+
+		// The events of the users and of their tasks, they carry the
+		// email and the contents of the tasks.
+		outboxQuery    = "DELETE FROM outbox WHERE aggregate_id = ANY($1::text[]) OR aggregate_id IN (SELECT id::text FROM tasks WHERE user_id = ANY($1::uuid[]))"
+		tasksQuery     = "DELETE FROM tasks WHERE user_id = ANY($1)"
+		templatesQuery = "DELETE FROM task_templates WHERE user_id = ANY($1)"
+		settingsQuery  = "DELETE FROM user_settings WHERE user_id = ANY($1)"
+		// The workspaces of the users that have no other members and no
+		// tasks left.
+		workspacesQuery = "DELETE FROM workspaces w WHERE w.id IN (SELECT workspace_id FROM workspace_members WHERE user_id = ANY($1)) " +
+			"AND NOT EXISTS (SELECT 1 FROM workspace_members m WHERE m.workspace_id = w.id AND m.user_id <> ALL($1)) " +
+			"AND NOT EXISTS (SELECT 1 FROM tasks t WHERE t.workspace_id = w.id)"
+		usersQuery = "DELETE FROM users WHERE id = ANY($1)"
+	)
+
+	var ids []string
 
-	stmt, err := s.db.PrepareContext(prepareCtx, query)
+	err := storages.ScopedTx(ctx, s.stmts, func(ctx context.Context, _ *sql.Tx) error {
+		stmt, err := s.stmts.Prepare(ctx, selectQuery)
+		if err != nil {
+			return err
+		}
+
+		rows, err := stmt.QueryContext(ctx, before.UTC())
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var id string
+			if err = rows.Scan(&id); err != nil {
+				break
+			}
+			ids = append(ids, id)
+		}
+
+		if closeErr := rows.Close(); closeErr != nil {
+			return closeErr
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		for _, query := range []string{outboxQuery, tasksQuery, templatesQuery, settingsQuery, workspacesQuery, usersQuery} {
+			stmt, err := s.stmts.Prepare(ctx, query)
+			if err != nil {
+				return err
+			}
+
+			if _, err = stmt.ExecContext(ctx, pq.Array(ids)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 	if err != nil {
-		return data.User{}, err
+		return nil, err
+	}
+
+	if len(ids) > 0 {
+		s.replicas.Wrote(ctx)
+	}
+
+	return ids, nil
+}
+
+// exec executes the statement of the query on the primary.
+//
+// If count of affected rows is not 1 returns users.ErrNotFound.
+func (s *UsersStorage) exec(ctx context.Context, query string, args ...any) error {
+	err := storages.Scoped(ctx, s.stmts, func(ctx context.Context, _ *sql.Tx) error {
+		stmt, err := s.stmts.Prepare(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		res, err := stmt.ExecContext(ctx, args...)
+		if err != nil {
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if n != 1 {
+			return users.ErrNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	defer stmt.Close()
 
-	var u data.User
-	err = stmt.QueryRowContext(ctx, param).
-		Scan(&u.ID, &u.Email, &u.Username, &u.EncryptedPassword, &u.Name, &u.CreatedOn)
+	s.replicas.Wrote(ctx)
+
+	return nil
+}
+
+func (s *UsersStorage) findUser(ctx context.Context, query, param string) (u data.User, err error) {
+	stmts := s.reader(ctx)
+
+	var deletedOn sql.NullTime
+
+	err = storages.Scoped(ctx, stmts, func(ctx context.Context, _ *sql.Tx) error {
+		stmt, err := stmts.Prepare(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		return stmt.QueryRowContext(ctx, param).
+			Scan(&u.ID, &u.Email, &u.Username, &u.EncryptedPassword, &u.Name, &u.CreatedOn, &deletedOn)
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return data.User{}, users.ErrNotFound
@@ -95,5 +265,16 @@ func (s *UsersStorage) findUser(ctx context.Context, query, param string) (data.
 		return data.User{}, err
 	}
 
+	u.DeletedOn = deletedOn.Time
+
 	return u, nil
 }
+
+// reader returns the statements of a replica for a read-only query,
+// or of the primary, see storages.Replicas.
+func (s *UsersStorage) reader(ctx context.Context) *storages.Statements {
+	if stmts := s.replicas.Reader(ctx); stmts != nil {
+		return stmts
+	}
+	return s.stmts
+}
@@ -0,0 +1,46 @@
+package pg_test
+
+import (
+	"testing"
+
+	outboxpg "github.com/romankravchuk/eldorado/internal/storages/outbox/pg"
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+	taskspg "github.com/romankravchuk/eldorado/internal/storages/tasks/pg"
+	"github.com/romankravchuk/eldorado/internal/storages/users/pg"
+	workspacespg "github.com/romankravchuk/eldorado/internal/storages/workspaces/pg"
+)
+
+func TestConformance(t *testing.T) {
+	users, err := pg.New(storagetest.Postgres(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storagetest.Users(t, users)
+}
+
+func TestPurgedEvents(t *testing.T) {
+	db := storagetest.Postgres(t)
+
+	users, err := pg.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workspaces, err := workspacespg.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := taskspg.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := outboxpg.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storagetest.PurgedEvents(t, users, workspaces, tasks, events)
+}
@@ -0,0 +1,72 @@
+package resilient
+
+import (
+	"context"
+	"time"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages/resilience"
+	"github.com/romankravchuk/eldorado/internal/storages/users"
+)
+
+// Storage is a users.Storage decorator that retries the reads failed on
+// transient errors and fails all calls fast while the breaker is open,
+// see resilience.Policy.
+type Storage struct {
+	next   users.Storage
+	policy *resilience.Policy
+}
+
+func New(next users.Storage, policy *resilience.Policy) *Storage {
+	return &Storage{next: next, policy: policy}
+}
+
+func (s *Storage) FindByUsername(ctx context.Context, username string) (u data.User, err error) {
+	err = s.policy.Read(ctx, func(ctx context.Context) error {
+		u, err = s.next.FindByUsername(ctx, username)
+		return err
+	})
+	return u, err
+}
+
+func (s *Storage) FindByID(ctx context.Context, id string) (u data.User, err error) {
+	err = s.policy.Read(ctx, func(ctx context.Context) error {
+		u, err = s.next.FindByID(ctx, id)
+		return err
+	})
+	return u, err
+}
+
+func (s *Storage) FindByEmail(ctx context.Context, email string) (u data.User, err error) {
+	err = s.policy.Read(ctx, func(ctx context.Context) error {
+		u, err = s.next.FindByEmail(ctx, email)
+		return err
+	})
+	return u, err
+}
+
+func (s *Storage) Save(ctx context.Context, u *data.User) error {
+	return s.policy.Write(ctx, func(ctx context.Context) error {
+		return s.next.Save(ctx, u)
+	})
+}
+
+func (s *Storage) Delete(ctx context.Context, id string) error {
+	return s.policy.Write(ctx, func(ctx context.Context) error {
+		return s.next.Delete(ctx, id)
+	})
+}
+
+func (s *Storage) Restore(ctx context.Context, id string) error {
+	return s.policy.Write(ctx, func(ctx context.Context) error {
+		return s.next.Restore(ctx, id)
+	})
+}
+
+func (s *Storage) Purge(ctx context.Context, before time.Time) (ids []string, err error) {
+	err = s.policy.Write(ctx, func(ctx context.Context) error {
+		ids, err = s.next.Purge(ctx, before)
+		return err
+	})
+	return ids, err
+}
@@ -0,0 +1,247 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/users"
+)
+
+// UsersStorage is a sqlite implementation of users.Storage.
+type UsersStorage struct {
+	db    *sql.DB
+	stmts *storages.Statements
+}
+
+// New retuns new UsersStorage instance with sqlite db pool,
+// see storages.NewSQLitePool.
+//
+// If db is nil returns storages.ErrNilDBPool.
+func New(db *sql.DB) (*UsersStorage, error) {
+	if db == nil {
+		return nil, storages.ErrNilDBPool
+	}
+
+	return &UsersStorage{
+		db:    db,
+		stmts: storages.NewStatements(db),
+	}, nil
+}
+
+// FindByUsername returns user by given username.
+//
+// If user is not found returns users.ErrNotFound.
+func (s *UsersStorage) FindByUsername(ctx context.Context, username string) (data.User, error) {
+	const query = "SELECT id, email, username, encrypted_password, name, created_on, deleted_on FROM users WHERE username = $1 AND deleted_on IS NULL"
+
+	return s.findUser(ctx, query, username)
+}
+
+// FindByID returns user by given ID.
+//
+// If user is not found returns users.ErrNotFound.
+func (s *UsersStorage) FindByID(ctx context.Context, id string) (data.User, error) {
+	const query = "SELECT id, email, username, encrypted_password, name, created_on, deleted_on FROM users WHERE id = $1 AND deleted_on IS NULL"
+
+	return s.findUser(ctx, query, id)
+}
+
+// FindByEmail returns user by given email, the users marked deleted
+// included.
+//
+// If user is not found returns users.ErrNotFound.
+func (s *UsersStorage) FindByEmail(ctx context.Context, email string) (data.User, error) {
+	const query = "SELECT id, email, username, encrypted_password, name, created_on, deleted_on FROM users WHERE email = $1"
+
+	return s.findUser(ctx, query, email)
+}
+
+// Save saves a given user in database. The ID is generated here,
+// since SQLite has no uuid generator.
+//
+// If user with given email or username already exists returns users.ErrAlreadyExists.
+func (s *UsersStorage) Save(ctx context.Context, u *data.User) error {
+	const query = "INSERT INTO users (id, email, username, name, encrypted_password) VALUES ($1, $2, $3, $4, $5)"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	id := uuid.NewString()
+
+	_, err = stmt.ExecContext(ctx, id, u.Email, u.Username, u.Name, u.EncryptedPassword)
+	if err != nil {
+		if storages.IsSQLiteUniqueViolation(err) {
+			return users.ErrAlreadyExists
+		}
+
+		return err
+	}
+
+	u.ID = id
+
+	return nil
+}
+
+// Delete marks the user deleted.
+//
+// If the user is not found or is already marked returns users.ErrNotFound.
+func (s *UsersStorage) Delete(ctx context.Context, id string) error {
+	const query = "UPDATE users SET deleted_on = " + nowUTC + " WHERE id = $1 AND deleted_on IS NULL"
+
+	return s.exec(ctx, query, id)
+}
+
+// Restore clears the deletion mark of the user.
+//
+// If the user is not found or is not marked returns users.ErrNotFound.
+func (s *UsersStorage) Restore(ctx context.Context, id string) error {
+	const query = "UPDATE users SET deleted_on = NULL WHERE id = $1 AND deleted_on IS NOT NULL"
+
+	return s.exec(ctx, query, id)
+}
+
+// Purge deletes the users marked deleted before the given time and
+// everything they own in one transaction, their events in the outbox
+// included. The dependencies of the tasks and the memberships are deleted
+// by the cascades.
+func (s *UsersStorage) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	const (
+		selectQuery = "SELECT id FROM users WHERE julianday(deleted_on) < julianday($1)"
+
+		// The events of the users and of their tasks, they carry the
+		// email and the contents of the tasks.
+		outboxQuery    = "DELETE FROM outbox WHERE aggregate_id IN (SELECT value FROM json_each($1)) OR aggregate_id IN (SELECT id FROM tasks WHERE user_id IN (SELECT value FROM json_each($1)))"
+		tasksQuery     = "DELETE FROM tasks WHERE user_id IN (SELECT value FROM json_each($1))"
+		templatesQuery = "DELETE FROM task_templates WHERE user_id IN (SELECT value FROM json_each($1))"
+		settingsQuery  = "DELETE FROM user_settings WHERE user_id IN (SELECT value FROM json_each($1))"
+		// The workspaces of the users that have no other members and no
+		// tasks left.
+		workspacesQuery = "DELETE FROM workspaces WHERE id IN (SELECT workspace_id FROM workspace_members WHERE user_id IN (SELECT value FROM json_each($1))) " +
+			"AND NOT EXISTS (SELECT 1 FROM workspace_members m WHERE m.workspace_id = workspaces.id AND m.user_id NOT IN (SELECT value FROM json_each($1))) " +
+			"AND NOT EXISTS (SELECT 1 FROM tasks t WHERE t.workspace_id = workspaces.id)"
+		usersQuery = "DELETE FROM users WHERE id IN (SELECT value FROM json_each($1))"
+	)
+
+	var ids []string
+
+	err := storages.RunInTx(ctx, s.db, func(ctx context.Context, _ *sql.Tx) error {
+		stmt, err := s.stmts.Prepare(ctx, selectQuery)
+		if err != nil {
+			return err
+		}
+
+		rows, err := stmt.QueryContext(ctx, before.UTC())
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var id string
+			if err = rows.Scan(&id); err != nil {
+				break
+			}
+			ids = append(ids, id)
+		}
+
+		if closeErr := rows.Close(); closeErr != nil {
+			return closeErr
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		b, err := json.Marshal(ids)
+		if err != nil {
+			return err
+		}
+
+		for _, query := range []string{outboxQuery, tasksQuery, templatesQuery, settingsQuery, workspacesQuery, usersQuery} {
+			stmt, err := s.stmts.Prepare(ctx, query)
+			if err != nil {
+				return err
+			}
+
+			if _, err = stmt.ExecContext(ctx, string(b)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// exec executes the statement of the query.
+//
+// If count of affected rows is not 1 returns users.ErrNotFound.
+func (s *UsersStorage) exec(ctx context.Context, query string, args ...any) error {
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	res, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n != 1 {
+		return users.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *UsersStorage) findUser(ctx context.Context, query, param string) (data.User, error) {
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return data.User{}, err
+	}
+
+	var (
+		u                    data.User
+		createdOn, deletedOn storages.SQLiteTime
+	)
+	err = stmt.QueryRowContext(ctx, param).
+		Scan(&u.ID, &u.Email, &u.Username, &u.EncryptedPassword, &u.Name, &createdOn, &deletedOn)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data.User{}, users.ErrNotFound
+		}
+
+		return data.User{}, err
+	}
+
+	u.CreatedOn = createdOn.Time
+	u.DeletedOn = deletedOn.Time
+
+	return u, nil
+}
+
+const nowUTC = "strftime('%Y-%m-%d %H:%M:%f', 'now')"
@@ -0,0 +1,46 @@
+package sqlite_test
+
+import (
+	"testing"
+
+	outboxsqlite "github.com/romankravchuk/eldorado/internal/storages/outbox/sqlite"
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+	taskssqlite "github.com/romankravchuk/eldorado/internal/storages/tasks/sqlite"
+	"github.com/romankravchuk/eldorado/internal/storages/users/sqlite"
+	workspacessqlite "github.com/romankravchuk/eldorado/internal/storages/workspaces/sqlite"
+)
+
+func TestConformance(t *testing.T) {
+	users, err := sqlite.New(storagetest.SQLite(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storagetest.Users(t, users)
+}
+
+func TestPurgedEvents(t *testing.T) {
+	db := storagetest.SQLite(t)
+
+	users, err := sqlite.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workspaces, err := workspacessqlite.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := taskssqlite.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := outboxsqlite.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storagetest.PurgedEvents(t, users, workspaces, tasks, events)
+}
@@ -3,6 +3,7 @@ package users
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/romankravchuk/eldorado/internal/data"
 )
@@ -14,7 +15,18 @@ var (
 
 //go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name Storage
 type Storage interface {
+	FindByID(ctx context.Context, id string) (data.User, error)
 	FindByUsername(ctx context.Context, username string) (data.User, error)
+	// FindByEmail also finds the users marked deleted, with DeletedOn set,
+	// so they can sign in and cancel the deletion.
 	FindByEmail(ctx context.Context, email string) (data.User, error)
 	Save(ctx context.Context, u *data.User) error
+	// Delete marks the user deleted, Restore clears the mark.
+	Delete(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) error
+	// Purge deletes the users marked deleted before the given time with
+	// their tasks, templates, settings and events of the outbox, and the
+	// workspaces nobody else is a member of. It returns the IDs of the
+	// deleted users.
+	Purge(ctx context.Context, before time.Time) ([]string, error)
 }
@@ -0,0 +1,104 @@
+package storages
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNoWorkspace is returned by Scoped if the context is neither scoped
+	// to a workspace nor to all of them.
+	ErrNoWorkspace = errors.New("the context is not scoped to a workspace")
+	// ErrBypassRLS is returned by CheckRowSecurity for a role that skips
+	// the row-level security policies.
+	ErrBypassRLS = errors.New("the database role bypasses row-level security")
+)
+
+type workspaceKey struct{}
+
+// allWorkspaces is the scope of the contexts returned by AllWorkspaces.
+// It is not a valid workspace ID, so it can not be passed in a token.
+const allWorkspaces = "*"
+
+// WithWorkspace returns a copy of ctx scoped to the workspace. The postgres
+// storages of the users and the tasks see only the rows of the workspace,
+// see Scoped.
+func WithWorkspace(ctx context.Context, workspaceID string) context.Context {
+	return context.WithValue(ctx, workspaceKey{}, workspaceID)
+}
+
+// AllWorkspaces returns a copy of ctx that sees the rows of every
+// workspace. It is meant for the calls that are not made on behalf of a
+// workspace, such as the sign in, the archiving and the statistic.
+func AllWorkspaces(ctx context.Context) context.Context {
+	return context.WithValue(ctx, workspaceKey{}, allWorkspaces)
+}
+
+// WorkspaceID returns the workspace ctx is scoped to. It returns false for
+// the contexts that are not scoped or see all workspaces.
+func WorkspaceID(ctx context.Context) (string, bool) {
+	id, _ := ctx.Value(workspaceKey{}).(string)
+	if id == "" || id == allWorkspaces {
+		return "", false
+	}
+
+	return id, true
+}
+
+// Scoped runs fn with the row-level security of the workspace of ctx set
+// up. The policies of the users and tasks tables read it from the
+// app.workspace_id and app.all_workspaces settings, which the postgres
+// connections set from the context of every statement, see NewDBPool, so
+// a pooled connection never keeps the scope of another request. fn gets
+// the transaction of the pool of stmts held by ctx, nil if there is none.
+//
+// If ctx is not scoped returns ErrNoWorkspace without running fn.
+func Scoped(ctx context.Context, stmts *Statements, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	if scope, _ := ctx.Value(workspaceKey{}).(string); scope == "" {
+		return ErrNoWorkspace
+	}
+
+	tx, _ := Tx(ctx, stmts.db)
+
+	return fn(ctx, tx)
+}
+
+// ScopedTx is Scoped for the calls of several statements that have to run
+// in one transaction, see RunInTx.
+func ScopedTx(ctx context.Context, stmts *Statements, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	if scope, _ := ctx.Value(workspaceKey{}).(string); scope == "" {
+		return ErrNoWorkspace
+	}
+
+	return RunInTx(ctx, stmts.db, fn)
+}
+
+// CheckRowSecurity checks that the role of the postgres database at url
+// is subject to the row-level security policies. Superusers and the roles
+// with BYPASSRLS see the rows of every workspace, for them returns
+// ErrBypassRLS.
+func CheckRowSecurity(ctx context.Context, url string, opts ...PoolOption) error {
+	const query = "SELECT rolname, rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user"
+
+	db, err := OpenDB(DriverPostgres, url, opts...)
+	if err != nil {
+		return err
+	}
+	defer ClosePool(db)
+
+	var (
+		role   string
+		bypass bool
+	)
+	if err = db.QueryRowContext(ctx, query).Scan(&role, &bypass); err != nil {
+		return err
+	}
+
+	if bypass {
+		return fmt.Errorf("%w: %q", ErrBypassRLS, role)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages/workspaces"
+)
+
+// WorkspacesStorage is an in-memory implementation of workspaces.Storage.
+// It is safe for concurrent use.
+type WorkspacesStorage struct {
+	mu          sync.RWMutex
+	workspaces  map[string]data.Workspace
+	memberships []data.Membership
+}
+
+func New() *WorkspacesStorage {
+	return &WorkspacesStorage{workspaces: make(map[string]data.Workspace)}
+}
+
+// Create creates the workspace with the owner as its first member and
+// fills its ID and CreatedOn.
+func (s *WorkspacesStorage) Create(_ context.Context, w *data.Workspace, ownerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.ID = uuid.NewString()
+	w.CreatedOn = time.Now().UTC()
+
+	s.workspaces[w.ID] = *w
+	s.memberships = append(s.memberships, data.Membership{
+		WorkspaceID: w.ID,
+		UserID:      ownerID,
+		Role:        data.RoleOwner,
+		CreatedOn:   w.CreatedOn,
+	})
+
+	return nil
+}
+
+// FindMembership returns the membership of the user in the workspace.
+//
+// If the user is not a member returns workspaces.ErrNotFound.
+func (s *WorkspacesStorage) FindMembership(_ context.Context, workspaceID, userID string) (data.Membership, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, m := range s.memberships {
+		if m.WorkspaceID == workspaceID && m.UserID == userID {
+			return m, nil
+		}
+	}
+
+	return data.Membership{}, workspaces.ErrNotFound
+}
+
+// Memberships returns the memberships of the user, the oldest first,
+// since they are kept in the order they were created.
+func (s *WorkspacesStorage) Memberships(_ context.Context, userID string) ([]data.Membership, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var mm []data.Membership
+	for _, m := range s.memberships {
+		if m.UserID == userID {
+			mm = append(mm, m)
+		}
+	}
+
+	return mm, nil
+}
@@ -0,0 +1,13 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+	usersmemory "github.com/romankravchuk/eldorado/internal/storages/users/memory"
+	"github.com/romankravchuk/eldorado/internal/storages/workspaces/memory"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Workspaces(t, memory.New(), usersmemory.New())
+}
@@ -0,0 +1,158 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/romankravchuk/eldorado/internal/data"
+	"github.com/romankravchuk/eldorado/internal/storages"
+	"github.com/romankravchuk/eldorado/internal/storages/workspaces"
+)
+
+// WorkspacesStorage is a sqlite implementation of workspaces.Storage.
+type WorkspacesStorage struct {
+	db    *sql.DB
+	stmts *storages.Statements
+}
+
+// New returns new WorkspacesStorage instance with sqlite db pool,
+// see storages.NewSQLitePool.
+//
+// If db is nil returns storages.ErrNilDBPool.
+func New(db *sql.DB) (*WorkspacesStorage, error) {
+	if db == nil {
+		return nil, storages.ErrNilDBPool
+	}
+
+	return &WorkspacesStorage{db: db, stmts: storages.NewStatements(db)}, nil
+}
+
+// Create creates the workspace with the owner as its first member. It
+// joins the transaction of ctx, if there is one, see storages.RunInTx.
+//
+// If create succeeds ID and CreatedOn fields are filled.
+// SQLite has no uuid generator, so the ID is generated here.
+func (s *WorkspacesStorage) Create(ctx context.Context, w *data.Workspace, ownerID string) error {
+	const (
+		workspaceQuery = "INSERT INTO workspaces (id, name, created_on) VALUES ($1, $2, $3)"
+		memberQuery    = "INSERT INTO workspace_members (workspace_id, user_id, role, created_on) VALUES ($1, $2, $3, $4)"
+	)
+
+	var (
+		id        = uuid.NewString()
+		createdOn = time.Now().UTC().Truncate(time.Microsecond)
+	)
+
+	err := storages.RunInTx(ctx, s.db, func(ctx context.Context, _ *sql.Tx) error {
+		stmt, err := s.stmts.Prepare(ctx, workspaceQuery)
+		if err != nil {
+			return err
+		}
+
+		if _, err = stmt.ExecContext(ctx, id, w.Name, createdOn); err != nil {
+			return err
+		}
+
+		stmt, err = s.stmts.Prepare(ctx, memberQuery)
+		if err != nil {
+			return err
+		}
+
+		_, err = stmt.ExecContext(ctx, id, ownerID, data.RoleOwner, createdOn)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	w.ID = id
+	w.CreatedOn = createdOn
+
+	return nil
+}
+
+// FindMembership returns the membership of the user in the workspace.
+//
+// If the user is not a member returns workspaces.ErrNotFound.
+func (s *WorkspacesStorage) FindMembership(ctx context.Context, workspaceID, userID string) (data.Membership, error) {
+	const query = "SELECT workspace_id, user_id, role, created_on FROM workspace_members WHERE workspace_id = $1 AND user_id = $2"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return data.Membership{}, err
+	}
+
+	m, err := scanMembership(stmt.QueryRowContext(ctx, workspaceID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data.Membership{}, workspaces.ErrNotFound
+		}
+
+		return data.Membership{}, err
+	}
+
+	return m, nil
+}
+
+// Memberships returns the memberships of the user, the oldest first.
+// julianday has millisecond precision, the ties are broken by the order
+// of the inserts.
+func (s *WorkspacesStorage) Memberships(ctx context.Context, userID string) ([]data.Membership, error) {
+	const query = "SELECT workspace_id, user_id, role, created_on FROM workspace_members WHERE user_id = $1 ORDER BY julianday(created_on), rowid"
+
+	stmt, err := s.stmts.Prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := stmt.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var mm []data.Membership
+	for rows.Next() {
+		var m data.Membership
+		if m, err = scanMembership(rows); err != nil {
+			break
+		}
+		mm = append(mm, m)
+	}
+
+	if closeErr := rows.Close(); closeErr != nil {
+		return nil, closeErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return mm, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMembership(row scanner) (data.Membership, error) {
+	var (
+		m         data.Membership
+		createdOn storages.SQLiteTime
+	)
+
+	if err := row.Scan(&m.WorkspaceID, &m.UserID, &m.Role, &createdOn); err != nil {
+		return data.Membership{}, err
+	}
+
+	m.CreatedOn = createdOn.Time
+
+	return m, nil
+}
@@ -0,0 +1,25 @@
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/romankravchuk/eldorado/internal/storages/storagetest"
+	userssqlite "github.com/romankravchuk/eldorado/internal/storages/users/sqlite"
+	"github.com/romankravchuk/eldorado/internal/storages/workspaces/sqlite"
+)
+
+func TestConformance(t *testing.T) {
+	db := storagetest.SQLite(t)
+
+	users, err := userssqlite.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workspaces, err := sqlite.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storagetest.Workspaces(t, workspaces, users)
+}
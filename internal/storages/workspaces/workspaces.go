@@ -0,0 +1,23 @@
+package workspaces
+
+import (
+	"context"
+	"errors"
+
+	"github.com/romankravchuk/eldorado/internal/data"
+)
+
+var ErrNotFound = errors.New("the user is not a member of the workspace")
+
+// Storage keeps the workspaces and their members. The tables are not
+// under the row-level security, since they define it.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.20.2 --name Storage
+type Storage interface {
+	// Create creates the workspace with the owner as its first member.
+	Create(ctx context.Context, w *data.Workspace, ownerID string) error
+	// FindMembership returns the membership of the user in the workspace.
+	FindMembership(ctx context.Context, workspaceID, userID string) (data.Membership, error)
+	// Memberships returns the memberships of the user, the oldest first.
+	Memberships(ctx context.Context, userID string) ([]data.Membership, error)
+}